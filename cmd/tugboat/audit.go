@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+func runAudit(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	output, targetNames := parseOutput(args, "csv", "tsv", "ndjson")
+	checkOutputFlags(jsonOutput, "", output)
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.Output = output
+
+	if err := manager.Audit(targetNames, workers); err != nil {
+		if errors.Is(err, repo.ErrDrift) {
+			os.Exit(exitNotClean)
+		}
+		fmt.Fprintf(os.Stderr, "Error auditing repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
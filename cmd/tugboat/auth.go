@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/gitea"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/github"
+)
+
+// githubScopes are requested by auth login: repo access for clone/pull/push
+// and org membership for listing org repos.
+var githubScopes = []string{"repo", "read:org"}
+
+func runAuth(args []string) {
+	if len(args) == 0 || args[0] != "login" {
+		fmt.Fprintln(os.Stderr, "Usage: tugboat auth login PROVIDER [--client-id ID]")
+		os.Exit(exitError)
+	}
+
+	var providerName, clientID string
+	rest := args[1:]
+	for i := 0; i < len(rest); i++ {
+		arg := rest[i]
+		switch {
+		case arg == "--client-id":
+			if i+1 >= len(rest) {
+				fmt.Fprintln(os.Stderr, "Error: --client-id requires a value")
+				os.Exit(exitError)
+			}
+			i++
+			clientID = rest[i]
+		case strings.HasPrefix(arg, "--client-id="):
+			clientID = strings.TrimPrefix(arg, "--client-id=")
+		case providerName == "":
+			providerName = arg
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument %q\n", arg)
+			os.Exit(exitError)
+		}
+	}
+	if providerName == "" {
+		fmt.Fprintln(os.Stderr, "Usage: tugboat auth login PROVIDER [--client-id ID]")
+		os.Exit(exitError)
+	}
+
+	configPath := config.ConfigPath()
+	if configPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: no config file found (run 'tugboat init' first)")
+		os.Exit(exitError)
+	}
+	p, err := config.ReadProvider(configPath, providerName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	// Log in through the provider's proxy and CA like its API requests.
+	transport, err := p.Transport()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: provider %q: %v\n", providerName, err)
+		os.Exit(exitError)
+	}
+
+	var token string
+	switch p.Type {
+	case "github":
+		token, err = githubLogin(transport, p.APIURL, clientID)
+	case "gitea":
+		token, err = giteaLogin(transport, p.APIURL)
+	default:
+		err = fmt.Errorf("%s providers do not support login; create a personal access token and set it as the provider's token", p.Type)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error logging in to %s: %v\n", providerName, err)
+		os.Exit(exitError)
+	}
+
+	if err := config.SetProviderToken(configPath, providerName, token); err != nil {
+		fmt.Fprintf(os.Stderr, "Error storing token: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Stored token for provider %s in %s\n", providerName, configPath)
+}
+
+// githubLogin runs the OAuth device flow. GitHub needs the client ID of an
+// OAuth app with device flow enabled.
+func githubLogin(transport http.RoundTripper, apiURL, clientID string) (string, error) {
+	if clientID == "" {
+		clientID = os.Getenv("TUGBOAT_GITHUB_CLIENT_ID")
+	}
+	if clientID == "" {
+		return "", fmt.Errorf("the device flow needs an OAuth app client ID; pass --client-id or set TUGBOAT_GITHUB_CLIENT_ID")
+	}
+	web := github.WebBase(apiURL)
+	code, err := github.RequestDeviceCode(transport, web, clientID, githubScopes)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Open %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+	fmt.Println("Waiting for approval...")
+	return github.PollAccessToken(transport, web, clientID, code)
+}
+
+// giteaLogin signs in with username and password and mints a token. This is
+// not OAuth: Gitea has no device flow, and its authorization code flow needs
+// an OAuth app registered on every server.
+func giteaLogin(transport http.RoundTripper, apiURL string) (string, error) {
+	if apiURL == "" {
+		return "", fmt.Errorf("provider has no api_url")
+	}
+	in := bufio.NewReader(os.Stdin)
+	fmt.Print("Username: ")
+	username, err := in.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	username = strings.TrimSpace(username)
+	password, err := readPassword(in, "Password: ")
+	if err != nil {
+		return "", err
+	}
+
+	host, _ := os.Hostname()
+	name := fmt.Sprintf("tugboat-%s-%s", host, time.Now().Format("20060102-150405"))
+	return gitea.CreateToken(transport, apiURL, username, password, name, gitea.TokenScopes)
+}
+
+// readPassword prompts on stdout and reads a line from in with terminal echo
+// turned off.
+func readPassword(in *bufio.Reader, prompt string) (string, error) {
+	fmt.Print(prompt)
+	echoOff := exec.Command("stty", "-echo")
+	echoOff.Stdin = os.Stdin
+	if err := echoOff.Run(); err == nil {
+		defer func() {
+			echoOn := exec.Command("stty", "echo")
+			echoOn.Stdin = os.Stdin
+			echoOn.Run()
+			fmt.Println()
+		}()
+	}
+	line, err := in.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
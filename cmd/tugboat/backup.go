@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+const backupUsage = `Usage:
+  tugboat backup DIR [target ...] [--full] [--dry-run] [--json]
+  tugboat backup restore DIR [target ...] [--dry-run] [--json]`
+
+func runBackup(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	output, args := parseOutput(args, "ndjson")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	full, args := parseBoolFlag(args, "--full")
+	restore := len(args) > 0 && args[0] == "restore"
+	if restore {
+		args = args[1:]
+	}
+	if len(args) == 0 || (restore && full) {
+		fmt.Fprintln(os.Stderr, backupUsage)
+		os.Exit(exitError)
+	}
+	dir, targetNames := args[0], args[1:]
+
+	// Backups are made from and restored into local checkouts without the
+	// provider, so no provider clients are built.
+	manager := repo.NewManager(nil, cfg)
+	configureOutput(manager)
+	checkOutputFlags(jsonOutput, "", output)
+	manager.JSON = jsonOutput
+	manager.Output = output
+	manager.DryRun = dryRun
+
+	if restore {
+		err = manager.RestoreBackup(dir, targetNames, workers)
+	} else {
+		err = manager.Backup(dir, targetNames, full, workers)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: backup: %v\n", err)
+		os.Exit(exitError)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+const cacheUsage = `Usage:
+  tugboat cache update [target ...]`
+
+func runCache(args []string) {
+	if len(args) == 0 || args[0] != "update" {
+		fmt.Fprintln(os.Stderr, cacheUsage)
+		os.Exit(exitError)
+	}
+	args = args[1:]
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+
+	// The cache is filled from local clones and their origins, so no
+	// provider clients are built.
+	manager := repo.NewManager(nil, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.DryRun = dryRun
+
+	if err := manager.UpdateCache(args, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating cache: %v\n", err)
+		os.Exit(exitError)
+	}
+}
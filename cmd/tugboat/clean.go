@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+func runClean(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	force, args := parseBoolFlag(args, "--force", "-f")
+	ignored, targetNames := parseBoolFlag(args, "--ignored", "-x")
+
+	// clean only touches local checkouts, so no provider clients are built.
+	manager := repo.NewManager(nil, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.DryRun = dryRun
+
+	var confirm func(int, int) bool
+	if !force {
+		confirm = func(files, repos int) bool {
+			fmt.Fprintf(os.Stderr, "Remove %d untracked paths in %d repos? [y/N] ", files, repos)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			return answer == "y" || answer == "yes"
+		}
+	}
+	if err := manager.Clean(targetNames, ignored, confirm, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error cleaning repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
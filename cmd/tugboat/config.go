@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+)
+
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Fprintln(os.Stderr, "Usage: tugboat config show")
+		os.Exit(exitError)
+	}
+
+	result, err := config.LoadWithMetadata()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	shown := result.Config.Redacted()
+	// Spell out the defaults that are otherwise only applied at run time.
+	if shown.Workers == 0 {
+		shown.Workers = runtime.GOMAXPROCS(0)
+	}
+	if shown.HTTPCache == nil {
+		useCache := true
+		shown.HTTPCache = &useCache
+	}
+	for name, p := range shown.Providers {
+		if p.Options.Sync.FFOnly == nil {
+			ffOnly := true
+			p.Options.Sync.FFOnly = &ffOnly
+		}
+		shown.Providers[name] = p
+	}
+
+	data, err := json.MarshalIndent(shown, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding config: %v\n", err)
+		os.Exit(exitError)
+	}
+	source := result.ConfigPath
+	if result.IsDeprecated {
+		source += " (v1, migrated in memory)"
+	}
+	fmt.Fprintf(os.Stderr, "# Effective config from %s\n", source)
+	fmt.Println(string(data))
+}
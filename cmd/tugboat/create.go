@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+const createUsage = `Usage:
+  tugboat create PROVIDER OWNER/NAME [--private] [--description TEXT] [--default-branch BRANCH] [--foldout TARGET]`
+
+func runCreate(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+
+	var opts remote.CreateOptions
+	opts.Private, args = parseBoolFlag(args, "--private")
+	var foldout string
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--description", "--default-branch", "--foldout":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(exitError)
+			}
+			switch args[i] {
+			case "--description":
+				opts.Description = args[i+1]
+			case "--default-branch":
+				opts.DefaultBranch = args[i+1]
+			case "--foldout":
+				foldout = args[i+1]
+			}
+			i++
+		default:
+			positional = append(positional, args[i])
+		}
+	}
+	if len(positional) != 2 {
+		fmt.Fprintln(os.Stderr, createUsage)
+		os.Exit(exitError)
+	}
+	providerName := positional[0]
+	owner, name, ok := cutLast(positional[1], "/")
+	if !ok || owner == "" || name == "" {
+		fmt.Fprintf(os.Stderr, "Error: expected OWNER/NAME, got %q\n", positional[1])
+		os.Exit(exitError)
+	}
+	if _, ok := cfg.Providers[providerName]; !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown provider %q\n", providerName)
+		os.Exit(exitError)
+	}
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.DryRun = dryRun
+
+	if err := manager.Create(providerName, owner, name, opts, foldout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s/%s: %v\n", owner, name, err)
+		os.Exit(exitError)
+	}
+}
+
+// cutLast splits s around the last sep, so GitLab subgroups stay in the
+// owner part.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+func runDiscover(args []string) {
+	write, args := parseBoolFlag(args, "--write")
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: tugboat discover DIR [--write]")
+		os.Exit(exitError)
+	}
+
+	var existing *config.Config
+	configPath := config.ConfigPath()
+	if configPath != "" {
+		result, err := config.LoadWithMetadata()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(exitError)
+		}
+		existing = result.Config
+	}
+
+	d, err := repo.Discover(args[0], existing)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error discovering repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+	for _, s := range d.Skipped {
+		fmt.Fprintf(os.Stderr, "  [SKIP]  %s\n", s)
+	}
+	if len(d.Targets) == 0 {
+		fmt.Println("No unmanaged repositories found")
+		return
+	}
+
+	if !write {
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding config: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	names := make([]string, 0, len(d.Providers))
+	for name := range d.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if configPath == "" {
+		if configPath, err = writeNewConfig(d); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
+			os.Exit(exitError)
+		}
+	} else {
+		for _, name := range names {
+			if err := config.AppendProvider(configPath, name, d.Providers[name]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error adding provider %s: %v\n", name, err)
+				os.Exit(exitError)
+			}
+		}
+		for _, t := range d.Targets {
+			if err := config.AppendTarget(configPath, t); err != nil {
+				fmt.Fprintf(os.Stderr, "Error adding target %s: %v\n", t.Name, err)
+				os.Exit(exitError)
+			}
+		}
+	}
+	fmt.Printf("Added %d providers and %d targets to %s\n", len(d.Providers), len(d.Targets), configPath)
+	for _, name := range names {
+		fmt.Printf("  Provider %s has no token yet: run 'tugboat auth login %s' or edit the config\n", name, name)
+	}
+}
+
+// writeNewConfig creates a config file at the default location holding the
+// discovered providers and targets.
+func writeNewConfig(d *repo.Discovery) (string, error) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0600)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+const foldoutUsage = `Usage:
+  tugboat foldout init [DIR] [--from PROVIDER/OWNER [--topic T]...] [--force] [--dry-run]`
+
+func runFoldout(args []string) {
+	if len(args) == 0 || args[0] != "init" {
+		fmt.Fprintln(os.Stderr, foldoutUsage)
+		os.Exit(exitError)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	dryRun, args := parseBoolFlag(args[1:], "--dry-run", "-n")
+
+	var opts repo.FoldoutInitOptions
+	opts.Force, args = parseBoolFlag(args, "--force", "-f")
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--from", "--topic":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(exitError)
+			}
+			if args[i] == "--from" {
+				opts.From = args[i+1]
+			} else {
+				opts.Topics = append(opts.Topics, args[i+1])
+			}
+			i++
+		default:
+			positional = append(positional, args[i])
+		}
+	}
+	if len(positional) > 1 || (len(opts.Topics) > 0 && opts.From == "") {
+		fmt.Fprintln(os.Stderr, foldoutUsage)
+		os.Exit(exitError)
+	}
+	dir := "."
+	if len(positional) == 1 {
+		dir = positional[0]
+	}
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.DryRun = dryRun
+
+	if err := manager.InitFoldout(dir, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing foldout file: %v\n", err)
+		os.Exit(exitError)
+	}
+}
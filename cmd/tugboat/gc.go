@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+func runGC(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	output, args := parseOutput(args, "ndjson")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+
+	var tasks, targetNames []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--task":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Error: --task requires a value")
+				os.Exit(exitError)
+			}
+			i++
+			if !config.ValidGCTask(args[i]) {
+				fmt.Fprintf(os.Stderr, "Error: unsupported task %q (want gc, commit-graph, prefetch, loose-objects, incremental-repack or pack-refs)\n", args[i])
+				os.Exit(exitError)
+			}
+			tasks = append(tasks, args[i])
+		default:
+			targetNames = append(targetNames, args[i])
+		}
+	}
+	if len(tasks) == 0 {
+		tasks = cfg.GCTasks
+	}
+	for _, task := range tasks {
+		if task == "prefetch" && offline {
+			fmt.Fprintln(os.Stderr, "Error: the prefetch task needs the network and cannot run with --offline")
+			os.Exit(exitError)
+		}
+	}
+
+	// Maintenance runs in local checkouts, so no provider clients are built.
+	manager := repo.NewManager(nil, cfg)
+	configureOutput(manager)
+	checkOutputFlags(jsonOutput, "", output)
+	manager.JSON = jsonOutput
+	manager.Output = output
+	manager.DryRun = dryRun
+
+	if err := manager.GC(targetNames, tasks, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error maintaining repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
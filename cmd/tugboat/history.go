@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/state"
+)
+
+const historyUsage = `Usage:
+  tugboat history [--limit N] [--json]
+  tugboat history show ID [--json]`
+
+// defaultHistoryLimit is how many runs history lists without --limit.
+const defaultHistoryLimit = 20
+
+func runHistory(args []string) {
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	limits, args, err := parseRepeatedFlag(args, "--limit")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	limit := defaultHistoryLimit
+	if len(limits) > 0 {
+		if limit, err = strconv.Atoi(limits[len(limits)-1]); err != nil || limit <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: --limit must be a positive number\n")
+			os.Exit(exitError)
+		}
+	}
+
+	store := openState()
+	if store == nil {
+		os.Exit(exitError)
+	}
+	runs, err := store.Runs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	switch {
+	case len(args) == 0:
+		// Newest first.
+		var listed []state.Run
+		for i := len(runs) - 1; i >= 0 && len(listed) < limit; i-- {
+			listed = append(listed, runs[i])
+		}
+		if jsonOutput {
+			if listed == nil {
+				listed = []state.Run{}
+			}
+			printHistoryJSON(listed)
+			return
+		}
+		if len(listed) == 0 {
+			fmt.Println("No runs recorded yet.")
+			return
+		}
+		for _, run := range listed {
+			fmt.Printf("%4d  %s  %-5s %8s  %s\n", run.ID, run.Started.Local().Format("2006-01-02 15:04"), run.Command, formatRunDuration(run.Duration()), runSummary(run))
+		}
+	case args[0] == "show" && len(args) == 2:
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: history show: invalid run ID %q\n", args[1])
+			os.Exit(exitError)
+		}
+		i := slices.IndexFunc(runs, func(run state.Run) bool { return run.ID == id })
+		if i < 0 {
+			fmt.Fprintf(os.Stderr, "Error: history show: no run %d\n", id)
+			os.Exit(exitError)
+		}
+		run := runs[i]
+		if jsonOutput {
+			printHistoryJSON(run)
+			return
+		}
+		targets := "all targets"
+		if len(run.Targets) > 0 {
+			targets = strings.Join(run.Targets, ", ")
+		}
+		fmt.Printf("Run %d: %s of %s\n", run.ID, run.Command, targets)
+		fmt.Printf("Started:  %s\n", run.Started.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("Duration: %s\n", formatRunDuration(run.Duration()))
+		if run.Error != "" {
+			fmt.Printf("Error:    %s\n", run.Error)
+		}
+		for _, r := range run.Repos {
+			line := fmt.Sprintf("  [%s] %s", strings.ToUpper(r.Result), r.Path)
+			if r.Message != "" {
+				line += ": " + r.Message
+			}
+			fmt.Println(line)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, historyUsage)
+		os.Exit(exitError)
+	}
+}
+
+func printHistoryJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// runSummary counts a run's repos by result, e.g. "3 pulled, 1 failed",
+// most common first.
+func runSummary(run state.Run) string {
+	counts := run.Counts()
+	results := make([]string, 0, len(counts))
+	for result := range counts {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if counts[results[i]] != counts[results[j]] {
+			return counts[results[i]] > counts[results[j]]
+		}
+		return results[i] < results[j]
+	})
+	parts := make([]string, 0, len(results)+1)
+	for _, result := range results {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[result], result))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no repos")
+	}
+	if run.Error != "" && counts["failed"] == 0 {
+		parts = append(parts, "error: "+run.Error)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatRunDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
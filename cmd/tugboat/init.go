@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+)
+
+// defaultAPIURLs are offered by init for each provider type; gitea has no
+// public default.
+var defaultAPIURLs = map[string]string{
+	"github": "https://api.github.com",
+	"gitlab": "https://gitlab.com",
+}
+
+func runInit(args []string) {
+	force, args := parseBoolFlag(args, "-f", "--force")
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: tugboat init [--force]")
+		os.Exit(exitError)
+	}
+
+	path := config.ConfigPath()
+	if path == "" {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use --force to overwrite)\n", path)
+		os.Exit(exitError)
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	cfg, err := initWizard(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	data, err := cfg.ToJSON()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating config: %v\n", err)
+		os.Exit(exitError)
+	}
+	if _, err := config.ReadV2(data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: generated config is invalid: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", filepath.Dir(path), err)
+		os.Exit(exitError)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("\nWrote %s. Run 'tugboat clone' to clone your targets.\n", path)
+}
+
+// initWizard asks for providers and their orgs or users until the user is
+// done, and returns the resulting config.
+func initWizard(in *bufio.Reader) (*config.Config, error) {
+	cfg := &config.Config{Providers: make(map[string]config.Provider)}
+	for {
+		name, p, err := askProvider(in, cfg)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Providers[name] = p
+
+		owners, err := ask(in, "Orgs or users to manage (space-separated)", "")
+		if err != nil {
+			return nil, err
+		}
+		for _, owner := range strings.Fields(owners) {
+			kind, err := askChoice(in, fmt.Sprintf("Is %s an org or a user", owner), []string{"org", "user"}, "org")
+			if err != nil {
+				return nil, err
+			}
+			dir, err := ask(in, fmt.Sprintf("Local path for %s", owner), "~/src/"+owner)
+			if err != nil {
+				return nil, err
+			}
+			t := config.Target{Provider: name, Path: dir}
+			if kind == "user" {
+				t.User = owner
+			} else {
+				t.Org = owner
+			}
+			if cfg.GetTargetByName(owner) != nil {
+				t.Name = name + "-" + owner
+			}
+			cfg.Targets = append(cfg.Targets, t)
+		}
+
+		more, err := askChoice(in, "Add another provider", []string{"y", "n"}, "n")
+		if err != nil {
+			return nil, err
+		}
+		if more == "n" {
+			break
+		}
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("no orgs or users entered; nothing to manage")
+	}
+	return cfg, nil
+}
+
+func askProvider(in *bufio.Reader, cfg *config.Config) (string, config.Provider, error) {
+	var p config.Provider
+	var err error
+	if p.Type, err = askChoice(in, "Provider type", []string{"github", "gitea", "gitlab"}, "github"); err != nil {
+		return "", p, err
+	}
+	defaultName := p.Type
+	for i := 2; cfg.Providers[defaultName].Type != ""; i++ {
+		defaultName = fmt.Sprintf("%s-%d", p.Type, i)
+	}
+	name, err := ask(in, "Provider name", defaultName)
+	if err != nil {
+		return "", p, err
+	}
+	if _, taken := cfg.Providers[name]; taken {
+		return "", p, fmt.Errorf("provider %q entered twice", name)
+	}
+	for p.APIURL == "" {
+		if p.APIURL, err = ask(in, "API URL", defaultAPIURLs[p.Type]); err != nil {
+			return "", p, err
+		}
+	}
+	for p.Token == "" && p.TokenCmd == "" {
+		if p.Token, err = readPassword(in, "Token (input hidden; leave empty to use a command): "); err != nil {
+			return "", p, err
+		}
+		if p.Token == "" {
+			if p.TokenCmd, err = ask(in, "Command that prints the token", ""); err != nil {
+				return "", p, err
+			}
+		}
+	}
+	return name, p, nil
+}
+
+// ask prompts with an optional default and returns the trimmed answer.
+func ask(in *bufio.Reader, prompt, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", prompt, def)
+	} else {
+		fmt.Printf("%s: ", prompt)
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading answer: %w", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// askChoice asks until the answer is one of choices.
+func askChoice(in *bufio.Reader, prompt string, choices []string, def string) (string, error) {
+	for {
+		answer, err := ask(in, fmt.Sprintf("%s (%s)", prompt, strings.Join(choices, "/")), def)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range choices {
+			if strings.EqualFold(answer, c) {
+				return c, nil
+			}
+		}
+		fmt.Printf("Please answer one of: %s\n", strings.Join(choices, ", "))
+	}
+}
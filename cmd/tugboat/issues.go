@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+func runIssues(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	output, args := parseOutput(args, "csv", "tsv", "ndjson")
+	checkOutputFlags(jsonOutput, "", output)
+
+	var filter remote.IssueFilter
+	var targetNames []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--label", "--assignee":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(exitError)
+			}
+			if args[i] == "--label" {
+				filter.Labels = append(filter.Labels, args[i+1])
+			} else {
+				filter.Assignee = args[i+1]
+			}
+			i++
+		default:
+			targetNames = append(targetNames, args[i])
+		}
+	}
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.Output = output
+
+	if err := manager.Issues(targetNames, filter, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error counting issues: %v\n", err)
+		os.Exit(exitError)
+	}
+}
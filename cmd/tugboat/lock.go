@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+// parseLockFile removes --lockfile PATH from args and returns the path,
+// repo.DefaultLockFile when the flag is absent.
+func parseLockFile(args []string) (string, []string) {
+	values, args, err := parseRepeatedFlag(args, "--lockfile")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if len(values) == 0 {
+		return repo.DefaultLockFile, args
+	}
+	return values[len(values)-1], args
+}
+
+func runLock(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	path, targetNames := parseLockFile(args)
+
+	// Locking reads local checkouts, so no provider clients are built.
+	manager := repo.NewManager(nil, cfg)
+	configureOutput(manager)
+	manager.DryRun = dryRun
+
+	if err := manager.Lock(path, targetNames, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error locking repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// runRestore serves both `tugboat restore` and `tugboat sync --locked`.
+func runRestore(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	output, args := parseOutput(args, "ndjson")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	path, targetNames := parseLockFile(args)
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	checkOutputFlags(jsonOutput, "", output)
+	manager.JSON = jsonOutput
+	manager.Output = output
+	manager.DryRun = dryRun
+
+	if err := manager.Restore(path, targetNames, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
@@ -1,13 +1,24 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"text/template"
+	"time"
 
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/state"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/tui"
 )
 
 // parseWorkers extracts the --workers/-w flag value from args.
@@ -39,6 +50,85 @@ func parseWorkers(args []string) (int, []string) {
 	return workers, remaining
 }
 
+// parseFormat parses the --format template of status and list; it returns
+// nil when none was given.
+func parseFormat(text string) *template.Template {
+	if text == "" {
+		return nil
+	}
+	tmpl, err := repo.ParseFormat(text)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --format template: %v\n", err)
+		os.Exit(exitError)
+	}
+	return tmpl
+}
+
+// parseOutput removes --output FORMAT (or --output=FORMAT) from args and
+// returns the format, or "" when it is absent. Formats other than allowed are
+// an error.
+func parseOutput(args []string, allowed ...string) (string, []string) {
+	var remaining []string
+	output := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--output":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --output requires a format (%s)\n", strings.Join(allowed, ", "))
+				os.Exit(exitError)
+			}
+			i++
+			output = args[i]
+		case strings.HasPrefix(args[i], "--output="):
+			output = strings.TrimPrefix(args[i], "--output=")
+		default:
+			remaining = append(remaining, args[i])
+		}
+	}
+	if output != "" && !slices.Contains(allowed, output) {
+		fmt.Fprintf(os.Stderr, "Error: unsupported --output %q (want %s)\n", output, strings.Join(allowed, " or "))
+		os.Exit(exitError)
+	}
+	return output, remaining
+}
+
+// checkOutputFlags exits when more than one of --json, --format and --output
+// was given.
+func checkOutputFlags(jsonOutput bool, format, output string) {
+	n := 0
+	for _, set := range []bool{jsonOutput, format != "", output != ""} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		fmt.Fprintln(os.Stderr, "Error: --json, --format and --output cannot be combined")
+		os.Exit(exitError)
+	}
+}
+
+// parseBoolFlag removes every occurrence of the given flag names from args.
+// Returns whether any of them was present and the remaining args.
+func parseBoolFlag(args []string, names ...string) (bool, []string) {
+	var remaining []string
+	found := false
+	for _, arg := range args {
+		matched := false
+		for _, name := range names {
+			if arg == name {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			found = true
+		} else {
+			remaining = append(remaining, arg)
+		}
+	}
+	return found, remaining
+}
+
 // resolveWorkers returns CLI workers if set, otherwise config workers (0 = use CPU count)
 func resolveWorkers(cliWorkers int, cfg *config.Config) int {
 	if cliWorkers > 0 {
@@ -49,6 +139,142 @@ func resolveWorkers(cliWorkers int, cfg *config.Config) int {
 
 var version = "dev"
 
+// Exit codes. Only status uses exitNotClean; any command exits with exitError
+// when it cannot run or when one of its repos failed.
+const (
+	exitNotClean = 1
+	exitError    = 2
+)
+
+// Settings shared by every command, set by main from the global flags and
+// environment.
+var (
+	colorOutput    bool
+	logLevel       slog.Level
+	logger         *slog.Logger
+	offline        bool
+	ordered        bool
+	deadline       time.Time
+	tags           []string
+	excludeTargets []string
+)
+
+// configureOutput applies the global settings to a manager.
+func configureOutput(m *repo.Manager) {
+	m.Color = colorOutput
+	m.LogLevel = logLevel
+	m.Logger = logger
+	m.Offline = offline
+	m.Ordered = ordered
+	m.Deadline = deadline
+	m.TargetTags = tags
+	m.ExcludeTargets = excludeTargets
+	m.State = openState()
+}
+
+var stateStore *state.Store
+
+// openState opens the state store once per run. Without one, tugboat works
+// as before but cannot tell when repos were last synced.
+func openState() *state.Store {
+	if stateStore != nil {
+		return stateStore
+	}
+	path, err := state.DefaultPath()
+	if err == nil {
+		stateStore, err = state.Open(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: state store unavailable: %v\n", err)
+	}
+	return stateStore
+}
+
+// offlineCommands work from local checkouts alone and so accept --offline.
+var offlineCommands = map[string]bool{
+	"status": true, "st": true, "branch": true, "br": true, "checkout": true, "co": true,
+	"grep": true, "tag": true, "watch": true, "ui": true, "migrate": true, "target": true, "config": true,
+	"worktree": true, "gc": true, "clean": true, "reset": true, "lock": true, "snapshot": true, "history": true,
+	"backup": true, "export": true,
+}
+
+// parseLogging removes -q/--quiet, -v/--verbose and --log-format from args and
+// sets logLevel and logger accordingly.
+func parseLogging(args []string) ([]string, error) {
+	quiet, args := parseBoolFlag(args, "-q", "--quiet")
+	verbose, args := parseBoolFlag(args, "-v", "--verbose")
+	if quiet && verbose {
+		return nil, fmt.Errorf("--quiet and --verbose cannot be combined")
+	}
+	switch {
+	case quiet:
+		logLevel = slog.LevelWarn
+	case verbose:
+		logLevel = slog.LevelDebug
+	}
+
+	var format string
+	var remaining []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--log-format":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("--log-format requires json or text")
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(arg, "--log-format="):
+			format = strings.TrimPrefix(arg, "--log-format=")
+		default:
+			remaining = append(remaining, arg)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	switch format {
+	case "":
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	case "text":
+		logger = slog.New(slog.NewTextHandler(os.Stderr, opts))
+	default:
+		return nil, fmt.Errorf("invalid --log-format %q (want json or text)", format)
+	}
+	return remaining, nil
+}
+
+// parseRepeatedFlag removes every `flag VALUE` and `flag=VALUE` from args
+// and returns the values.
+func parseRepeatedFlag(args []string, flag string) ([]string, []string, error) {
+	var values, remaining []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == flag:
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("%s requires a value", flag)
+			}
+			i++
+			values = append(values, args[i])
+		case strings.HasPrefix(arg, flag+"="):
+			values = append(values, strings.TrimPrefix(arg, flag+"="))
+		default:
+			remaining = append(remaining, arg)
+		}
+	}
+	return values, remaining, nil
+}
+
+// stdoutSupportsColor reports whether stdout is a terminal that should get
+// ANSI colors. A non-empty NO_COLOR and TERM=dumb turn colors off.
+func stdoutSupportsColor() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		printHelp()
@@ -56,22 +282,119 @@ func main() {
 	}
 
 	cmd := os.Args[1]
+	noColor, args := parseBoolFlag(os.Args[2:], "--no-color")
+	colorOutput = !noColor && stdoutSupportsColor()
+	args, err := parseLogging(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	tags, args, err = parseRepeatedFlag(args, "--tag")
+	if err == nil {
+		excludeTargets, args, err = parseRepeatedFlag(args, "--exclude")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	offline, args = parseBoolFlag(args, "--offline")
+	ordered, args = parseBoolFlag(args, "--ordered")
+	if deadline, args, err = parseTimeout(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if offline && !offlineCommands[cmd] {
+		fmt.Fprintf(os.Stderr, "Error: %s needs the network and cannot run with --offline\n", cmd)
+		os.Exit(exitError)
+	}
 
 	switch cmd {
 	case "clone", "c":
-		runClone(os.Args[2:])
+		runClone(args)
 	case "sync", "s":
-		runSync(os.Args[2:])
+		runSync(args)
 	case "status", "st":
-		runStatus(os.Args[2:])
+		runStatus(args)
 	case "list", "ls":
-		runList(os.Args[2:])
+		runList(args)
 	case "pull":
-		runPull(os.Args[2:])
+		runPull(args)
 	case "push":
-		runPush(os.Args[2:])
+		runPush(args)
+	case "fork-sync":
+		runForkSync(args)
+	case "unshallow":
+		runUnshallow(args)
+	case "cache":
+		runCache(args)
+	case "gc":
+		runGC(args)
+	case "clean":
+		runClean(args)
+	case "reset":
+		runReset(args)
+	case "lock":
+		runLock(args)
+	case "restore":
+		runRestore(args)
+	case "snapshot":
+		runSnapshot(args)
+	case "backup":
+		runBackup(args)
+	case "export":
+		runExport(args)
+	case "apply":
+		runApply(args)
+	case "history":
+		runHistory(args)
+	case "query":
+		runQuery(args)
+	case "branch", "br":
+		runBranch(args)
+	case "checkout", "co":
+		runCheckout(args)
+	case "switch-default":
+		runSwitchDefault(args)
+	case "worktree":
+		runWorktree(args)
+	case "tag":
+		runTag(args)
+	case "grep":
+		runGrep(args)
+	case "pr":
+		runPR(args)
+	case "issues":
+		runIssues(args)
+	case "audit":
+		runAudit(args)
+	case "watch":
+		runWatch(args)
+	case "serve":
+		runServe(args)
+	case "create":
+		runCreate(args)
+	case "prune":
+		runPrune(args)
+	case "adopt":
+		runAdopt(args)
+	case "foldout":
+		runFoldout(args)
 	case "migrate":
-		runMigrate(os.Args[2:])
+		runMigrate(args)
+	case "migrate-repos":
+		runMigrateRepos(args)
+	case "auth":
+		runAuth(args)
+	case "discover":
+		runDiscover(args)
+	case "init":
+		runInit(args)
+	case "target":
+		runTarget(args)
+	case "config":
+		runConfig(args)
+	case "ui":
+		runUI(args)
 	case "help", "-h", "--help":
 		printHelp()
 	case "version", "-v", "--version":
@@ -79,29 +402,117 @@ func main() {
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", cmd)
 		printHelp()
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
 
 func printHelp() {
-	help := `tugboat - Multi-repository management tool for Gitea and GitHub (repo-centric)
+	help := `tugboat - Multi-repository management tool for Gitea, GitHub, and GitLab (repo-centric)
 
 Usage: tugboat <command> [options]
 
 Commands:
-  clone, c      Clone targets (org or repo); -E/--exclude-empty, -a/--include-archived
-  sync, s       Sync targets (ff-only)
-  status, st    Show status for targets (foldouts included)
-  list, ls      List targets (local vs remote); -a/--include-archived
+  clone, c      Clone targets (org or repo); -E/--exclude-empty, -a/--include-archived, --mirror,
+                --allow-hooks (run the post_clone hooks of every repo's .tugboat.json),
+                --retry-failed (clone only the repos that failed in the last clone run),
+                --include-templates (template repos are left out by default),
+                --exclude-forks/--only-forks, --only-public/--only-private, --language NAME (override
+                the targets' forks, visibility and languages settings; --language may repeat)
+  sync, s       Sync targets (ff-only); --locked checks out the commits of tugboat.lock instead (see restore);
+                --allow-hooks runs the post_sync hooks of every repo's .tugboat.json
+  status, st    Show status for targets (foldouts included); --no-fetch/--fast uses the last fetched refs;
+                --detail lists changed files, --all-branches flags unpushed commits on any local branch;
+                --sort name|target|behind|ahead|mtime; --format TEMPLATE renders each repo (Go template);
+                --problems shows only repos needing attention plus the summary
+  list, ls      List targets (local vs remote); -a/--include-archived, --exclude-forks/--only-forks,
+                --only-public/--only-private, --language NAME, --exclude-templates, --format TEMPLATE
   pull          Update targets on their default branch (ff-only)
-  push          Push targets
+  push          Push targets; --force-with-lease for diverged repos of allow_force targets
+  fork-sync     Fast-forward forks' default branches from upstream and push to origin; --api uses the provider's sync-fork endpoint
+  grep PATTERN  Search tracked files of all local repos (git grep); -i, -w, -F, -E
+  tag list|create NAME
+                List tags, or tag HEAD of each repo; -m MESSAGE, --sign, --push
+  pr list|create
+                List open PRs (author, age, review state), or open one from each ahead feature branch; --title, --body, --branch, --draft
+  issues        Count open issues per repo, most first; --label L (repeatable), --assignee USER
+  audit         Compare remote repo settings with the config's audit policy; exits 1 on drift
+  unshallow     Fetch full history for shallow clones
+  gc            Run git gc --auto in every repo, or git maintenance tasks: --task T (repeatable) or config gc_tasks
+  clean         List untracked files per repo and remove them after confirmation; -f/--force, -x/--ignored
+  reset         Hard-reset repos onto origin's default branch after confirmation; -y/--yes, --dirty-too
+  lock          Record the HEAD commit of every repo in tugboat.lock; --lockfile PATH
+  restore       Check out the commits recorded in tugboat.lock (fetching missing ones); --lockfile PATH
+  snapshot save|restore|list|delete NAME
+                Save each repo's branch and commit (--dirty: and uncommitted changes) under the config
+                dir, or put them all back; changes made since are stashed first
+  backup [restore] DIR [target ...]
+                Write a git bundle of every repo into DIR with a manifest, incrementally after the first
+                run (--full starts over), or recreate missing repos from DIR without the provider
+  export        Print the workspace as JSON: providers without tokens, targets and every repo's commit
+  apply FILE    Reproduce an exported workspace: add its providers (asking for tokens) and targets to the
+                config, clone the repos and check out the exported commits
+  query [target ...]
+                Answer line queries on stdin for editor plugins, one JSON line each: roots, repo PATH,
+                status PATH (no fetch), reload, quit
+  history [show ID]
+                List recent clone/pull/push/sync runs with per-result counts and durations, or one run's
+                per-repo outcomes; --limit N (default 20)
+  cache update  Add local repos to their clone reference_dir and fetch the cached repos
+  branch, br    Show each repo's branch and local branches with unpushed commits
+  checkout, co BRANCH
+                Switch repos to BRANCH (tracking origin/BRANCH if needed); skips dirty repos
+  switch-default
+                Move clean repos onto a renamed remote default branch (e.g. master -> main)
+  worktree add REPO BRANCH
+                Create a linked worktree of REPO on BRANCH under worktree_dir and print its path
+  create PROVIDER OWNER/NAME
+                Create a repo on the provider and clone it; --private, --description, --default-branch, --foldout TARGET
+  adopt PATH... Record stray local clones as foldouts or new repo targets
+  foldout init [DIR]
+                Write DIR/.tugboat.json listing the clones below DIR, or the repos of
+                --from PROVIDER/OWNER (--topic T to filter); --force replaces an existing file
+  prune         Delete (or --move-to DIR) local repos removed from the remote; -y/--yes, --force
   migrate       Migrate config from v1 to v2 format
+  migrate-repos --from P:OWNER --to P:OWNER
+                Copy every repo of OWNER to another provider; --include GLOB, --rename OLD=NEW
+  init          Create a config interactively; --force overwrites an existing one
+  discover DIR  Propose providers/targets for existing checkouts under DIR; --write adds them to the config
+  target add|remove|rename
+                Edit targets in the config file (previous version kept as .bak)
+  config show   Print the effective config (defaults and includes applied, tokens masked)
+  auth login P  Store a token for provider P: GitHub uses the OAuth device flow; Gitea asks
+                for your username and password once and creates an access token (not OAuth)
+  watch         Re-check status every --interval (default 15m), logging changes; --sync also syncs
+  serve         Pull repos when Gitea/GitHub push webhooks arrive; --listen ADDR (default :8080);
+                --api ADDR also serves status, list and sync as JSON over HTTP (token auth)
+  ui            Interactive dashboard; --refresh DURATION (default 30s, 0 disables)
   help          Show this help message
   version       Show version information
 
 Global Options:
   -w, --workers N   Number of parallel workers (default: config "workers" or CPU cores)
   -d, --debug       Show timing information (status command only)
+  --json            Emit results as JSON (status, list, branch, checkout, switch-default, tag, grep, pr list, pr create, issues, audit, pull, push, sync, fork-sync, unshallow, gc, clean, reset, restore, snapshot, backup, history, cache update, migrate-repos)
+  --output F        Write csv or tsv rows for spreadsheets (status, list, branch, issues, audit), or ndjson:
+                    one JSON object per line, streamed per repo by clone, pull, push, sync, fork-sync,
+                    unshallow, gc, reset and restore
+  -n, --dry-run     Show what clone/pull/push/sync/fork-sync/unshallow/gc/clean/reset/lock/restore/snapshot/backup/cache update/checkout/switch-default/worktree add/tag create/pr create/create/migrate-repos/prune/adopt/foldout init would do without changing anything
+  --tag TAG         Only act on targets tagged TAG (repeatable; any of the tags matches)
+  --exclude TARGET  Leave out a target or group (repeatable), e.g. sync everything but a monorepo
+  --offline         Skip provider API calls and git fetches; status and other local commands use the last fetched refs
+  --timeout D       Stop the whole run after D (e.g. 10m): clone, pull, push and sync skip the repos they
+                    have not started, stop running git commands and exit 2
+  --ordered         Print per-repo lines and ndjson results in repo order rather than as repos finish,
+                    so runs can be diffed (clone, push, sync, unshallow, gc, reset, restore)
+  --no-color        Disable colored output (also NO_COLOR env; off when stdout is not a terminal)
+  -q, --quiet       Only print warnings and errors (status and list tables are still shown)
+  -v, --verbose     Also print repos that needed nothing
+  --log-format F    Write progress as structured json or text records to stderr
+
+Exit codes:
+  0  success (status: every repo clean)
+  1  status found dirty, ahead or behind repos (or unpushed branches with --all-branches); grep found no match; audit found drift
+  2  error, or any repo failed (including failed fetches in status)
 
 Configuration:
   tugboat reads from ~/.config/tugboat/config.json or TUGBOAT_CONFIG env var
@@ -110,23 +521,30 @@ Configuration:
   {
     "providers": {
       "gitea":  {"type": "gitea",  "api_url": "https://gitea.acme.com", "token": "gitea-token"},
-      "github": {"type": "github", "api_url": "https://api.github.com", "token": "ghp_your_token"}
+      "github": {"type": "github", "api_url": "https://api.github.com", "token": "ghp_your_token"},
+      "gitlab": {"type": "gitlab", "api_url": "https://gitlab.com", "token": "glpat-your_token"}
     },
     "targets": [
       { "provider": "gitea",  "org": "acme-rideshare", "path": "~/acme/rideshare", "name": "rideshare" },
       { "provider": "gitea",  "org": "acme-infra",     "path": "~/acme/infra",     "name": "infra" },
       { "provider": "github", "org": "acme",           "repo": "mobile-app",       "path": "~/acme/mobile-app", "name": "mobile-app" }
-    ]
+    ],
+    "groups": { "work": ["rideshare", "infra"] }
   }
 
+  Commands that take target names also take group names, globs ('acme-*')
+  and regexps between slashes ('/^(api|web)-/').
   You can also set GITEA_TOKEN environment variable.
 
 Examples:
   tugboat clone          # Clone all repos from configured orgs
   tugboat sync           # Sync default branches safely
+  tugboat sync --dry-run # Show what sync would do
   tugboat status         # Show which repos have changes
   tugboat status -w 16   # Use 16 parallel workers
   tugboat list           # List all managed repos
+  tugboat status --json | jq '.[] | select(.dirty)'
+  tugboat ui --refresh 1m  # Dashboard that reloads every minute
 `
 	fmt.Print(help)
 }
@@ -135,13 +553,26 @@ func runClone(args []string) {
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	cliWorkers, args := parseWorkers(args)
 	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	allowHooks, args := parseBoolFlag(args, "--allow-hooks")
+	retryFailed, args := parseBoolFlag(args, "--retry-failed")
+	// Template repos are left out of clones unless asked for.
+	includeTemplates, args := parseBoolFlag(args, "--include-templates")
+	_, args = parseBoolFlag(args, "--exclude-templates")
+	filters, args, err := parseRepoFilters(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	output, args := parseOutput(args, "ndjson")
 	excludeEmpty := false
 	includeArchived := false
+	mirror := false
 	var targetNames []string
 	for _, arg := range args {
 		switch arg {
@@ -149,6 +580,8 @@ func runClone(args []string) {
 			excludeEmpty = true
 		case "--include-archived", "-a":
 			includeArchived = true
+		case "--mirror":
+			mirror = true
 		default:
 			targetNames = append(targetNames, arg)
 		}
@@ -157,36 +590,70 @@ func runClone(args []string) {
 	clients, err := cfg.BuildRemoteClients()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.DryRun = dryRun
+	manager.Output = output
+	manager.Mirror = mirror
+	manager.AllowRepoHooks = allowHooks
+	filters.apply(manager)
+	manager.ExcludeTemplates = !includeTemplates
 
-	if err := manager.Clone(targetNames, excludeEmpty, includeArchived, workers); err != nil {
+	clone := manager.Clone
+	if retryFailed {
+		clone = manager.RetryFailedClones
+	}
+	if err := clone(targetNames, excludeEmpty, includeArchived, workers); err != nil {
 		fmt.Fprintf(os.Stderr, "Error cloning repositories: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
 
 func runSync(args []string) {
+	if locked, rest := parseBoolFlag(args, "--locked"); locked {
+		runRestore(rest)
+		return
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	cliWorkers, args := parseWorkers(args)
 	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	allowHooks, args := parseBoolFlag(args, "--allow-hooks")
+	output, args := parseOutput(args, "ndjson")
+	jsonOutput := false
+	var targetNames []string
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			jsonOutput = true
+		default:
+			targetNames = append(targetNames, arg)
+		}
+	}
 
 	clients, err := cfg.BuildRemoteClients()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	checkOutputFlags(jsonOutput, "", output)
+	manager.JSON = jsonOutput
+	manager.Output = output
+	manager.DryRun = dryRun
+	manager.AllowRepoHooks = allowHooks
 
-	if err := manager.Sync(args, workers); err != nil {
+	if err := manager.Sync(targetNames, workers); err != nil {
 		fmt.Fprintf(os.Stderr, "Error syncing repositories: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
 
@@ -194,17 +661,53 @@ func runStatus(args []string) {
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	cliWorkers, args := parseWorkers(args)
 	workers := resolveWorkers(cliWorkers, cfg)
 	debug := false
+	jsonOutput := false
+	noFetch := false
+	detail := false
+	allBranches := false
+	problems := false
+	sortBy := ""
+	format := ""
+	output, args := parseOutput(args, "csv", "tsv", "ndjson")
 	var targetNames []string
-	for _, arg := range args {
-		switch arg {
-		case "--debug", "-d":
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--debug" || arg == "-d":
 			debug = true
+		case arg == "--detail":
+			detail = true
+		case arg == "--all-branches":
+			allBranches = true
+		case arg == "--problems":
+			problems = true
+		case arg == "--json":
+			jsonOutput = true
+		case arg == "--no-fetch" || arg == "--fast":
+			noFetch = true
+		case arg == "--sort":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Error: --sort requires a value")
+				os.Exit(exitError)
+			}
+			i++
+			sortBy = args[i]
+		case strings.HasPrefix(arg, "--sort="):
+			sortBy = strings.TrimPrefix(arg, "--sort=")
+		case arg == "--format":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Error: --format requires a template")
+				os.Exit(exitError)
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
 		default:
 			targetNames = append(targetNames, arg)
 		}
@@ -213,13 +716,26 @@ func runStatus(args []string) {
 	clients, err := cfg.BuildRemoteClients()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.NoFetch = noFetch
+	manager.Detail = detail
+	manager.AllBranches = allBranches
+	manager.Sort = sortBy
+	manager.ProblemsOnly = problems
+	checkOutputFlags(jsonOutput, format, output)
+	manager.Format = parseFormat(format)
+	manager.Output = output
 
 	if err := manager.Status(targetNames, debug, workers); err != nil {
+		if errors.Is(err, repo.ErrNotClean) {
+			os.Exit(exitNotClean)
+		}
 		fmt.Fprintf(os.Stderr, "Error showing status: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
 
@@ -227,17 +743,37 @@ func runList(args []string) {
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	cliWorkers, args := parseWorkers(args)
 	workers := resolveWorkers(cliWorkers, cfg)
+	filters, args, err := parseRepoFilters(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	excludeTemplates, args := parseBoolFlag(args, "--exclude-templates")
 	includeArchived := false
+	jsonOutput := false
+	format := ""
+	output, args := parseOutput(args, "csv", "tsv", "ndjson")
 	var targetNames []string
-	for _, arg := range args {
-		switch arg {
-		case "--include-archived", "-a":
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--include-archived" || arg == "-a":
 			includeArchived = true
+		case arg == "--json":
+			jsonOutput = true
+		case arg == "--format":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Error: --format requires a template")
+				os.Exit(exitError)
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
 		default:
 			targetNames = append(targetNames, arg)
 		}
@@ -246,13 +782,20 @@ func runList(args []string) {
 	clients, err := cfg.BuildRemoteClients()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	checkOutputFlags(jsonOutput, format, output)
+	manager.Format = parseFormat(format)
+	manager.Output = output
+	filters.apply(manager)
+	manager.ExcludeTemplates = excludeTemplates
 
 	if err := manager.List(targetNames, includeArchived, workers); err != nil {
 		fmt.Fprintf(os.Stderr, "Error listing repositories: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
 
@@ -260,22 +803,39 @@ func runPull(args []string) {
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	cliWorkers, args := parseWorkers(args)
 	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	output, args := parseOutput(args, "ndjson")
+	jsonOutput := false
+	var targetNames []string
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			jsonOutput = true
+		default:
+			targetNames = append(targetNames, arg)
+		}
+	}
 
 	clients, err := cfg.BuildRemoteClients()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	checkOutputFlags(jsonOutput, "", output)
+	manager.JSON = jsonOutput
+	manager.Output = output
+	manager.DryRun = dryRun
 
-	if err := manager.Pull(args, workers); err != nil {
+	if err := manager.Pull(targetNames, workers); err != nil {
 		fmt.Fprintf(os.Stderr, "Error pulling repositories: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
 
@@ -283,22 +843,391 @@ func runPush(args []string) {
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	cliWorkers, args := parseWorkers(args)
 	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	output, args := parseOutput(args, "ndjson")
+	forceWithLease, args := parseBoolFlag(args, "--force-with-lease")
+	jsonOutput := false
+	var targetNames []string
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			jsonOutput = true
+		case "--force", "-f":
+			fmt.Fprintln(os.Stderr, "Error: push does not support --force; use --force-with-lease")
+			os.Exit(exitError)
+		default:
+			targetNames = append(targetNames, arg)
+		}
+	}
 
 	clients, err := cfg.BuildRemoteClients()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	checkOutputFlags(jsonOutput, "", output)
+	manager.JSON = jsonOutput
+	manager.Output = output
+	manager.DryRun = dryRun
+	manager.ForceWithLease = forceWithLease
 
-	if err := manager.Push(args, workers); err != nil {
+	if err := manager.Push(targetNames, workers); err != nil {
 		fmt.Fprintf(os.Stderr, "Error pushing repositories: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+}
+
+func runForkSync(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	output, args := parseOutput(args, "ndjson")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	useAPI, targetNames := parseBoolFlag(args, "--api")
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	checkOutputFlags(jsonOutput, "", output)
+	manager.JSON = jsonOutput
+	manager.Output = output
+	manager.DryRun = dryRun
+
+	if err := manager.ForkSync(targetNames, useAPI, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error syncing forks: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+const migrateReposUsage = `Usage:
+  tugboat migrate-repos --from PROVIDER:OWNER --to PROVIDER:OWNER [--include GLOB]... [--rename OLD=NEW]...`
+
+func runMigrateRepos(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+
+	var from, to string
+	opts := repo.MigrateOptions{Rename: make(map[string]string)}
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		if i+1 >= len(args) {
+			fmt.Fprintln(os.Stderr, migrateReposUsage)
+			os.Exit(exitError)
+		}
+		i++
+		switch flag {
+		case "--from":
+			from = args[i]
+		case "--to":
+			to = args[i]
+		case "--include":
+			opts.Include = append(opts.Include, args[i])
+		case "--rename":
+			old, name, ok := strings.Cut(args[i], "=")
+			if !ok || old == "" || name == "" {
+				fmt.Fprintf(os.Stderr, "Error: --rename expects OLD=NEW, got %q\n", args[i])
+				os.Exit(exitError)
+			}
+			opts.Rename[old] = name
+		default:
+			fmt.Fprintln(os.Stderr, migrateReposUsage)
+			os.Exit(exitError)
+		}
+	}
+	fromProvider, fromOwner, ok1 := strings.Cut(from, ":")
+	toProvider, toOwner, ok2 := strings.Cut(to, ":")
+	if !ok1 || !ok2 || fromOwner == "" || toOwner == "" {
+		fmt.Fprintln(os.Stderr, migrateReposUsage)
+		os.Exit(exitError)
+	}
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.DryRun = dryRun
+
+	if err := manager.MigrateRepos(fromProvider, fromOwner, toProvider, toOwner, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+func runUnshallow(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	output, args := parseOutput(args, "ndjson")
+	jsonOutput := false
+	var targetNames []string
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			jsonOutput = true
+		default:
+			targetNames = append(targetNames, arg)
+		}
+	}
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	checkOutputFlags(jsonOutput, "", output)
+	manager.JSON = jsonOutput
+	manager.Output = output
+	manager.DryRun = dryRun
+
+	if err := manager.Unshallow(targetNames, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error unshallowing repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+func runUI(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	refresh := 30 * time.Second
+	var targetNames []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := ""
+		switch {
+		case arg == "--refresh":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Error: --refresh requires a duration")
+				os.Exit(exitError)
+			}
+			i++
+			value = args[i]
+		case strings.HasPrefix(arg, "--refresh="):
+			value = strings.TrimPrefix(arg, "--refresh=")
+		default:
+			targetNames = append(targetNames, arg)
+			continue
+		}
+		d, err := parseRefresh(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --refresh %q: %v\n", value, err)
+			os.Exit(exitError)
+		}
+		refresh = d
+	}
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+
+	if err := tui.New(manager, targetNames, workers, refresh).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running ui: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// repoFilters are the target filter overrides of clone and list.
+type repoFilters struct {
+	forks, visibility string
+	languages         []string
+}
+
+// apply sets the overrides on m.
+func (f repoFilters) apply(m *repo.Manager) {
+	m.Forks = f.forks
+	m.Visibility = f.visibility
+	m.Languages = f.languages
+}
+
+// parseRepoFilters removes --exclude-forks/--only-forks,
+// --only-public/--only-private and --language NAME from args and returns
+// the filters they ask for.
+func parseRepoFilters(args []string) (repoFilters, []string, error) {
+	var f repoFilters
+	excludeForks, args := parseBoolFlag(args, "--exclude-forks")
+	onlyForks, args := parseBoolFlag(args, "--only-forks")
+	onlyPublic, args := parseBoolFlag(args, "--only-public")
+	onlyPrivate, args := parseBoolFlag(args, "--only-private")
+	switch {
+	case excludeForks && onlyForks:
+		return f, nil, fmt.Errorf("--exclude-forks and --only-forks cannot be combined")
+	case onlyPublic && onlyPrivate:
+		return f, nil, fmt.Errorf("--only-public and --only-private cannot be combined")
+	}
+	switch {
+	case excludeForks:
+		f.forks = "exclude"
+	case onlyForks:
+		f.forks = "only"
+	}
+	switch {
+	case onlyPublic:
+		f.visibility = "public"
+	case onlyPrivate:
+		f.visibility = "private"
+	}
+	languages, args, err := parseRepeatedFlag(args, "--language")
+	if err != nil {
+		return f, nil, err
+	}
+	f.languages = languages
+	return f, args, nil
+}
+
+// parseTimeout removes --timeout DURATION from args and returns the deadline
+// it sets for the run, counted from now; zero without --timeout.
+func parseTimeout(args []string) (time.Time, []string, error) {
+	values, args, err := parseRepeatedFlag(args, "--timeout")
+	if err != nil || len(values) == 0 {
+		return time.Time{}, args, err
+	}
+	d, err := time.ParseDuration(values[len(values)-1])
+	if err != nil || d <= 0 {
+		return time.Time{}, nil, fmt.Errorf("--timeout must be a positive duration such as 10m, got %q", values[len(values)-1])
+	}
+	return time.Now().Add(d), args, nil
+}
+
+// parseRefresh parses a refresh interval; a bare "0" disables refreshing.
+func parseRefresh(value string) (time.Duration, error) {
+	if value == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
+
+func runPrune(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	yes, args := parseBoolFlag(args, "--yes", "-y")
+	force, args := parseBoolFlag(args, "--force")
+	moveTo := ""
+	var targetNames []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--move-to":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Error: --move-to requires a directory")
+				os.Exit(exitError)
+			}
+			i++
+			moveTo = args[i]
+		case strings.HasPrefix(arg, "--move-to="):
+			moveTo = strings.TrimPrefix(arg, "--move-to=")
+		default:
+			targetNames = append(targetNames, arg)
+		}
+	}
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.DryRun = dryRun
+
+	var confirm func(int) bool
+	if !yes {
+		confirm = func(count int) bool {
+			verb := "Delete"
+			if moveTo != "" {
+				verb = "Move"
+			}
+			fmt.Printf("%s %d repos? [y/N] ", verb, count)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			return answer == "y" || answer == "yes"
+		}
+	}
+
+	if err := manager.Prune(targetNames, moveTo, force, confirm); err != nil {
+		fmt.Fprintf(os.Stderr, "Error pruning repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+func runAdopt(args []string) {
+	result, err := config.LoadWithMetadata()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	dryRun, paths := parseBoolFlag(args, "--dry-run", "-n")
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: tugboat adopt [--dry-run] PATH...")
+		os.Exit(exitError)
+	}
+
+	clients, err := result.Config.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, result.Config)
+	configureOutput(manager)
+	manager.DryRun = dryRun
+
+	if err := manager.Adopt(paths, result.ConfigPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error adopting repositories: %v\n", err)
+		os.Exit(exitError)
 	}
 }
 
@@ -314,7 +1243,7 @@ func runMigrate(args []string) {
 	result, err := config.LoadWithMetadata()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	if result.Version == 2 {
@@ -326,7 +1255,7 @@ func runMigrate(args []string) {
 	v2JSON, err := result.Config.ToJSON()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error generating v2 config: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	if writeInPlace {
@@ -335,14 +1264,14 @@ func runMigrate(args []string) {
 		data, _ := os.ReadFile(result.ConfigPath)
 		if err := os.WriteFile(backupPath, data, 0644); err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating backup: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
 		fmt.Printf("Backed up v1 config to: %s\n", backupPath)
 
 		// Write new config
 		if err := os.WriteFile(result.ConfigPath, v2JSON, 0644); err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing v2 config: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
 		fmt.Printf("Migrated config to v2: %s\n", result.ConfigPath)
 	} else {
@@ -350,3 +1279,187 @@ func runMigrate(args []string) {
 		fmt.Println(string(v2JSON))
 	}
 }
+
+func runBranch(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	output, targetNames := parseOutput(args, "csv", "tsv", "ndjson")
+	checkOutputFlags(jsonOutput, "", output)
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.Output = output
+
+	if err := manager.Branches(targetNames, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing branches: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+func runCheckout(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: tugboat checkout BRANCH [target ...]")
+		os.Exit(exitError)
+	}
+	branch, targetNames := args[0], args[1:]
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.DryRun = dryRun
+
+	if err := manager.Checkout(branch, targetNames, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking out %s: %v\n", branch, err)
+		os.Exit(exitError)
+	}
+}
+
+func runSwitchDefault(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	jsonOutput, targetNames := parseBoolFlag(args, "--json")
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.DryRun = dryRun
+
+	if err := manager.SwitchDefault(targetNames, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error switching default branches: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+func runGrep(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	opts, cliWorkers, args := parseGrepArgs(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: tugboat grep [-i] [-w] [-F|-E] [--workers N] PATTERN [target ...]")
+		os.Exit(exitError)
+	}
+
+	// grep only reads local checkouts, so no provider clients are built.
+	manager := repo.NewManager(nil, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+
+	if err := manager.Grep(args[0], opts, args[1:], workers); err != nil {
+		if errors.Is(err, repo.ErrNoMatches) {
+			os.Exit(exitNotClean)
+		}
+		fmt.Fprintf(os.Stderr, "Error searching repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// parseGrepArgs takes grep's own flags out of args before the worker count,
+// so -w means --word-regexp as in git grep; workers are set with --workers.
+func parseGrepArgs(args []string) (repo.GrepOptions, int, []string) {
+	var opts repo.GrepOptions
+	opts.IgnoreCase, args = parseBoolFlag(args, "-i", "--ignore-case")
+	opts.Word, args = parseBoolFlag(args, "-w", "--word-regexp")
+	opts.Fixed, args = parseBoolFlag(args, "-F", "--fixed-strings")
+	opts.Extended, args = parseBoolFlag(args, "-E", "--extended-regexp")
+	workers, args := parseWorkers(args)
+	return opts, workers, args
+}
+
+func runWatch(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	sync, args := parseBoolFlag(args, "--sync")
+	interval := 15 * time.Minute
+	var targetNames []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := ""
+		switch {
+		case arg == "--interval":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Error: --interval requires a duration")
+				os.Exit(exitError)
+			}
+			i++
+			value = args[i]
+		case strings.HasPrefix(arg, "--interval="):
+			value = strings.TrimPrefix(arg, "--interval=")
+		default:
+			targetNames = append(targetNames, arg)
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: invalid --interval %q\n", value)
+			os.Exit(exitError)
+		}
+		interval = d
+	}
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := manager.Watch(ctx, targetNames, interval, sync, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error watching repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
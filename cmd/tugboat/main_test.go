@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+func TestParseGrepArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		opts    repo.GrepOptions
+		workers int
+		rest    []string
+	}{
+		{[]string{"-w", "foo"}, repo.GrepOptions{Word: true}, 0, []string{"foo"}},
+		{[]string{"-w", "4"}, repo.GrepOptions{Word: true}, 0, []string{"4"}},
+		{[]string{"-i", "-F", "foo", "api"}, repo.GrepOptions{IgnoreCase: true, Fixed: true}, 0, []string{"foo", "api"}},
+		{[]string{"--workers", "4", "-w", "foo", "--json"}, repo.GrepOptions{Word: true}, 4, []string{"foo", "--json"}},
+		{[]string{"-E", "--workers=2", "a|b"}, repo.GrepOptions{Extended: true}, 2, []string{"a|b"}},
+	}
+	for _, tt := range tests {
+		opts, workers, rest := parseGrepArgs(tt.args)
+		if opts != tt.opts || workers != tt.workers || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("parseGrepArgs(%q) = %+v, %d, %q; want %+v, %d, %q", tt.args, opts, workers, rest, tt.opts, tt.workers, tt.rest)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+const prUsage = `Usage:
+  tugboat pr list [target ...]
+  tugboat pr create [--title TEMPLATE] [--body TEMPLATE | --body-file FILE] [--branch BRANCH] [--draft] [target ...]`
+
+func runPR(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, prUsage)
+		os.Exit(exitError)
+	}
+	sub, args := args[0], args[1:]
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.DryRun = dryRun
+
+	switch sub {
+	case "list", "ls":
+		if err := manager.PullRequests(args, workers); err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing pull requests: %v\n", err)
+			os.Exit(exitError)
+		}
+	case "create":
+		var opts repo.PROptions
+		opts.Draft, args = parseBoolFlag(args, "--draft")
+		var targetNames []string
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--title", "--body", "--body-file", "--branch":
+				if i+1 >= len(args) {
+					fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+					os.Exit(exitError)
+				}
+				switch args[i] {
+				case "--title":
+					opts.Title = args[i+1]
+				case "--body":
+					opts.Body = args[i+1]
+				case "--body-file":
+					data, err := os.ReadFile(args[i+1])
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error reading body file: %v\n", err)
+						os.Exit(exitError)
+					}
+					opts.Body = string(data)
+				case "--branch":
+					opts.Branch = args[i+1]
+				}
+				i++
+			default:
+				targetNames = append(targetNames, args[i])
+			}
+		}
+		if err := manager.CreatePullRequests(targetNames, opts, workers); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating pull requests: %v\n", err)
+			os.Exit(exitError)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, prUsage)
+		os.Exit(exitError)
+	}
+}
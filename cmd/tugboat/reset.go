@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+func runReset(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	output, args := parseOutput(args, "ndjson")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+	yes, args := parseBoolFlag(args, "--yes", "-y")
+	dirtyToo, targetNames := parseBoolFlag(args, "--dirty-too")
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	checkOutputFlags(jsonOutput, "", output)
+	manager.JSON = jsonOutput
+	manager.Output = output
+	manager.DryRun = dryRun
+
+	var confirm func(int) bool
+	if !yes {
+		confirm = func(count int) bool {
+			fmt.Fprintf(os.Stderr, "Reset %d repos to origin, discarding their local changes? [y/N] ", count)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			return answer == "y" || answer == "yes"
+		}
+	}
+	if err := manager.Reset(targetNames, dirtyToo, confirm, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error resetting repositories: %v\n", err)
+		os.Exit(exitError)
+	}
+}
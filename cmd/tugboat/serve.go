@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/api"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/webhook"
+)
+
+func runServe(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	listen, apiListen := ":8080", ""
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--listen" || arg == "-l" || arg == "--api":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires an address\n", arg)
+				os.Exit(exitError)
+			}
+			i++
+			if arg == "--api" {
+				apiListen = args[i]
+			} else {
+				listen = args[i]
+			}
+		case strings.HasPrefix(arg, "--listen="):
+			listen = strings.TrimPrefix(arg, "--listen=")
+		case strings.HasPrefix(arg, "--api="):
+			apiListen = strings.TrimPrefix(arg, "--api=")
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown argument %q\n", arg)
+			os.Exit(exitError)
+		}
+	}
+
+	sources := make(map[string]webhook.Source)
+	for name, p := range cfg.Providers {
+		if p.WebhookSecret != "" && (p.Type == "gitea" || p.Type == "github") {
+			sources[name] = webhook.Source{Type: p.Type, Secret: p.WebhookSecret}
+		}
+	}
+	if len(sources) == 0 && apiListen == "" {
+		fmt.Fprintln(os.Stderr, "Error: no gitea or github provider sets webhook_secret")
+		os.Exit(exitError)
+	}
+	apiToken := cfg.APIToken
+	if token := os.Getenv("TUGBOAT_API_TOKEN"); token != "" {
+		apiToken = token
+	}
+	if apiListen != "" && apiToken == "" {
+		fmt.Fprintln(os.Stderr, "Error: --api needs api_token in the config or TUGBOAT_API_TOKEN")
+		os.Exit(exitError)
+	}
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+
+	// Pulls and API syncs run one at a time so two updates of the same repo
+	// never race.
+	var mu sync.Mutex
+	handler := &webhook.Handler{
+		Sources: sources,
+		OnPush: func(p webhook.Push) {
+			if branch := p.Branch(); branch == "" || (p.DefaultBranch != "" && branch != p.DefaultBranch) {
+				return // only default-branch pushes change what pull does
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results, err := manager.PullRemoteRepo(p.Provider, p.FullName, p.DefaultBranch)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error pulling %s: %v\n", p.FullName, err)
+			} else if len(results) == 0 {
+				fmt.Fprintf(os.Stderr, "Ignoring push to %s/%s: not managed\n", p.Provider, p.FullName)
+			}
+		},
+	}
+
+	errs := make(chan error, 2)
+	if apiListen != "" {
+		apiHandler := &api.Handler{
+			Token: apiToken,
+			NewManager: func() *repo.Manager {
+				m := repo.NewManager(clients, cfg)
+				configureOutput(m)
+				return m
+			},
+			Workers: workers,
+			Lock:    &mu,
+		}
+		fmt.Printf("Listening on %s for API requests at /api/status, /api/list and /api/sync\n", apiListen)
+		server := &http.Server{Addr: apiListen, Handler: apiHandler, ReadHeaderTimeout: 10 * time.Second}
+		go func() { errs <- fmt.Errorf("serving the API: %w", server.ListenAndServe()) }()
+	}
+	if len(sources) > 0 {
+		names := make([]string, 0, len(sources))
+		for name := range sources {
+			names = append(names, "/hooks/"+name)
+		}
+		sort.Strings(names)
+		fmt.Printf("Listening on %s for webhooks at %s\n", listen, strings.Join(names, ", "))
+		server := &http.Server{Addr: listen, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+		go func() { errs <- fmt.Errorf("serving webhooks: %w", server.ListenAndServe()) }()
+	}
+	fmt.Fprintf(os.Stderr, "Error %v\n", <-errs)
+	os.Exit(exitError)
+}
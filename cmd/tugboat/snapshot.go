@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+const snapshotUsage = `Usage:
+  tugboat snapshot save NAME [target ...] [--dirty] [--force] [--dry-run]
+  tugboat snapshot restore NAME [--dry-run] [--json]
+  tugboat snapshot list [--json]
+  tugboat snapshot delete NAME`
+
+func runSnapshot(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, snapshotUsage)
+		os.Exit(exitError)
+	}
+	sub := args[0]
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	// Snapshots live next to the config file they refer to.
+	dir := filepath.Join(filepath.Dir(config.ConfigPath()), "snapshots")
+	cliWorkers, args := parseWorkers(args[1:])
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	output, args := parseOutput(args, "ndjson")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+
+	// Snapshots are taken from and restored into local checkouts, so no
+	// provider clients are built.
+	manager := repo.NewManager(nil, cfg)
+	configureOutput(manager)
+	checkOutputFlags(jsonOutput, "", output)
+	manager.JSON = jsonOutput
+	manager.Output = output
+	manager.DryRun = dryRun
+
+	switch {
+	case sub == "save" && len(args) > 0:
+		dirty, rest := parseBoolFlag(args[1:], "--dirty")
+		force, targetNames := parseBoolFlag(rest, "--force", "-f")
+		err = manager.SaveSnapshot(dir, args[0], targetNames, dirty, force, workers)
+	case sub == "restore" && len(args) == 1:
+		err = manager.RestoreSnapshot(dir, args[0], workers)
+	case sub == "list" && len(args) == 0:
+		var snaps []repo.Snapshot
+		if snaps, err = repo.ListSnapshots(dir); err != nil {
+			break
+		}
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(snaps)
+			break
+		}
+		for _, s := range snaps {
+			stashed := 0
+			for _, e := range s.Repos {
+				if e.Stash != "" {
+					stashed++
+				}
+			}
+			fmt.Printf("%-24s %s  %d repos, %d with saved changes\n", s.Name, s.Created.Local().Format("2006-01-02 15:04"), len(s.Repos), stashed)
+		}
+	case sub == "delete" && len(args) == 1:
+		err = manager.DeleteSnapshot(dir, args[0])
+	default:
+		fmt.Fprintln(os.Stderr, snapshotUsage)
+		os.Exit(exitError)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: snapshot %s: %v\n", sub, err)
+		os.Exit(exitError)
+	}
+}
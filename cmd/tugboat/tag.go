@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+const tagUsage = `Usage:
+  tugboat tag list [target ...]
+  tugboat tag create NAME [-m MESSAGE] [--sign] [--push] [target ...]`
+
+func runTag(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, tagUsage)
+		os.Exit(exitError)
+	}
+	sub, args := args[0], args[1:]
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	jsonOutput, args := parseBoolFlag(args, "--json")
+
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	manager.JSON = jsonOutput
+	manager.DryRun = dryRun
+
+	switch sub {
+	case "list", "ls":
+		if err := manager.Tags(args, workers); err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing tags: %v\n", err)
+			os.Exit(exitError)
+		}
+	case "create":
+		var opts repo.TagOptions
+		opts.Sign, args = parseBoolFlag(args, "--sign", "-s")
+		opts.Push, args = parseBoolFlag(args, "--push")
+		var positional []string
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "-m", "--message":
+				if i+1 >= len(args) {
+					fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+					os.Exit(exitError)
+				}
+				i++
+				opts.Message = args[i]
+			default:
+				positional = append(positional, args[i])
+			}
+		}
+		if len(positional) == 0 {
+			fmt.Fprintln(os.Stderr, tagUsage)
+			os.Exit(exitError)
+		}
+		name := positional[0]
+		if err := manager.CreateTag(name, opts, positional[1:], workers); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating tag %s: %v\n", name, err)
+			os.Exit(exitError)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, tagUsage)
+		os.Exit(exitError)
+	}
+}
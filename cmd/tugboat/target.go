@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+)
+
+const targetUsage = `Usage:
+  tugboat target add NAME --provider P (--org O | --user U) [--repo R] --path DIR
+  tugboat target remove NAME
+  tugboat target rename OLD NEW`
+
+func runTarget(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, targetUsage)
+		os.Exit(exitError)
+	}
+
+	result, err := config.LoadWithMetadata()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	if result.Version != 2 {
+		fmt.Fprintln(os.Stderr, "Error: config is v1; run 'tugboat migrate --write' first")
+		os.Exit(exitError)
+	}
+	cfg, path := result.Config, result.ConfigPath
+
+	switch args[0] {
+	case "add":
+		t, err := parseTargetAdd(args[1:])
+		if err == nil {
+			err = checkNewTarget(cfg, t)
+		}
+		if err == nil {
+			err = config.AppendTarget(path, t)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error adding target: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Printf("Added target %s (%s/%s) -> %s\n", t.Name, t.Provider, t.Owner(), t.Path)
+	case "remove", "rm":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, targetUsage)
+			os.Exit(exitError)
+		}
+		if err := config.RemoveTarget(path, args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error removing target: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Printf("Removed target %s (local files were not touched)\n", args[1])
+	case "rename", "mv":
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, targetUsage)
+			os.Exit(exitError)
+		}
+		if cfg.GetTargetByName(args[2]) != nil {
+			fmt.Fprintf(os.Stderr, "Error renaming target: duplicate target name %q\n", args[2])
+			os.Exit(exitError)
+		}
+		if _, ok := cfg.Groups[args[2]]; ok {
+			fmt.Fprintf(os.Stderr, "Error renaming target: %q is already a group name\n", args[2])
+			os.Exit(exitError)
+		}
+		if err := config.RenameTarget(path, args[1], args[2]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error renaming target: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Printf("Renamed target %s to %s\n", args[1], args[2])
+	default:
+		fmt.Fprintln(os.Stderr, targetUsage)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Previous config saved as %s.bak\n", path)
+}
+
+// parseTargetAdd reads the name and flags of 'target add'.
+func parseTargetAdd(args []string) (config.Target, error) {
+	var t config.Target
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			if t.Name != "" {
+				return t, fmt.Errorf("unexpected argument %q", arg)
+			}
+			t.Name = arg
+			continue
+		}
+		flag, value, hasValue := strings.Cut(arg, "=")
+		if !hasValue {
+			if i+1 >= len(args) {
+				return t, fmt.Errorf("%s requires a value", flag)
+			}
+			i++
+			value = args[i]
+		}
+		switch flag {
+		case "--provider":
+			t.Provider = value
+		case "--org":
+			t.Org = value
+		case "--user":
+			t.User = value
+		case "--repo":
+			t.Repo = value
+		case "--path":
+			t.Path = value
+		default:
+			return t, fmt.Errorf("unknown flag %s", flag)
+		}
+	}
+
+	switch {
+	case t.Name == "":
+		return t, fmt.Errorf("a target name is required")
+	case t.Provider == "":
+		return t, fmt.Errorf("--provider is required")
+	case (t.Org == "") == (t.User == ""):
+		return t, fmt.Errorf("exactly one of --org and --user is required")
+	case t.Path == "":
+		return t, fmt.Errorf("--path is required")
+	}
+	if strings.HasPrefix(t.Path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			t.Path = filepath.Join(home, t.Path[2:])
+		}
+	}
+	abs, err := filepath.Abs(t.Path)
+	if err != nil {
+		return t, err
+	}
+	t.Path = abs
+	return t, nil
+}
+
+// checkNewTarget validates t against the loaded config, including targets
+// and providers from included files.
+func checkNewTarget(cfg *config.Config, t config.Target) error {
+	if _, ok := cfg.Providers[t.Provider]; !ok {
+		return fmt.Errorf("unknown provider %q", t.Provider)
+	}
+	if cfg.GetTargetByName(t.Name) != nil {
+		return fmt.Errorf("duplicate target name %q", t.Name)
+	}
+	if _, ok := cfg.Groups[t.Name]; ok {
+		return fmt.Errorf("%q is already a group name", t.Name)
+	}
+	for _, existing := range cfg.Targets {
+		if existing.Path == t.Path {
+			return fmt.Errorf("path %s is already used by target %q", t.Path, existing.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+func runExport(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	cliWorkers, args := parseWorkers(args)
+	workers := resolveWorkers(cliWorkers, cfg)
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: tugboat export [-j N] > workspace.json")
+		os.Exit(exitError)
+	}
+
+	// Exporting reads local checkouts, so no provider clients are built.
+	manager := repo.NewManager(nil, cfg)
+	configureOutput(manager)
+	if err := manager.Export(workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error exporting workspace: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+func runApply(args []string) {
+	cliWorkers, args := parseWorkers(args)
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: tugboat apply FILE [-j N]")
+		os.Exit(exitError)
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	ws, err := repo.ParseWorkspace(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", args[0], err)
+		os.Exit(exitError)
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	if path := config.ConfigPath(); path == "" {
+		err = writeWorkspaceConfig(in, ws.Config)
+	} else {
+		err = mergeWorkspaceConfig(in, path, ws.Config)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	workers := resolveWorkers(cliWorkers, cfg)
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clients: %v\n", err)
+		os.Exit(exitError)
+	}
+	manager := repo.NewManager(clients, cfg)
+	configureOutput(manager)
+	if err := manager.Apply(ws, workers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error applying workspace: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// writeWorkspaceConfig writes the exported config as the new config file,
+// asking for the tokens the export left out.
+func writeWorkspaceConfig(in *bufio.Reader, cfg *config.Config) error {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedProviderNames(cfg.Providers) {
+		p, err := askToken(in, name, cfg.Providers[name])
+		if err != nil {
+			return err
+		}
+		cfg.Providers[name] = p
+	}
+	data, err := cfg.ToJSON()
+	if err != nil {
+		return fmt.Errorf("generating config: %w", err)
+	}
+	if _, err := config.ReadV2(data); err != nil {
+		return fmt.Errorf("exported config is invalid: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	fmt.Printf("Wrote %s\n", path)
+	return nil
+}
+
+// mergeWorkspaceConfig adds the exported providers and targets the config
+// file at path lacks. Those it already has are kept as they are; so are its
+// groups and other settings.
+func mergeWorkspaceConfig(in *bufio.Reader, path string, exported *config.Config) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	for _, name := range sortedProviderNames(exported.Providers) {
+		p := exported.Providers[name]
+		if have, ok := cfg.Providers[name]; ok {
+			if have.Type != p.Type || have.APIURL != p.APIURL {
+				fmt.Fprintf(os.Stderr, "Warning: provider %s differs from the exported one; keeping yours\n", name)
+			}
+			continue
+		}
+		if p, err = askToken(in, name, p); err != nil {
+			return err
+		}
+		if err := config.AppendProvider(path, name, p); err != nil {
+			return err
+		}
+		fmt.Printf("Added provider %s to %s\n", name, path)
+	}
+	for _, t := range exported.Targets {
+		if have := cfg.GetTargetByName(t.Name); have != nil {
+			if have.Provider != t.Provider || have.Owner() != t.Owner() || have.Repo != t.Repo {
+				fmt.Fprintf(os.Stderr, "Warning: target %s differs from the exported one; keeping yours\n", t.Name)
+			}
+			continue
+		}
+		if err := config.AppendTarget(path, t); err != nil {
+			return err
+		}
+		fmt.Printf("Added target %s to %s\n", t.Name, path)
+	}
+	return nil
+}
+
+// askToken asks for the token of an exported provider that has no
+// token_cmd, as init does.
+func askToken(in *bufio.Reader, name string, p config.Provider) (config.Provider, error) {
+	var err error
+	for p.Token == "" && p.TokenCmd == "" {
+		if p.Token, err = readPassword(in, fmt.Sprintf("Token for provider %s (input hidden; leave empty to use a command): ", name)); err != nil {
+			return p, err
+		}
+		if p.Token == "" {
+			if p.TokenCmd, err = ask(in, "Command that prints the token", ""); err != nil {
+				return p, err
+			}
+		}
+	}
+	return p, nil
+}
+
+func sortedProviderNames(providers map[string]config.Provider) []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
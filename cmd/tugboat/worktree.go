@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+const worktreeUsage = `Usage:
+  tugboat worktree add REPO BRANCH`
+
+func runWorktree(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitError)
+	}
+	dryRun, args := parseBoolFlag(args, "--dry-run", "-n")
+	if len(args) != 3 || args[0] != "add" {
+		fmt.Fprintln(os.Stderr, worktreeUsage)
+		os.Exit(exitError)
+	}
+
+	// Worktrees are made from local checkouts, so no provider clients are built.
+	manager := repo.NewManager(nil, cfg)
+	configureOutput(manager)
+	manager.DryRun = dryRun
+
+	if err := manager.AddWorktree(args[1], args[2]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error adding worktree: %v\n", err)
+		os.Exit(exitError)
+	}
+}
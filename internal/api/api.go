@@ -0,0 +1,152 @@
+// Package api serves repo status, list and sync over HTTP for dashboards,
+// answering with the same JSON the commands print with --json.
+package api
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+// Handler serves
+//
+//	GET  /api/status?target=NAME&no_fetch=true&problems=true&detail=true
+//	GET  /api/list?target=NAME&include_archived=true
+//	POST /api/sync?target=NAME&dry_run=true
+//
+// to requests carrying "Authorization: Bearer <Token>". target may be
+// repeated and defaults to all targets. Responses are the commands' --json
+// output; a repo that is dirty or failed to sync does not change the status
+// code, only errors that stop a command do: 400 for bad parameters and
+// unknown targets, 500 for everything else, such as a target whose path is
+// missing.
+type Handler struct {
+	Token string
+	// NewManager returns a manager for one request, configured like the
+	// command line would.
+	NewManager func() *repo.Manager
+	Workers    int
+	// Lock, when set, is held while a request works on the repos, so it
+	// never races other writers such as webhook pulls.
+	Lock sync.Locker
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="tugboat"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid token")
+		return
+	}
+	command := strings.TrimPrefix(r.URL.Path, "/api/")
+	method := http.MethodGet
+	if command == "sync" {
+		method = http.MethodPost
+	}
+	switch command {
+	case "status", "list", "sync":
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint "+r.URL.Path)
+		return
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "use "+method)
+		return
+	}
+
+	q := r.URL.Query()
+	targets := q["target"]
+	m := h.NewManager()
+	var out bytes.Buffer
+	m.Out = &out
+	m.JSON = true
+
+	var err error
+	switch command {
+	case "status":
+		var noFetch, problems, detail bool
+		if noFetch, err = boolParam(q, "no_fetch"); err == nil {
+			if problems, err = boolParam(q, "problems"); err == nil {
+				detail, err = boolParam(q, "detail")
+			}
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		m.NoFetch, m.ProblemsOnly, m.Detail = noFetch, problems, detail
+		err = h.locked(func() error { return m.Status(targets, false, h.Workers) })
+	case "list":
+		includeArchived, perr := boolParam(q, "include_archived")
+		if perr != nil {
+			writeError(w, http.StatusBadRequest, perr.Error())
+			return
+		}
+		err = m.List(targets, includeArchived, h.Workers)
+	case "sync":
+		if m.DryRun, err = boolParam(q, "dry_run"); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		err = h.locked(func() error { return m.Sync(targets, h.Workers) })
+	}
+	// The commands write their JSON before reporting dirty or failed repos
+	// as an error; only a command that wrote nothing failed outright.
+	if out.Len() == 0 {
+		code, msg := http.StatusInternalServerError, "no output"
+		if err != nil {
+			msg = err.Error()
+		}
+		if errors.Is(err, repo.ErrUnknownTargets) {
+			code = http.StatusBadRequest
+		}
+		writeError(w, code, msg)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out.Bytes())
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	if h.Token == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) == 1
+}
+
+func (h *Handler) locked(fn func() error) error {
+	if h.Lock != nil {
+		h.Lock.Lock()
+		defer h.Lock.Unlock()
+	}
+	return fn()
+}
+
+// boolParam reads an optional boolean query parameter.
+func boolParam(q url.Values, name string) (bool, error) {
+	v := q.Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", name, v)
+	}
+	return b, nil
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
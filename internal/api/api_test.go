@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+func TestHandlerServesStatusWithToken(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "app")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main", dir},
+		{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	cfg := &config.Config{
+		Providers: map[string]config.Provider{"gh": {Type: "github"}},
+		Targets:   []config.Target{{Name: "app", Provider: "gh", Org: "acme", Repo: "app", Path: dir}},
+	}
+	h := &Handler{
+		Token: "s3cret",
+		NewManager: func() *repo.Manager {
+			m := repo.NewManager(nil, cfg)
+			m.Offline = true
+			return m
+		},
+		Workers: 1,
+	}
+	serve := func(method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(http.MethodGet, "/api/status", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: status = %d, want 401", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/api/sync", "s3cret"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET sync: status = %d, want 405", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/api/status?target=nope", "s3cret"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown target: status = %d, want 400", rec.Code)
+	}
+
+	rec := serve(http.MethodGet, "/api/status?target=app&no_fetch=true", "s3cret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: code = %d, body %s", rec.Code, rec.Body)
+	}
+	var statuses []repo.RepoStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("parsing status body %s: %v", rec.Body, err)
+	}
+	if len(statuses) != 1 || statuses[0].Path != dir || statuses[0].Branch != "main" {
+		t.Fatalf("statuses = %+v, want the app repo on main", statuses)
+	}
+}
+
+func TestHandlerErrorCodes(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]config.Provider{"gh": {Type: "github"}},
+		Targets:   []config.Target{{Name: "acme", Provider: "gh", Org: "acme", Path: filepath.Join(t.TempDir(), "missing")}},
+	}
+	h := &Handler{
+		Token: "s3cret",
+		NewManager: func() *repo.Manager {
+			m := repo.NewManager(nil, cfg)
+			m.Offline = true
+			return m
+		},
+		Workers: 1,
+	}
+	serve := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for target, want := range map[string]int{
+		"/api/status?target=nope":         http.StatusBadRequest,
+		"/api/status?target=[bad":         http.StatusBadRequest,
+		"/api/status?no_fetch=maybe":      http.StatusBadRequest,
+		"/api/list?include_archived=nope": http.StatusBadRequest,
+		"/api/status?target=acme":         http.StatusInternalServerError,
+	} {
+		if rec := serve(target); rec.Code != want {
+			t.Errorf("%s: status = %d, want %d; body %s", target, rec.Code, want, rec.Body)
+		}
+	}
+}
@@ -1,27 +1,128 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
 
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/gitea"
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/github"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/gitlab"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/httpcache"
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
 )
 
 // BuildRemoteClients instantiates remote clients for each configured provider.
+// Providers with a token_cmd run it first and keep the resulting token, so
+// git operations authenticate with it too.
 func (c *Config) BuildRemoteClients() (map[string]remote.Client, error) {
 	clients := make(map[string]remote.Client, len(c.Providers))
 
+	// Without a cache directory requests simply go uncached.
+	var cacheDir string
+	if c.UseHTTPCache() {
+		if dir, err := httpcache.DefaultDir(); err == nil {
+			cacheDir = dir
+		}
+	}
+
 	for name, p := range c.Providers {
+		if p.TokenCmd != "" {
+			token, err := runTokenCmd(p.TokenCmd)
+			if err != nil {
+				return nil, fmt.Errorf("provider %q token_cmd: %w", name, err)
+			}
+			p.Token = token
+			c.Providers[name] = p
+		}
+		var client interface {
+			remote.Client
+			SetTransport(http.RoundTripper)
+		}
 		switch p.Type {
 		case "gitea":
-			clients[name] = gitea.NewClient(p.APIURL, p.Token)
+			client = gitea.NewClient(p.APIURL, p.Token)
 		case "github":
-			clients[name] = github.NewClient(p.APIURL, p.Token)
+			client = github.NewClient(p.APIURL, p.Token)
+		case "gitlab":
+			client = gitlab.NewClient(p.APIURL, p.Token)
 		default:
 			return nil, fmt.Errorf("unsupported provider type %q", p.Type)
 		}
+		transport, err := p.Transport()
+		if err != nil {
+			return nil, fmt.Errorf("provider %q: %w", name, err)
+		}
+		if cacheDir != "" {
+			transport = &httpcache.Transport{Dir: cacheDir, Base: transport}
+		}
+		client.SetTransport(transport)
+		clients[name] = client
 	}
 
 	return clients, nil
 }
+
+// Transport returns the transport the API requests of p go through, without
+// the response cache: http.DefaultTransport, which honors HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY, unless p sets a proxy or TLS options of its own.
+func (p Provider) Transport() (http.RoundTripper, error) {
+	if p.Proxy == "" && p.CACertFile == "" && !p.InsecureSkipVerify {
+		return http.DefaultTransport, nil
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if p.Proxy != "" {
+		proxy, err := url.Parse(p.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	}
+	t.TLSClientConfig = &tls.Config{InsecureSkipVerify: p.InsecureSkipVerify}
+	if p.CACertFile != "" {
+		pem, err := os.ReadFile(expandPath(p.CACertFile))
+		if err != nil {
+			return nil, fmt.Errorf("reading ca_cert_file: %w", err)
+		}
+		// Trust the bundle on top of the system roots, so one file can
+		// hold just the corporate CA.
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_cert_file %s holds no PEM certificates", p.CACertFile)
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+	return t, nil
+}
+
+// runTokenCmd runs command through the shell and returns its trimmed stdout.
+// Stderr and stdin stay attached so the command can prompt (e.g. for an MFA
+// code).
+func runTokenCmd(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("command printed no token")
+	}
+	return token, nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/gitea"
+)
+
+func TestBuildRemoteClientsRunsTokenCmd(t *testing.T) {
+	cfg, err := ReadV2([]byte(`{
+		"providers": {
+			"github": {"type": "github", "token_cmd": "echo '  short-lived-token  '"}
+		},
+		"targets": [
+			{"provider": "github", "org": "acme", "path": "/acme"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ReadV2() error = %v", err)
+	}
+
+	if _, err := cfg.BuildRemoteClients(); err != nil {
+		t.Fatalf("BuildRemoteClients() error = %v", err)
+	}
+	if got := cfg.Providers["github"].Token; got != "short-lived-token" {
+		t.Errorf("token = %q, want token_cmd output", got)
+	}
+}
+
+func TestBuildRemoteClientsTokenCmdFailure(t *testing.T) {
+	cfg := &Config{Providers: map[string]Provider{
+		"github": {Type: "github", TokenCmd: "exit 3"},
+	}}
+	if _, err := cfg.BuildRemoteClients(); err == nil {
+		t.Error("BuildRemoteClients() should fail when token_cmd fails")
+	}
+
+	cfg = &Config{Providers: map[string]Provider{
+		"github": {Type: "github", TokenCmd: "true"},
+	}}
+	if _, err := cfg.BuildRemoteClients(); err == nil {
+		t.Error("BuildRemoteClients() should fail when token_cmd prints nothing")
+	}
+}
+
+func TestBuildRemoteClientsUsesProviderProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "widget", "full_name": "acme/widget"}`))
+	}))
+	defer proxy.Close()
+
+	cfg, err := ReadV2([]byte(`{
+		"providers": {
+			"gitea": {"type": "gitea", "api_url": "http://gitea.invalid", "token": "t", "proxy": "` + proxy.URL + `"}
+		},
+		"targets": [
+			{"provider": "gitea", "org": "acme", "path": "/acme"}
+		],
+		"http_cache": false
+	}`))
+	if err != nil {
+		t.Fatalf("ReadV2() error = %v", err)
+	}
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		t.Fatalf("BuildRemoteClients() error = %v", err)
+	}
+	if _, err := clients["gitea"].GetRepo("acme", "widget"); err != nil {
+		t.Fatalf("GetRepo() error = %v", err)
+	}
+	if proxied != "http://gitea.invalid/api/v1/repos/acme/widget" {
+		t.Errorf("proxy saw %q, want the API request", proxied)
+	}
+}
+
+func TestBuildRemoteClientsTLSOptions(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "widget", "full_name": "acme/widget"}`))
+	}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	getRepo := func(p Provider) error {
+		p.Type, p.APIURL, p.Token = "gitea", server.URL, "t"
+		off := false
+		cfg := &Config{Providers: map[string]Provider{"gitea": p}, HTTPCache: &off}
+		clients, err := cfg.BuildRemoteClients()
+		if err != nil {
+			return err
+		}
+		_, err = clients["gitea"].GetRepo("acme", "widget")
+		return err
+	}
+	if err := getRepo(Provider{}); err == nil {
+		t.Error("GetRepo() should fail verification without the CA")
+	}
+	if err := getRepo(Provider{CACertFile: caFile}); err != nil {
+		t.Errorf("GetRepo() with ca_cert_file error = %v", err)
+	}
+	if err := getRepo(Provider{InsecureSkipVerify: true}); err != nil {
+		t.Errorf("GetRepo() with insecure_skip_verify error = %v", err)
+	}
+	if err := getRepo(Provider{CACertFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("BuildRemoteClients() should fail when ca_cert_file is missing")
+	}
+}
+
+func TestProviderTransportForLogin(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sha1": "abc123"}`))
+	}))
+	defer proxy.Close()
+
+	transport, err := Provider{Type: "gitea", Proxy: proxy.URL}.Transport()
+	if err != nil {
+		t.Fatalf("Transport() error = %v", err)
+	}
+	token, err := gitea.CreateToken(transport, "http://gitea.invalid", "alice", "secret", "tugboat", gitea.TokenScopes)
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	if token != "abc123" || proxied != "http://gitea.invalid/api/v1/users/alice/tokens" {
+		t.Errorf("token = %q, proxy saw %q; want the token request to go through the proxy", token, proxied)
+	}
+}
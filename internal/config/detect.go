@@ -10,6 +10,7 @@ type versionProbe struct {
 	// V2 indicators
 	Version   int                    `json:"version,omitempty"`
 	Providers map[string]interface{} `json:"providers,omitempty"`
+	Include   []string               `json:"include,omitempty"`
 
 	// V1 indicators
 	GiteaURL string `json:"gitea_url,omitempty"`
@@ -24,7 +25,7 @@ func DetectVersion(data []byte) (int, error) {
 	}
 
 	// Check for V2 indicators first (providers map takes precedence)
-	if probe.Providers != nil {
+	if probe.Providers != nil || len(probe.Include) > 0 {
 		return 2, nil
 	}
 
@@ -0,0 +1,550 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configDoc is a config file edited in place: each edit splices the bytes of
+// one value, so unknown fields, key order and the layout of everything not
+// edited stay exactly as they were written.
+type configDoc struct {
+	src []byte
+}
+
+// span is where a JSON value is in the document.
+type span struct {
+	start, end int
+}
+
+// entry is one member of a JSON object or element of an array. start is
+// where its key begins, or for array elements its value.
+type entry struct {
+	key   string
+	start int
+	value span
+}
+
+func readConfigDoc(path string) (*configDoc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file %s: %w", path, err)
+	}
+	version, err := DetectVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	if version != 2 {
+		return nil, fmt.Errorf("config %s is v%d; run 'tugboat migrate --write' first", path, version)
+	}
+
+	doc := &configDoc{src: data}
+	root, err := doc.root()
+	if err == nil {
+		_, err = doc.entries(root)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// root returns where the top-level object is.
+func (d *configDoc) root() (span, error) {
+	dec := json.NewDecoder(bytes.NewReader(d.src))
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return span{}, err
+	}
+	end := int(dec.InputOffset())
+	return span{end - len(raw), end}, nil
+}
+
+// entries lists the members of the object, or the elements of the array, at
+// sp.
+func (d *configDoc) entries(sp span) ([]entry, error) {
+	dec := json.NewDecoder(bytes.NewReader(d.src[sp.start:sp.end]))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	object := tok == json.Delim('{')
+	if !object && tok != json.Delim('[') {
+		return nil, fmt.Errorf("expected a JSON object or array")
+	}
+	var list []entry
+	for dec.More() {
+		e := entry{start: skipSeparators(d.src, sp.start+int(dec.InputOffset()))}
+		valueStart := e.start
+		if object {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			e.key, _ = tok.(string)
+			valueStart = skipSeparators(d.src, sp.start+int(dec.InputOffset()))
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		e.value = span{valueStart, sp.start + int(dec.InputOffset())}
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+// skipSeparators returns the offset of the first byte at or after i that is
+// not whitespace, a comma or a colon.
+func skipSeparators(src []byte, i int) int {
+	for i < len(src) && strings.IndexByte(" \t\r\n,:", src[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// find returns where the value at the path of object keys is.
+func (d *configDoc) find(keys ...string) (span, bool, error) {
+	sp, err := d.root()
+	if err != nil {
+		return span{}, false, err
+	}
+	for _, key := range keys {
+		list, err := d.entries(sp)
+		if err != nil {
+			return span{}, false, err
+		}
+		found := false
+		for _, e := range list {
+			if e.key == key {
+				sp, found = e.value, true
+			}
+		}
+		if !found {
+			return span{}, false, nil
+		}
+	}
+	return sp, true, nil
+}
+
+// raw returns the bytes of the value at sp.
+func (d *configDoc) raw(sp span) json.RawMessage {
+	return json.RawMessage(d.src[sp.start:sp.end])
+}
+
+func (d *configDoc) splice(start, end int, text []byte) {
+	src := make([]byte, 0, len(d.src)-(end-start)+len(text))
+	src = append(src, d.src[:start]...)
+	src = append(src, text...)
+	d.src = append(src, d.src[end:]...)
+}
+
+// replace sets the value at sp to v.
+func (d *configDoc) replace(sp span, v any) error {
+	text, err := formatValue(v, "\n"+lineIndent(d.src, sp.start))
+	if err != nil {
+		return err
+	}
+	d.splice(sp.start, sp.end, text)
+	return nil
+}
+
+// set sets key of the object at obj to v, adding it after the object's last
+// member when it is new.
+func (d *configDoc) set(obj span, key string, v any) error {
+	list, err := d.entries(obj)
+	if err != nil {
+		return err
+	}
+	for _, e := range list {
+		if e.key == key {
+			return d.replace(e.value, v)
+		}
+	}
+	name, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return d.add(obj, list, append(name, ": "...), v)
+}
+
+// append adds v after the last element of the array at arr.
+func (d *configDoc) append(arr span, v any) error {
+	list, err := d.entries(arr)
+	if err != nil {
+		return err
+	}
+	return d.add(arr, list, nil, v)
+}
+
+// add inserts prefix and v as a new last entry of the container at sp,
+// laid out like the entry before it.
+func (d *configDoc) add(sp span, list []entry, prefix []byte, v any) error {
+	if len(list) == 0 {
+		text, err := formatValue(v, "")
+		if err != nil {
+			return err
+		}
+		d.splice(sp.start+1, sp.end-1, append(prefix, text...))
+		return nil
+	}
+	last := list[len(list)-1]
+	lead := leadingSpace(d.src, last.start)
+	text, err := formatValue(v, lead)
+	if err != nil {
+		return err
+	}
+	insert := append([]byte(","+lead), prefix...)
+	d.splice(last.value.end, last.value.end, append(insert, text...))
+	return nil
+}
+
+// remove deletes entry i of the object or array at sp with its separator.
+func (d *configDoc) remove(sp span, i int) error {
+	list, err := d.entries(sp)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(list) == 1:
+		d.splice(sp.start+1, sp.end-1, nil)
+	case i > 0:
+		d.splice(list[i-1].value.end, list[i].value.end, nil)
+	default:
+		d.splice(list[0].start, list[1].start, nil)
+	}
+	return nil
+}
+
+// leadingSpace returns the whitespace right before offset i.
+func leadingSpace(src []byte, i int) string {
+	start := i
+	for start > 0 && strings.IndexByte(" \t\r\n", src[start-1]) >= 0 {
+		start--
+	}
+	return string(src[start:i])
+}
+
+// lineIndent returns the indentation of the line offset i is on.
+func lineIndent(src []byte, i int) string {
+	start := bytes.LastIndexByte(src[:i], '\n') + 1
+	end := start
+	for end < i && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	return string(src[start:end])
+}
+
+// formatValue encodes v for a place preceded by lead: indented to match when
+// lead starts a new line, else on one line.
+func formatValue(v any, lead string) ([]byte, error) {
+	i := strings.LastIndexByte(lead, '\n')
+	if i >= 0 {
+		return json.MarshalIndent(v, lead[i+1:], "  ")
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if n := len(out); n > 0 && out[n-1] != '{' && out[n-1] != '[' && line[0] != '}' && line[0] != ']' {
+			out = append(out, ' ')
+		}
+		out = append(out, line...)
+	}
+	return out, nil
+}
+
+// write replaces the file at path atomically, keeping the previous version
+// as path.bak.
+func (d *configDoc) write(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	previous, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".bak", previous, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("writing backup: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, d.src, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
+
+// AppendTarget adds t to the targets of the v2 config file at path. Other
+// settings, unknown fields, key order and layout are kept; the path is
+// written with the home directory collapsed to ~.
+func AppendTarget(path string, t Target) error {
+	doc, err := readConfigDoc(path)
+	if err != nil {
+		return err
+	}
+	arr, targets, err := doc.targets()
+	if err != nil {
+		return err
+	}
+	if findTarget(doc, targets, t.Name) >= 0 {
+		return fmt.Errorf("duplicate target name %q", t.Name)
+	}
+
+	t.Path = collapseHome(t.Path)
+	if arr == nil {
+		root, err := doc.root()
+		if err != nil {
+			return err
+		}
+		err = doc.set(root, "targets", []Target{t})
+	} else {
+		err = doc.append(*arr, t)
+	}
+	if err != nil {
+		return err
+	}
+	return doc.write(path)
+}
+
+// RemoveTarget deletes the target called name from the v2 config file at
+// path. Its local checkouts are left alone.
+func RemoveTarget(path, name string) error {
+	doc, err := readConfigDoc(path)
+	if err != nil {
+		return err
+	}
+	arr, targets, err := doc.targets()
+	if err != nil {
+		return err
+	}
+	i := findTarget(doc, targets, name)
+	if i < 0 {
+		return fmt.Errorf("target %q is not defined in %s", name, path)
+	}
+	if err := doc.remove(*arr, i); err != nil {
+		return err
+	}
+	if err := doc.renameGroupMember(name, ""); err != nil {
+		return err
+	}
+	return doc.write(path)
+}
+
+// RenameTarget sets the name of target oldName in the v2 config file at path
+// to newName, keeping the target's other fields and their order.
+func RenameTarget(path, oldName, newName string) error {
+	doc, err := readConfigDoc(path)
+	if err != nil {
+		return err
+	}
+	_, targets, err := doc.targets()
+	if err != nil {
+		return err
+	}
+	i := findTarget(doc, targets, oldName)
+	if i < 0 {
+		return fmt.Errorf("target %q is not defined in %s", oldName, path)
+	}
+	if findTarget(doc, targets, newName) >= 0 {
+		return fmt.Errorf("duplicate target name %q", newName)
+	}
+	if err := doc.set(targets[i].value, "name", newName); err != nil {
+		return fmt.Errorf("renaming target %q: %w", oldName, err)
+	}
+	if err := doc.renameGroupMember(oldName, newName); err != nil {
+		return err
+	}
+	return doc.write(path)
+}
+
+// renameGroupMember replaces target oldName with newName in the groups of
+// the config, or drops it when newName is empty. Groups left without
+// targets are removed.
+func (d *configDoc) renameGroupMember(oldName, newName string) error {
+	// Every edit moves the bytes after it, so the groups are looked up
+	// again after each one.
+	for {
+		groupsSpan, ok, err := d.find("groups")
+		if err != nil || !ok {
+			return err
+		}
+		groups, err := d.entries(groupsSpan)
+		if err != nil {
+			return fmt.Errorf("parsing groups: %w", err)
+		}
+		edited := false
+		for gi, g := range groups {
+			members, err := d.entries(g.value)
+			if err != nil {
+				return fmt.Errorf("parsing group %q: %w", g.key, err)
+			}
+			for mi, m := range members {
+				var member string
+				if err := json.Unmarshal(d.raw(m.value), &member); err != nil {
+					return fmt.Errorf("parsing group %q: %w", g.key, err)
+				}
+				if member != oldName {
+					continue
+				}
+				switch {
+				case newName != "":
+					err = d.replace(m.value, newName)
+				case len(members) == 1:
+					err = d.remove(groupsSpan, gi)
+				default:
+					err = d.remove(g.value, mi)
+				}
+				if err != nil {
+					return err
+				}
+				edited = true
+				break
+			}
+			if edited {
+				break
+			}
+		}
+		if !edited {
+			return nil
+		}
+	}
+}
+
+// targets returns where the targets array is, or nil when the config has
+// none, and its entries.
+func (d *configDoc) targets() (*span, []entry, error) {
+	sp, ok, err := d.find("targets")
+	if err != nil || !ok {
+		return nil, nil, err
+	}
+	list, err := d.entries(sp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing targets: %w", err)
+	}
+	return &sp, list, nil
+}
+
+// findTarget returns the index of the target whose name, or default name,
+// is name, or -1.
+func findTarget(d *configDoc, targets []entry, name string) int {
+	for i, e := range targets {
+		var t Target
+		if err := json.Unmarshal(d.raw(e.value), &t); err != nil {
+			continue
+		}
+		effective := t.Name
+		if effective == "" && t.Repo != "" {
+			effective = t.Repo
+		} else if effective == "" {
+			effective = t.Owner()
+		}
+		if effective == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// AppendProvider adds provider p as name to the v2 config file at path.
+func AppendProvider(path, name string, p Provider) error {
+	doc, err := readConfigDoc(path)
+	if err != nil {
+		return err
+	}
+	providers, ok, err := doc.find("providers")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		root, err := doc.root()
+		if err != nil {
+			return err
+		}
+		if err := doc.set(root, "providers", map[string]Provider{name: p}); err != nil {
+			return err
+		}
+		return doc.write(path)
+	}
+	if _, exists, err := doc.find("providers", name); err != nil {
+		return fmt.Errorf("parsing providers: %w", err)
+	} else if exists {
+		return fmt.Errorf("provider %q already exists", name)
+	}
+	if err := doc.set(providers, name, p); err != nil {
+		return err
+	}
+	return doc.write(path)
+}
+
+// ReadProvider returns the named provider from the v2 config file at path
+// without validating the rest of the config, so it works before a token has
+// been stored.
+func ReadProvider(path, name string) (Provider, error) {
+	doc, err := readConfigDoc(path)
+	if err != nil {
+		return Provider{}, err
+	}
+	sp, ok, err := doc.find("providers", name)
+	if err != nil {
+		return Provider{}, fmt.Errorf("parsing providers: %w", err)
+	}
+	if !ok {
+		return Provider{}, fmt.Errorf("provider %q is not defined in %s", name, path)
+	}
+	var p Provider
+	if err := json.Unmarshal(doc.raw(sp), &p); err != nil {
+		return Provider{}, fmt.Errorf("parsing provider %q: %w", name, err)
+	}
+	return p, nil
+}
+
+// SetProviderToken stores token as the token of the named provider in the v2
+// config file at path, keeping everything else as it is. Providers that get
+// their token from token_cmd are refused.
+func SetProviderToken(path, provider, token string) error {
+	doc, err := readConfigDoc(path)
+	if err != nil {
+		return err
+	}
+	sp, ok, err := doc.find("providers", provider)
+	if err != nil {
+		return fmt.Errorf("parsing providers: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("provider %q is not defined in %s", provider, path)
+	}
+	if cmd, ok, err := doc.find("providers", provider, "token_cmd"); err != nil {
+		return fmt.Errorf("parsing provider %q: %w", provider, err)
+	} else if ok && string(doc.raw(cmd)) != `""` {
+		return fmt.Errorf("provider %q uses token_cmd; remove it before storing a token", provider)
+	}
+	if err := doc.set(sp, "token", token); err != nil {
+		return err
+	}
+	return doc.write(path)
+}
+
+// collapseHome rewrites paths under the home directory as ~/..., the inverse
+// of expandPath.
+func collapseHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(home, path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		return "~/" + filepath.ToSlash(rel)
+	}
+	return path
+}
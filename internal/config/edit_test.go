@@ -0,0 +1,234 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppendTargetKeepsSettingsAndOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	original := `{
+  "workers": 4,
+  "providers": {"github": {"type": "github", "token": "t"}},
+  "targets": [{"provider": "github", "org": "acme", "path": "/src/acme"}],
+  "x-notes": "kept"
+}`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AppendTarget(path, Target{Name: "api", Provider: "github", Org: "other", Repo: "api", Path: "/src/api"}); err != nil {
+		t.Fatalf("AppendTarget() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	if !(strings.Index(text, `"workers"`) < strings.Index(text, `"providers"`) &&
+		strings.Index(text, `"providers"`) < strings.Index(text, `"targets"`) &&
+		strings.Index(text, `"targets"`) < strings.Index(text, `"x-notes"`)) {
+		t.Errorf("key order not preserved:\n%s", text)
+	}
+	cfg, err := ReadV2(data)
+	if err != nil {
+		t.Fatalf("ReadV2() error = %v", err)
+	}
+	if len(cfg.Targets) != 2 || cfg.Targets[1].Name != "api" {
+		t.Errorf("targets = %+v, want appended api target", cfg.Targets)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+}
+
+func TestAppendTargetRejectsDuplicateName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"providers": {"github": {"type": "github", "token": "t"}}, "targets": [{"provider": "github", "org": "acme", "path": "/src/acme"}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The existing target's name defaults to its org.
+	if err := AppendTarget(path, Target{Name: "acme", Provider: "github", Org: "acme", Repo: "x", Path: "/x"}); err == nil {
+		t.Error("AppendTarget() should reject a duplicate default name")
+	}
+}
+
+func TestSetProviderTokenKeepsProviderFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"providers": {"gitea": {"type": "gitea", "api_url": "https://gitea.acme.com", "token": "old"}, "github": {"type": "github", "token": "gh"}}, "targets": [{"provider": "gitea", "org": "acme", "path": "/src/acme"}]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetProviderToken(path, "gitea", "new"); err != nil {
+		t.Fatalf("SetProviderToken() error = %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReadV2(written)
+	if err != nil {
+		t.Fatalf("ReadV2() error = %v", err)
+	}
+	if got := cfg.Providers["gitea"]; got.Token != "new" || got.APIURL != "https://gitea.acme.com" {
+		t.Errorf("gitea provider = %+v, want new token and api_url kept", got)
+	}
+	if cfg.Providers["github"].Token != "gh" {
+		t.Errorf("github token changed to %q", cfg.Providers["github"].Token)
+	}
+	if strings.Index(string(written), `"gitea"`) > strings.Index(string(written), `"github"`) {
+		t.Errorf("provider order not preserved:\n%s", written)
+	}
+
+	if err := SetProviderToken(path, "gitlab", "x"); err == nil {
+		t.Error("SetProviderToken() should reject an unknown provider")
+	}
+}
+
+func TestRenameAndRemoveTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	original := `{"providers": {"github": {"type": "github", "token": "t"}}, "targets": [{"provider": "github", "org": "acme", "path": "/src/acme"}, {"provider": "github", "org": "acme", "repo": "api", "path": "/src/api", "name": "api"}]}`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RenameTarget(path, "acme", "api"); err == nil {
+		t.Error("RenameTarget() should reject a name already in use")
+	}
+	if err := RenameTarget(path, "acme", "platform"); err != nil {
+		t.Fatalf("RenameTarget() error = %v", err)
+	}
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatalf("backup not written: %v", err)
+	}
+	if string(backup) != original {
+		t.Errorf("backup = %s, want the previous file", backup)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Index(string(data), `"provider"`) > strings.Index(string(data), `"name": "platform"`) {
+		t.Errorf("renamed target should keep its field order:\n%s", data)
+	}
+
+	if err := RemoveTarget(path, "api"); err != nil {
+		t.Fatalf("RemoveTarget() error = %v", err)
+	}
+	if err := RemoveTarget(path, "missing"); err == nil {
+		t.Error("RemoveTarget() should reject an unknown target")
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReadV2(data)
+	if err != nil {
+		t.Fatalf("ReadV2() error = %v", err)
+	}
+	if len(cfg.Targets) != 1 || cfg.Targets[0].Name != "platform" {
+		t.Errorf("targets = %+v, want only platform", cfg.Targets)
+	}
+}
+
+func TestRenameAndRemoveTargetUpdateGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	original := `{"providers": {"github": {"type": "github", "token": "t"}}, "targets": [{"provider": "github", "org": "acme", "path": "/src/acme"}, {"provider": "github", "org": "infra", "path": "/src/infra"}], "groups": {"work": ["acme", "infra"], "ops": ["infra"]}}`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RenameTarget(path, "acme", "platform"); err != nil {
+		t.Fatalf("RenameTarget() error = %v", err)
+	}
+	if err := RemoveTarget(path, "infra"); err != nil {
+		t.Fatalf("RemoveTarget() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReadV2(data)
+	if err != nil {
+		t.Fatalf("ReadV2() error = %v", err)
+	}
+	if len(cfg.Groups) != 1 || strings.Join(cfg.Groups["work"], ",") != "platform" {
+		t.Errorf("groups = %v, want only work: [platform]", cfg.Groups)
+	}
+}
+
+func TestEditsKeepUntouchedBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	compact := `{"path": "/src/acme",   "org": "acme", "provider": "github"}`
+	spread := `{
+      "provider": "github",
+      "org": "infra",
+      "path": "/src/infra"
+    }`
+	original := `{
+    "providers":   {"github": {"type": "github", "token": "t"}},
+    "targets": [
+    ` + compact + `,
+    ` + spread + `
+    ],
+    "groups": {"ops": ["infra", "acme"]}
+}
+`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AppendTarget(path, Target{Name: "api", Provider: "github", Org: "acme", Repo: "api", Path: "/src/api"}); err != nil {
+		t.Fatalf("AppendTarget() error = %v", err)
+	}
+	if err := RenameTarget(path, "infra", "platform"); err != nil {
+		t.Fatalf("RenameTarget() error = %v", err)
+	}
+	if err := SetProviderToken(path, "github", "new"); err != nil {
+		t.Fatalf("SetProviderToken() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	for _, kept := range []string{compact, `"providers":   {"github": {"type": "github", "token": "new"}},`, `"groups": {"ops": ["platform", "acme"]}`} {
+		if !strings.Contains(text, kept) {
+			t.Errorf("edited config lost %s:\n%s", kept, text)
+		}
+	}
+	if !strings.Contains(text, `"path": "/src/infra",
+      "name": "platform"
+    }`) {
+		t.Errorf("renamed target not laid out like its fields:\n%s", text)
+	}
+
+	if err := RemoveTarget(path, "api"); err != nil {
+		t.Fatalf("RemoveTarget() error = %v", err)
+	}
+	if err := RemoveTarget(path, "platform"); err != nil {
+		t.Fatalf("RemoveTarget() error = %v", err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+    "providers":   {"github": {"type": "github", "token": "new"}},
+    "targets": [
+    ` + compact + `
+    ],
+    "groups": {"ops": ["acme"]}
+}
+`
+	if string(data) != want {
+		t.Errorf("config after removing the added targets =\n%s\nwant\n%s", data, want)
+	}
+}
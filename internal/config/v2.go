@@ -3,15 +3,38 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 )
 
-// ReadV2 parses a v2 (current) config format
+// ReadV2 parses a v2 (current) config format. Relative includes are resolved
+// against the working directory.
 func ReadV2(data []byte) (*Config, error) {
+	return readV2(data, "")
+}
+
+// readV2 parses a v2 config read from path, merging its includes before
+// validation so targets may live entirely in included files.
+func readV2(data []byte, path string) (*Config, error) {
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("parsing v2 config: %w", err)
 	}
 
+	seen := make(map[string]bool)
+	if path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			seen[abs] = true
+		}
+	}
+	if err := mergeIncludes(&cfg, path, seen); err != nil {
+		return nil, err
+	}
+
 	// Validate and apply defaults
 	if err := validateAndNormalizeV2(&cfg); err != nil {
 		return nil, err
@@ -20,6 +43,58 @@ func ReadV2(data []byte) (*Config, error) {
 	return &cfg, nil
 }
 
+// mergeIncludes folds the providers, targets and groups of cfg's includes
+// (and theirs, recursively) into cfg. A provider or group defined in two
+// files, or a file included twice, is an error.
+func mergeIncludes(cfg *Config, from string, seen map[string]bool) error {
+	for _, inc := range cfg.Include {
+		incPath := expandPath(inc)
+		if !filepath.IsAbs(incPath) {
+			incPath = filepath.Join(filepath.Dir(from), incPath)
+		}
+		if abs, err := filepath.Abs(incPath); err == nil {
+			incPath = abs
+		}
+		if seen[incPath] {
+			return fmt.Errorf("config %s is included more than once", incPath)
+		}
+		seen[incPath] = true
+
+		data, err := os.ReadFile(incPath)
+		if err != nil {
+			return fmt.Errorf("reading included config: %w", err)
+		}
+		var included Config
+		if err := json.Unmarshal(data, &included); err != nil {
+			return fmt.Errorf("parsing included config %s: %w", incPath, err)
+		}
+		if err := mergeIncludes(&included, incPath, seen); err != nil {
+			return err
+		}
+
+		for name, p := range included.Providers {
+			if _, dup := cfg.Providers[name]; dup {
+				return fmt.Errorf("provider %q is defined more than once (again in %s)", name, incPath)
+			}
+			if cfg.Providers == nil {
+				cfg.Providers = make(map[string]Provider)
+			}
+			cfg.Providers[name] = p
+		}
+		cfg.Targets = append(cfg.Targets, included.Targets...)
+		for name, members := range included.Groups {
+			if _, dup := cfg.Groups[name]; dup {
+				return fmt.Errorf("group %q is defined more than once (again in %s)", name, incPath)
+			}
+			if cfg.Groups == nil {
+				cfg.Groups = make(map[string][]string)
+			}
+			cfg.Groups[name] = members
+		}
+	}
+	return nil
+}
+
 // validateAndNormalizeV2 validates the config and applies default values
 func validateAndNormalizeV2(cfg *Config) error {
 	// Validate providers
@@ -28,7 +103,7 @@ func validateAndNormalizeV2(cfg *Config) error {
 	}
 
 	for name, p := range cfg.Providers {
-		if p.Type != "gitea" && p.Type != "github" {
+		if p.Type != "gitea" && p.Type != "github" && p.Type != "gitlab" {
 			return fmt.Errorf("provider %q has unsupported type %q", name, p.Type)
 		}
 		if p.Type == "gitea" && p.APIURL == "" {
@@ -38,16 +113,99 @@ func validateAndNormalizeV2(cfg *Config) error {
 			p.APIURL = "https://api.github.com"
 			cfg.Providers[name] = p
 		}
-		if p.Token == "" {
-			return fmt.Errorf("provider %q requires token", name)
+		if p.Type == "gitlab" && p.APIURL == "" {
+			p.APIURL = "https://gitlab.com"
+		}
+		if p.Token == "" && p.TokenCmd == "" {
+			return fmt.Errorf("provider %q requires token or token_cmd", name)
+		}
+		if p.Token != "" && p.TokenCmd != "" {
+			return fmt.Errorf("provider %q sets both token and token_cmd", name)
+		}
+		if p.SSHKey != "" && p.SSHCommand != "" {
+			return fmt.Errorf("provider %q sets both ssh_key and ssh_command", name)
+		}
+		p.SSHKey = expandPath(p.SSHKey)
+		p.CACertFile = expandPath(p.CACertFile)
+		if p.Proxy != "" {
+			u, err := url.Parse(p.Proxy)
+			if err != nil || u.Host == "" {
+				return fmt.Errorf("provider %q has invalid proxy %q", name, p.Proxy)
+			}
+			switch u.Scheme {
+			case "http", "https", "socks5", "socks5h":
+			default:
+				return fmt.Errorf("provider %q has unsupported proxy scheme %q (want http, https or socks5)", name, u.Scheme)
+			}
 		}
 		// Default clone protocol
 		if p.Options.Clone.Protocol == "" {
 			p.Options.Clone.Protocol = "https"
 		}
+		if p.Options.Clone.Depth < 0 {
+			return fmt.Errorf("provider %q has negative clone depth %d", name, p.Options.Clone.Depth)
+		}
+		if !validCloneMode(p.Options.Clone.Mode) {
+			return fmt.Errorf("provider %q has unsupported clone mode %q", name, p.Options.Clone.Mode)
+		}
+		if !validCloneFilter(p.Options.Clone.Filter) {
+			return fmt.Errorf("provider %q has unsupported clone filter %q", name, p.Options.Clone.Filter)
+		}
+		p.Options.Clone.ReferenceDir = expandPath(p.Options.Clone.ReferenceDir)
+		if p.Options.Clone.Releases && !hasReleases(p.Type) {
+			return fmt.Errorf("provider %q sets clone.releases, but %s has no release backups", name, p.Type)
+		}
 		cfg.Providers[name] = p
 	}
 
+	if cfg.NetworkWorkers < 0 {
+		return fmt.Errorf("network_workers must not be negative, got %d", cfg.NetworkWorkers)
+	}
+	cfg.WorktreeDir = expandPath(cfg.WorktreeDir)
+	for _, task := range cfg.GCTasks {
+		if !ValidGCTask(task) {
+			return fmt.Errorf("gc_tasks has unsupported task %q", task)
+		}
+	}
+
+	// Validate notifications
+	if n := cfg.Notifications; n != nil {
+		if n.URL == "" {
+			return fmt.Errorf("notifications requires url")
+		}
+		for _, event := range n.Events {
+			if event != "failed" && event != "diverged" && event != "orphan" {
+				return fmt.Errorf("notifications has unsupported event %q (want failed, diverged or orphan)", event)
+			}
+		}
+	}
+
+	// Validate audit policy
+	if a := cfg.Audit; a != nil && a.Visibility != "" && a.Visibility != "public" && a.Visibility != "private" {
+		return fmt.Errorf("audit has unsupported visibility %q (want public or private)", a.Visibility)
+	}
+
+	// Validate hooks
+	for event, hooks := range cfg.Hooks {
+		if !slices.Contains(HookEvents, event) {
+			return fmt.Errorf("hooks has unsupported event %q (want %s)", event, strings.Join(HookEvents, ", "))
+		}
+		for _, h := range hooks {
+			if strings.TrimSpace(h.Run) == "" {
+				return fmt.Errorf("hooks %s has a hook without a command", event)
+			}
+			// There is no repo to run in before it is cloned.
+			if event == "pre_clone" && !h.Once {
+				return fmt.Errorf("hooks pre_clone only runs once per run; set \"once\": true")
+			}
+		}
+	}
+	for _, pattern := range cfg.AllowRepoHooks {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("allow_repo_hooks has invalid pattern %q: %w", pattern, err)
+		}
+	}
+
 	// Validate targets
 	if len(cfg.Targets) == 0 {
 		return fmt.Errorf("at least one target must be configured")
@@ -62,20 +220,90 @@ func validateAndNormalizeV2(cfg *Config) error {
 		if _, ok := cfg.Providers[t.Provider]; !ok {
 			return fmt.Errorf("target %d references unknown provider %q", i, t.Provider)
 		}
-		if t.Org == "" {
-			return fmt.Errorf("target %d missing org", i)
+		if t.Org == "" && t.User == "" {
+			return fmt.Errorf("target %d missing org or user", i)
+		}
+		if t.Org != "" && t.User != "" {
+			return fmt.Errorf("target %d sets both org and user", i)
 		}
 		if t.Path == "" {
-			return fmt.Errorf("target %s missing path", t.Org)
+			return fmt.Errorf("target %s missing path", t.Owner())
+		}
+		if t.Repo != "" && len(t.Topics) > 0 {
+			return fmt.Errorf("target %s/%s: topics only apply to org or user targets", t.Owner(), t.Repo)
+		}
+		if t.Repo != "" && (len(t.Include) > 0 || len(t.Exclude) > 0) {
+			return fmt.Errorf("target %s/%s: include/exclude only apply to org or user targets", t.Owner(), t.Repo)
+		}
+		if t.Repo != "" && t.Forks != "" {
+			return fmt.Errorf("target %s/%s: forks only applies to org or user targets", t.Owner(), t.Repo)
+		}
+		if t.Forks != "" && t.Forks != "exclude" && t.Forks != "only" {
+			return fmt.Errorf("target %s has unsupported forks %q (want exclude or only)", t.Owner(), t.Forks)
+		}
+		if t.Repo != "" && t.Visibility != "" {
+			return fmt.Errorf("target %s/%s: visibility only applies to org or user targets", t.Owner(), t.Repo)
+		}
+		if t.Visibility != "" && t.Visibility != "public" && t.Visibility != "private" {
+			return fmt.Errorf("target %s has unsupported visibility %q (want public or private)", t.Owner(), t.Visibility)
+		}
+		if t.Repo != "" && t.Search != "" {
+			return fmt.Errorf("target %s/%s: search only applies to org or user targets", t.Owner(), t.Repo)
+		}
+		if t.Team != "" && (t.Org == "" || t.Repo != "") {
+			return fmt.Errorf("target %s: team only applies to org targets", t.Owner())
+		}
+		if t.Team != "" && t.Search != "" {
+			return fmt.Errorf("target %s sets both team and search", t.Owner())
+		}
+		if t.Repo != "" && len(t.Languages) > 0 {
+			return fmt.Errorf("target %s/%s: languages only apply to org or user targets", t.Owner(), t.Repo)
+		}
+		if t.Repo != "" && (t.Recurse || t.MaxDepth != 0) {
+			return fmt.Errorf("target %s/%s: recurse only applies to org or user targets", t.Owner(), t.Repo)
+		}
+		if t.MaxDepth < 0 || (t.MaxDepth > 0 && !t.Recurse) {
+			return fmt.Errorf("target %s: max_depth must be positive and needs recurse", t.Owner())
+		}
+		if t.Repo != "" && t.Layout != "" {
+			return fmt.Errorf("target %s/%s: layout only applies to org or user targets", t.Owner(), t.Repo)
+		}
+		if _, err := t.RepoDir("name", []string{"topic"}); err != nil {
+			return fmt.Errorf("target %s has invalid layout: %w", t.Owner(), err)
+		}
+		for _, pattern := range append(append([]string{}, t.Include...), t.Exclude...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("target %s has invalid pattern %q: %w", t.Owner(), pattern, err)
+			}
 		}
 		t.Path = expandPath(t.Path)
+		if t.Clone != nil && t.Clone.Depth < 0 {
+			return fmt.Errorf("target %s has negative clone depth %d", t.Owner(), t.Clone.Depth)
+		}
+		if t.Clone != nil && !validCloneMode(t.Clone.Mode) {
+			return fmt.Errorf("target %s has unsupported clone mode %q", t.Owner(), t.Clone.Mode)
+		}
+		if t.Clone != nil && !validCloneFilter(t.Clone.Filter) {
+			return fmt.Errorf("target %s has unsupported clone filter %q", t.Owner(), t.Clone.Filter)
+		}
+		if t.Clone != nil {
+			t.Clone.ReferenceDir = expandPath(t.Clone.ReferenceDir)
+		}
+		if opts := cfg.CloneOptionsFor(*t); opts.Releases {
+			if provider := cfg.Providers[t.Provider]; !hasReleases(provider.Type) {
+				return fmt.Errorf("target %s sets clone.releases, but %s has no release backups", t.Owner(), provider.Type)
+			}
+			if opts.Mode != "mirror" {
+				return fmt.Errorf("target %s sets clone.releases without clone.mode mirror; releases are only backed up into mirrors", t.Owner())
+			}
+		}
 
-		// Default name to repo or org
+		// Default name to repo, org or user
 		if t.Name == "" {
 			if t.Repo != "" {
 				t.Name = t.Repo
 			} else {
-				t.Name = t.Org
+				t.Name = t.Owner()
 			}
 		}
 
@@ -85,5 +313,52 @@ func validateAndNormalizeV2(cfg *Config) error {
 		nameSet[t.Name] = true
 	}
 
+	for name, members := range cfg.Groups {
+		if nameSet[name] {
+			return fmt.Errorf("group %q has the name of a target", name)
+		}
+		if len(members) == 0 {
+			return fmt.Errorf("group %q has no targets", name)
+		}
+		for _, member := range members {
+			if !nameSet[member] {
+				return fmt.Errorf("group %q references unknown target %q", name, member)
+			}
+		}
+	}
+
 	return nil
 }
+
+// hasReleases reports whether tugboat can back up the releases of providers
+// of type typ.
+func hasReleases(typ string) bool {
+	return typ == "github" || typ == "gitea"
+}
+
+func validCloneMode(mode string) bool {
+	return mode == "" || mode == "mirror"
+}
+
+// ValidGCTask reports whether task is a `git maintenance run --task` name.
+func ValidGCTask(task string) bool {
+	switch task {
+	case "gc", "commit-graph", "prefetch", "loose-objects", "incremental-repack", "pack-refs":
+		return true
+	}
+	return false
+}
+
+// validCloneFilter accepts the filter specs of `git clone --filter`, e.g.
+// blob:none, blob:limit=1m or tree:0.
+func validCloneFilter(filter string) bool {
+	if filter == "" || filter == "blob:none" {
+		return true
+	}
+	for _, prefix := range []string{"blob:limit=", "tree:", "object:type=", "sparse:oid=", "combine:"} {
+		if strings.HasPrefix(filter, prefix) && len(filter) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TokenScopes are the access token scopes tugboat needs: reading orgs and
+// users to list repos, and repository access for clone, pull and push.
+var TokenScopes = []string{"read:organization", "read:user", "write:repository"}
+
+// CreateToken signs in with a username and password and creates a personal
+// access token called name. Gitea only hands out new tokens to basic auth,
+// so this is the programmatic equivalent of Settings > Applications. The
+// request goes through transport; nil means http.DefaultTransport.
+func CreateToken(transport http.RoundTripper, baseURL, username, password, name string, scopes []string) (string, error) {
+	body, err := json.Marshal(map[string]any{"name": name, "scopes": scopes})
+	if err != nil {
+		return "", err
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/users/%s/tokens", strings.TrimSuffix(baseURL, "/"), username)
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("creating token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var token struct {
+		SHA1 string `json:"sha1"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	if token.SHA1 == "" {
+		return "", fmt.Errorf("no token returned")
+	}
+	return token.SHA1, nil
+}
@@ -1,31 +1,66 @@
 package gitea
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
 )
 
 // Repository mirrors the Gitea API response. It stays here for direct use and
 // to convert into the provider-agnostic remote.Repository.
 type Repository struct {
-	ID            int64  `json:"id"`
-	Name          string `json:"name"`
-	FullName      string `json:"full_name"`
-	Description   string `json:"description"`
-	CloneURL      string `json:"clone_url"`
-	SSHURL        string `json:"ssh_url"`
-	HTMLURL       string `json:"html_url"`
-	DefaultBranch string `json:"default_branch"`
-	Empty         bool   `json:"empty"`
-	Archived      bool   `json:"archived"`
-	Private       bool   `json:"private"`
-	Fork          bool   `json:"fork"`
+	ID            int64       `json:"id"`
+	Name          string      `json:"name"`
+	FullName      string      `json:"full_name"`
+	Description   string      `json:"description"`
+	CloneURL      string      `json:"clone_url"`
+	SSHURL        string      `json:"ssh_url"`
+	HTMLURL       string      `json:"html_url"`
+	DefaultBranch string      `json:"default_branch"`
+	Empty         bool        `json:"empty"`
+	Archived      bool        `json:"archived"`
+	Private       bool        `json:"private"`
+	Fork          bool        `json:"fork"`
+	Template      bool        `json:"template"`
+	Topics        []string    `json:"topics"`
+	Language      string      `json:"language"`
+	Parent        *Repository `json:"parent,omitempty"`
+}
+
+// toRemote converts a Gitea repository into the provider-agnostic form.
+func (r Repository) toRemote() remote.Repository {
+	out := remote.Repository{
+		ID:            r.ID,
+		Name:          r.Name,
+		FullName:      r.FullName,
+		Description:   r.Description,
+		CloneURL:      r.CloneURL,
+		SSHURL:        r.SSHURL,
+		HTMLURL:       r.HTMLURL,
+		DefaultBranch: r.DefaultBranch,
+		Empty:         r.Empty,
+		Archived:      r.Archived,
+		Private:       r.Private,
+		Fork:          r.Fork,
+		Template:      r.Template,
+		Topics:        r.Topics,
+		Language:      r.Language,
+	}
+	if r.Parent != nil {
+		parent := r.Parent.toRemote()
+		out.Parent = &parent
+	}
+	return out
 }
 
 // Client is a Gitea API client
@@ -46,68 +81,110 @@ func NewClient(baseURL, token string) *Client {
 	}
 }
 
+// SetTransport replaces the transport used for API requests, e.g. with a
+// caching one.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
 // ListOrgRepos lists all repositories in an organization
 func (c *Client) ListOrgRepos(orgName string) ([]remote.Repository, error) {
-	var allRepos []remote.Repository
-	page := 1
-	limit := 50
+	return c.listRepos(fmt.Sprintf("%s/api/v1/orgs/%s/repos", c.baseURL, orgName))
+}
 
-	for {
-		url := fmt.Sprintf("%s/api/v1/orgs/%s/repos?page=%d&limit=%d", c.baseURL, orgName, page, limit)
+// ListUserRepos lists all repositories owned by a user account
+func (c *Client) ListUserRepos(userName string) ([]remote.Repository, error) {
+	return c.listRepos(fmt.Sprintf("%s/api/v1/users/%s/repos", c.baseURL, userName))
+}
 
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, fmt.Errorf("creating request: %w", err)
-		}
+// pageWorkers caps how many listing pages are requested at once.
+const pageWorkers = 8
 
-		req.Header.Set("Authorization", "token "+c.token)
-		req.Header.Set("Accept", "application/json")
+// listRepos pages through a repository listing endpoint. When the first page
+// reports X-Total-Count the remaining pages are fetched concurrently;
+// otherwise pages are read one by one until a short page.
+func (c *Client) listRepos(endpoint string) ([]remote.Repository, error) {
+	limit := 50
 
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("fetching repos: %w", err)
-		}
-		defer resp.Body.Close()
+	allRepos, header, err := c.fetchPage(endpoint, 1, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(allRepos) < limit {
+		return allRepos, nil
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	if total, err := strconv.Atoi(header.Get("X-Total-Count")); err == nil && total > 0 {
+		var pages []int
+		for page := 2; page <= (total+limit-1)/limit; page++ {
+			pages = append(pages, page)
 		}
-
-		var repos []Repository
-		if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-			return nil, fmt.Errorf("decoding response: %w", err)
+		type pageResult struct {
+			page  int
+			repos []remote.Repository
+			err   error
 		}
-
-		if len(repos) == 0 {
-			break
+		results := pool.Run(pages, pageWorkers, func(page int) pageResult {
+			repos, _, err := c.fetchPage(endpoint, page, limit)
+			return pageResult{page: page, repos: repos, err: err}
+		})
+		sort.Slice(results, func(i, j int) bool { return results[i].page < results[j].page })
+		for _, r := range results {
+			if r.err != nil {
+				return nil, r.err
+			}
+			allRepos = append(allRepos, r.repos...)
 		}
+		return allRepos, nil
+	}
 
-		for _, r := range repos {
-			allRepos = append(allRepos, remote.Repository{
-				ID:            r.ID,
-				Name:          r.Name,
-				FullName:      r.FullName,
-				Description:   r.Description,
-				CloneURL:      r.CloneURL,
-				SSHURL:        r.SSHURL,
-				HTMLURL:       r.HTMLURL,
-				DefaultBranch: r.DefaultBranch,
-				Empty:         r.Empty,
-				Archived:      r.Archived,
-				Private:       r.Private,
-				Fork:          r.Fork,
-			})
+	for page := 2; ; page++ {
+		repos, _, err := c.fetchPage(endpoint, page, limit)
+		if err != nil {
+			return nil, err
 		}
-
+		allRepos = append(allRepos, repos...)
 		if len(repos) < limit {
 			break
 		}
+	}
+	return allRepos, nil
+}
+
+// fetchPage requests one page of a repository listing and returns the repos
+// with the response headers.
+func (c *Client) fetchPage(endpoint string, page, limit int) ([]remote.Repository, http.Header, error) {
+	url := fmt.Sprintf("%s?page=%d&limit=%d", endpoint, page, limit)
 
-		page++
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	return allRepos, nil
+	req.Header.Set("Authorization", "token "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching repos: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var repos []Repository
+	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	out := make([]remote.Repository, 0, len(repos))
+	for _, r := range repos {
+		out = append(out, r.toRemote())
+	}
+	return out, resp.Header, nil
 }
 
 // GetRepo gets a specific repository
@@ -142,18 +219,378 @@ func (c *Client) GetRepo(owner, repoName string) (*remote.Repository, error) {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
-	return &remote.Repository{
-		ID:            repo.ID,
-		Name:          repo.Name,
-		FullName:      repo.FullName,
-		Description:   repo.Description,
-		CloneURL:      repo.CloneURL,
-		SSHURL:        repo.SSHURL,
-		HTMLURL:       repo.HTMLURL,
-		DefaultBranch: repo.DefaultBranch,
-		Empty:         repo.Empty,
-		Archived:      repo.Archived,
-		Private:       repo.Private,
-		Fork:          repo.Fork,
-	}, nil
+	r := repo.toRemote()
+	return &r, nil
+}
+
+// CreateRepo creates an empty repository under an organization, or under
+// the token's own account when owner is that user.
+func (c *Client) CreateRepo(owner, name string, opts remote.CreateOptions) (*remote.Repository, error) {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := c.sendJSON("GET", c.baseURL+"/api/v1/user", nil, &user); err != nil {
+		return nil, fmt.Errorf("fetching current user: %w", err)
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/orgs/%s/repos", c.baseURL, owner)
+	if strings.EqualFold(user.Login, owner) {
+		endpoint = c.baseURL + "/api/v1/user/repos"
+	}
+
+	body := map[string]any{"name": name, "description": opts.Description, "private": opts.Private}
+	if opts.DefaultBranch != "" {
+		body["default_branch"] = opts.DefaultBranch
+	}
+	var repo Repository
+	if err := c.sendJSON("POST", endpoint, body, &repo); err != nil {
+		return nil, fmt.Errorf("creating repo: %w", err)
+	}
+	r := repo.toRemote()
+	return &r, nil
+}
+
+// SyncFork updates branch of the fork owner/name from its parent with the
+// merge-upstream endpoint (Gitea 1.23 and later).
+func (c *Client) SyncFork(owner, name, branch string) error {
+	endpoint := fmt.Sprintf("%s/api/v1/repos/%s/%s/merge-upstream", c.baseURL, owner, name)
+	if err := c.sendJSON("POST", endpoint, map[string]string{"branch": branch}, nil); err != nil {
+		return fmt.Errorf("syncing fork: %w", err)
+	}
+	return nil
+}
+
+// migrateTimeout bounds a migration request; Gitea answers only once the
+// import has finished.
+const migrateTimeout = 30 * time.Minute
+
+// MigrateRepo imports src into owner/name with Gitea's migration API, taking
+// issues, labels, milestones, releases, pull requests and the wiki along
+// when the source is another forge.
+func (c *Client) MigrateRepo(owner, name string, src remote.MigrateSource) (*remote.Repository, error) {
+	body := map[string]any{
+		"clone_addr":  src.CloneURL,
+		"repo_owner":  owner,
+		"repo_name":   name,
+		"service":     src.Service,
+		"auth_token":  src.Token,
+		"description": src.Description,
+		"private":     src.Private,
+	}
+	if src.Service != "" && src.Service != "git" {
+		for _, item := range []string{"issues", "labels", "milestones", "releases", "pull_requests", "wiki"} {
+			body[item] = true
+		}
+	}
+	slow := *c.httpClient
+	slow.Timeout = migrateTimeout
+	var repo Repository
+	if err := c.doJSON(&slow, "POST", c.baseURL+"/api/v1/repos/migrate", body, &repo); err != nil {
+		return nil, fmt.Errorf("migrating repo: %w", err)
+	}
+	r := repo.toRemote()
+	return &r, nil
+}
+
+// PullRequest mirrors the subset of the Gitea pull request API response
+// tugboat uses.
+type PullRequest struct {
+	Number    int64     `json:"number"`
+	Title     string    `json:"title"`
+	HTMLURL   string    `json:"html_url"`
+	Draft     bool      `json:"draft"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	User      struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Head struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// toRemote converts a Gitea pull request into the provider-agnostic form.
+func (pr PullRequest) toRemote(review string) remote.PullRequest {
+	return remote.PullRequest{
+		Number:  pr.Number,
+		Title:   pr.Title,
+		Author:  pr.User.Login,
+		Head:    pr.Head.Ref,
+		Base:    pr.Base.Ref,
+		URL:     pr.HTMLURL,
+		Draft:   pr.Draft,
+		Created: pr.CreatedAt,
+		Updated: pr.UpdatedAt,
+		Review:  review,
+	}
+}
+
+// Release mirrors the parts of the Gitea release API response tugboat uses.
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
+		Name               string `json:"name"`
+		Size               int64  `json:"size"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (r Release) toRemote() remote.Release {
+	out := remote.Release{Tag: r.TagName, Name: r.Name, Published: r.PublishedAt}
+	for _, a := range r.Assets {
+		out.Assets = append(out.Assets, remote.ReleaseAsset{Name: a.Name, Size: a.Size, URL: a.BrowserDownloadURL})
+	}
+	return out
+}
+
+// ListReleases lists the releases of owner/name, newest first.
+func (c *Client) ListReleases(owner, name string) ([]remote.Release, error) {
+	releasesURL := fmt.Sprintf("%s/api/v1/repos/%s/%s/releases", c.baseURL, owner, name)
+	limit := 50
+
+	var out []remote.Release
+	for page := 1; ; page++ {
+		var releases []Release
+		if err := c.sendJSON("GET", fmt.Sprintf("%s?page=%d&limit=%d", releasesURL, page, limit), nil, &releases); err != nil {
+			return nil, fmt.Errorf("listing releases: %w", err)
+		}
+		for _, r := range releases {
+			out = append(out, r.toRemote())
+		}
+		if len(releases) < limit {
+			return out, nil
+		}
+	}
+}
+
+// DownloadAsset writes the content of a release asset to w.
+func (c *Client) DownloadAsset(asset remote.ReleaseAsset, w io.Writer) error {
+	req, err := http.NewRequest("GET", asset.URL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "token "+c.token)
+
+	// Assets may take far longer to download than an API call.
+	download := *c.httpClient
+	download.Timeout = 0
+	resp, err := download.Do(req)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", asset.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("downloading %s: API error (status %d): %s", asset.Name, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("downloading %s: %w", asset.Name, err)
+	}
+	return nil
+}
+
+// ListPullRequests lists the open pull requests of owner/name with their
+// review state. Reviews cost one request per pull request.
+func (c *Client) ListPullRequests(owner, name string) ([]remote.PullRequest, error) {
+	pullsURL := fmt.Sprintf("%s/api/v1/repos/%s/%s/pulls", c.baseURL, owner, name)
+	limit := 50
+
+	var out []remote.PullRequest
+	for page := 1; ; page++ {
+		var prs []PullRequest
+		if err := c.sendJSON("GET", fmt.Sprintf("%s?state=open&page=%d&limit=%d", pullsURL, page, limit), nil, &prs); err != nil {
+			return nil, fmt.Errorf("listing pull requests: %w", err)
+		}
+		for _, pr := range prs {
+			review, err := c.reviewState(pullsURL, pr.Number)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, pr.toRemote(review))
+		}
+		if len(prs) < limit {
+			return out, nil
+		}
+	}
+}
+
+// CreatePullRequest opens a pull request from pr.Head into pr.Base. Gitea
+// marks drafts by a "WIP:" title prefix.
+func (c *Client) CreatePullRequest(owner, name string, pr remote.NewPullRequest) (*remote.PullRequest, error) {
+	title := pr.Title
+	if pr.Draft {
+		title = "WIP: " + title
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/repos/%s/%s/pulls", c.baseURL, owner, name)
+	body := map[string]any{"title": title, "body": pr.Body, "head": pr.Head, "base": pr.Base}
+	var created PullRequest
+	if err := c.sendJSON("POST", endpoint, body, &created); err != nil {
+		return nil, fmt.Errorf("creating pull request: %w", err)
+	}
+	out := created.toRemote(remote.ReviewPending)
+	return &out, nil
+}
+
+// CountOpenIssues counts the open issues of owner/name.
+func (c *Client) CountOpenIssues(owner, name string, filter remote.IssueFilter) (int, error) {
+	query := url.Values{"state": {"open"}, "type": {"issues"}, "limit": {"50"}}
+	if len(filter.Labels) > 0 {
+		query.Set("labels", strings.Join(filter.Labels, ","))
+	}
+	if filter.Assignee != "" {
+		query.Set("assigned_by", filter.Assignee)
+	}
+	issuesURL := fmt.Sprintf("%s/api/v1/repos/%s/%s/issues", c.baseURL, owner, name)
+
+	count := 0
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var issues []json.RawMessage
+		if err := c.sendJSON("GET", issuesURL+"?"+query.Encode(), nil, &issues); err != nil {
+			return 0, fmt.Errorf("listing issues: %w", err)
+		}
+		count += len(issues)
+		if len(issues) < 50 {
+			return count, nil
+		}
+	}
+}
+
+// GetRepoSettings reads the settings of owner/name.
+func (c *Client) GetRepoSettings(owner, name string) (*remote.RepoSettings, error) {
+	repoURL := fmt.Sprintf("%s/api/v1/repos/%s/%s", c.baseURL, owner, name)
+	var r struct {
+		DefaultBranch       string `json:"default_branch"`
+		Private             bool   `json:"private"`
+		Empty               bool   `json:"empty"`
+		AllowMergeCommit    *bool  `json:"allow_merge_commits"`
+		AllowSquashMerge    *bool  `json:"allow_squash_merge"`
+		AllowRebaseMerge    *bool  `json:"allow_rebase"`
+		DeleteBranchOnMerge *bool  `json:"default_delete_branch_after_merge"`
+	}
+	if err := c.sendJSON("GET", repoURL, nil, &r); err != nil {
+		return nil, fmt.Errorf("fetching repo: %w", err)
+	}
+	settings := &remote.RepoSettings{
+		DefaultBranch:       r.DefaultBranch,
+		Private:             r.Private,
+		AllowMergeCommit:    r.AllowMergeCommit,
+		AllowSquashMerge:    r.AllowSquashMerge,
+		AllowRebaseMerge:    r.AllowRebaseMerge,
+		DeleteBranchOnMerge: r.DeleteBranchOnMerge,
+	}
+	if !r.Empty {
+		var branch struct {
+			Protected bool `json:"protected"`
+		}
+		if err := c.sendJSON("GET", repoURL+"/branches/"+url.PathEscape(r.DefaultBranch), nil, &branch); err != nil {
+			return nil, fmt.Errorf("fetching default branch: %w", err)
+		}
+		settings.BranchProtected = branch.Protected
+	}
+	return settings, nil
+}
+
+// GetFile returns the content of path on the default branch of owner/name,
+// or nil when there is no such file.
+func (c *Client) GetFile(owner, name, path string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/repos/%s/%s/raw/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(name), path)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "token "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// reviewState reads the reviews of one pull request, oldest first. Comments
+// and review requests leave a reviewer's verdict as it was; dismissed
+// reviews do not count.
+func (c *Client) reviewState(pullsURL string, number int64) (string, error) {
+	var reviews []struct {
+		State     string `json:"state"`
+		Dismissed bool   `json:"dismissed"`
+		User      struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := c.sendJSON("GET", fmt.Sprintf("%s/%d/reviews", pullsURL, number), nil, &reviews); err != nil {
+		return "", fmt.Errorf("listing reviews of #%d: %w", number, err)
+	}
+	latest := make(map[string]string)
+	for _, r := range reviews {
+		if r.Dismissed {
+			continue
+		}
+		switch r.State {
+		case "APPROVED":
+			latest[r.User.Login] = remote.ReviewApproved
+		case "REQUEST_CHANGES":
+			latest[r.User.Login] = remote.ReviewChangesRequested
+		}
+	}
+	return remote.ReviewState(latest), nil
+}
+
+// sendJSON sends in (if not nil) as the JSON request body and decodes a
+// successful response into out (if not nil).
+func (c *Client) sendJSON(method, endpoint string, in, out any) error {
+	return c.doJSON(c.httpClient, method, endpoint, in, out)
+}
+
+// doJSON is sendJSON with an explicit HTTP client.
+func (c *Client) doJSON(hc *http.Client, method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "token "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
 }
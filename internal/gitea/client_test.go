@@ -2,8 +2,10 @@ package gitea
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
@@ -65,6 +67,41 @@ func TestListOrgRepos(t *testing.T) {
 	}
 }
 
+func TestListOrgReposFetchesPagesFromTotalCount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 || page > 3 {
+			t.Errorf("unexpected page %d", page)
+		}
+		w.Header().Set("X-Total-Count", "120")
+		size := 50
+		if page == 3 {
+			size = 20
+		}
+		repos := make([]Repository, size)
+		for i := range repos {
+			n := (page-1)*50 + i
+			repos[i] = Repository{ID: int64(n), Name: fmt.Sprintf("repo%03d", n)}
+		}
+		json.NewEncoder(w).Encode(repos)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	result, err := client.ListOrgRepos("testorg")
+	if err != nil {
+		t.Fatalf("ListOrgRepos() error = %v", err)
+	}
+	if len(result) != 120 {
+		t.Fatalf("len(result) = %d, want 120", len(result))
+	}
+	for i, r := range result {
+		if r.ID != int64(i) {
+			t.Fatalf("result[%d].ID = %d, want pages in order", i, r.ID)
+		}
+	}
+}
+
 func TestListOrgReposAPIError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
@@ -171,3 +208,147 @@ func TestRepositoryGetCloneURLNoSSH(t *testing.T) {
 		t.Errorf("GetCloneURL(true) with no SSH = %q, want HTTPS fallback", result)
 	}
 }
+
+func TestCreateToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v1/users/alice/tokens" {
+			t.Errorf("request = %s %s, want POST to alice's tokens", r.Method, r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "secret" {
+			t.Errorf("basic auth = %q/%q, want alice/secret", user, pass)
+		}
+		var body struct {
+			Name   string   `json:"name"`
+			Scopes []string `json:"scopes"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Name != "tugboat" || len(body.Scopes) != len(TokenScopes) {
+			t.Errorf("body = %+v, want name and scopes", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"sha1": "abc123"})
+	}))
+	defer server.Close()
+
+	token, err := CreateToken(nil, server.URL, "alice", "secret", "tugboat", TokenScopes)
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	if token != "abc123" {
+		t.Errorf("token = %q, want %q", token, "abc123")
+	}
+}
+
+func TestCreateRepoPicksOrgOrUserEndpoint(t *testing.T) {
+	var created []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.Path == "/api/v1/user" {
+			json.NewEncoder(w).Encode(map[string]string{"login": "alice"})
+			return
+		}
+		var body struct {
+			Name          string `json:"name"`
+			Private       bool   `json:"private"`
+			DefaultBranch string `json:"default_branch"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !body.Private || body.DefaultBranch != "trunk" {
+			t.Errorf("body = %+v, want private with default branch trunk", body)
+		}
+		created = append(created, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Repository{Name: body.Name, FullName: "x/" + body.Name, Empty: true})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	opts := remote.CreateOptions{Private: true, DefaultBranch: "trunk"}
+	repo, err := client.CreateRepo("acme", "svc", opts)
+	if err != nil {
+		t.Fatalf("CreateRepo() error = %v", err)
+	}
+	if repo.Name != "svc" || !repo.Empty {
+		t.Errorf("repo = %+v, want empty svc", repo)
+	}
+	if _, err := client.CreateRepo("Alice", "notes", opts); err != nil {
+		t.Fatalf("CreateRepo() error = %v", err)
+	}
+
+	want := []string{"POST /api/v1/orgs/acme/repos", "POST /api/v1/user/repos"}
+	if fmt.Sprint(created) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", created, want)
+	}
+}
+
+func TestListPullRequestsReviewState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/acme/app/pulls":
+			if r.URL.Query().Get("state") != "open" {
+				t.Errorf("state = %q, want open", r.URL.Query().Get("state"))
+			}
+			fmt.Fprint(w, `[
+				{"number": 1, "title": "Fix", "user": {"login": "ann"}, "head": {"ref": "fix"}, "base": {"ref": "main"}},
+				{"number": 2, "title": "Feature", "draft": true, "user": {"login": "bob"}, "head": {"ref": "feat"}, "base": {"ref": "main"}},
+				{"number": 3, "title": "Docs", "user": {"login": "cy"}, "head": {"ref": "docs"}, "base": {"ref": "main"}}
+			]`)
+		case "/api/v1/repos/acme/app/pulls/1/reviews":
+			// A later approval replaces the reviewer's request for changes.
+			fmt.Fprint(w, `[
+				{"state": "REQUEST_CHANGES", "user": {"login": "dee"}},
+				{"state": "COMMENT", "user": {"login": "eve"}},
+				{"state": "APPROVED", "user": {"login": "dee"}}
+			]`)
+		case "/api/v1/repos/acme/app/pulls/2/reviews":
+			fmt.Fprint(w, `[
+				{"state": "APPROVED", "user": {"login": "dee"}},
+				{"state": "REQUEST_CHANGES", "user": {"login": "eve"}}
+			]`)
+		case "/api/v1/repos/acme/app/pulls/3/reviews":
+			fmt.Fprint(w, `[{"state": "APPROVED", "dismissed": true, "user": {"login": "dee"}}]`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	prs, err := NewClient(server.URL, "test-token").ListPullRequests("acme", "app")
+	if err != nil {
+		t.Fatalf("ListPullRequests() error = %v", err)
+	}
+	want := []string{remote.ReviewApproved, remote.ReviewChangesRequested, remote.ReviewPending}
+	if len(prs) != len(want) {
+		t.Fatalf("got %d pull requests, want %d", len(prs), len(want))
+	}
+	for i, pr := range prs {
+		if pr.Review != want[i] {
+			t.Errorf("#%d review = %q, want %q", pr.Number, pr.Review, want[i])
+		}
+	}
+	if prs[1].Author != "bob" || prs[1].Head != "feat" || !prs[1].Draft {
+		t.Errorf("#2 = %+v, want draft by bob from feat", prs[1])
+	}
+}
+
+func TestGetFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/acme/app/raw/.tugboat.json":
+			fmt.Fprint(w, `{"repos": []}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	data, err := client.GetFile("acme", "app", ".tugboat.json")
+	if err != nil || string(data) != `{"repos": []}` {
+		t.Fatalf("GetFile() = %q, %v", data, err)
+	}
+	data, err = client.GetFile("acme", "lib", ".tugboat.json")
+	if err != nil || data != nil {
+		t.Fatalf("GetFile() of a missing file = %q, %v, want nil", data, err)
+	}
+}
@@ -0,0 +1,120 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DeviceCode is GitHub's answer to a device authorization request: the user
+// enters UserCode at VerificationURI while the caller polls for the token.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// WebBase returns the web root that serves OAuth endpoints for an API root:
+// api.github.com maps to github.com, and Enterprise's <host>/api/v3 to <host>.
+func WebBase(apiBase string) string {
+	apiBase = strings.TrimSuffix(apiBase, "/")
+	if apiBase == "" || apiBase == "https://api.github.com" {
+		return "https://github.com"
+	}
+	return strings.TrimSuffix(apiBase, "/api/v3")
+}
+
+// RequestDeviceCode starts the OAuth device flow for the OAuth app clientID.
+// Requests go through transport; nil means http.DefaultTransport.
+func RequestDeviceCode(transport http.RoundTripper, webBase, clientID string, scopes []string) (*DeviceCode, error) {
+	var code DeviceCode
+	err := postForm(transport, webBase+"/login/device/code", url.Values{
+		"client_id": {clientID},
+		"scope":     {strings.Join(scopes, " ")},
+	}, &code)
+	if err != nil {
+		return nil, fmt.Errorf("requesting device code: %w", err)
+	}
+	if code.DeviceCode == "" {
+		return nil, fmt.Errorf("requesting device code: no device code returned")
+	}
+	return &code, nil
+}
+
+// PollAccessToken waits until the user approved the device code and returns
+// the access token. It fails when the code expires or the user denies access.
+func PollAccessToken(transport http.RoundTripper, webBase, clientID string, code *DeviceCode) (string, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for {
+		var resp struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+			Interval    int    `json:"interval"`
+		}
+		err := postForm(transport, webBase+"/login/oauth/access_token", url.Values{
+			"client_id":   {clientID},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, &resp)
+		if err != nil {
+			return "", fmt.Errorf("polling for token: %w", err)
+		}
+
+		switch resp.Error {
+		case "":
+			if resp.AccessToken == "" {
+				return "", fmt.Errorf("polling for token: no access token returned")
+			}
+			return resp.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval = time.Duration(resp.Interval) * time.Second
+			if resp.Interval == 0 {
+				interval += 5 * time.Second
+			}
+		default:
+			if resp.Description != "" {
+				return "", fmt.Errorf("%s: %s", resp.Error, resp.Description)
+			}
+			return "", fmt.Errorf("%s", resp.Error)
+		}
+
+		if code.ExpiresIn > 0 && time.Now().Add(interval).After(deadline) {
+			return "", fmt.Errorf("device code expired before it was approved")
+		}
+		time.Sleep(interval)
+	}
+}
+
+// postForm posts form values and decodes the JSON response into out. GitHub
+// reports OAuth errors in the body with status 200.
+func postForm(transport http.RoundTripper, endpoint string, form url.Values, out any) error {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
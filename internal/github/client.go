@@ -1,17 +1,68 @@
 package github
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
 )
 
+// repository is the subset of the GitHub repository API response tugboat uses.
+type repository struct {
+	ID            int64       `json:"id"`
+	Name          string      `json:"name"`
+	FullName      string      `json:"full_name"`
+	Description   string      `json:"description"`
+	CloneURL      string      `json:"clone_url"`
+	SSHURL        string      `json:"ssh_url"`
+	HTMLURL       string      `json:"html_url"`
+	DefaultBranch string      `json:"default_branch"`
+	Archived      bool        `json:"archived"`
+	Private       bool        `json:"private"`
+	Fork          bool        `json:"fork"`
+	IsTemplate    bool        `json:"is_template"`
+	Size          int64       `json:"size"`
+	Topics        []string    `json:"topics"`
+	Language      string      `json:"language"`
+	Parent        *repository `json:"parent,omitempty"` // only in single-repo responses
+}
+
+// toRemote converts a GitHub repository into the provider-agnostic form.
+// GitHub reports no emptiness flag; a zero size stands in for it.
+func (r repository) toRemote() remote.Repository {
+	out := remote.Repository{
+		ID:            r.ID,
+		Name:          r.Name,
+		FullName:      r.FullName,
+		Description:   r.Description,
+		CloneURL:      r.CloneURL,
+		SSHURL:        r.SSHURL,
+		HTMLURL:       r.HTMLURL,
+		DefaultBranch: r.DefaultBranch,
+		Archived:      r.Archived,
+		Private:       r.Private,
+		Fork:          r.Fork,
+		Template:      r.IsTemplate,
+		Empty:         r.Size == 0,
+		Topics:        r.Topics,
+		Language:      r.Language,
+	}
+	if r.Parent != nil {
+		parent := r.Parent.toRemote()
+		out.Parent = &parent
+	}
+	return out
+}
+
 // Client is a GitHub API client (Cloud or Enterprise).
 type Client struct {
 	apiBase    string
@@ -31,81 +82,187 @@ func NewClient(apiBase, token string) *Client {
 	}
 }
 
+// SetTransport replaces the transport used for API requests, e.g. with a
+// caching one.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
 // ListOrgRepos lists all repositories in a GitHub organization.
 func (c *Client) ListOrgRepos(orgName string) ([]remote.Repository, error) {
-	var all []remote.Repository
-	page := 1
-	perPage := 100
-
-	for {
-		endpoint := fmt.Sprintf("%s/orgs/%s/repos?per_page=%d&page=%d&type=all", c.apiBase, url.PathEscape(orgName), perPage, page)
+	return c.listRepos(fmt.Sprintf("%s/orgs/%s/repos?type=all", c.apiBase, url.PathEscape(orgName)))
+}
 
-		req, err := http.NewRequest("GET", endpoint, nil)
-		if err != nil {
-			return nil, fmt.Errorf("creating request: %w", err)
+// ListUserRepos lists repositories owned by a GitHub user. The public
+// listing of /users/{user}/repos never includes private repos, so the token's
+// own account is listed through /user/repos instead; other users' private
+// repos stay invisible.
+func (c *Client) ListUserRepos(userName string) ([]remote.Repository, error) {
+	if c.token != "" {
+		if login, err := c.currentLogin(); err == nil && strings.EqualFold(login, userName) {
+			return c.listRepos(c.apiBase + "/user/repos?affiliation=owner&visibility=all")
 		}
-		c.addHeaders(req)
+	}
+	return c.listRepos(fmt.Sprintf("%s/users/%s/repos?type=owner", c.apiBase, url.PathEscape(userName)))
+}
 
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("fetching repos: %w", err)
-		}
-		defer resp.Body.Close()
+// currentLogin returns the login of the token's user.
+func (c *Client) currentLogin() (string, error) {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := c.sendJSON("GET", c.apiBase+"/user", nil, &user); err != nil {
+		return "", fmt.Errorf("fetching current user: %w", err)
+	}
+	return user.Login, nil
+}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-		}
+// ListTeamRepos lists the repositories the team of an organization, named by
+// its slug, has access to.
+func (c *Client) ListTeamRepos(orgName, team string) ([]remote.Repository, error) {
+	return c.listRepos(fmt.Sprintf("%s/orgs/%s/teams/%s/repos?", c.apiBase, url.PathEscape(orgName), url.PathEscape(team)))
+}
 
-		var repos []struct {
-			ID            int64  `json:"id"`
-			Name          string `json:"name"`
-			FullName      string `json:"full_name"`
-			Description   string `json:"description"`
-			CloneURL      string `json:"clone_url"`
-			SSHURL        string `json:"ssh_url"`
-			HTMLURL       string `json:"html_url"`
-			DefaultBranch string `json:"default_branch"`
-			Archived      bool   `json:"archived"`
-			Private       bool   `json:"private"`
-			Fork          bool   `json:"fork"`
-			Size          int64  `json:"size"`
-		}
+// searchLimit is how many results GitHub returns of one search at most.
+const searchLimit = 1000
 
-		if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-			return nil, fmt.Errorf("decoding response: %w", err)
+// SearchRepos returns the repositories of owner that query finds, in GitHub's
+// search syntax (e.g. "topic:sdk archived:false"). The search is limited to
+// owner with an org: or user: qualifier, and GitHub only returns its first
+// 1000 results.
+func (c *Client) SearchRepos(owner string, user bool, query string) ([]remote.Repository, error) {
+	qualifier := "org:"
+	if user {
+		qualifier = "user:"
+	}
+	searchURL := fmt.Sprintf("%s/search/repositories?q=%s", c.apiBase, url.QueryEscape(query+" "+qualifier+owner))
+	perPage := 100
+	var all []remote.Repository
+	for page := 1; len(all) < searchLimit; page++ {
+		var result struct {
+			TotalCount int          `json:"total_count"`
+			Items      []repository `json:"items"`
 		}
-
-		if len(repos) == 0 {
+		if err := c.sendJSON("GET", fmt.Sprintf("%s&per_page=%d&page=%d", searchURL, perPage, page), nil, &result); err != nil {
+			return nil, fmt.Errorf("searching repos: %w", err)
+		}
+		for _, r := range result.Items {
+			all = append(all, r.toRemote())
+		}
+		if len(result.Items) < perPage || len(all) >= result.TotalCount {
 			break
 		}
+	}
+	return all, nil
+}
+
+// pageWorkers caps how many listing pages are requested at once.
+const pageWorkers = 8
 
-		for _, r := range repos {
-			all = append(all, remote.Repository{
-				ID:            r.ID,
-				Name:          r.Name,
-				FullName:      r.FullName,
-				Description:   r.Description,
-				CloneURL:      r.CloneURL,
-				SSHURL:        r.SSHURL,
-				HTMLURL:       r.HTMLURL,
-				DefaultBranch: r.DefaultBranch,
-				Archived:      r.Archived,
-				Private:       r.Private,
-				Fork:          r.Fork,
-				Empty:         r.Size == 0,
-			})
+// listRepos pages through a repository listing endpoint. endpoint must
+// already carry a query string. When the first page's Link header names the
+// last page the remaining pages are fetched concurrently; otherwise pages are
+// read one by one until a short page.
+func (c *Client) listRepos(listURL string) ([]remote.Repository, error) {
+	perPage := 100
+
+	all, header, err := c.fetchPage(listURL, 1, perPage)
+	if err != nil {
+		return nil, err
+	}
+	if len(all) < perPage {
+		return all, nil
+	}
+
+	if last := lastPage(header.Get("Link")); last > 1 {
+		var pages []int
+		for page := 2; page <= last; page++ {
+			pages = append(pages, page)
+		}
+		type pageResult struct {
+			page  int
+			repos []remote.Repository
+			err   error
 		}
+		results := pool.Run(pages, pageWorkers, func(page int) pageResult {
+			repos, _, err := c.fetchPage(listURL, page, perPage)
+			return pageResult{page: page, repos: repos, err: err}
+		})
+		sort.Slice(results, func(i, j int) bool { return results[i].page < results[j].page })
+		for _, r := range results {
+			if r.err != nil {
+				return nil, r.err
+			}
+			all = append(all, r.repos...)
+		}
+		return all, nil
+	}
 
+	for page := 2; ; page++ {
+		repos, _, err := c.fetchPage(listURL, page, perPage)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, repos...)
 		if len(repos) < perPage {
 			break
 		}
-		page++
 	}
-
 	return all, nil
 }
 
+// fetchPage requests one page of a repository listing and returns the repos
+// with the response headers.
+func (c *Client) fetchPage(listURL string, page, perPage int) ([]remote.Repository, http.Header, error) {
+	endpoint := fmt.Sprintf("%s&per_page=%d&page=%d", listURL, perPage, page)
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating request: %w", err)
+	}
+	c.addHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching repos: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var repos []repository
+	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	out := make([]remote.Repository, 0, len(repos))
+	for _, r := range repos {
+		out = append(out, r.toRemote())
+	}
+	return out, resp.Header, nil
+}
+
+// lastPage returns the page number of the rel="last" entry of a Link header,
+// or 0 when there is none.
+func lastPage(link string) int {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.Contains(params, `rel="last"`) {
+			continue
+		}
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return 0
+		}
+		n, _ := strconv.Atoi(u.Query().Get("page"))
+		return n
+	}
+	return 0
+}
+
 // GetRepo fetches a single repository by owner/name.
 func (c *Client) GetRepo(owner, repoName string) (*remote.Repository, error) {
 	endpoint := fmt.Sprintf("%s/repos/%s/%s", c.apiBase, url.PathEscape(owner), url.PathEscape(repoName))
@@ -131,41 +288,348 @@ func (c *Client) GetRepo(owner, repoName string) (*remote.Repository, error) {
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
+	var r repository
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	repo := r.toRemote()
+	return &repo, nil
+}
+
+// CreateRepo creates an empty repository in an organization, or in the
+// token's own account when owner is that user. GitHub has no default branch
+// setting for empty repos; the first branch pushed becomes the default.
+func (c *Client) CreateRepo(owner, name string, opts remote.CreateOptions) (*remote.Repository, error) {
+	login, err := c.currentLogin()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/orgs/%s/repos", c.apiBase, url.PathEscape(owner))
+	if strings.EqualFold(login, owner) {
+		endpoint = c.apiBase + "/user/repos"
+	}
+
+	body := map[string]any{"name": name, "description": opts.Description, "private": opts.Private}
+	var r repository
+	if err := c.sendJSON("POST", endpoint, body, &r); err != nil {
+		return nil, fmt.Errorf("creating repo: %w", err)
+	}
+	repo := r.toRemote()
+	return &repo, nil
+}
+
+// SyncFork updates branch of the fork owner/name from its parent with the
+// merge-upstream endpoint. GitHub answers 409 when the branches conflict.
+func (c *Client) SyncFork(owner, name, branch string) error {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/merge-upstream", c.apiBase, url.PathEscape(owner), url.PathEscape(name))
+	if err := c.sendJSON("POST", endpoint, map[string]string{"branch": branch}, nil); err != nil {
+		return fmt.Errorf("syncing fork: %w", err)
+	}
+	return nil
+}
+
+// release is the subset of the GitHub release API response tugboat uses.
+type release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+		URL  string `json:"url"`
+	} `json:"assets"`
+}
+
+func (r release) toRemote() remote.Release {
+	out := remote.Release{Tag: r.TagName, Name: r.Name, Published: r.PublishedAt}
+	for _, a := range r.Assets {
+		out.Assets = append(out.Assets, remote.ReleaseAsset{Name: a.Name, Size: a.Size, URL: a.URL})
+	}
+	return out
+}
+
+// ListReleases lists the releases of owner/name, newest first. Drafts are
+// only listed to tokens with push access.
+func (c *Client) ListReleases(owner, name string) ([]remote.Release, error) {
+	releasesURL := fmt.Sprintf("%s/repos/%s/%s/releases", c.apiBase, url.PathEscape(owner), url.PathEscape(name))
+	perPage := 100
+
+	var out []remote.Release
+	for page := 1; ; page++ {
+		var releases []release
+		if err := c.sendJSON("GET", fmt.Sprintf("%s?per_page=%d&page=%d", releasesURL, perPage, page), nil, &releases); err != nil {
+			return nil, fmt.Errorf("listing releases: %w", err)
+		}
+		for _, r := range releases {
+			out = append(out, r.toRemote())
+		}
+		if len(releases) < perPage {
+			return out, nil
+		}
+	}
+}
+
+// DownloadAsset writes the content of a release asset to w. Assets are
+// fetched through the API so those of private repos download too; the
+// API redirects to storage that does not get the token.
+func (c *Client) DownloadAsset(asset remote.ReleaseAsset, w io.Writer) error {
+	req, err := http.NewRequest("GET", asset.URL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	c.addHeaders(req)
+	req.Header.Set("Accept", "application/octet-stream")
+
+	// Assets may take far longer to download than an API call.
+	download := *c.httpClient
+	download.Timeout = 0
+	resp, err := download.Do(req)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", asset.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("downloading %s: API error (status %d): %s", asset.Name, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("downloading %s: %w", asset.Name, err)
+	}
+	return nil
+}
+
+// pullRequest is the subset of the GitHub pull request API response tugboat
+// uses.
+type pullRequest struct {
+	Number    int64     `json:"number"`
+	Title     string    `json:"title"`
+	HTMLURL   string    `json:"html_url"`
+	Draft     bool      `json:"draft"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	User      struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Head struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// toRemote converts a GitHub pull request into the provider-agnostic form.
+func (pr pullRequest) toRemote(review string) remote.PullRequest {
+	return remote.PullRequest{
+		Number:  pr.Number,
+		Title:   pr.Title,
+		Author:  pr.User.Login,
+		Head:    pr.Head.Ref,
+		Base:    pr.Base.Ref,
+		URL:     pr.HTMLURL,
+		Draft:   pr.Draft,
+		Created: pr.CreatedAt,
+		Updated: pr.UpdatedAt,
+		Review:  review,
+	}
+}
+
+// ListPullRequests lists the open pull requests of owner/name with their
+// review state. Reviews cost one request per pull request.
+func (c *Client) ListPullRequests(owner, name string) ([]remote.PullRequest, error) {
+	pullsURL := fmt.Sprintf("%s/repos/%s/%s/pulls", c.apiBase, url.PathEscape(owner), url.PathEscape(name))
+	perPage := 100
+
+	var out []remote.PullRequest
+	for page := 1; ; page++ {
+		var prs []pullRequest
+		if err := c.sendJSON("GET", fmt.Sprintf("%s?state=open&per_page=%d&page=%d", pullsURL, perPage, page), nil, &prs); err != nil {
+			return nil, fmt.Errorf("listing pull requests: %w", err)
+		}
+		for _, pr := range prs {
+			review, err := c.reviewState(pullsURL, pr.Number)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, pr.toRemote(review))
+		}
+		if len(prs) < perPage {
+			return out, nil
+		}
+	}
+}
+
+// CreatePullRequest opens a pull request from pr.Head into pr.Base.
+func (c *Client) CreatePullRequest(owner, name string, pr remote.NewPullRequest) (*remote.PullRequest, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/pulls", c.apiBase, url.PathEscape(owner), url.PathEscape(name))
+	body := map[string]any{"title": pr.Title, "body": pr.Body, "head": pr.Head, "base": pr.Base, "draft": pr.Draft}
+	var created pullRequest
+	if err := c.sendJSON("POST", endpoint, body, &created); err != nil {
+		return nil, fmt.Errorf("creating pull request: %w", err)
+	}
+	out := created.toRemote(remote.ReviewPending)
+	return &out, nil
+}
+
+// CountOpenIssues counts the open issues of owner/name. GitHub lists pull
+// requests as issues too; those are left out.
+func (c *Client) CountOpenIssues(owner, name string, filter remote.IssueFilter) (int, error) {
+	query := url.Values{"state": {"open"}, "per_page": {"100"}}
+	if len(filter.Labels) > 0 {
+		query.Set("labels", strings.Join(filter.Labels, ","))
+	}
+	if filter.Assignee != "" {
+		query.Set("assignee", filter.Assignee)
+	}
+	issuesURL := fmt.Sprintf("%s/repos/%s/%s/issues", c.apiBase, url.PathEscape(owner), url.PathEscape(name))
+
+	count := 0
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var issues []struct {
+			PullRequest *struct{} `json:"pull_request"`
+		}
+		if err := c.sendJSON("GET", issuesURL+"?"+query.Encode(), nil, &issues); err != nil {
+			return 0, fmt.Errorf("listing issues: %w", err)
+		}
+		for _, issue := range issues {
+			if issue.PullRequest == nil {
+				count++
+			}
+		}
+		if len(issues) < 100 {
+			return count, nil
+		}
+	}
+}
+
+// GetRepoSettings reads the settings of owner/name. GitHub only reports
+// merge settings to tokens with admin rights on the repo.
+func (c *Client) GetRepoSettings(owner, name string) (*remote.RepoSettings, error) {
+	repoURL := fmt.Sprintf("%s/repos/%s/%s", c.apiBase, url.PathEscape(owner), url.PathEscape(name))
 	var r struct {
-		ID            int64  `json:"id"`
-		Name          string `json:"name"`
-		FullName      string `json:"full_name"`
-		Description   string `json:"description"`
-		CloneURL      string `json:"clone_url"`
-		SSHURL        string `json:"ssh_url"`
-		HTMLURL       string `json:"html_url"`
-		DefaultBranch string `json:"default_branch"`
-		Archived      bool   `json:"archived"`
-		Private       bool   `json:"private"`
-		Fork          bool   `json:"fork"`
-		Size          int64  `json:"size"`
+		DefaultBranch       string `json:"default_branch"`
+		Private             bool   `json:"private"`
+		Size                int64  `json:"size"`
+		AllowMergeCommit    *bool  `json:"allow_merge_commit"`
+		AllowSquashMerge    *bool  `json:"allow_squash_merge"`
+		AllowRebaseMerge    *bool  `json:"allow_rebase_merge"`
+		DeleteBranchOnMerge *bool  `json:"delete_branch_on_merge"`
 	}
+	if err := c.sendJSON("GET", repoURL, nil, &r); err != nil {
+		return nil, fmt.Errorf("fetching repo: %w", err)
+	}
+	settings := &remote.RepoSettings{
+		DefaultBranch:       r.DefaultBranch,
+		Private:             r.Private,
+		AllowMergeCommit:    r.AllowMergeCommit,
+		AllowSquashMerge:    r.AllowSquashMerge,
+		AllowRebaseMerge:    r.AllowRebaseMerge,
+		DeleteBranchOnMerge: r.DeleteBranchOnMerge,
+	}
+	if r.Size > 0 { // empty repos have no branch to protect
+		var branch struct {
+			Protected bool `json:"protected"`
+		}
+		if err := c.sendJSON("GET", repoURL+"/branches/"+url.PathEscape(r.DefaultBranch), nil, &branch); err != nil {
+			return nil, fmt.Errorf("fetching default branch: %w", err)
+		}
+		settings.BranchProtected = branch.Protected
+	}
+	return settings, nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+// GetFile returns the content of path on the default branch of owner/name,
+// or nil when there is no such file.
+func (c *Client) GetFile(owner, name, path string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.apiBase, url.PathEscape(owner), url.PathEscape(name), path)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
+	c.addHeaders(req)
+	req.Header.Set("Accept", "application/vnd.github.raw+json")
 
-	repo := &remote.Repository{
-		ID:            r.ID,
-		Name:          r.Name,
-		FullName:      r.FullName,
-		Description:   r.Description,
-		CloneURL:      r.CloneURL,
-		SSHURL:        r.SSHURL,
-		HTMLURL:       r.HTMLURL,
-		DefaultBranch: r.DefaultBranch,
-		Archived:      r.Archived,
-		Private:       r.Private,
-		Fork:          r.Fork,
-		Empty:         r.Size == 0,
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// reviewState reads the reviews of one pull request, oldest first. Comments
+// leave a reviewer's verdict as it was; a dismissal clears it.
+func (c *Client) reviewState(pullsURL string, number int64) (string, error) {
+	var reviews []struct {
+		State string `json:"state"`
+		User  struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := c.sendJSON("GET", fmt.Sprintf("%s/%d/reviews?per_page=100", pullsURL, number), nil, &reviews); err != nil {
+		return "", fmt.Errorf("listing reviews of #%d: %w", number, err)
+	}
+	latest := make(map[string]string)
+	for _, r := range reviews {
+		switch r.State {
+		case "APPROVED":
+			latest[r.User.Login] = remote.ReviewApproved
+		case "CHANGES_REQUESTED":
+			latest[r.User.Login] = remote.ReviewChangesRequested
+		case "DISMISSED":
+			delete(latest, r.User.Login)
+		}
+	}
+	return remote.ReviewState(latest), nil
+}
+
+// sendJSON sends in (if not nil) as the JSON request body and decodes a
+// successful response into out (if not nil).
+func (c *Client) sendJSON(method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	c.addHeaders(req)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
 
-	return repo, nil
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
 }
 
 func (c *Client) addHeaders(req *http.Request) {
@@ -0,0 +1,52 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListUserRepos(t *testing.T) {
+	tests := []struct {
+		user     string
+		wantPath string
+		wantArgs map[string]string
+	}{
+		{user: "me", wantPath: "/user/repos", wantArgs: map[string]string{"affiliation": "owner", "visibility": "all"}},
+		{user: "someone", wantPath: "/users/someone/repos", wantArgs: map[string]string{"type": "owner"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user, func(t *testing.T) {
+			var listed string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if auth := r.Header.Get("Authorization"); auth != "token test-token" {
+					t.Errorf("Authorization header = %q, want %q", auth, "token test-token")
+				}
+				if r.URL.Path == "/user" {
+					json.NewEncoder(w).Encode(map[string]string{"login": "Me"})
+					return
+				}
+				listed = r.URL.Path
+				for k, v := range tt.wantArgs {
+					if got := r.URL.Query().Get(k); got != v {
+						t.Errorf("%s=%q, want %q", k, got, v)
+					}
+				}
+				json.NewEncoder(w).Encode([]repository{{ID: 1, Name: "repo1", Private: true}})
+			}))
+			defer server.Close()
+
+			repos, err := NewClient(server.URL, "test-token").ListUserRepos(tt.user)
+			if err != nil {
+				t.Fatalf("ListUserRepos() error = %v", err)
+			}
+			if listed != tt.wantPath {
+				t.Errorf("listed %s, want %s", listed, tt.wantPath)
+			}
+			if len(repos) != 1 || repos[0].Name != "repo1" {
+				t.Errorf("repos = %+v, want repo1", repos)
+			}
+		})
+	}
+}
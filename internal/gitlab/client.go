@@ -0,0 +1,445 @@
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+// Project mirrors the subset of the GitLab project API response tugboat uses.
+type Project struct {
+	ID                int64    `json:"id"`
+	Name              string   `json:"name"`
+	Path              string   `json:"path"`
+	PathWithNamespace string   `json:"path_with_namespace"`
+	Description       string   `json:"description"`
+	HTTPURLToRepo     string   `json:"http_url_to_repo"`
+	SSHURLToRepo      string   `json:"ssh_url_to_repo"`
+	WebURL            string   `json:"web_url"`
+	DefaultBranch     string   `json:"default_branch"`
+	EmptyRepo         bool     `json:"empty_repo"`
+	Archived          bool     `json:"archived"`
+	Visibility        string   `json:"visibility"`
+	ForkedFromProject *Project `json:"forked_from_project,omitempty"`
+	Topics            []string `json:"topics"`
+}
+
+// toRemote converts a GitLab project into the provider-agnostic form. The
+// project path (URL slug) is used as the name because it is what appears in
+// clone URLs and local directory names.
+func (p Project) toRemote() remote.Repository {
+	out := remote.Repository{
+		ID:            p.ID,
+		Name:          p.Path,
+		FullName:      p.PathWithNamespace,
+		Description:   p.Description,
+		CloneURL:      p.HTTPURLToRepo,
+		SSHURL:        p.SSHURLToRepo,
+		HTMLURL:       p.WebURL,
+		DefaultBranch: p.DefaultBranch,
+		Empty:         p.EmptyRepo,
+		Archived:      p.Archived,
+		Private:       p.Visibility == "private",
+		Fork:          p.ForkedFromProject != nil,
+		Topics:        p.Topics,
+	}
+	if p.ForkedFromProject != nil {
+		parent := p.ForkedFromProject.toRemote()
+		out.Parent = &parent
+	}
+	return out
+}
+
+// Client is a GitLab API client (gitlab.com or self-hosted).
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a GitLab API client. baseURL is the instance root
+// (e.g. https://gitlab.com); the /api/v4 prefix is appended per request.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// SetTransport replaces the transport used for API requests, e.g. with a
+// caching one.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
+// ListOrgRepos lists all projects directly inside a GitLab group. Nested
+// groups can be addressed by their full path (e.g. "acme/platform").
+func (c *Client) ListOrgRepos(orgName string) ([]remote.Repository, error) {
+	return c.listProjects(fmt.Sprintf("%s/api/v4/groups/%s/projects", c.baseURL, url.PathEscape(orgName)))
+}
+
+// ListUserRepos lists projects in a user's personal namespace.
+func (c *Client) ListUserRepos(userName string) ([]remote.Repository, error) {
+	return c.listProjects(fmt.Sprintf("%s/api/v4/users/%s/projects", c.baseURL, url.PathEscape(userName)))
+}
+
+// listProjects pages through a project listing endpoint.
+func (c *Client) listProjects(listURL string) ([]remote.Repository, error) {
+	var all []remote.Repository
+	page := 1
+	perPage := 100
+
+	for {
+		endpoint := fmt.Sprintf("%s?per_page=%d&page=%d", listURL, perPage, page)
+
+		req, err := http.NewRequest("GET", endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		c.addHeaders(req)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetching repos: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		}
+
+		var projects []Project
+		if err := json.NewDecoder(resp.Body).Decode(&projects); err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+
+		for _, p := range projects {
+			all = append(all, p.toRemote())
+		}
+
+		// GitLab reports the next page in X-Next-Page; it is empty on the
+		// last page. Fall back to a short page when the header is absent.
+		next := resp.Header.Get("X-Next-Page")
+		if next != "" {
+			n, err := strconv.Atoi(next)
+			if err != nil || n <= page {
+				break
+			}
+			page = n
+			continue
+		}
+		if len(projects) < perPage || resp.Header.Get("X-Page") != "" {
+			break
+		}
+		page++
+	}
+
+	return all, nil
+}
+
+// GetRepo fetches a single project by namespace/path.
+func (c *Client) GetRepo(owner, repoName string) (*remote.Repository, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s", c.baseURL, url.PathEscape(owner+"/"+repoName))
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	c.addHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching repo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var p Project
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	repo := p.toRemote()
+	return &repo, nil
+}
+
+// CreateRepo creates an empty project in a group or user namespace.
+func (c *Client) CreateRepo(owner, name string, opts remote.CreateOptions) (*remote.Repository, error) {
+	var ns struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.sendJSON("GET", fmt.Sprintf("%s/api/v4/namespaces/%s", c.baseURL, url.PathEscape(owner)), nil, &ns); err != nil {
+		return nil, fmt.Errorf("looking up namespace %s: %w", owner, err)
+	}
+
+	visibility := "public"
+	if opts.Private {
+		visibility = "private"
+	}
+	body := map[string]any{
+		"name":         name,
+		"path":         name,
+		"namespace_id": ns.ID,
+		"description":  opts.Description,
+		"visibility":   visibility,
+	}
+	if opts.DefaultBranch != "" {
+		body["default_branch"] = opts.DefaultBranch
+	}
+	var p Project
+	if err := c.sendJSON("POST", c.baseURL+"/api/v4/projects", body, &p); err != nil {
+		return nil, fmt.Errorf("creating project: %w", err)
+	}
+	repo := p.toRemote()
+	return &repo, nil
+}
+
+// MergeRequest mirrors the subset of the GitLab merge request API response
+// tugboat uses.
+type MergeRequest struct {
+	IID          int64     `json:"iid"`
+	Title        string    `json:"title"`
+	WebURL       string    `json:"web_url"`
+	Draft        bool      `json:"draft"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	SourceBranch string    `json:"source_branch"`
+	TargetBranch string    `json:"target_branch"`
+	MergeStatus  string    `json:"detailed_merge_status"`
+	Author       struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+// toRemote converts a GitLab merge request into the provider-agnostic form.
+func (mr MergeRequest) toRemote(review string) remote.PullRequest {
+	return remote.PullRequest{
+		Number:  mr.IID,
+		Title:   mr.Title,
+		Author:  mr.Author.Username,
+		Head:    mr.SourceBranch,
+		Base:    mr.TargetBranch,
+		URL:     mr.WebURL,
+		Draft:   mr.Draft,
+		Created: mr.CreatedAt,
+		Updated: mr.UpdatedAt,
+		Review:  review,
+	}
+}
+
+// ListPullRequests lists the open merge requests of owner/name with their
+// review state. Approvals cost one request per merge request; requested
+// changes are only reported by GitLab 17 and later.
+func (c *Client) ListPullRequests(owner, name string) ([]remote.PullRequest, error) {
+	mrsURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests", c.baseURL, url.PathEscape(owner+"/"+name))
+	perPage := 100
+
+	var out []remote.PullRequest
+	for page := 1; ; page++ {
+		var mrs []MergeRequest
+		if err := c.sendJSON("GET", fmt.Sprintf("%s?state=opened&per_page=%d&page=%d", mrsURL, perPage, page), nil, &mrs); err != nil {
+			return nil, fmt.Errorf("listing merge requests: %w", err)
+		}
+		for _, mr := range mrs {
+			review := remote.ReviewPending
+			if mr.MergeStatus == "requested_changes" {
+				review = remote.ReviewChangesRequested
+			} else {
+				var approvals struct {
+					ApprovedBy []json.RawMessage `json:"approved_by"`
+				}
+				if err := c.sendJSON("GET", fmt.Sprintf("%s/%d/approvals", mrsURL, mr.IID), nil, &approvals); err != nil {
+					return nil, fmt.Errorf("reading approvals of !%d: %w", mr.IID, err)
+				}
+				if len(approvals.ApprovedBy) > 0 {
+					review = remote.ReviewApproved
+				}
+			}
+			out = append(out, mr.toRemote(review))
+		}
+		if len(mrs) < perPage {
+			return out, nil
+		}
+	}
+}
+
+// CreatePullRequest opens a merge request from pr.Head into pr.Base. GitLab
+// marks drafts by a "Draft:" title prefix.
+func (c *Client) CreatePullRequest(owner, name string, pr remote.NewPullRequest) (*remote.PullRequest, error) {
+	title := pr.Title
+	if pr.Draft {
+		title = "Draft: " + title
+	}
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests", c.baseURL, url.PathEscape(owner+"/"+name))
+	body := map[string]any{
+		"source_branch": pr.Head,
+		"target_branch": pr.Base,
+		"title":         title,
+		"description":   pr.Body,
+	}
+	var created MergeRequest
+	if err := c.sendJSON("POST", endpoint, body, &created); err != nil {
+		return nil, fmt.Errorf("creating merge request: %w", err)
+	}
+	out := created.toRemote(remote.ReviewPending)
+	return &out, nil
+}
+
+// CountOpenIssues counts the open issues of owner/name.
+func (c *Client) CountOpenIssues(owner, name string, filter remote.IssueFilter) (int, error) {
+	query := url.Values{"state": {"opened"}, "per_page": {"100"}}
+	if len(filter.Labels) > 0 {
+		query.Set("labels", strings.Join(filter.Labels, ","))
+	}
+	if filter.Assignee != "" {
+		query.Set("assignee_username", filter.Assignee)
+	}
+	issuesURL := fmt.Sprintf("%s/api/v4/projects/%s/issues", c.baseURL, url.PathEscape(owner+"/"+name))
+
+	count := 0
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var issues []json.RawMessage
+		if err := c.sendJSON("GET", issuesURL+"?"+query.Encode(), nil, &issues); err != nil {
+			return 0, fmt.Errorf("listing issues: %w", err)
+		}
+		count += len(issues)
+		if len(issues) < 100 {
+			return count, nil
+		}
+	}
+}
+
+// GetRepoSettings reads the settings of owner/name. GitLab has a single
+// merge method instead of per-strategy switches: merge commits are allowed
+// unless it is fast-forward only, and there is no separate rebase merge, so
+// AllowRebaseMerge stays nil.
+func (c *Client) GetRepoSettings(owner, name string) (*remote.RepoSettings, error) {
+	projectURL := fmt.Sprintf("%s/api/v4/projects/%s", c.baseURL, url.PathEscape(owner+"/"+name))
+	var p struct {
+		DefaultBranch       string `json:"default_branch"`
+		Visibility          string `json:"visibility"`
+		EmptyRepo           bool   `json:"empty_repo"`
+		MergeMethod         string `json:"merge_method"`
+		SquashOption        string `json:"squash_option"`
+		DeleteBranchOnMerge *bool  `json:"remove_source_branch_after_merge"`
+	}
+	if err := c.sendJSON("GET", projectURL, nil, &p); err != nil {
+		return nil, fmt.Errorf("fetching project: %w", err)
+	}
+	settings := &remote.RepoSettings{
+		DefaultBranch:       p.DefaultBranch,
+		Private:             p.Visibility == "private",
+		DeleteBranchOnMerge: p.DeleteBranchOnMerge,
+	}
+	if p.MergeMethod != "" {
+		allow := p.MergeMethod != "ff"
+		settings.AllowMergeCommit = &allow
+	}
+	if p.SquashOption != "" {
+		allow := p.SquashOption != "never"
+		settings.AllowSquashMerge = &allow
+	}
+	if !p.EmptyRepo {
+		var branch struct {
+			Protected bool `json:"protected"`
+		}
+		if err := c.sendJSON("GET", projectURL+"/repository/branches/"+url.PathEscape(p.DefaultBranch), nil, &branch); err != nil {
+			return nil, fmt.Errorf("fetching default branch: %w", err)
+		}
+		settings.BranchProtected = branch.Protected
+	}
+	return settings, nil
+}
+
+// GetFile returns the content of path on the default branch of owner/name,
+// or nil when there is no such file.
+func (c *Client) GetFile(owner, name, path string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/repository/files/%s/raw?ref=HEAD", c.baseURL, url.PathEscape(owner+"/"+name), url.PathEscape(path))
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	c.addHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// sendJSON sends in (if not nil) as the JSON request body and decodes a
+// successful response into out (if not nil).
+func (c *Client) sendJSON(method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	c.addHeaders(req)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) addHeaders(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", c.token)
+	}
+	req.Header.Set("Accept", "application/json")
+}
@@ -0,0 +1,172 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+func TestNewClientTrimsTrailingSlash(t *testing.T) {
+	client := NewClient("https://gitlab.example.com/", "test-token")
+
+	if client.baseURL != "https://gitlab.example.com" {
+		t.Errorf("baseURL = %q, want trailing slash trimmed", client.baseURL)
+	}
+}
+
+func TestListOrgReposFollowsNextPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.Header.Get("PRIVATE-TOKEN"); token != "test-token" {
+			t.Errorf("PRIVATE-TOKEN header = %q, want %q", token, "test-token")
+		}
+		if r.URL.EscapedPath() != "/api/v4/groups/acme%2Fplatform/projects" {
+			t.Errorf("path = %q, want escaped group path", r.URL.EscapedPath())
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("X-Page", "1")
+			w.Header().Set("X-Next-Page", "2")
+			json.NewEncoder(w).Encode([]Project{{ID: 1, Name: "Repo One", Path: "repo1"}})
+		case "2":
+			w.Header().Set("X-Page", "2")
+			w.Header().Set("X-Next-Page", "")
+			json.NewEncoder(w).Encode([]Project{{ID: 2, Name: "Repo Two", Path: "repo2", Archived: true}})
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	result, err := client.ListOrgRepos("acme/platform")
+	if err != nil {
+		t.Fatalf("ListOrgRepos() error = %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("len(result) = %d, want 2", len(result))
+	}
+	if result[0].Name != "repo1" {
+		t.Errorf("result[0].Name = %q, want project path %q", result[0].Name, "repo1")
+	}
+	if !result[1].Archived {
+		t.Error("result[1].Archived = false, want true")
+	}
+}
+
+func TestListOrgReposAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("401 Unauthorized"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "bad-token")
+	if _, err := client.ListOrgRepos("acme"); err == nil {
+		t.Error("ListOrgRepos() should return error for unauthorized")
+	}
+}
+
+func TestGetRepo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/acme%2Fapp" {
+			t.Errorf("path = %q, want escaped project path", r.URL.EscapedPath())
+		}
+		json.NewEncoder(w).Encode(Project{
+			ID:                7,
+			Name:              "App",
+			Path:              "app",
+			PathWithNamespace: "acme/app",
+			HTTPURLToRepo:     "https://gitlab.example.com/acme/app.git",
+			SSHURLToRepo:      "git@gitlab.example.com:acme/app.git",
+			DefaultBranch:     "main",
+			Visibility:        "private",
+			ForkedFromProject: &Project{ID: 1},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	result, err := client.GetRepo("acme", "app")
+	if err != nil {
+		t.Fatalf("GetRepo() error = %v", err)
+	}
+
+	if result.Name != "app" || result.FullName != "acme/app" {
+		t.Errorf("result name = %q (%q), want %q (%q)", result.Name, result.FullName, "app", "acme/app")
+	}
+	if result.DefaultBranch != "main" {
+		t.Errorf("result.DefaultBranch = %q, want %q", result.DefaultBranch, "main")
+	}
+	if !result.Private {
+		t.Error("result.Private = false, want true for private visibility")
+	}
+	if !result.Fork {
+		t.Error("result.Fork = false, want true when forked_from_project is set")
+	}
+}
+
+func TestGetRepoNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	result, err := client.GetRepo("acme", "missing")
+	if err != nil {
+		t.Fatalf("GetRepo() error = %v", err)
+	}
+	if result != nil {
+		t.Error("GetRepo() should return nil for not found")
+	}
+}
+
+func TestCountOpenIssuesPassesFilterAndPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/acme%2Fapp/issues" {
+			t.Errorf("path = %q, want escaped project issues path", r.URL.EscapedPath())
+		}
+		q := r.URL.Query()
+		if q.Get("state") != "opened" || q.Get("labels") != "bug,triage" || q.Get("assignee_username") != "ann" {
+			t.Errorf("query = %q, want opened issues labeled bug,triage assigned to ann", r.URL.RawQuery)
+		}
+		n := 100
+		if q.Get("page") == "2" {
+			n = 3
+		}
+		json.NewEncoder(w).Encode(make([]struct{}, n))
+	}))
+	defer server.Close()
+
+	filter := remote.IssueFilter{Labels: []string{"bug", "triage"}, Assignee: "ann"}
+	count, err := NewClient(server.URL, "test-token").CountOpenIssues("acme", "app", filter)
+	if err != nil {
+		t.Fatalf("CountOpenIssues() error = %v", err)
+	}
+	if count != 103 {
+		t.Errorf("count = %d, want 103", count)
+	}
+}
+
+func TestGetFileEscapesProjectAndPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/acme%2Fplatform%2Fapp/repository/files/.tugboat.json/raw" || r.URL.Query().Get("ref") != "HEAD" {
+			t.Errorf("request = %s, want the raw file of acme/platform/app at HEAD", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"repos": []}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	data, err := client.GetFile("acme/platform", "app", ".tugboat.json")
+	if err != nil || string(data) != `{"repos": []}` {
+		t.Fatalf("GetFile() = %q, %v", data, err)
+	}
+}
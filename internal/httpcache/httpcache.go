@@ -0,0 +1,150 @@
+// Package httpcache is an http.RoundTripper that revalidates GET responses
+// with their ETag or Last-Modified and serves 304 Not Modified replies from a
+// disk cache.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Transport caches successful GET responses that carry validators. Cached
+// responses are always revalidated; a 304 reply is turned into a 200 with the
+// cached headers and body.
+type Transport struct {
+	// Dir holds one file per cached request.
+	Dir string
+	// Base performs the requests; http.DefaultTransport when nil.
+	Base http.RoundTripper
+}
+
+// New returns a Transport caching under dir.
+func New(dir string) *Transport {
+	return &Transport{Dir: dir}
+}
+
+// DefaultDir returns the per-user cache directory for tugboat's API cache.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tugboat", "http"), nil
+}
+
+// entry is the on-disk form of a cached response.
+type entry struct {
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return base.RoundTrip(req)
+	}
+
+	path := t.pathFor(req)
+	cached := t.load(path)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        cached.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+			ContentLength: int64(len(cached.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK || (resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.store(path, entry{URL: req.URL.String(), Header: resp.Header, Body: body})
+	return resp, nil
+}
+
+// pathFor keys the cache on the URL and credentials, so different tokens
+// never see each other's responses.
+func (t *Transport) pathFor(req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, req.URL.String())
+	for _, name := range []string{"Authorization", "Private-Token", "Accept"} {
+		io.WriteString(h, "\n"+req.Header.Get(name))
+	}
+	return filepath.Join(t.Dir, hex.EncodeToString(h.Sum(nil))+".json")
+}
+
+func (t *Transport) load(path string) *entry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var e entry
+	if json.Unmarshal(data, &e) != nil {
+		return nil
+	}
+	return &e
+}
+
+// store writes e atomically. Failures only cost a future cache miss, so they
+// are ignored.
+func (t *Transport) store(path string, e entry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(t.Dir, 0700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(t.Dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,74 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestNotModifiedServedFromCache(t *testing.T) {
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-Total-Count", "2")
+		io.WriteString(w, `["a","b"]`)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: New(t.TempDir())}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL + "/repos")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != `["a","b"]` {
+			t.Fatalf("request %d: status %d body %q, want cached 200", i, resp.StatusCode, body)
+		}
+		if resp.Header.Get("X-Total-Count") != "2" {
+			t.Fatalf("request %d: cached headers missing", i)
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Fatalf("requests = %d, 304s = %d; want 2 requests with 1 revalidated", requests, notModified)
+	}
+}
+
+func TestCacheIsKeyedByCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("request with token %q reused another token's cache entry", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("ETag", `"`+r.Header.Get("Authorization")+`"`)
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := &http.Client{Transport: New(dir)}
+	for _, token := range []string{"token a", "token b"} {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req.Header.Set("Authorization", token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("cache has %d entries, want one per token", len(entries))
+	}
+}
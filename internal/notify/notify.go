@@ -0,0 +1,163 @@
+// Package notify posts Slack-compatible webhook messages about repos that
+// failed to update or need attention.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Event kinds.
+const (
+	Failed   = "failed"   // a pull, push or sync of the repo failed
+	Diverged = "diverged" // local and remote branches both have new commits
+	Orphan   = "orphan"   // the repo no longer exists on the provider
+)
+
+// Event is one repo worth notifying about.
+type Event struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"`
+	Message string `json:"message,omitempty"`
+}
+
+// Notifier posts events to a webhook URL. Diverged and orphan events are only
+// sent when a repo enters that state; failures are sent every time.
+type Notifier struct {
+	URL string
+	// Kinds limits the events sent; all kinds when empty.
+	Kinds []string
+	// StatePath remembers which repos were already reported as diverged or
+	// orphaned; without it every run reports them again.
+	StatePath string
+	Client    *http.Client
+}
+
+// DefaultStatePath returns the per-user file used for Notifier.StatePath.
+func DefaultStatePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tugboat", "notify-state.json"), nil
+}
+
+// message is the webhook payload: Slack reads text, other receivers can use
+// the structured fields.
+type message struct {
+	Text    string  `json:"text"`
+	Command string  `json:"command"`
+	Events  []Event `json:"events"`
+}
+
+// Send reports the events of one command run. checked lists every repo the
+// run looked at, so repos that recovered can be reported again later.
+func (n *Notifier) Send(command string, checked []string, events []Event) error {
+	var selected []Event
+	for _, e := range events {
+		if n.wants(e.Kind) {
+			selected = append(selected, e)
+		}
+	}
+	selected = n.dropKnown(checked, selected)
+	if len(selected) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(message{Text: summarize(command, selected), Command: command, Events: selected})
+	if err != nil {
+		return err
+	}
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("posting notification: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("posting notification: %s", resp.Status)
+	}
+	return nil
+}
+
+func (n *Notifier) wants(kind string) bool {
+	if len(n.Kinds) == 0 {
+		return true
+	}
+	for _, k := range n.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// dropKnown removes diverged and orphan events that were already sent and
+// records the current ones. Repos that were checked without the event are
+// forgotten, so a repo that diverges again is reported again.
+func (n *Notifier) dropKnown(checked []string, events []Event) []Event {
+	if n.StatePath == "" {
+		return events
+	}
+	sent := make(map[string]bool)
+	if data, err := os.ReadFile(n.StatePath); err == nil {
+		var keys []string
+		if json.Unmarshal(data, &keys) == nil {
+			for _, k := range keys {
+				sent[k] = true
+			}
+		}
+	}
+
+	known := make(map[string]bool, len(sent))
+	for k := range sent {
+		known[k] = true
+	}
+	for _, path := range checked {
+		delete(known, Diverged+" "+path)
+		delete(known, Orphan+" "+path)
+	}
+	var fresh []Event
+	for _, e := range events {
+		key := e.Kind + " " + e.Path
+		if e.Kind == Failed || !sent[key] {
+			fresh = append(fresh, e)
+		}
+		if e.Kind != Failed {
+			known[key] = true
+		}
+	}
+
+	keys := make([]string, 0, len(known))
+	for k := range known {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if data, err := json.Marshal(keys); err == nil {
+		os.MkdirAll(filepath.Dir(n.StatePath), 0o755)
+		os.WriteFile(n.StatePath, data, 0o600)
+	}
+	return fresh
+}
+
+func summarize(command string, events []Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tugboat %s: %d repos need attention", command, len(events))
+	for _, e := range events {
+		fmt.Fprintf(&b, "\n• %s %s", e.Kind, e.Path)
+		if e.Message != "" {
+			fmt.Fprintf(&b, ": %s", e.Message)
+		}
+	}
+	return b.String()
+}
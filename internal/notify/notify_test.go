@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSendReportsNewStatesOnce(t *testing.T) {
+	var got []message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		got = append(got, msg)
+	}))
+	defer srv.Close()
+
+	n := &Notifier{URL: srv.URL, StatePath: filepath.Join(t.TempDir(), "state.json")}
+	checked := []string{"/src/a", "/src/b"}
+	events := []Event{
+		{Kind: Failed, Path: "/src/a", Message: "pull failed"},
+		{Kind: Diverged, Path: "/src/b", Message: "1 ahead, 2 behind"},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := n.Send("sync", checked, events); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d messages, want 2", len(got))
+	}
+	if len(got[0].Events) != 2 || !strings.Contains(got[0].Text, "diverged /src/b") {
+		t.Errorf("first message = %+v, want both events", got[0])
+	}
+	if len(got[1].Events) != 1 || got[1].Events[0].Kind != Failed {
+		t.Errorf("second message = %+v, want only the failure", got[1])
+	}
+
+	// Once the repo recovers, diverging again is reported again.
+	if err := n.Send("sync", checked, nil); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := n.Send("sync", checked, events[1:]); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(got) != 3 || got[2].Events[0].Kind != Diverged {
+		t.Errorf("got %d messages, want the diverged repo reported again", len(got))
+	}
+}
+
+func TestSendFiltersKindsAndReportsHTTPErrors(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	n := &Notifier{URL: srv.URL, Kinds: []string{Failed}}
+	if err := n.Send("push", []string{"/src/a"}, []Event{{Kind: Orphan, Path: "/src/a"}}); err != nil {
+		t.Fatalf("Send() error = %v, want nothing sent", err)
+	}
+	if calls != 0 {
+		t.Fatalf("webhook called %d times for a filtered event", calls)
+	}
+	if err := n.Send("push", []string{"/src/a"}, []Event{{Kind: Failed, Path: "/src/a"}}); err == nil {
+		t.Error("Send() error = nil, want error for 403")
+	}
+}
@@ -13,6 +13,25 @@ func Run[T, R any](items []T, workers int, fn func(T) R) []R {
 		return nil
 	}
 
+	// Collect results
+	out := make([]R, 0, len(items))
+	for r := range Stream(items, workers, fn) {
+		out = append(out, r)
+	}
+	return out
+}
+
+// Stream is Run delivering each result on the returned channel as soon as
+// it is done, so callers can report progress while the rest still run. The
+// channel is closed after the last result. It is buffered for all results,
+// so workers never wait for a slow reader.
+func Stream[T, R any](items []T, workers int, fn func(T) R) <-chan R {
+	results := make(chan R, len(items))
+	if len(items) == 0 {
+		close(results)
+		return results
+	}
+
 	if workers <= 0 {
 		workers = runtime.GOMAXPROCS(0)
 	}
@@ -21,7 +40,6 @@ func Run[T, R any](items []T, workers int, fn func(T) R) []R {
 	}
 
 	jobs := make(chan T, len(items))
-	results := make(chan R, len(items))
 
 	// Start workers
 	var wg sync.WaitGroup
@@ -46,11 +64,50 @@ func Run[T, R any](items []T, workers int, fn func(T) R) []R {
 		wg.Wait()
 		close(results)
 	}()
+	return results
+}
+
+// RunOrdered is Run returning the results in the order of items.
+func RunOrdered[T, R any](items []T, workers int, fn func(T) R) []R {
+	if len(items) == 0 {
+		return nil
+	}
 
-	// Collect results
 	out := make([]R, 0, len(items))
-	for r := range results {
+	for r := range StreamOrdered(items, workers, fn) {
 		out = append(out, r)
 	}
 	return out
 }
+
+// StreamOrdered is Stream delivering the results in the order of items: each
+// result is held back until the results of the items before it are out.
+func StreamOrdered[T, R any](items []T, workers int, fn func(T) R) <-chan R {
+	type indexed struct {
+		i int
+		r R
+	}
+	indices := make([]int, len(items))
+	for i := range indices {
+		indices[i] = i
+	}
+	done := Stream(indices, workers, func(i int) indexed {
+		return indexed{i, fn(items[i])}
+	})
+
+	results := make(chan R, len(items))
+	go func() {
+		defer close(results)
+		pending := make(map[int]R)
+		next := 0
+		for d := range done {
+			pending[d.i] = d.r
+			for r, ok := pending[next]; ok; r, ok = pending[next] {
+				results <- r
+				delete(pending, next)
+				next++
+			}
+		}
+	}()
+	return results
+}
@@ -0,0 +1,63 @@
+package pool
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRunReturnsAllResults(t *testing.T) {
+	got := Run([]int{1, 2, 3, 4, 5}, 2, func(i int) int { return i * i })
+	sort.Ints(got)
+	want := []int{1, 4, 9, 16, 25}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	if got := Run(nil, 4, func(i int) int { return i }); got != nil {
+		t.Errorf("Run(nil) = %v, want nil", got)
+	}
+}
+
+func TestStreamDeliversBeforeAllFinish(t *testing.T) {
+	release := make(chan struct{})
+	results := Stream([]int{1, 2}, 2, func(i int) int {
+		if i == 2 {
+			<-release
+		}
+		return i
+	})
+	// The fast job arrives while the slow one is still blocked.
+	if r := <-results; r != 1 {
+		t.Fatalf("first result = %d, want 1", r)
+	}
+	close(release)
+	if r := <-results; r != 2 {
+		t.Fatalf("second result = %d, want 2", r)
+	}
+	if _, ok := <-results; ok {
+		t.Error("channel not closed after the last result")
+	}
+
+	if _, ok := <-Stream(nil, 1, func(i int) int { return i }); ok {
+		t.Error("Stream(nil) channel not closed")
+	}
+}
+
+func TestRunOrderedKeepsItemOrder(t *testing.T) {
+	items := []int{5, 4, 3, 2, 1}
+	// Later items finish first.
+	got := RunOrdered(items, len(items), func(i int) int {
+		time.Sleep(time.Duration(i) * time.Millisecond)
+		return i * 10
+	})
+	for i, r := range got {
+		if r != items[i]*10 {
+			t.Fatalf("got %v, want results in item order", got)
+		}
+	}
+}
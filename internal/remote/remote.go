@@ -1,5 +1,10 @@
 package remote
 
+import (
+	"io"
+	"time"
+)
+
 // Repository is a normalized representation of a source control repository
 // independent of the backing service (Gitea, GitHub, etc.).
 type Repository struct {
@@ -15,6 +20,15 @@ type Repository struct {
 	Archived      bool
 	Private       bool
 	Fork          bool
+	Template      bool // a template repository new repos are generated from
+	Topics        []string
+	// Language is the primary language the provider detected, e.g. "Go";
+	// empty when it reports none.
+	Language string
+
+	// Parent is the repository a fork was created from. It may be nil for
+	// forks in listings (GitHub only reports it for single-repo lookups).
+	Parent *Repository
 }
 
 // GetCloneURL returns the preferred clone URL (SSH when available and requested).
@@ -29,5 +43,175 @@ func (r Repository) GetCloneURL(preferSSH bool) string {
 // remote provider.
 type Client interface {
 	ListOrgRepos(orgName string) ([]Repository, error)
+	ListUserRepos(userName string) ([]Repository, error)
 	GetRepo(owner, repoName string) (*Repository, error)
 }
+
+// CreateOptions describes a repository to create.
+type CreateOptions struct {
+	Description   string
+	Private       bool
+	DefaultBranch string // provider default when empty
+}
+
+// Creator is implemented by clients that can create repositories. owner is
+// an organization (group) or the token's own account.
+type Creator interface {
+	CreateRepo(owner, name string, opts CreateOptions) (*Repository, error)
+}
+
+// Searcher is implemented by clients whose provider can search
+// repositories. query is in the provider's search syntax and is limited to
+// the repos of owner, an organization or, with user, a user account.
+type Searcher interface {
+	SearchRepos(owner string, user bool, query string) ([]Repository, error)
+}
+
+// TeamLister is implemented by clients whose provider has teams that are
+// given access to an organization's repositories.
+type TeamLister interface {
+	ListTeamRepos(orgName, team string) ([]Repository, error)
+}
+
+// ForkSyncer is implemented by clients whose provider can update a fork's
+// branch from its parent server-side.
+type ForkSyncer interface {
+	SyncFork(owner, name, branch string) error
+}
+
+// MigrateSource is a repository on another forge to import.
+type MigrateSource struct {
+	CloneURL    string
+	Service     string // provider type of the source: gitea | github | gitlab
+	Token       string // read access to the source
+	Description string
+	Private     bool
+}
+
+// Migrator is implemented by clients whose provider can import a repository
+// from another forge server-side, including issues, releases and wiki where
+// the source service supports it.
+type Migrator interface {
+	MigrateRepo(owner, name string, src MigrateSource) (*Repository, error)
+}
+
+// PullRequest is an open pull request (GitLab: merge request) in provider
+// agnostic form.
+type PullRequest struct {
+	Number  int64     `json:"number"` // GitLab: the project-scoped IID
+	Title   string    `json:"title"`
+	Author  string    `json:"author"`
+	Head    string    `json:"head"` // source branch
+	Base    string    `json:"base"` // target branch
+	URL     string    `json:"url"`
+	Draft   bool      `json:"draft,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Review  string    `json:"review"` // approved | changes-requested | pending
+}
+
+// Review states of a PullRequest.
+const (
+	ReviewApproved         = "approved"
+	ReviewChangesRequested = "changes-requested"
+	ReviewPending          = "pending"
+)
+
+// PullRequestLister is implemented by clients that can list the open pull
+// requests of a repository.
+type PullRequestLister interface {
+	ListPullRequests(owner, name string) ([]PullRequest, error)
+}
+
+// NewPullRequest describes a pull request to open from Head into Base.
+type NewPullRequest struct {
+	Title string
+	Body  string
+	Head  string
+	Base  string
+	Draft bool
+}
+
+// PullRequestCreator is implemented by clients that can open pull requests.
+type PullRequestCreator interface {
+	CreatePullRequest(owner, name string, pr NewPullRequest) (*PullRequest, error)
+}
+
+// Release is a published release of a repository with the files uploaded to
+// it.
+type Release struct {
+	Tag       string
+	Name      string
+	Published time.Time
+	Assets    []ReleaseAsset
+}
+
+// ReleaseAsset is a file attached to a release.
+type ReleaseAsset struct {
+	Name string
+	Size int64
+	URL  string // what DownloadAsset fetches
+}
+
+// ReleaseLister is implemented by clients whose provider publishes releases
+// with downloadable assets.
+type ReleaseLister interface {
+	ListReleases(owner, name string) ([]Release, error)
+	DownloadAsset(asset ReleaseAsset, w io.Writer) error
+}
+
+// IssueFilter narrows the issues IssueCounter counts. Empty fields match
+// every issue.
+type IssueFilter struct {
+	Labels   []string // issues must carry all of them
+	Assignee string   // username
+}
+
+// IssueCounter is implemented by clients that can count the open issues of
+// a repository. Pull requests are not counted.
+type IssueCounter interface {
+	CountOpenIssues(owner, name string, filter IssueFilter) (int, error)
+}
+
+// RepoSettings are the repository settings `tugboat audit` checks. Nil
+// fields are settings the provider does not have or did not report (merge
+// settings usually need admin rights on the repo).
+type RepoSettings struct {
+	DefaultBranch       string
+	Private             bool
+	BranchProtected     bool // default branch
+	AllowMergeCommit    *bool
+	AllowSquashMerge    *bool
+	AllowRebaseMerge    *bool
+	DeleteBranchOnMerge *bool
+}
+
+// SettingsReader is implemented by clients that can read repository
+// settings.
+type SettingsReader interface {
+	GetRepoSettings(owner, name string) (*RepoSettings, error)
+}
+
+// FileReader is implemented by clients that can read a file of a repository
+// without cloning it.
+type FileReader interface {
+	// GetFile returns the content of path on the default branch, or nil
+	// when the repository has no such file.
+	GetFile(owner, name, path string) ([]byte, error)
+}
+
+// ReviewState combines the latest verdict of each reviewer into the review
+// state of a PullRequest. One request for changes outweighs any number of
+// approvals.
+func ReviewState(latest map[string]string) string {
+	state := ReviewPending
+	for _, verdict := range latest {
+		switch verdict {
+		case ReviewChangesRequested:
+			return ReviewChangesRequested
+		case ReviewApproved:
+			state = ReviewApproved
+		}
+	}
+	return state
+}
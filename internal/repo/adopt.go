@@ -0,0 +1,238 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+// Adopt makes stray local clones managed. Each path's remote repo is looked
+// up across the configured providers by its origin URL (or, failing that, by
+// directory name under the configured owners) and recorded as a foldout of
+// the repo target it sits in, or else as a new repo target in the config file
+// at configPath.
+func (m *Manager) Adopt(paths []string, configPath string) error {
+	var failed int
+	for _, p := range paths {
+		if err := m.adoptOne(p, configPath); err != nil {
+			m.logEvent(slog.LevelError, "error", p, "%v", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d paths could not be adopted", failed, len(paths))
+	}
+	return nil
+}
+
+func (m *Manager) adoptOne(repoPath, configPath string) error {
+	repoPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return err
+	}
+	if !isGitRepo(repoPath) {
+		return fmt.Errorf("not a git repository")
+	}
+	for _, t := range m.config.Targets {
+		if t.Path == repoPath {
+			return fmt.Errorf("already managed by target %q", t.Name)
+		}
+	}
+
+	origin, _ := gitOutput(repoPath, "remote", "get-url", "origin")
+	origin = strings.TrimSpace(origin)
+	providerName, owner, r, err := m.findRemoteRepo(repoPath, origin)
+	if err != nil {
+		return err
+	}
+
+	// Inside an org/user target's directory: managed once the name matches.
+	for _, t := range m.config.Targets {
+		if t.Repo != "" || t.Provider != providerName || filepath.Dir(repoPath) != t.Path {
+			continue
+		}
+		if !strings.EqualFold(t.Owner(), owner) {
+			continue
+		}
+		if filepath.Base(repoPath) != r.Name {
+			return fmt.Errorf("remote repo is named %q; rename the directory to manage it under target %q", r.Name, t.Name)
+		}
+		return fmt.Errorf("already managed by target %q", t.Name)
+	}
+
+	// Inside a repo target: record as a foldout, naming the provider when it
+	// is not the target's.
+	for _, t := range m.config.Targets {
+		if t.Repo == "" || !strings.HasPrefix(repoPath, t.Path+string(filepath.Separator)) {
+			continue
+		}
+		if strings.Contains(owner, "/") {
+			return fmt.Errorf("foldouts cannot reference nested group %s", owner)
+		}
+		rel, _ := filepath.Rel(t.Path, repoPath)
+		entry := foldoutRepo{Name: owner + "/" + r.Name, Target: filepath.ToSlash(rel)}
+		if t.Provider != providerName {
+			entry.Name = providerName + ":" + entry.Name
+		}
+		if m.DryRun {
+			m.printPlan(repoPath, "would-adopt", fmt.Sprintf("foldout %s of target %s", entry.Name, t.Name))
+			return nil
+		}
+		if err := appendFoldout(t.Path, entry); err != nil {
+			return err
+		}
+		m.logEvent(slog.LevelInfo, "adopt", repoPath, "foldout %s of target %s", entry.Name, t.Name)
+		return nil
+	}
+
+	// Otherwise: a new repo target.
+	t := config.Target{Name: r.Name, Provider: providerName, Repo: r.Name, Path: repoPath}
+	if m.ownerIsUser(providerName, owner) {
+		t.User = owner
+	} else {
+		t.Org = owner
+	}
+	if m.config.GetTargetByName(t.Name) != nil {
+		t.Name = strings.ReplaceAll(owner, "/", "-") + "-" + r.Name
+		if m.config.GetTargetByName(t.Name) != nil {
+			return fmt.Errorf("target names %q and %q are both taken", r.Name, t.Name)
+		}
+	}
+	if m.DryRun {
+		m.printPlan(repoPath, "would-adopt", fmt.Sprintf("new target %s (%s/%s)", t.Name, owner, r.Name))
+		return nil
+	}
+	if err := config.AppendTarget(configPath, t); err != nil {
+		return err
+	}
+	m.config.Targets = append(m.config.Targets, t)
+	m.logEvent(slog.LevelInfo, "adopt", repoPath, "new target %s (%s/%s)", t.Name, owner, r.Name)
+	return nil
+}
+
+// findRemoteRepo locates the remote repo for a local clone. Candidates come
+// from the origin URL path on every provider, then from the directory name
+// under each configured owner. When origin is set the remote's clone URLs
+// must match it, so a same-named repo elsewhere is never picked.
+func (m *Manager) findRemoteRepo(repoPath, origin string) (providerName, owner string, repo *remote.Repository, err error) {
+	type candidate struct{ provider, owner, name string }
+	var candidates []candidate
+	seen := make(map[candidate]bool)
+	add := func(c candidate) {
+		if c.owner != "" && c.name != "" && !seen[c] {
+			seen[c] = true
+			candidates = append(candidates, c)
+		}
+	}
+
+	providerNames := make([]string, 0, len(m.providers))
+	for name := range m.providers {
+		providerNames = append(providerNames, name)
+	}
+	sort.Strings(providerNames)
+
+	if origin != "" {
+		if urlPath := normalizeGitURL(origin); strings.Count(urlPath, "/") >= 2 {
+			parts := strings.Split(urlPath, "/")
+			for _, p := range providerNames {
+				add(candidate{p, strings.Join(parts[1:len(parts)-1], "/"), parts[len(parts)-1]})
+			}
+		}
+	}
+	for _, t := range m.config.Targets {
+		add(candidate{t.Provider, t.Owner(), filepath.Base(repoPath)})
+	}
+
+	for _, c := range candidates {
+		client, ok := m.providers[c.provider]
+		if !ok {
+			continue
+		}
+		r, err := client.GetRepo(c.owner, c.name)
+		if err != nil || r == nil {
+			continue
+		}
+		if origin != "" && !sameGitURL(origin, r.CloneURL) && !sameGitURL(origin, r.SSHURL) {
+			continue
+		}
+		return c.provider, c.owner, r, nil
+	}
+	if origin != "" {
+		return "", "", nil, fmt.Errorf("no configured provider has a repo for origin %s", origin)
+	}
+	return "", "", nil, fmt.Errorf("no origin remote and no repo named %q under the configured owners", filepath.Base(repoPath))
+}
+
+// ownerIsUser reports whether owner is configured as a user account.
+func (m *Manager) ownerIsUser(providerName, owner string) bool {
+	for _, t := range m.config.Targets {
+		if t.Provider == providerName && t.IsUser() && strings.EqualFold(t.User, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeGitURL reduces https, ssh and scp-style git URLs to host/path
+// without credentials, port or .git suffix. Local paths are returned cleaned.
+func normalizeGitURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	var host, p string
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return raw
+		}
+		host, p = u.Hostname(), u.Path
+	} else if i := strings.Index(raw, ":"); i > 0 && !strings.Contains(raw[:i], "/") {
+		host = raw[:i]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		p = raw[i+1:]
+	} else {
+		return strings.TrimSuffix(filepath.Clean(raw), ".git")
+	}
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	return strings.ToLower(host) + "/" + p
+}
+
+func sameGitURL(a, b string) bool {
+	return b != "" && normalizeGitURL(a) == normalizeGitURL(b)
+}
+
+// appendFoldout adds entry to the .tugboat.json of a repo target, creating the
+// file if needed.
+func appendFoldout(targetPath string, entry foldoutRepo) error {
+	path := filepath.Join(targetPath, ".tugboat.json")
+	var fc foldoutConfig
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &fc); err != nil {
+			return fmt.Errorf("parsing .tugboat.json: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	for _, fr := range fc.Repos {
+		if fr.Name == entry.Name {
+			return fmt.Errorf("%s is already a foldout of %s", entry.Name, targetPath)
+		}
+	}
+	fc.Repos = append(fc.Repos, entry)
+	data, err := json.MarshalIndent(fc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,165 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+// ErrDrift is returned by Audit when every repo was read but some do not
+// match the policy.
+var ErrDrift = errors.New("some repositories drift from the audit policy")
+
+// AuditEntry is one repo of `tugboat audit`.
+type AuditEntry struct {
+	Target    string   `json:"target"`
+	Provider  string   `json:"provider"`
+	Repo      string   `json:"repo"`                // owner/name
+	Drift     []string `json:"drift"`               // settings that differ from the policy
+	Unchecked []string `json:"unchecked,omitempty"` // policy settings the provider did not report
+	Error     string   `json:"error,omitempty"`
+}
+
+type auditJob struct {
+	target   string
+	provider string
+	owner    string
+	name     string
+}
+
+// Audit compares the remote settings of the repos of the named targets with
+// the audit policy of the config: repo targets and the (unarchived, selected)
+// repos of org and user targets, whether cloned or not.
+func (m *Manager) Audit(targetNames []string, workers int) error {
+	policy := m.config.Audit
+	if policy == nil {
+		return errors.New(`no "audit" policy in the config`)
+	}
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+
+	var jobs []auditJob
+	entries := []AuditEntry{}
+	for _, t := range targets {
+		if t.Repo != "" {
+			jobs = append(jobs, auditJob{target: t.Name, provider: t.Provider, owner: t.Owner(), name: t.Repo})
+			continue
+		}
+		repos, err := m.listTargetRepos(t)
+		if err != nil {
+			entries = append(entries, AuditEntry{Target: t.Name, Provider: t.Provider, Repo: t.Owner(), Drift: []string{}, Error: fmt.Sprintf("listing repos: %v", err)})
+			continue
+		}
+		for _, r := range repos {
+			if r.Archived || !selectsRepo(t, r) {
+				continue
+			}
+			jobs = append(jobs, auditJob{target: t.Name, provider: t.Provider, owner: t.Owner(), name: r.Name})
+		}
+	}
+
+	entries = append(entries, pool.Run(jobs, workers, func(job auditJob) AuditEntry {
+		e := AuditEntry{Target: job.target, Provider: job.provider, Repo: job.owner + "/" + job.name, Drift: []string{}}
+		reader, ok := m.providers[job.provider].(remote.SettingsReader)
+		if !ok {
+			e.Error = fmt.Sprintf("provider %q cannot read repo settings", job.provider)
+			return e
+		}
+		settings, err := reader.GetRepoSettings(job.owner, job.name)
+		if err != nil {
+			e.Error = err.Error()
+			return e
+		}
+		e.Drift, e.Unchecked = auditSettings(*policy, *settings)
+		return e
+	})...)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Provider != entries[j].Provider {
+			return entries[i].Provider < entries[j].Provider
+		}
+		return entries[i].Repo < entries[j].Repo
+	})
+
+	var errored, drifted int
+	for _, e := range entries {
+		switch {
+		case e.Error != "":
+			errored++
+			m.printf("  [ERROR]  %s/%s: %s\n", e.Provider, e.Repo, e.Error)
+		case len(e.Drift) > 0:
+			drifted++
+			m.printf("  [DRIFT]  %s/%s: %s\n", e.Provider, e.Repo, strings.Join(e.Drift, "; "))
+		default:
+			m.logEvent(slog.LevelDebug, "ok", e.Provider+"/"+e.Repo, "matches the policy")
+		}
+		if len(e.Unchecked) > 0 {
+			m.logEvent(slog.LevelDebug, "unchecked", e.Provider+"/"+e.Repo, "%s not reported", strings.Join(e.Unchecked, ", "))
+		}
+	}
+	if m.Output != "" {
+		if err := writeRows(m.out(), entries, m.Output); err != nil {
+			return err
+		}
+	} else if m.JSON {
+		if err := m.writeJSON(entries); err != nil {
+			return err
+		}
+	} else {
+		m.printf("\n%d of %d repos drift from the policy\n", drifted, len(entries))
+	}
+
+	if errored > 0 {
+		return &RepoFailures{Failed: errored, Total: len(entries)}
+	}
+	if drifted > 0 {
+		return ErrDrift
+	}
+	return nil
+}
+
+// auditSettings lists how settings differ from policy, and which policy
+// settings the provider did not report.
+func auditSettings(policy config.AuditPolicy, settings remote.RepoSettings) (drift, unchecked []string) {
+	drift = []string{}
+	if policy.DefaultBranch != "" && settings.DefaultBranch != policy.DefaultBranch {
+		drift = append(drift, fmt.Sprintf("default_branch is %s, want %s", settings.DefaultBranch, policy.DefaultBranch))
+	}
+	if policy.Visibility != "" {
+		visibility := "public"
+		if settings.Private {
+			visibility = "private"
+		}
+		if visibility != policy.Visibility {
+			drift = append(drift, fmt.Sprintf("visibility is %s, want %s", visibility, policy.Visibility))
+		}
+	}
+	if policy.BranchProtection != nil && settings.BranchProtected != *policy.BranchProtection {
+		drift = append(drift, fmt.Sprintf("branch_protection is %t, want %t", settings.BranchProtected, *policy.BranchProtection))
+	}
+	for _, check := range []struct {
+		name       string
+		want, have *bool
+	}{
+		{"allow_merge_commit", policy.AllowMergeCommit, settings.AllowMergeCommit},
+		{"allow_squash_merge", policy.AllowSquashMerge, settings.AllowSquashMerge},
+		{"allow_rebase_merge", policy.AllowRebaseMerge, settings.AllowRebaseMerge},
+		{"delete_branch_on_merge", policy.DeleteBranchOnMerge, settings.DeleteBranchOnMerge},
+	} {
+		switch {
+		case check.want == nil:
+		case check.have == nil:
+			unchecked = append(unchecked, check.name)
+		case *check.have != *check.want:
+			drift = append(drift, fmt.Sprintf("%s is %t, want %t", check.name, *check.have, *check.want))
+		}
+	}
+	return drift, unchecked
+}
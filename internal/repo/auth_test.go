@@ -4,6 +4,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
 )
 
 func TestGitEnvWithAuth(t *testing.T) {
@@ -47,6 +49,34 @@ func TestGitEnvWithAuth(t *testing.T) {
 
 }
 
+func TestGitAuthEnvSetsSSHCommand(t *testing.T) {
+	env := authFor(config.Provider{Token: "tok", SSHCommand: "ssh -i /keys/gitea"}).env()
+	var sshCommand string
+	for _, e := range env {
+		if strings.HasPrefix(e, "GIT_SSH_COMMAND=") {
+			sshCommand = strings.TrimPrefix(e, "GIT_SSH_COMMAND=")
+		}
+	}
+	if sshCommand != "ssh -i /keys/gitea" {
+		t.Errorf("GIT_SSH_COMMAND = %q, want the provider's ssh_command", sshCommand)
+	}
+}
+
+func TestGitAuthEnvSetsProxy(t *testing.T) {
+	env := authFor(config.Provider{Token: "tok", Proxy: "socks5h://127.0.0.1:1080"}).env()
+	got := map[string]string{}
+	for _, e := range env {
+		if k, v, ok := strings.Cut(e, "="); ok {
+			got[k] = v
+		}
+	}
+	for _, k := range []string{"http_proxy", "https_proxy", "HTTPS_PROXY"} {
+		if got[k] != "socks5h://127.0.0.1:1080" {
+			t.Errorf("%s = %q, want the provider's proxy", k, got[k])
+		}
+	}
+}
+
 func TestGitEnvNoPrompt(t *testing.T) {
 	env := gitEnvNoPrompt()
 	found := false
@@ -0,0 +1,323 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+)
+
+// gitBackend performs the git operations behind clone, status, pull and
+// push. Branch switching and the other commands still shell out directly.
+type gitBackend interface {
+	// Clone clones cloneURL into dest.
+	Clone(cloneURL, dest string, auth gitAuth, opts config.CloneOptions) error
+	// Fetch updates remote-tracking refs; the error message is the first line
+	// git reported.
+	Fetch(repoPath string, auth gitAuth) error
+	// CurrentBranch returns the checked-out branch ("HEAD" when detached).
+	CurrentBranch(repoPath string) (string, error)
+	// IsDirty reports uncommitted or untracked changes.
+	IsDirty(repoPath string) (bool, error)
+	// AheadBehind counts commits on local but not upstream and vice versa.
+	AheadBehind(repoPath, local, upstream string) (ahead, behind int, err error)
+	// IsAncestor reports whether ancestor is reachable from descendant.
+	IsAncestor(repoPath, ancestor, descendant string) bool
+	// SubmoduleDrift lists submodules that are uninitialized or checked out
+	// at a different commit than the superproject records.
+	SubmoduleDrift(repoPath string) ([]string, error)
+	// StashCount returns the number of stash entries.
+	StashCount(repoPath string) (int, error)
+	// Pull and Push run git pull and git push with args in the repo and
+	// return git's combined output.
+	Pull(repoPath string, auth gitAuth, args ...string) ([]byte, error)
+	Push(repoPath string, auth gitAuth, args ...string) ([]byte, error)
+}
+
+// hostLimitBackend lets at most n fetches, clones, pulls and pushes run
+// against one host at a time, however many workers handle repos in parallel.
+// Everything else is passed through.
+type hostLimitBackend struct {
+	gitBackend
+	n int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{} // by host
+}
+
+func limitPerHost(b gitBackend, n int) gitBackend {
+	if n <= 0 {
+		return b
+	}
+	return &hostLimitBackend{gitBackend: b, n: n, slots: make(map[string]chan struct{})}
+}
+
+// acquire waits for a free slot for host and returns its release.
+func (b *hostLimitBackend) acquire(host string) func() {
+	b.mu.Lock()
+	slot, ok := b.slots[host]
+	if !ok {
+		slot = make(chan struct{}, b.n)
+		b.slots[host] = slot
+	}
+	b.mu.Unlock()
+	slot <- struct{}{}
+	return func() { <-slot }
+}
+
+func (b *hostLimitBackend) Clone(cloneURL, dest string, auth gitAuth, opts config.CloneOptions) error {
+	defer b.acquire(urlHost(cloneURL))()
+	return b.gitBackend.Clone(cloneURL, dest, auth, opts)
+}
+
+func (b *hostLimitBackend) Fetch(repoPath string, auth gitAuth) error {
+	defer b.acquire(originHost(repoPath))()
+	return b.gitBackend.Fetch(repoPath, auth)
+}
+
+func (b *hostLimitBackend) Pull(repoPath string, auth gitAuth, args ...string) ([]byte, error) {
+	defer b.acquire(originHost(repoPath))()
+	return b.gitBackend.Pull(repoPath, auth, args...)
+}
+
+func (b *hostLimitBackend) Push(repoPath string, auth gitAuth, args ...string) ([]byte, error) {
+	defer b.acquire(originHost(repoPath))()
+	return b.gitBackend.Push(repoPath, auth, args...)
+}
+
+// deadlineBackend stops the fetches, clones, pulls and pushes that are
+// still running at the manager's Deadline.
+type deadlineBackend struct {
+	gitBackend
+	m *Manager
+}
+
+func (b deadlineBackend) Clone(cloneURL, dest string, auth gitAuth, opts config.CloneOptions) error {
+	auth.deadline = b.m.Deadline
+	return b.gitBackend.Clone(cloneURL, dest, auth, opts)
+}
+
+func (b deadlineBackend) Fetch(repoPath string, auth gitAuth) error {
+	auth.deadline = b.m.Deadline
+	return b.gitBackend.Fetch(repoPath, auth)
+}
+
+func (b deadlineBackend) Pull(repoPath string, auth gitAuth, args ...string) ([]byte, error) {
+	auth.deadline = b.m.Deadline
+	return b.gitBackend.Pull(repoPath, auth, args...)
+}
+
+func (b deadlineBackend) Push(repoPath string, auth gitAuth, args ...string) ([]byte, error) {
+	auth.deadline = b.m.Deadline
+	return b.gitBackend.Push(repoPath, auth, args...)
+}
+
+// originHost returns the host of the repo's origin remote.
+func originHost(repoPath string) string {
+	origin, _ := gitOutput(repoPath, "config", "--get", "remote.origin.url")
+	return urlHost(strings.TrimSpace(origin))
+}
+
+// urlHost returns the host of a git remote URL: https://host/..., ssh://
+// user@host:port/... or scp-like user@host:path. Local paths yield "".
+func urlHost(remoteURL string) string {
+	if u, err := url.Parse(remoteURL); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if at := strings.Index(remoteURL, "@"); at >= 0 {
+		if host, _, ok := strings.Cut(remoteURL[at+1:], ":"); ok {
+			return host
+		}
+	}
+	return ""
+}
+
+// execBackend runs the git binary found on PATH.
+type execBackend struct{}
+
+// timedGit returns a git command for args that is stopped once timeout
+// passes or at deadline; zero values mean no limit. Its done function must
+// be called with the command's error when it has finished; it reports a
+// stopped command as having timed out.
+func timedGit(timeout config.Duration, deadline time.Time, args ...string) (cmd *exec.Cmd, done func(error) error) {
+	if timeout <= 0 && deadline.IsZero() {
+		return exec.Command("git", args...), func(err error) error { return err }
+	}
+	run, cancelRun := context.Background(), context.CancelFunc(func() {})
+	if !deadline.IsZero() {
+		run, cancelRun = context.WithDeadline(run, deadline)
+	}
+	ctx, cancel := run, context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(run, time.Duration(timeout))
+	}
+	cmd = exec.CommandContext(ctx, "git", args...)
+	// Interrupted, git removes its lock files on the way out; it is killed
+	// if it does not exit soon. WaitDelay also stops waiting for an ssh or
+	// credential helper that holds git's output open after git is gone.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = 2 * time.Second
+	return cmd, func(err error) error {
+		defer cancelRun()
+		defer cancel()
+		switch {
+		case err == nil || ctx.Err() == nil:
+			return err
+		case run.Err() != nil:
+			return fmt.Errorf("git %s stopped: %w", args[0], ErrDeadline)
+		default:
+			return fmt.Errorf("git %s timed out after %s", args[0], time.Duration(timeout))
+		}
+	}
+}
+
+func (execBackend) Clone(cloneURL, dest string, auth gitAuth, opts config.CloneOptions) error {
+	args := []string{"clone"}
+	if opts.Mode == "mirror" {
+		args = append(args, "--mirror")
+	}
+	if opts.Depth > 0 {
+		// --depth implies --single-branch; keep every branch so checkout and
+		// switch-default still see them.
+		args = append(args, "--depth", strconv.Itoa(opts.Depth), "--no-single-branch")
+	}
+	if opts.GetSubmodules() && opts.Mode != "mirror" {
+		args = append(args, "--recurse-submodules")
+	}
+	if opts.Filter != "" {
+		args = append(args, "--filter="+opts.Filter)
+	}
+	if opts.ReferenceDir != "" {
+		args = append(args, "--reference-if-able", referencePath(opts.ReferenceDir, cloneURL))
+		if opts.Dissociate {
+			args = append(args, "--dissociate")
+		}
+	}
+	_, statErr := os.Stat(dest)
+	cmd, done := timedGit(auth.timeouts.Clone, auth.deadline, append(args, cloneURL, dest)...)
+	cmd.Env = auth.env()
+	output, err := cmd.CombinedOutput()
+	timedOut := done(err)
+	if err == nil {
+		return nil
+	}
+	// A failed clone may leave a partial checkout behind that would pass
+	// for a clone on the next run, or block cloning again.
+	if os.IsNotExist(statErr) {
+		os.RemoveAll(dest)
+	}
+	if timedOut != err {
+		return timedOut
+	}
+	return fmt.Errorf("%v: %s", err, output)
+}
+
+func (execBackend) Fetch(repoPath string, auth gitAuth) error {
+	cmd, done := timedGit(auth.timeouts.Fetch, auth.deadline, "fetch", "--quiet")
+	cmd.Dir = repoPath
+	cmd.Env = auth.env()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if timedOut := done(err); timedOut != err {
+		return timedOut
+	}
+	if err != nil {
+		output := strings.TrimSpace(stderr.String())
+		if idx := strings.Index(output, "\n"); idx > 0 {
+			output = output[:idx]
+		}
+		if output == "" {
+			return err
+		}
+		return errors.New(output)
+	}
+	return nil
+}
+
+func (execBackend) Pull(repoPath string, auth gitAuth, args ...string) ([]byte, error) {
+	cmd, done := timedGit(auth.timeouts.Pull, auth.deadline, append([]string{"pull"}, args...)...)
+	cmd.Dir = repoPath
+	cmd.Env = auth.env()
+	out, err := cmd.CombinedOutput()
+	return out, done(err)
+}
+
+func (execBackend) Push(repoPath string, auth gitAuth, args ...string) ([]byte, error) {
+	cmd, done := timedGit(auth.timeouts.Push, auth.deadline, append([]string{"push"}, args...)...)
+	cmd.Dir = repoPath
+	cmd.Env = auth.env()
+	out, err := cmd.CombinedOutput()
+	return out, done(err)
+}
+
+func (execBackend) CurrentBranch(repoPath string) (string, error) {
+	branch, err := gitOutput(repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+	return strings.TrimSpace(branch), err
+}
+
+func (execBackend) IsDirty(repoPath string) (bool, error) {
+	output, err := gitOutput(repoPath, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(output) != "", nil
+}
+
+func (execBackend) AheadBehind(repoPath, local, upstream string) (int, int, error) {
+	revList, err := gitOutput(repoPath, "rev-list", "--left-right", "--count", fmt.Sprintf("%s...%s", local, upstream))
+	if err != nil {
+		return 0, 0, err
+	}
+	var ahead, behind int
+	parts := strings.Fields(strings.TrimSpace(revList))
+	if len(parts) == 2 {
+		fmt.Sscanf(parts[0], "%d", &ahead)
+		fmt.Sscanf(parts[1], "%d", &behind)
+	}
+	return ahead, behind, nil
+}
+
+func (execBackend) IsAncestor(repoPath, ancestor, descendant string) bool {
+	return gitRun(repoPath, "merge-base", "--is-ancestor", ancestor, descendant) == nil
+}
+
+func (execBackend) SubmoduleDrift(repoPath string) ([]string, error) {
+	output, err := gitOutput(repoPath, "submodule", "status", "--recursive")
+	if err != nil {
+		return nil, err
+	}
+	// Each line is "<flag><sha> <path> (<describe>)"; a space flag means the
+	// submodule matches the recorded commit.
+	var drifted []string
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 2 || line[0] == ' ' {
+			continue
+		}
+		if fields := strings.Fields(line[1:]); len(fields) >= 2 {
+			drifted = append(drifted, fields[1])
+		}
+	}
+	return drifted, nil
+}
+
+func (execBackend) StashCount(repoPath string) (int, error) {
+	// Without refs/stash there is nothing to count, and rev-list would fail.
+	if gitRun(repoPath, "rev-parse", "--verify", "--quiet", "refs/stash") != nil {
+		return 0, nil
+	}
+	output, err := gitOutput(repoPath, "rev-list", "--walk-reflogs", "--count", "refs/stash")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(output))
+}
@@ -0,0 +1,470 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+)
+
+// backupManifest is DIR/manifest.json of a backup: every backed-up repo with
+// the bundles that restore it.
+type backupManifest struct {
+	Updated time.Time     `json:"updated"`
+	Repos   []backupEntry `json:"repos"`
+}
+
+// backupEntry is one repo of a backup. Bundles are applied in order: a full
+// bundle, then incremental ones holding what was new at later backups. Refs
+// are the repo's refs at the last backup, which restore puts back exactly.
+type backupEntry struct {
+	Target  string            `json:"target"`
+	Path    string            `json:"path"` // relative to the target's path, slash-separated
+	Repo    string            `json:"repo"` // owner/name
+	Remote  string            `json:"remote,omitempty"`
+	Bare    bool              `json:"bare,omitempty"`
+	Branch  string            `json:"branch,omitempty"` // checked-out branch; empty when detached
+	Commit  string            `json:"commit,omitempty"` // HEAD commit
+	Refs    map[string]string `json:"refs"`
+	Bundles []string          `json:"bundles"` // relative to the backup dir, slash-separated
+	Updated time.Time         `json:"updated"`
+}
+
+func (e backupEntry) key() string {
+	return e.Target + "\x00" + e.Path
+}
+
+func readBackupManifest(dir string) (*backupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if os.IsNotExist(err) {
+		return &backupManifest{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var manifest backupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Join(dir, "manifest.json"), err)
+	}
+	return &manifest, nil
+}
+
+func writeBackupManifest(dir string, manifest *backupManifest) error {
+	sort.Slice(manifest.Repos, func(i, j int) bool { return manifest.Repos[i].key() < manifest.Repos[j].key() })
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, ".manifest.json.tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, "manifest.json"))
+}
+
+type backupJob struct {
+	statusJob
+	rel  string
+	prev *backupEntry
+}
+
+type backupResult struct {
+	result RepoResult
+	entry  *backupEntry // nil when the manifest keeps its previous entry
+}
+
+// Backup writes a git bundle of every local repo of the named targets into
+// dir, mirrors included, and records them in dir/manifest.json. A repo that
+// was backed up before only gets an incremental bundle of what is new since,
+// and none when its refs did not change; full starts every repo over with a
+// full bundle. Entries of targets that were not selected are kept.
+func (m *Manager) Backup(dir string, targetNames []string, full bool, workers int) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	var existing []config.Target
+	for _, t := range targets {
+		if _, err := os.Stat(t.Path); err == nil {
+			existing = append(existing, t)
+		}
+	}
+	repos, _, err := m.localRepos(existing)
+	if err != nil {
+		return err
+	}
+	manifest, err := readBackupManifest(dir)
+	if err != nil {
+		return err
+	}
+	prev := make(map[string]*backupEntry, len(manifest.Repos))
+	for i, e := range manifest.Repos {
+		prev[e.key()] = &manifest.Repos[i]
+	}
+	var jobs []backupJob
+	for _, job := range repos {
+		t := m.config.GetTargetByName(job.target)
+		rel, err := filepath.Rel(t.Path, job.path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		jobs = append(jobs, backupJob{statusJob: job, rel: rel, prev: prev[job.target+"\x00"+rel]})
+	}
+	if !m.DryRun {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	results := runJobs(m, jobs, workers, func(m *Manager, job backupJob) backupResult {
+		return m.backupRepo(dir, job, full)
+	}, func(r backupResult) { m.emit(r.result) })
+
+	entries := make(map[string]backupEntry, len(manifest.Repos))
+	for _, e := range manifest.Repos {
+		entries[e.key()] = e
+	}
+	var out []RepoResult
+	for _, r := range results {
+		if r.entry != nil {
+			entries[r.entry.key()] = *r.entry
+		}
+		out = append(out, r.result)
+	}
+	if !m.DryRun {
+		manifest.Updated = time.Now().UTC().Truncate(time.Second)
+		manifest.Repos = manifest.Repos[:0]
+		for _, e := range entries {
+			manifest.Repos = append(manifest.Repos, e)
+		}
+		if err := writeBackupManifest(dir, manifest); err != nil {
+			return err
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+
+	if m.JSON {
+		if out == nil {
+			out = []RepoResult{}
+		}
+		if err := m.writeJSON(out); err != nil {
+			return err
+		}
+		return resultsOutcome(out)
+	}
+	done, skipped, failed := countResults(out)
+	unchanged := len(out) - done - skipped - failed
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Backup dry run: %d to back up, %d unchanged, %d skipped, %d failed", done, unchanged, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Backup complete: %d backed up, %d unchanged, %d skipped, %d failed", done, unchanged, skipped, failed)
+	}
+	return resultsOutcome(out)
+}
+
+// backupRepo bundles one repo into dir, incrementally on top of its previous
+// bundles unless full is set or they are gone.
+func (m *Manager) backupRepo(dir string, job backupJob, full bool) backupResult {
+	r := RepoResult{Path: job.path, Target: job.target, Name: job.name}
+	fail := func(err error) backupResult {
+		m.logEvent(slog.LevelError, "error", job.path, "%v", err)
+		r.Result, r.Message = "failed", err.Error()
+		return backupResult{result: r}
+	}
+	refs, err := gitRefs(job.path)
+	if err != nil {
+		return fail(err)
+	}
+	if len(refs) == 0 {
+		m.logEvent(slog.LevelInfo, "skip", job.path, "no commits")
+		r.Result, r.Message = "skipped", "no commits"
+		return backupResult{result: r}
+	}
+
+	prev := job.prev
+	if prev != nil && !full {
+		for _, b := range prev.Bundles {
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(b))); err != nil {
+				prev = nil // a bundle of the chain is gone; start over
+				break
+			}
+		}
+	}
+	if full {
+		prev = nil
+	}
+	entry := backupEntry{Target: job.target, Path: job.rel, Repo: job.org + "/" + job.name, Bare: isBareRepo(job.path), Refs: refs, Updated: time.Now().UTC().Truncate(time.Second)}
+	if remote, err := gitOutput(job.path, "remote", "get-url", "origin"); err == nil {
+		entry.Remote = strings.TrimSpace(remote)
+	}
+	if commit, err := gitOutput(job.path, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+		entry.Commit = strings.TrimSpace(commit)
+	}
+	if branch, err := gitOutput(job.path, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+		entry.Branch = strings.TrimSpace(branch)
+	}
+	if prev != nil && maps.Equal(prev.Refs, refs) && prev.Branch == entry.Branch && prev.Commit == entry.Commit && prev.Remote == entry.Remote {
+		m.logEvent(slog.LevelDebug, "unchanged", job.path, "")
+		r.Result = "unchanged"
+		return backupResult{result: r}
+	}
+	kind := "full"
+	if prev != nil {
+		kind = "incremental"
+	}
+	if m.DryRun {
+		m.printPlan(job.path, "would-back-up", kind)
+		r.Result, r.Message = "would-back-up", kind
+		return backupResult{result: r}
+	}
+
+	repoDir := filepath.Join("bundles", job.target, filepath.FromSlash(job.rel))
+	if err := os.MkdirAll(filepath.Join(dir, repoDir), 0755); err != nil {
+		return fail(err)
+	}
+	if prev != nil {
+		// Leave out what the previous bundles hold: everything reachable from
+		// the refs they recorded that the repo still has.
+		var exclude []string
+		seen := make(map[string]bool)
+		for _, commit := range prev.Refs {
+			if !seen[commit] && gitRun(job.path, "cat-file", "-e", commit) == nil {
+				exclude = append(exclude, "^"+commit)
+			}
+			seen[commit] = true
+		}
+		name := filepath.ToSlash(filepath.Join(repoDir, fmt.Sprintf("%04d.bundle", len(prev.Bundles)+1)))
+		err := createBundle(job.path, filepath.Join(dir, filepath.FromSlash(name)), exclude)
+		switch {
+		case err == nil:
+			entry.Bundles = append(append([]string{}, prev.Bundles...), name)
+		case strings.Contains(err.Error(), "empty bundle"):
+			// Nothing new to bundle: only refs or HEAD moved, e.g. a branch
+			// was deleted or checked out; the manifest carries that.
+			entry.Bundles = prev.Bundles
+		default:
+			return fail(err)
+		}
+	} else {
+		name := filepath.ToSlash(filepath.Join(repoDir, "0001.bundle"))
+		if err := createBundle(job.path, filepath.Join(dir, filepath.FromSlash(name)), nil); err != nil {
+			return fail(err)
+		}
+		entry.Bundles = []string{name}
+		// Bundles of an earlier chain are of no use anymore.
+		old, _ := filepath.Glob(filepath.Join(dir, repoDir, "*.bundle"))
+		for _, p := range old {
+			if filepath.Base(p) != "0001.bundle" {
+				os.Remove(p)
+			}
+		}
+	}
+
+	m.logEvent(slog.LevelInfo, "backup", job.path, "%s", kind)
+	r.Result, r.Message = "backed-up", kind
+	return backupResult{result: r, entry: &entry}
+}
+
+// gitRefs returns the refs of the repo at path with the objects they point
+// to.
+func gitRefs(path string) (map[string]string, error) {
+	out, err := gitOutput(path, "for-each-ref", "--format=%(objectname) %(refname)")
+	if err != nil {
+		return nil, fmt.Errorf("listing refs: %w", err)
+	}
+	refs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		object, ref, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		refs[ref] = object
+	}
+	return refs, nil
+}
+
+// createBundle writes a bundle of all refs of the repo at path to dest,
+// without the commits reachable from exclude (^COMMIT arguments). It goes
+// through a temporary file so a failed backup never leaves half a bundle.
+func createBundle(path, dest string, exclude []string) error {
+	tmp := dest + ".tmp"
+	args := append([]string{"bundle", "create", "--quiet", tmp, "--all"}, exclude...)
+	if err := gitCombined(path, args...); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// gitCombined runs git in dir and returns its output as part of the error
+// when it fails.
+func gitCombined(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = gitEnvNoPrompt()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RestoreBackup recreates the repos of the named targets recorded in the
+// backup in dir that are not cloned, from their bundles and without the
+// provider: refs, checked-out branch and origin remote are as they were at
+// the last backup. Repos that exist are skipped, and repos of targets that
+// are no longer configured are left out.
+func (m *Manager) RestoreBackup(dir string, targetNames []string, workers int) error {
+	manifest, err := readBackupManifest(dir)
+	if err != nil {
+		return err
+	}
+	if len(manifest.Repos) == 0 {
+		return fmt.Errorf("no backup in %s", dir)
+	}
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	selected := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		selected[t.Name] = true
+	}
+	var entries []backupEntry
+	for _, e := range manifest.Repos {
+		if selected[e.Target] {
+			entries = append(entries, e)
+		}
+	}
+
+	results := emitJobs(m, entries, workers, func(m *Manager, e backupEntry) RepoResult {
+		return m.restoreBackupRepo(dir, e)
+	})
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+
+	if m.JSON {
+		if results == nil {
+			results = []RepoResult{}
+		}
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	restored, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Backup restore dry run: %d to restore, %d skipped, %d failed", restored, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Backup restore complete: %d restored, %d skipped, %d failed", restored, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
+
+func (m *Manager) restoreBackupRepo(dir string, e backupEntry) RepoResult {
+	t := m.config.GetTargetByName(e.Target)
+	path := filepath.Join(t.Path, filepath.FromSlash(e.Path))
+	r := RepoResult{Path: path, Target: e.Target, Name: filepath.Base(path), Branch: e.Branch}
+	if isGitRepo(path) || isBareRepo(path) {
+		m.logEvent(slog.LevelDebug, "skip", path, "already cloned")
+		r.Result, r.Message = "skipped", "already cloned"
+		return r
+	}
+	if m.DryRun {
+		m.printPlan(path, "would-restore", fmt.Sprintf("%d bundles", len(e.Bundles)))
+		r.Result, r.Message = "would-restore", fmt.Sprintf("%d bundles", len(e.Bundles))
+		return r
+	}
+	_, statErr := os.Stat(path)
+	if err := restoreFromBundles(dir, path, e); err != nil {
+		// Like a failed clone, a failed restore leaves nothing behind.
+		if os.IsNotExist(statErr) {
+			os.RemoveAll(path)
+		}
+		m.logEvent(slog.LevelError, "error", path, "%v", err)
+		r.Result, r.Message = "failed", err.Error()
+		return r
+	}
+	m.logEvent(slog.LevelInfo, "restored", path, "")
+	r.Result = "restored"
+	return r
+}
+
+// restoreFromBundles creates the repo of e at path from its bundles.
+func restoreFromBundles(dir, path string, e backupEntry) error {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	initArgs := []string{"init", "--quiet"}
+	if e.Bare {
+		initArgs = append(initArgs, "--bare")
+	}
+	if err := gitCombined(path, initArgs...); err != nil {
+		return err
+	}
+	for _, b := range e.Bundles {
+		bundle, err := filepath.Abs(filepath.Join(dir, filepath.FromSlash(b)))
+		if err != nil {
+			return err
+		}
+		if err := gitCombined(path, "fetch", "--quiet", "--update-head-ok", bundle, "+refs/*:refs/*"); err != nil {
+			return fmt.Errorf("applying %s: %w", b, err)
+		}
+	}
+	// The bundles may hold refs that were deleted or moved since; the
+	// manifest has them as they were at the last backup.
+	have, err := gitRefs(path)
+	if err != nil {
+		return err
+	}
+	for ref := range have {
+		if _, ok := e.Refs[ref]; !ok {
+			if err := gitCombined(path, "update-ref", "-d", ref); err != nil {
+				return err
+			}
+		}
+	}
+	for ref, object := range e.Refs {
+		if have[ref] != object {
+			if err := gitCombined(path, "update-ref", ref, object); err != nil {
+				return err
+			}
+		}
+	}
+
+	if e.Remote != "" {
+		remoteArgs := []string{"remote", "add", "origin", e.Remote}
+		if e.Bare {
+			remoteArgs = []string{"remote", "add", "--mirror=fetch", "origin", e.Remote}
+		}
+		if err := gitCombined(path, remoteArgs...); err != nil {
+			return err
+		}
+	}
+	switch {
+	case e.Branch != "":
+		if err := gitCombined(path, "symbolic-ref", "HEAD", "refs/heads/"+e.Branch); err != nil {
+			return err
+		}
+	case e.Commit != "":
+		if err := gitCombined(path, "update-ref", "--no-deref", "HEAD", e.Commit); err != nil {
+			return err
+		}
+	}
+	if e.Bare {
+		return nil
+	}
+	if err := gitCombined(path, "reset", "--quiet", "--hard"); err != nil {
+		return err
+	}
+	if _, ok := e.Refs["refs/remotes/origin/"+e.Branch]; ok && e.Branch != "" && e.Remote != "" {
+		_ = gitRun(path, "branch", "--quiet", "--set-upstream-to=origin/"+e.Branch, e.Branch)
+	}
+	return nil
+}
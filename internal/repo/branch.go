@@ -0,0 +1,333 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
+)
+
+// BranchEntry is one repo line of the branch command.
+type BranchEntry struct {
+	Path          string           `json:"path"`
+	Target        string           `json:"target"`
+	Name          string           `json:"name"`
+	Branch        string           `json:"branch"`
+	DefaultBranch string           `json:"default_branch,omitempty"`
+	OffDefault    bool             `json:"off_default"`
+	Unpushed      []UnpushedBranch `json:"unpushed,omitempty"`
+	Error         string           `json:"error,omitempty"`
+}
+
+// UnpushedBranch is a local branch with commits that are on no origin ref.
+type UnpushedBranch struct {
+	Name    string `json:"name"`
+	Commits int    `json:"commits"`
+}
+
+// Branches prints, per repo, the checked-out branch and every local branch
+// with unpushed commits, flagging repos that are not on their default branch.
+func (m *Manager) Branches(targetNames []string, workers int) error {
+	statuses, err := m.Statuses(targetNames, workers)
+	if err != nil {
+		return err
+	}
+
+	entries := pool.Run(statuses, workers, branchEntry)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	if m.Output != "" {
+		return writeRows(m.out(), entries, m.Output)
+	}
+	if m.JSON {
+		if entries == nil {
+			entries = []BranchEntry{}
+		}
+		return m.writeJSON(entries)
+	}
+
+	var offDefault, unpushed, errored int
+	for _, e := range entries {
+		if e.Error != "" {
+			m.printf("  [ERROR]  %s: %s\n", e.Path, e.Error)
+			errored++
+			continue
+		}
+		line := fmt.Sprintf("  %s (%s)", e.Path, e.Branch)
+		if e.OffDefault {
+			line = fmt.Sprintf("  %s (%s) [%s]", e.Path, m.paint(colorYellow, e.Branch), m.paint(colorYellow, "not on "+e.DefaultBranch))
+			offDefault++
+		}
+		m.printf("%s\n", line)
+		for _, b := range e.Unpushed {
+			m.printf("      %s: %s\n", b.Name, m.paint(colorCyan, fmt.Sprintf("%d unpushed", b.Commits)))
+		}
+		if len(e.Unpushed) > 0 {
+			unpushed++
+		}
+	}
+	m.printf("\nSummary: %d repos, %d not on default branch, %d with unpushed commits, %d errors\n",
+		len(entries), offDefault, unpushed, errored)
+	if errored > 0 {
+		return &RepoFailures{Failed: errored, Total: len(entries)}
+	}
+	return nil
+}
+
+// branchEntry reads the branch overview of one repo from its status.
+func branchEntry(s RepoStatus) BranchEntry {
+	e := BranchEntry{Path: s.Path, Target: s.Target, Name: s.Name, Branch: s.Branch, Error: s.Error}
+	if s.Error != "" || s.Mirror {
+		return e
+	}
+	e.DefaultBranch = s.DefaultBranch
+	if e.DefaultBranch == "" {
+		e.DefaultBranch, _ = defaultBranchFromOriginHead(s.Path)
+	}
+	e.OffDefault = e.DefaultBranch != "" && s.Branch != e.DefaultBranch
+
+	unpushed, err := unpushedBranches(s.Path)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	e.Unpushed = unpushed
+	return e
+}
+
+// unpushedBranches lists the local branches of a repo that have commits not
+// reachable from any origin ref, so branches without an upstream count too.
+func unpushedBranches(repoPath string) ([]UnpushedBranch, error) {
+	out, err := gitOutput(repoPath, "for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("listing branches: %w", err)
+	}
+	var branches []UnpushedBranch
+	for _, name := range strings.Fields(out) {
+		n, err := unpushedCommits(repoPath, name)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			branches = append(branches, UnpushedBranch{Name: name, Commits: n})
+		}
+	}
+	return branches, nil
+}
+
+// unpushedCommits counts the commits of a local branch that are on no origin
+// ref.
+func unpushedCommits(repoPath, branch string) (int, error) {
+	out, err := gitOutput(repoPath, "rev-list", "--count", "refs/heads/"+branch, "--not", "--remotes=origin")
+	if err != nil {
+		return 0, fmt.Errorf("counting unpushed commits on %s: %w", branch, err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		return 0, fmt.Errorf("counting unpushed commits on %s: %w", branch, err)
+	}
+	return n, nil
+}
+
+// Checkout switches every repo of the named targets to branch, creating a
+// local tracking branch from origin where needed. Dirty repos are skipped and
+// repos without the branch are reported.
+func (m *Manager) Checkout(branch string, targetNames []string, workers int) error {
+	statuses, err := m.Statuses(targetNames, workers)
+	if err != nil {
+		return err
+	}
+
+	results := pool.Run(statuses, workers, func(s RepoStatus) RepoResult {
+		return m.CheckoutRepo(s, branch)
+	})
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+
+	if m.JSON {
+		if results == nil {
+			results = []RepoResult{}
+		}
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	var missing []string
+	for _, r := range results {
+		if r.Result == "skipped" && r.Message == missingBranchMessage(branch) {
+			missing = append(missing, r.Path)
+		}
+	}
+	switched, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Checkout dry run: %d to switch, %d skipped, %d failed", switched, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Checkout complete: %d switched, %d skipped, %d failed", switched, skipped, failed)
+	}
+	if len(missing) > 0 {
+		m.logf(slog.LevelWarn, "No branch %s in %d repos:\n  %s", branch, len(missing), strings.Join(missing, "\n  "))
+	}
+	return resultsOutcome(results)
+}
+
+// CheckoutRepo switches one repo to branch. Repos already on it are left
+// alone.
+func (m *Manager) CheckoutRepo(s RepoStatus, branch string) RepoResult {
+	if s.Error != "" {
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", s.Error)
+		return newResult(s, "failed", s.Error)
+	}
+	if s.Mirror {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "mirror clone")
+		return newResult(s, "skipped", "mirror clone")
+	}
+	if s.Branch == branch {
+		m.logEvent(slog.LevelDebug, "ok", s.Path, "already on %s", branch)
+		return newResult(s, "unchanged", "")
+	}
+
+	local := localBranchExists(s.Path, branch)
+	if !local && !remoteTrackingRefExists(s.Path, branch) {
+		m.logEvent(slog.LevelWarn, "miss", s.Path, "%s", missingBranchMessage(branch))
+		return newResult(s, "skipped", missingBranchMessage(branch))
+	}
+	if s.Dirty {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "dirty")
+		return newResult(s, "skipped", "dirty")
+	}
+
+	reason := fmt.Sprintf("%s -> %s", s.Branch, branch)
+	if !local {
+		reason += " (tracking origin/" + branch + ")"
+	}
+	if m.DryRun {
+		m.printPlan(s.Path, "would-switch", reason)
+		return newResult(s, "would-switch", reason)
+	}
+
+	args := []string{"switch", branch}
+	if !local {
+		args = []string{"switch", "-c", branch, "--track", "origin/" + branch}
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.Path
+	cmd.Env = gitEnvNoPrompt()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		msg := fmt.Sprintf("git switch %s: %v: %s", branch, err, strings.TrimSpace(string(out)))
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", msg)
+		return newResult(s, "failed", msg)
+	}
+	m.logEvent(slog.LevelInfo, "switch", s.Path, "%s", reason)
+	r := newResult(s, "switched", "")
+	r.Branch = branch
+	r.SwitchedFrom = s.Branch
+	return r
+}
+
+// missingBranchMessage is the skip reason for repos that lack branch.
+func missingBranchMessage(branch string) string {
+	return fmt.Sprintf("no branch %s locally or on origin", branch)
+}
+
+// markDefaultDrift records repos whose origin/HEAD still names a branch other
+// than the remote default, as left behind by a master -> main rename.
+func markDefaultDrift(statuses []RepoStatus) {
+	for i := range statuses {
+		s := &statuses[i]
+		if s.Error != "" || s.Mirror || s.DefaultBranch == "" {
+			continue
+		}
+		if head, err := defaultBranchFromOriginHead(s.Path); err == nil && head != s.DefaultBranch {
+			s.DefaultDrift = head
+		}
+	}
+}
+
+// SwitchDefault moves repos whose remote default branch changed onto the new
+// default and points origin/HEAD at it.
+func (m *Manager) SwitchDefault(targetNames []string, workers int) error {
+	statuses, err := m.Statuses(targetNames, workers)
+	if err != nil {
+		return err
+	}
+
+	var results []RepoResult
+	for _, s := range statuses {
+		if s.Error != "" {
+			m.logEvent(slog.LevelError, "error", s.Path, "%s", s.Error)
+			results = append(results, newResult(s, "failed", s.Error))
+		} else if s.DefaultDrift != "" {
+			results = append(results, m.SwitchDefaultRepo(s))
+		}
+	}
+
+	if m.JSON {
+		if results == nil {
+			results = []RepoResult{}
+		}
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	switched, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Switch-default dry run: %d to switch, %d skipped, %d failed", switched, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Switch-default complete: %d switched, %d skipped, %d failed", switched, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
+
+// SwitchDefaultRepo moves one drifted repo to its new default branch. A repo
+// checked out on the old default is switched only when it is clean and its
+// commits are all pushed; repos on other branches just get origin/HEAD
+// updated.
+func (m *Manager) SwitchDefaultRepo(s RepoStatus) RepoResult {
+	old, def := s.DefaultDrift, s.DefaultBranch
+	onOld := s.Branch == old
+	reason := fmt.Sprintf("default branch %s -> %s", old, def)
+
+	if onOld {
+		if s.Dirty {
+			m.logEvent(slog.LevelInfo, "skip", s.Path, "on %s, dirty; not switching to %s", old, def)
+			return newResult(s, "skipped", "dirty")
+		}
+		if err := checkSwitchToDefaultBranch(s.Path, old, def); err != nil {
+			var skipErr *updateSkipError
+			if errors.As(err, &skipErr) {
+				m.logEvent(slog.LevelInfo, "skip", s.Path, "%s", skipErr.reason)
+				return newResult(s, "skipped", skipErr.reason)
+			}
+			m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+			return newResult(s, "failed", err.Error())
+		}
+	}
+	if m.DryRun {
+		m.printPlan(s.Path, "would-switch", reason)
+		return newResult(s, "would-switch", reason)
+	}
+
+	if onOld {
+		if err := switchToDefaultBranch(s.Path, old, def); err != nil {
+			m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+			return newResult(s, "failed", err.Error())
+		}
+	}
+	if err := gitRun(s.Path, "remote", "set-head", "origin", def); err != nil {
+		msg := fmt.Sprintf("git remote set-head origin %s: %v", def, err)
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", msg)
+		return newResult(s, "failed", msg)
+	}
+	m.logEvent(slog.LevelInfo, "switch", s.Path, "%s", reason)
+	r := newResult(s, "switched", reason)
+	if onOld {
+		r.Branch = def
+		r.SwitchedFrom = old
+	}
+	return r
+}
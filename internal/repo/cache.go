@@ -0,0 +1,169 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
+)
+
+// CacheEntry is one repo of the reference cache `cache update` maintains.
+type CacheEntry struct {
+	Cache   string   `json:"cache"`  // bare repo in the reference_dir
+	Source  string   `json:"source"` // origin URL
+	Repos   []string `json:"repos"`  // local clones of it
+	Result  string   `json:"result"` // added | updated | failed | would-add | would-update
+	Message string   `json:"message,omitempty"`
+}
+
+type cacheJob struct {
+	entry CacheEntry
+	seed  string // local clone a new cache repo is made from
+	auth  gitAuth
+}
+
+// UpdateCache keeps the reference cache of the named targets current: every
+// local repo of a target whose clone options set reference_dir gets a bare
+// repo there (made from the local clone, so nothing is downloaded), and
+// existing cache repos fetch their origin. Cache repos never prune branches
+// or objects, since clones made with --reference keep borrowing from them.
+func (m *Manager) UpdateCache(targetNames []string, workers int) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	jobs, _, err := m.localRepos(targets)
+	if err != nil {
+		return err
+	}
+
+	byCache := make(map[string]*cacheJob)
+	var cacheJobs []*cacheJob
+	for _, job := range jobs {
+		t := m.config.GetTargetByName(job.target)
+		dir := m.cloneOptionsFor(*t).ReferenceDir
+		if dir == "" {
+			continue
+		}
+		origin, err := gitOutput(job.path, "config", "--get", "remote.origin.url")
+		if err != nil || strings.TrimSpace(origin) == "" {
+			m.logEvent(slog.LevelWarn, "skip", job.path, "no origin remote")
+			continue
+		}
+		origin = strings.TrimSpace(origin)
+		cache := referencePath(dir, origin)
+		if cj, ok := byCache[cache]; ok {
+			cj.entry.Repos = append(cj.entry.Repos, job.path)
+			continue
+		}
+		cj := &cacheJob{entry: CacheEntry{Cache: cache, Source: origin, Repos: []string{job.path}}, seed: job.path, auth: job.auth}
+		byCache[cache] = cj
+		cacheJobs = append(cacheJobs, cj)
+	}
+	if len(cacheJobs) == 0 && !m.JSON {
+		m.logf(slog.LevelInfo, "No local repos of targets with a clone reference_dir")
+		return nil
+	}
+
+	entries := pool.Run(cacheJobs, workers, m.updateCacheRepo)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Cache < entries[j].Cache })
+
+	var added, updated, failed int
+	for _, e := range entries {
+		switch e.Result {
+		case "added", "would-add":
+			added++
+		case "updated", "would-update":
+			updated++
+		case "failed":
+			failed++
+		}
+	}
+	if m.JSON {
+		if entries == nil {
+			entries = []CacheEntry{}
+		}
+		if err := m.writeJSON(entries); err != nil {
+			return err
+		}
+	} else if m.DryRun {
+		m.logf(slog.LevelInfo, "Cache dry run: %d to add, %d to update", added, updated)
+	} else {
+		m.logf(slog.LevelInfo, "Cache update complete: %d added, %d updated, %d failed", added, updated, failed)
+	}
+	if failed > 0 {
+		return &RepoFailures{Failed: failed, Total: len(entries)}
+	}
+	return nil
+}
+
+// updateCacheRepo adds one repo to the reference cache or fetches it.
+func (m *Manager) updateCacheRepo(cj *cacheJob) CacheEntry {
+	e := cj.entry
+	fail := func(err error) CacheEntry {
+		m.logEvent(slog.LevelError, "error", e.Cache, "%v", err)
+		e.Result, e.Message = "failed", err.Error()
+		return e
+	}
+
+	if !isBareRepo(e.Cache) {
+		if m.DryRun {
+			m.printPlan(e.Cache, "would-add", "from "+cj.seed)
+			e.Result = "would-add"
+			return e
+		}
+		if err := os.MkdirAll(filepath.Dir(e.Cache), 0755); err != nil {
+			return fail(err)
+		}
+		// A local clone hardlinks the objects of the seed instead of
+		// downloading them again.
+		for _, args := range [][]string{
+			{"clone", "--bare", "--quiet", cj.seed, e.Cache},
+			{"-C", e.Cache, "remote", "set-url", "origin", e.Source},
+			{"-C", e.Cache, "config", "remote.origin.fetch", "+refs/heads/*:refs/heads/*"},
+			{"-C", e.Cache, "config", "gc.pruneExpire", "never"},
+		} {
+			if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+				return fail(fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out))))
+			}
+		}
+		m.logEvent(slog.LevelInfo, "add", e.Cache, "%s", e.Source)
+		e.Result = "added"
+		return e
+	}
+
+	if m.DryRun {
+		m.printPlan(e.Cache, "would-update", "fetch "+e.Source)
+		e.Result = "would-update"
+		return e
+	}
+	cmd := exec.Command("git", "fetch", "--quiet", "--tags", "origin")
+	cmd.Dir = e.Cache
+	cmd.Env = cj.auth.env()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fail(fmt.Errorf("fetch: %v: %s", err, strings.TrimSpace(string(out))))
+	}
+	m.logEvent(slog.LevelInfo, "update", e.Cache, "")
+	e.Result = "updated"
+	return e
+}
+
+// referencePath returns where the reference cache in dir keeps the repo at
+// remoteURL: dir/host/owner/name.git, the same for its HTTPS and SSH URLs.
+func referencePath(dir, remoteURL string) string {
+	key := remoteURL
+	if u, err := url.Parse(remoteURL); err == nil && u.Scheme != "" {
+		key = u.Hostname() + "/" + u.Path
+	} else if at := strings.Index(remoteURL, "@"); at >= 0 {
+		key = strings.Replace(remoteURL[at+1:], ":", "/", 1)
+	}
+	key = strings.TrimSuffix(strings.Trim(path.Clean("/"+key), "/"), ".git")
+	return filepath.Join(dir, filepath.FromSlash(key)+".git")
+}
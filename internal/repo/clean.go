@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
+)
+
+// CleanEntry is one repo of `tugboat clean`.
+type CleanEntry struct {
+	Path   string   `json:"path"`
+	Target string   `json:"target"`
+	Name   string   `json:"name"`
+	Files  []string `json:"files"`  // untracked paths, relative to the repo; directories end in /
+	Result string   `json:"result"` // cleaned | would-clean | unchanged | failed
+	Error  string   `json:"error,omitempty"`
+}
+
+// Clean removes untracked files and directories from every local repo of the
+// named targets, within each target's clean policy. Ignored files are only
+// included with ignored. The paths are listed per repo first; confirm, when
+// non-nil, is then asked and cancels the clean by returning false. Exactly
+// the listed paths are removed, so files created in the meantime survive.
+// Nested repositories (e.g. foldouts) are never removed.
+func (m *Manager) Clean(targetNames []string, ignored bool, confirm func(files, repos int) bool, workers int) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	jobs, _, err := m.localRepos(targets)
+	if err != nil {
+		return err
+	}
+
+	entries := pool.Run(jobs, workers, func(job statusJob) CleanEntry {
+		e := CleanEntry{Path: job.path, Target: job.target, Name: job.name, Files: []string{}, Result: "unchanged"}
+		if isBareRepo(job.path) {
+			return e
+		}
+		args := []string{"-c", "core.quotePath=false", "clean", "-n", "-d"}
+		if ignored {
+			args = append(args, "-x")
+		}
+		var allow []string
+		if t := m.config.GetTargetByName(job.target); t != nil && t.Clean != nil {
+			for _, deny := range t.Clean.Deny {
+				args = append(args, "-e", deny)
+			}
+			allow = t.Clean.Allow
+		}
+		out, err := gitOutput(job.path, append(append(args, "--"), allow...)...)
+		if err != nil {
+			e.Result, e.Error = "failed", fmt.Sprintf("git clean -n: %v", err)
+			return e
+		}
+		for _, line := range strings.Split(out, "\n") {
+			if file, ok := strings.CutPrefix(line, "Would remove "); ok {
+				e.Files = append(e.Files, file)
+			}
+		}
+		if len(e.Files) > 0 {
+			e.Result = "would-clean"
+		}
+		return e
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	var files, repos int
+	for _, e := range entries {
+		switch {
+		case e.Error != "":
+			m.printf("  [ERROR]  %s: %s\n", e.Path, e.Error)
+		case len(e.Files) > 0:
+			files += len(e.Files)
+			repos++
+			if !m.JSON {
+				m.printf("%s\n", m.paint(colorMagenta, e.Path))
+				for _, f := range e.Files {
+					m.printf("  %s\n", f)
+				}
+			}
+		}
+	}
+
+	if files > 0 && !m.DryRun {
+		if confirm != nil && !confirm(files, repos) {
+			m.logf(slog.LevelInfo, "Clean cancelled")
+			return nil
+		}
+		entries = pool.Run(entries, workers, func(e CleanEntry) CleanEntry {
+			if e.Result != "would-clean" {
+				return e
+			}
+			args := []string{"clean", "-f", "-d"}
+			if ignored {
+				args = append(args, "-x")
+			}
+			args = append(args, "--")
+			for _, f := range e.Files {
+				args = append(args, ":(literal)"+f)
+			}
+			cmd := exec.Command("git", args...)
+			cmd.Dir = e.Path
+			cmd.Env = gitEnvNoPrompt()
+			if out, err := cmd.CombinedOutput(); err != nil {
+				e.Result, e.Error = "failed", fmt.Sprintf("%v: %s", err, strings.TrimSpace(string(out)))
+				m.logEvent(slog.LevelError, "error", e.Path, "%s", e.Error)
+				return e
+			}
+			m.logEvent(slog.LevelInfo, "clean", e.Path, "%d paths removed", len(e.Files))
+			e.Result = "cleaned"
+			return e
+		})
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	}
+
+	var cleaned, failed int
+	for _, e := range entries {
+		switch e.Result {
+		case "cleaned":
+			cleaned++
+		case "failed":
+			failed++
+		}
+	}
+	if m.JSON {
+		if entries == nil {
+			entries = []CleanEntry{}
+		}
+		if err := m.writeJSON(entries); err != nil {
+			return err
+		}
+	} else if m.DryRun || files == 0 {
+		m.logf(slog.LevelInfo, "%d untracked paths in %d repos", files, repos)
+	} else {
+		m.logf(slog.LevelInfo, "Clean complete: %d repos cleaned, %d failed", cleaned, failed)
+	}
+	if failed > 0 {
+		return &RepoFailures{Failed: failed, Total: len(entries)}
+	}
+	return nil
+}
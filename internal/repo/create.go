@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+// Create creates the repo owner/name on a provider and clones it. The clone
+// goes into the org or user target of that owner, or, when foldout names a
+// repo target, into that repo and is recorded in its .tugboat.json.
+func (m *Manager) Create(providerName, owner, name string, opts remote.CreateOptions, foldout string) error {
+	client, ok := m.providers[providerName]
+	if !ok {
+		return fmt.Errorf("unknown provider %q", providerName)
+	}
+	creator, ok := client.(remote.Creator)
+	if !ok {
+		return fmt.Errorf("provider %q cannot create repositories", providerName)
+	}
+
+	t, dest, err := m.createDestination(providerName, owner, name, foldout)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("%s already exists", dest)
+	}
+	existing, err := client.GetRepo(owner, name)
+	if err != nil {
+		return fmt.Errorf("checking for %s/%s: %w", owner, name, err)
+	}
+	if existing != nil {
+		return fmt.Errorf("%s/%s already exists on %s", owner, name, providerName)
+	}
+
+	if m.DryRun {
+		reason := fmt.Sprintf("%s/%s on %s", owner, name, providerName)
+		if foldout != "" {
+			reason += ", foldout of " + t.Name
+		}
+		m.printPlan(dest, "would-create", reason)
+		return nil
+	}
+
+	r, err := creator.CreateRepo(owner, name, opts)
+	if err != nil {
+		return err
+	}
+	m.logEvent(slog.LevelInfo, "create", r.FullName, "%s", r.HTMLURL)
+
+	cloneOpts := m.cloneOptionsFor(t)
+	if err := m.git.Clone(pickCloneURL(r, cloneOpts.Protocol), dest, authFor(m.providerFor(t.Name)), cloneOpts); err != nil {
+		return fmt.Errorf("created %s but cloning failed: %w", r.FullName, err)
+	}
+	// The repo is empty, so point HEAD at the requested branch; the first
+	// push then creates it (and makes it the default on GitHub).
+	if opts.DefaultBranch != "" {
+		if err := gitRun(dest, "symbolic-ref", "HEAD", "refs/heads/"+opts.DefaultBranch); err != nil {
+			return fmt.Errorf("setting branch %s: %w", opts.DefaultBranch, err)
+		}
+	}
+	m.logEvent(slog.LevelInfo, "cloned", dest, "")
+
+	if foldout != "" {
+		entry := foldoutRepo{Name: owner + "/" + name, Target: name}
+		if err := appendFoldout(t.Path, entry); err != nil {
+			return err
+		}
+		m.logEvent(slog.LevelInfo, "foldout", dest, "added %s to %s", entry.Name, filepath.Join(t.Path, ".tugboat.json"))
+	}
+	return nil
+}
+
+// createDestination returns the target a new repo belongs to and where it is
+// cloned.
+func (m *Manager) createDestination(providerName, owner, name, foldout string) (config.Target, string, error) {
+	if foldout != "" {
+		t := m.config.GetTargetByName(foldout)
+		switch {
+		case t == nil:
+			return config.Target{}, "", fmt.Errorf("unknown target %q", foldout)
+		case t.Repo == "":
+			return config.Target{}, "", fmt.Errorf("target %q is not a repo target; foldouts only apply to repo targets", foldout)
+		case t.Provider != providerName:
+			return config.Target{}, "", fmt.Errorf("target %q uses provider %q; foldouts must use the same provider", foldout, t.Provider)
+		case strings.Contains(owner, "/"):
+			return config.Target{}, "", fmt.Errorf("foldouts cannot reference nested group %s", owner)
+		case !isGitRepo(t.Path):
+			return config.Target{}, "", fmt.Errorf("target %q is not cloned yet", foldout)
+		}
+		return *t, filepath.Join(t.Path, name), nil
+	}
+	for _, t := range m.config.Targets {
+		if t.Repo == "" && t.Provider == providerName && strings.EqualFold(t.Owner(), owner) {
+			return t, filepath.Join(t.Path, name), nil
+		}
+	}
+	return config.Target{}, "", fmt.Errorf("no org or user target for %s on %s (add one with `tugboat target add`, or pass --foldout)", owner, providerName)
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"strings"
+)
+
+// FileChange is one changed path of a dirty repo, as `status --detail`
+// reports it. A path staged and then edited again appears twice.
+type FileChange struct {
+	Path  string `json:"path"`
+	State string `json:"state"` // staged | modified | untracked
+}
+
+// maxDetailFiles caps the changed paths printed per repo by status --detail;
+// JSON output always carries all of them.
+const maxDetailFiles = 10
+
+// dirtyFiles lists the staged, modified and untracked paths of the working
+// tree at repoPath.
+func dirtyFiles(repoPath string) ([]FileChange, error) {
+	out, err := gitOutput(repoPath, "-c", "core.quotePath=false", "status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	var changes []FileChange
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		e := entries[i]
+		if len(e) < 4 {
+			continue
+		}
+		x, y, path := e[0], e[1], e[3:]
+		if x == 'R' || x == 'C' {
+			i++ // the source path of a rename or copy follows
+		}
+		switch {
+		case x == '?':
+			changes = append(changes, FileChange{Path: path, State: "untracked"})
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			// Unmerged paths are still work in progress.
+			changes = append(changes, FileChange{Path: path, State: "modified"})
+		default:
+			if x != ' ' {
+				changes = append(changes, FileChange{Path: path, State: "staged"})
+			}
+			if y != ' ' {
+				changes = append(changes, FileChange{Path: path, State: "modified"})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// countChanges returns how many of changes are staged, modified and
+// untracked.
+func countChanges(changes []FileChange) (staged, modified, untracked int) {
+	for _, c := range changes {
+		switch c.State {
+		case "staged":
+			staged++
+		case "modified":
+			modified++
+		case "untracked":
+			untracked++
+		}
+	}
+	return staged, modified, untracked
+}
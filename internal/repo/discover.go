@@ -0,0 +1,258 @@
+package repo
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+)
+
+// Discovery is the config proposed for the git checkouts found under a
+// directory: providers for hosts the existing config does not know yet, and
+// targets for the checkouts it does not manage yet.
+type Discovery struct {
+	Providers map[string]config.Provider `json:"providers"`
+	Targets   []config.Target            `json:"targets"`
+	// Skipped lists checkouts that were left out, with the reason.
+	Skipped []string `json:"-"`
+}
+
+// checkout is a git repo found on disk with its origin URL split up.
+type checkout struct {
+	path  string
+	host  string
+	owner string
+	name  string
+}
+
+// Discover walks root for git checkouts, without descending into them, and
+// proposes config for them. Checkouts are grouped by host and owner: two or
+// more side by side in a directory named after the owner become one org
+// target whose include patterns list exactly those repos; the others become
+// repo targets. existing may be nil.
+func Discover(root string, existing *config.Config) (*Discovery, error) {
+	if existing == nil {
+		existing = &config.Config{}
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Discovery{Providers: make(map[string]config.Provider)}
+	var found []checkout
+	err = filepath.WalkDir(root, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil // unreadable subtrees are not fatal
+		}
+		if !e.IsDir() || e.Name() == ".git" || !isGitRepo(p) {
+			return nil
+		}
+		if name := managingTarget(existing, p); name != "" {
+			d.Skipped = append(d.Skipped, fmt.Sprintf("%s: already managed by target %s", p, name))
+			return filepath.SkipDir
+		}
+		origin, _ := gitOutput(p, "remote", "get-url", "origin")
+		c, ok := parseOrigin(origin)
+		if !ok {
+			d.Skipped = append(d.Skipped, fmt.Sprintf("%s: no origin remote on a known host", p))
+			return filepath.SkipDir
+		}
+		c.path = p
+		found = append(found, c)
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// One provider per host, reusing configured ones.
+	providerFor := make(map[string]string)
+	for _, c := range found {
+		if _, ok := providerFor[c.host]; ok {
+			continue
+		}
+		name := existingProviderFor(existing, c.host)
+		if name == "" {
+			p, base := newProvider(c.host)
+			name = uniqueName(base, func(n string) bool {
+				_, inConfig := existing.Providers[n]
+				_, proposed := d.Providers[n]
+				return inConfig || proposed
+			})
+			d.Providers[name] = p
+		}
+		providerFor[c.host] = name
+	}
+
+	type groupKey struct{ provider, owner, dir string }
+	groups := make(map[groupKey][]checkout)
+	for _, c := range found {
+		k := groupKey{providerFor[c.host], c.owner, filepath.Dir(c.path)}
+		groups[k] = append(groups[k], c)
+	}
+	keys := make([]groupKey, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].dir < keys[j].dir || keys[i].dir == keys[j].dir && keys[i].owner < keys[j].owner
+	})
+
+	taken := func(n string) bool {
+		if existing.GetTargetByName(n) != nil {
+			return true
+		}
+		for _, t := range d.Targets {
+			if t.Name == n {
+				return true
+			}
+		}
+		return false
+	}
+	for _, k := range keys {
+		members := groups[k]
+		if isOrgLayout(k.owner, k.dir, members) {
+			t := config.Target{Provider: k.provider, Org: k.owner, Path: k.dir}
+			for _, c := range members {
+				t.Include = append(t.Include, c.name)
+			}
+			sort.Strings(t.Include)
+			t.Name = uniqueName(strings.ReplaceAll(k.owner, "/", "-"), taken)
+			d.Targets = append(d.Targets, t)
+			continue
+		}
+		for _, c := range members {
+			t := config.Target{Provider: k.provider, Org: c.owner, Repo: c.name, Path: c.path}
+			t.Name = c.name
+			if taken(t.Name) {
+				t.Name = uniqueName(strings.ReplaceAll(c.owner, "/", "-")+"-"+c.name, taken)
+			}
+			d.Targets = append(d.Targets, t)
+		}
+	}
+	sort.SliceStable(d.Targets, func(i, j int) bool { return d.Targets[i].Path < d.Targets[j].Path })
+	return d, nil
+}
+
+// isOrgLayout reports whether checkouts sit side by side in a directory named
+// after their owner, each in a directory named after its repo, as an org
+// target would lay them out.
+func isOrgLayout(owner, dir string, members []checkout) bool {
+	if len(members) < 2 || !strings.EqualFold(filepath.Base(dir), path.Base(owner)) {
+		return false
+	}
+	for _, c := range members {
+		if filepath.Base(c.path) != c.name {
+			return false
+		}
+		// Include patterns are globs; names that would not match themselves
+		// need repo targets.
+		if ok, _ := path.Match(c.name, c.name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// managingTarget returns the name of the configured target that already
+// manages the checkout at p, if any.
+func managingTarget(cfg *config.Config, p string) string {
+	for _, t := range cfg.Targets {
+		if t.Path == "" {
+			continue
+		}
+		if t.Path == p || (t.Repo == "" && filepath.Dir(p) == t.Path) ||
+			(t.Repo != "" && strings.HasPrefix(p, t.Path+string(filepath.Separator))) {
+			return t.Name
+		}
+	}
+	return ""
+}
+
+// parseOrigin splits a remote URL into host, owner (possibly a nested group
+// path) and repo name. Local paths have no host and are rejected.
+func parseOrigin(origin string) (checkout, bool) {
+	origin = strings.TrimSpace(origin)
+	if origin == "" || strings.HasPrefix(origin, "file://") {
+		return checkout{}, false
+	}
+	parts := strings.Split(normalizeGitURL(origin), "/")
+	if len(parts) < 3 || parts[0] == "" || parts[0] == "." {
+		return checkout{}, false
+	}
+	return checkout{
+		host:  parts[0],
+		owner: strings.Join(parts[1:len(parts)-1], "/"),
+		name:  parts[len(parts)-1],
+	}, true
+}
+
+// providerHost returns the git host a provider serves.
+func providerHost(p config.Provider) string {
+	switch {
+	case p.Type == "github" && (p.APIURL == "" || strings.Contains(p.APIURL, "://api.github.com")):
+		return "github.com"
+	case p.Type == "gitlab" && p.APIURL == "":
+		return "gitlab.com"
+	}
+	u, err := url.Parse(p.APIURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// existingProviderFor returns the configured provider for host, preferring
+// the alphabetically first when several match.
+func existingProviderFor(cfg *config.Config, host string) string {
+	var names []string
+	for name, p := range cfg.Providers {
+		if providerHost(p) == host {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// newProvider guesses the provider type for host from its name; anything not
+// recognisably GitHub or GitLab is assumed to be Gitea. The token is left
+// empty for 'tugboat auth login' or manual editing.
+func newProvider(host string) (config.Provider, string) {
+	switch {
+	case host == "github.com":
+		return config.Provider{Type: "github", APIURL: "https://api.github.com"}, "github"
+	case host == "gitlab.com":
+		return config.Provider{Type: "gitlab", APIURL: "https://gitlab.com"}, "gitlab"
+	}
+	base, _, _ := strings.Cut(host, ".")
+	switch {
+	case strings.Contains(host, "github"):
+		return config.Provider{Type: "github", APIURL: "https://" + host + "/api/v3"}, base
+	case strings.Contains(host, "gitlab"):
+		return config.Provider{Type: "gitlab", APIURL: "https://" + host}, base
+	default:
+		return config.Provider{Type: "gitea", APIURL: "https://" + host}, base
+	}
+}
+
+// uniqueName returns base, or base-2, base-3, ... when base is taken.
+func uniqueName(base string, taken func(string) bool) string {
+	name := base
+	for i := 2; taken(name); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
@@ -0,0 +1,135 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+)
+
+// FoldoutInitOptions controls `tugboat foldout init`.
+type FoldoutInitOptions struct {
+	From   string   // PROVIDER/OWNER whose repos are listed instead of scanning the directory
+	Topics []string // with From: only repos carrying one of these topics
+	Force  bool     // replace an existing .tugboat.json
+}
+
+// InitFoldout writes a .tugboat.json in dir listing foldout repos. By
+// default these are the clones found below dir (down to DefaultMaxDepth
+// levels), each looked up by its origin URL like adopt does, so a directory
+// of hand-made clones can be turned into a repo target. With opts.From they
+// are the unarchived repos of that remote owner, cloned into dir/<name>.
+func (m *Manager) InitFoldout(dir string, opts FoldoutInitOptions) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, ".tugboat.json")
+	if _, err := os.Stat(path); err == nil && !opts.Force {
+		return fmt.Errorf("%s already exists (use --force to replace it)", path)
+	}
+
+	var repos []foldoutRepo
+	if opts.From != "" {
+		repos, err = m.remoteFoldouts(opts.From, opts.Topics)
+	} else {
+		repos, err = m.localFoldouts(dir)
+	}
+	if err != nil {
+		return err
+	}
+	if len(repos) == 0 {
+		return errors.New("no repos to record as foldouts")
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Target < repos[j].Target })
+	if err := cleanFoldoutTargets(dir, repos); err != nil {
+		return err
+	}
+
+	if m.DryRun {
+		for _, r := range repos {
+			m.printPlan(filepath.Join(dir, filepath.FromSlash(r.Target)), "would-add", "foldout "+r.Name)
+		}
+		m.logf(slog.LevelInfo, "Foldout init dry run: %d foldouts for %s", len(repos), path)
+		return nil
+	}
+	data, err := json.MarshalIndent(foldoutConfig{Repos: repos}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	m.logf(slog.LevelInfo, "Wrote %d foldouts to %s", len(repos), path)
+	return nil
+}
+
+// localFoldouts finds the clones below dir and the remote repo of each. When
+// dir is a repo target, clones of other providers are named with theirs.
+func (m *Manager) localFoldouts(dir string) ([]foldoutRepo, error) {
+	var provider string
+	for _, t := range m.config.Targets {
+		if t.Repo != "" && t.Path == dir {
+			provider = t.Provider
+		}
+	}
+	paths, err := findRepoDirs(dir, config.DefaultMaxDepth, func(string) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	var repos []foldoutRepo
+	for _, p := range paths {
+		origin, _ := gitOutput(p, "remote", "get-url", "origin")
+		providerName, owner, r, err := m.findRemoteRepo(p, strings.TrimSpace(origin))
+		switch {
+		case err != nil:
+			m.logEvent(slog.LevelWarn, "skip", p, "%v", err)
+			continue
+		case strings.Contains(owner, "/"):
+			m.logEvent(slog.LevelWarn, "skip", p, "foldouts cannot reference nested group %s", owner)
+			continue
+		}
+		rel, _ := filepath.Rel(dir, p)
+		name := owner + "/" + r.Name
+		if provider != "" && providerName != provider {
+			name = providerName + ":" + name
+		}
+		repos = append(repos, foldoutRepo{Name: name, Target: filepath.ToSlash(rel)})
+	}
+	return repos, nil
+}
+
+// remoteFoldouts lists the unarchived repos of from (PROVIDER/OWNER) that
+// carry one of topics, each to be cloned into a directory of its name.
+func (m *Manager) remoteFoldouts(from string, topics []string) ([]foldoutRepo, error) {
+	providerName, owner, ok := strings.Cut(from, "/")
+	if !ok || providerName == "" || owner == "" {
+		return nil, fmt.Errorf("expected PROVIDER/OWNER, got %q", from)
+	}
+	client, ok := m.providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", providerName)
+	}
+	if strings.Contains(owner, "/") {
+		return nil, fmt.Errorf("foldouts cannot reference nested group %s", owner)
+	}
+	list, err := listOwnerRepos(client, owner, m.ownerIsUser(providerName, owner))
+	if err != nil {
+		return nil, fmt.Errorf("listing repos for %s: %w", owner, err)
+	}
+	filter := config.Target{Topics: topics}
+	var repos []foldoutRepo
+	for _, r := range list {
+		if r.Archived || !filter.MatchesTopics(r.Topics) {
+			continue
+		}
+		repos = append(repos, foldoutRepo{Name: owner + "/" + r.Name, Target: r.Name})
+	}
+	return repos, nil
+}
@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+// ForkSync fast-forwards the default branch of every fork clone (a repo with
+// an upstream remote) to its parent's default branch and pushes it to origin.
+// With useAPI the provider's sync-fork endpoint updates origin instead, where
+// the provider has one. Local checkouts of the default branch are then
+// fast-forwarded when clean.
+func (m *Manager) ForkSync(targetNames []string, useAPI bool, workers int) error {
+	statuses, err := m.Statuses(targetNames, workers)
+	if err != nil {
+		return err
+	}
+
+	results := []RepoResult{}
+	for _, s := range statuses {
+		if s.Mirror || (s.Error == "" && !hasRemote(s.Path, "upstream")) {
+			continue
+		}
+		r := m.ForkSyncRepo(s, useAPI)
+		m.emit(r)
+		results = append(results, r)
+	}
+
+	if m.JSON {
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	if len(results) == 0 {
+		m.logf(slog.LevelInfo, "Fork sync: no repos with an upstream remote found.")
+		return nil
+	}
+	synced, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Fork sync dry run: %d to sync, %d skipped, %d failed", synced, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Fork sync complete: %d synced, %d skipped, %d failed", synced, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
+
+// ForkSyncRepo brings origin's default branch of one fork level with its
+// parent. Only fast-forwards are made: forks whose default branch has
+// commits the parent lacks are skipped.
+func (m *Manager) ForkSyncRepo(s RepoStatus, useAPI bool) RepoResult {
+	if s.Error != "" {
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", s.Error)
+		return newResult(s, "failed", s.Error)
+	}
+	if s.RemoteError != "" {
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", s.RemoteError)
+		return newResult(s, "failed", s.RemoteError)
+	}
+	def := s.DefaultBranch
+	if def == "" {
+		head, err := gitOutput(s.Path, "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+		if err != nil {
+			m.logEvent(slog.LevelError, "error", s.Path, "cannot resolve origin default branch")
+			return newResult(s, "failed", "cannot resolve origin default branch")
+		}
+		def = strings.TrimPrefix(strings.TrimSpace(head), "origin/")
+	}
+	if s.UpstreamBehind == 0 {
+		m.logEvent(slog.LevelDebug, "ok", s.Path, "%s up to date with upstream", def)
+		return newResult(s, "unchanged", "")
+	}
+	upstreamRev, err := gitOutput(s.Path, "rev-parse", "refs/remotes/upstream/HEAD")
+	if err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "cannot resolve upstream/HEAD")
+		return newResult(s, "failed", "cannot resolve upstream/HEAD")
+	}
+	upstreamRev = strings.TrimSpace(upstreamRev)
+	if !m.git.IsAncestor(s.Path, "refs/remotes/origin/"+def, upstreamRev) {
+		reason := fmt.Sprintf("origin/%s has commits upstream lacks; merge by hand", def)
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "%s", reason)
+		return newResult(s, "skipped", reason)
+	}
+
+	reason := fmt.Sprintf("%s: %d commits from upstream", def, s.UpstreamBehind)
+	var syncer remote.ForkSyncer
+	if useAPI {
+		syncer, _ = m.providers[s.Provider].(remote.ForkSyncer)
+	}
+	if syncer != nil {
+		reason += " (provider API)"
+	}
+	if m.DryRun {
+		m.printPlan(s.Path, "would-sync", reason)
+		return newResult(s, "would-sync", reason)
+	}
+
+	auth := authFor(m.providerFor(s.Target))
+	if syncer != nil {
+		err = syncer.SyncFork(s.Org, s.Name, def)
+	} else {
+		err = m.gitPush(s.Path, auth, "origin", upstreamRev+":refs/heads/"+def)
+	}
+	if err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+
+	// origin moved; bring a clean checkout of the default branch along.
+	if err := fetchRemote(s.Path, "origin", auth); err != nil {
+		msg := fmt.Sprintf("origin updated, fetching it failed: %v", err)
+		m.logEvent(slog.LevelWarn, "sync", s.Path, "%s", msg)
+		return newResult(s, "synced", msg)
+	}
+	if s.Branch == def && !s.Dirty {
+		if err := gitRun(s.Path, "merge", "--ff-only", "--quiet", "refs/remotes/origin/"+def); err != nil {
+			msg := fmt.Sprintf("origin updated, local %s not fast-forwarded", def)
+			m.logEvent(slog.LevelWarn, "sync", s.Path, "%s", msg)
+			return newResult(s, "synced", msg)
+		}
+	}
+	m.logEvent(slog.LevelInfo, "sync", s.Path, "%s", reason)
+	return newResult(s, "synced", "")
+}
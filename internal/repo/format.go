@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// formatFuncs are available to --format templates besides the text/template
+// builtins.
+var formatFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": strings.Join,
+}
+
+// ParseFormat parses a --format template, rendered once per status or list
+// entry. As with docker's --format, a literal \t or \n in text becomes a tab
+// or newline, so shells need no special quoting.
+func ParseFormat(text string) (*template.Template, error) {
+	text = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(text)
+	return template.New("format").Funcs(formatFuncs).Parse(text)
+}
+
+// writeFormatted renders tmpl for every item on w, one line each.
+func writeFormatted[T any](w io.Writer, tmpl *template.Template, items []T) error {
+	for _, item := range items {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, item); err != nil {
+			return err
+		}
+		b.WriteString("\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRows writes items in the --output format: one JSON object per line
+// for "ndjson", else CSV ("csv") or tab-separated values ("tsv") with a
+// header row of the JSON field names and one row per item. In CSV and TSV,
+// lists of strings are joined with "; " and other nested values are written
+// as JSON.
+func writeRows[T any](out io.Writer, items []T, output string) error {
+	if output == "ndjson" {
+		enc := json.NewEncoder(out)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	w := csv.NewWriter(out)
+	if output == "tsv" {
+		w.Comma = '\t'
+	}
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	var header []string
+	var fields []int
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, item := range items {
+		v := reflect.ValueOf(item)
+		row := make([]string, len(fields))
+		for j, i := range fields {
+			cell, err := formatCell(v.Field(i))
+			if err != nil {
+				return err
+			}
+			row[j] = cell
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// formatCell renders one CSV or TSV field.
+func formatCell(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return "", nil
+		}
+		return formatCell(v.Elem())
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+		return fmt.Sprint(v.Interface()), nil
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return "", nil
+		}
+		if strs, ok := v.Interface().([]string); ok {
+			return strings.Join(strs, "; "), nil
+		}
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339), nil
+	}
+	b, err := json.Marshal(v.Interface())
+	return string(b), err
+}
@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GC runs repository maintenance in every local repo of the named targets:
+// `git maintenance run` with tasks (e.g. commit-graph, prefetch,
+// incremental-repack), or `git gc --auto` when tasks is empty, which only
+// does work once a repo has piled up enough loose objects or packs. It does
+// not fetch, though the prefetch task does.
+func (m *Manager) GC(targetNames []string, tasks []string, workers int) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	jobs, _, err := m.localRepos(targets)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"gc", "--auto", "--quiet"}
+	if len(tasks) > 0 {
+		args = []string{"maintenance", "run", "--quiet"}
+		for _, task := range tasks {
+			args = append(args, "--task="+task)
+		}
+	}
+	describe := "git " + strings.Join(args, " ")
+
+	results := emitJobs(m, jobs, workers, func(m *Manager, job statusJob) RepoResult {
+		r := RepoResult{Path: job.path, Target: job.target, Name: job.name}
+		if m.DryRun {
+			m.printPlan(job.path, "would-gc", describe)
+			r.Result, r.Message = "would-gc", describe
+			return r
+		}
+		start := time.Now()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = job.path
+		cmd.Env = job.auth.env()
+		if out, err := cmd.CombinedOutput(); err != nil {
+			msg := fmt.Sprintf("%v: %s", err, strings.TrimSpace(string(out)))
+			m.logEvent(slog.LevelError, "error", job.path, "%s", msg)
+			r.Result, r.Message = "failed", msg
+			return r
+		}
+		took := time.Since(start).Round(time.Millisecond)
+		m.logEvent(slog.LevelDebug, "gc", job.path, "%s", took)
+		r.Result, r.Message = "maintained", took.String()
+		return r
+	})
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+
+	if m.JSON {
+		if results == nil {
+			results = []RepoResult{}
+		}
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	done, _, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "GC dry run: %d repos to maintain with %s", done, describe)
+	} else {
+		m.logf(slog.LevelInfo, "GC complete: %d maintained, %d failed", done, failed)
+	}
+	return resultsOutcome(results)
+}
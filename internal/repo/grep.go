@@ -0,0 +1,150 @@
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
+)
+
+// GrepMatch is one matching line of `tugboat grep`.
+type GrepMatch struct {
+	Repo   string `json:"repo"` // path of the repo
+	Target string `json:"target"`
+	Name   string `json:"name"`
+	File   string `json:"file"` // relative to the repo
+	Line   int    `json:"line"`
+	Text   string `json:"text"`
+}
+
+// GrepOptions are passed through to git grep.
+type GrepOptions struct {
+	IgnoreCase bool // -i
+	Word       bool // -w
+	Fixed      bool // -F: pattern is a literal string
+	Extended   bool // -E: pattern is an extended regexp
+}
+
+// ErrNoMatches is returned by Grep when no repo matched, mirroring grep's
+// exit status.
+var ErrNoMatches = errors.New("no matches")
+
+type grepResult struct {
+	job     statusJob
+	matches []GrepMatch
+	err     error
+}
+
+// Grep runs git grep for pattern in the working tree of every local repo of
+// the named targets and prints the matches grouped by repo. It does not
+// fetch.
+func (m *Manager) Grep(pattern string, opts GrepOptions, targetNames []string, workers int) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	jobs, _, err := m.localRepos(targets)
+	if err != nil {
+		return err
+	}
+
+	results := pool.Run(jobs, workers, func(job statusJob) grepResult {
+		matches, err := gitGrep(job, pattern, opts)
+		return grepResult{job: job, matches: matches, err: err}
+	})
+	sort.Slice(results, func(i, j int) bool { return results[i].job.path < results[j].job.path })
+
+	all := []GrepMatch{}
+	var failed, matchedRepos int
+	for _, r := range results {
+		if r.err != nil {
+			m.logEvent(slog.LevelError, "error", r.job.path, "%v", r.err)
+			failed++
+			continue
+		}
+		if len(r.matches) == 0 {
+			continue
+		}
+		matchedRepos++
+		all = append(all, r.matches...)
+		m.printf("%s\n", m.paint(colorMagenta, r.job.path))
+		for _, match := range r.matches {
+			m.printf("  %s:%d: %s\n", m.paint(colorCyan, match.File), match.Line, match.Text)
+		}
+	}
+	if m.JSON {
+		if err := m.writeJSON(all); err != nil {
+			return err
+		}
+	} else {
+		m.printf("\n%d matches in %d repos (%d searched)\n", len(all), matchedRepos, len(results))
+	}
+
+	if failed > 0 {
+		return &RepoFailures{Failed: failed, Total: len(results)}
+	}
+	if len(all) == 0 {
+		return ErrNoMatches
+	}
+	return nil
+}
+
+// gitGrep searches the tracked files of one repo.
+func gitGrep(job statusJob, pattern string, opts GrepOptions) ([]GrepMatch, error) {
+	if isBareRepo(job.path) {
+		return nil, nil
+	}
+	args := []string{"grep", "-n", "-I", "-z", "--no-color"}
+	if opts.IgnoreCase {
+		args = append(args, "-i")
+	}
+	if opts.Word {
+		args = append(args, "-w")
+	}
+	if opts.Fixed {
+		args = append(args, "-F")
+	}
+	if opts.Extended {
+		args = append(args, "-E")
+	}
+	args = append(args, "-e", pattern)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = job.path
+	cmd.Env = gitEnvNoPrompt()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return nil, nil // no match
+		}
+		return nil, fmt.Errorf("git grep: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var matches []GrepMatch
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		// -z separates file, line number and text with NUL bytes.
+		parts := strings.SplitN(line, "\x00", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		n, _ := strconv.Atoi(parts[1])
+		matches = append(matches, GrepMatch{
+			Repo:   job.path,
+			Target: job.target,
+			Name:   job.name,
+			File:   parts[0],
+			Line:   n,
+			Text:   parts[2],
+		})
+	}
+	return matches, nil
+}
@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+)
+
+// hookCommand returns the command that runs hook through the shell.
+func hookCommand(hook string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", hook)
+	}
+	return exec.Command("sh", "-c", hook)
+}
+
+// hooks returns the configured hooks of event that run per repo, or with
+// once those that run once per run.
+func (m *Manager) hooks(event string, once bool) []config.Hook {
+	if m.DryRun {
+		return nil
+	}
+	var hooks []config.Hook
+	for _, h := range m.config.Hooks[event] {
+		if h.Once == once {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+// runOnceHooks runs the once-per-run hooks of event, e.g. pre_pull, with the
+// command's target names in TUGBOAT_TARGETS and, for post hooks, its error
+// in TUGBOAT_ERROR. Their output goes to stderr so JSON output stays clean.
+func (m *Manager) runOnceHooks(event string, targetNames []string, cmdErr error) error {
+	for _, h := range m.hooks(event, true) {
+		cmd := hookCommand(h.Run)
+		cmd.Env = append(os.Environ(),
+			"TUGBOAT_EVENT="+event,
+			"TUGBOAT_COMMAND="+strings.SplitN(event, "_", 2)[1],
+			"TUGBOAT_TARGETS="+strings.Join(targetNames, " "),
+		)
+		if cmdErr != nil {
+			cmd.Env = append(cmd.Env, "TUGBOAT_ERROR="+cmdErr.Error())
+		}
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = m.errOut()
+		cmd.Stderr = m.errOut()
+		m.logEvent(slog.LevelDebug, event, "", "%s", h.Run)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q: %w", event, h.Run, err)
+		}
+	}
+	return nil
+}
+
+// hookRepo is what a per-repo hook is told about its repo.
+type hookRepo struct {
+	path, name, target, org, provider, branch string
+}
+
+func hookRepoOf(s RepoStatus) hookRepo {
+	return hookRepo{path: s.Path, name: s.Name, target: s.Target, org: s.Org, provider: s.Provider, branch: s.Branch}
+}
+
+// allowsRepoHooks reports whether the hooks of r's own .tugboat.json may run.
+func (m *Manager) allowsRepoHooks(r hookRepo) bool {
+	if m.AllowRepoHooks {
+		return true
+	}
+	for _, pattern := range m.config.AllowRepoHooks {
+		if ok, _ := path.Match(pattern, r.org+"/"+r.name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// repoHooks returns the hooks of event that r's own .tugboat.json declares,
+// as it is checked out now. Hooks of repos that are not allowed to run them
+// are left out with a warning, once per repo and run.
+func (m *Manager) repoHooks(event string, r hookRepo) []config.Hook {
+	if m.DryRun {
+		return nil
+	}
+	fc, err := loadFoldout(r.path)
+	if err != nil {
+		m.logEvent(slog.LevelWarn, event, r.path, "repo hooks not run: %v", err)
+		return nil
+	}
+	if fc == nil || len(fc.Hooks[event]) == 0 {
+		return nil
+	}
+	if !m.allowsRepoHooks(r) {
+		if _, warned := m.hookWarned.LoadOrStore(r.path, true); !warned {
+			m.logEvent(slog.LevelWarn, event, r.path, "has .tugboat.json hooks that were not run; allow them with --allow-hooks or allow_repo_hooks")
+		}
+		return nil
+	}
+	return fc.Hooks[event]
+}
+
+// runRepoHooks runs the per-repo hooks of event in the repo, those of the
+// config first and then the repo's own, stopping at the first that fails.
+// Output is logged at debug level, and the last line of a failed hook's
+// output is part of its error.
+func (m *Manager) runRepoHooks(event string, r hookRepo, result string) error {
+	for _, h := range append(m.hooks(event, false), m.repoHooks(event, r)...) {
+		cmd := hookCommand(h.Run)
+		cmd.Dir = r.path
+		cmd.Env = append(os.Environ(),
+			"TUGBOAT_EVENT="+event,
+			"TUGBOAT_COMMAND="+strings.SplitN(event, "_", 2)[1],
+			"TUGBOAT_REPO_PATH="+r.path,
+			"TUGBOAT_REPO_NAME="+r.name,
+			"TUGBOAT_TARGET="+r.target,
+			"TUGBOAT_ORG="+r.org,
+			"TUGBOAT_PROVIDER="+r.provider,
+			"TUGBOAT_BRANCH="+r.branch,
+			"TUGBOAT_RESULT="+result,
+		)
+		out, err := cmd.CombinedOutput()
+		out = bytes.TrimSpace(out)
+		if len(out) > 0 {
+			m.logEvent(slog.LevelDebug, event, r.path, "%s", out)
+		}
+		if err != nil {
+			if i := bytes.LastIndexByte(out, '\n'); i >= 0 {
+				out = out[i+1:]
+			}
+			if len(out) > 0 {
+				return fmt.Errorf("%s hook %q: %w: %s", event, h.Run, err, out)
+			}
+			return fmt.Errorf("%s hook %q: %w", event, h.Run, err)
+		}
+	}
+	return nil
+}
+
+// withHooks runs op on repo s of command between its pre and post hooks. A
+// failing pre hook skips the repo; a failing post hook fails it. Post hooks
+// only run for repos op did not skip or fail. Repos reached after the run
+// deadline are skipped.
+func (m *Manager) withHooks(command string, s RepoStatus, op func(RepoStatus) RepoResult) RepoResult {
+	if m.pastDeadline() {
+		m.logEvent(slog.LevelWarn, "skip", s.Path, "%v", ErrDeadline)
+		return newResult(s, "skipped", ErrDeadline.Error())
+	}
+	if s.Error == "" {
+		if err := m.runRepoHooks("pre_"+command, hookRepoOf(s), ""); err != nil {
+			return newResult(s, "skipped", err.Error())
+		}
+	}
+	r := op(s)
+	if r.Result == "failed" || r.Result == "skipped" {
+		return r
+	}
+	if err := m.runRepoHooks("post_"+command, hookRepoOf(s), r.Result); err != nil {
+		r.Result, r.Message = "failed", err.Error()
+	}
+	return r
+}
+
+// startHooks runs the pre hooks of command that run once per run; the
+// returned function runs its post ones, which see and may set the command's
+// error.
+func (m *Manager) startHooks(command string, targetNames []string) (func(*error), error) {
+	if err := m.runOnceHooks("pre_"+command, targetNames, nil); err != nil {
+		return nil, err
+	}
+	return func(errp *error) {
+		if err := m.runOnceHooks("post_"+command, targetNames, *errp); err != nil && *errp == nil {
+			*errp = err
+		}
+	}, nil
+}
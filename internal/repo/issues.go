@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+// IssueEntry is one repo of `tugboat issues`.
+type IssueEntry struct {
+	Path   string `json:"path"`
+	Target string `json:"target"`
+	Name   string `json:"name"`
+	Open   int    `json:"open"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Issues prints the open issue count of every local repo of the named
+// targets, most issues first, counting only issues that match filter. Repos
+// without matching issues are left out of the text output. It does not
+// fetch.
+func (m *Manager) Issues(targetNames []string, filter remote.IssueFilter, workers int) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	jobs, _, err := m.localRepos(targets)
+	if err != nil {
+		return err
+	}
+
+	entries := pool.Run(jobs, workers, func(job statusJob) IssueEntry {
+		e := IssueEntry{Path: job.path, Target: job.target, Name: job.name}
+		counter, ok := m.providers[job.provider].(remote.IssueCounter)
+		if !ok {
+			e.Error = fmt.Sprintf("provider %q cannot count issues", job.provider)
+			return e
+		}
+		open, err := counter.CountOpenIssues(job.org, job.name, filter)
+		if err != nil {
+			e.Error = err.Error()
+			return e
+		}
+		e.Open = open
+		return e
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Open != entries[j].Open {
+			return entries[i].Open > entries[j].Open
+		}
+		return entries[i].Path < entries[j].Path
+	})
+
+	var errored, open, withOpen int
+	for _, e := range entries {
+		if e.Error != "" {
+			errored++
+		}
+		if e.Open > 0 {
+			open += e.Open
+			withOpen++
+		}
+	}
+	if m.Output != "" {
+		if err := writeRows(m.out(), entries, m.Output); err != nil {
+			return err
+		}
+	} else if m.JSON {
+		if entries == nil {
+			entries = []IssueEntry{}
+		}
+		if err := m.writeJSON(entries); err != nil {
+			return err
+		}
+	} else {
+		for _, e := range entries {
+			switch {
+			case e.Error != "":
+				m.printf("  [ERROR]  %s: %s\n", e.Path, e.Error)
+			case e.Open > 0:
+				m.printf("  %5d  %s\n", e.Open, e.Path)
+			}
+		}
+		m.printf("\n%d open issues%s in %d repos (%d checked)\n", open, describeIssueFilter(filter), withOpen, len(entries))
+	}
+
+	if errored > 0 {
+		return &RepoFailures{Failed: errored, Total: len(entries)}
+	}
+	return nil
+}
+
+// describeIssueFilter renders filter for the summary line, e.g.
+// ` labeled bug, triage assigned to ann`.
+func describeIssueFilter(filter remote.IssueFilter) string {
+	var desc string
+	if len(filter.Labels) > 0 {
+		desc += " labeled " + strings.Join(filter.Labels, ", ")
+	}
+	if filter.Assignee != "" {
+		desc += " assigned to " + filter.Assignee
+	}
+	return desc
+}
@@ -0,0 +1,340 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
+)
+
+// DefaultLockFile is where `tugboat lock` writes and `tugboat restore` reads
+// when no --lockfile is given: tugboat.lock in the current directory.
+const DefaultLockFile = "tugboat.lock"
+
+// LockFile is the content of a tugboat.lock: the checked-out commit of every
+// repo of the locked targets.
+type LockFile struct {
+	Version int         `json:"version"`
+	Repos   []LockEntry `json:"repos"`
+}
+
+// LockEntry is one locked repo. Path is relative to its target's path ("."
+// for a repo target itself), so a lock file works on any machine with the
+// same targets.
+type LockEntry struct {
+	Target string `json:"target"`
+	Path   string `json:"path"`
+	Repo   string `json:"repo"`             // owner/name
+	Commit string `json:"commit"`           // full HEAD commit
+	Branch string `json:"branch,omitempty"` // checked-out branch; empty when detached
+}
+
+type lockJob struct {
+	statusJob
+	rel string
+}
+
+// Lock records the HEAD commit of every local repo of the named targets in
+// the lock file at path. Entries of targets that were not selected are kept,
+// so locking some targets updates their part of an existing file. Mirrors
+// are left out; dirty repos and commits origin does not have are locked with
+// a warning, since restoring them elsewhere cannot reproduce them.
+func (m *Manager) Lock(path string, targetNames []string, workers int) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	lock := LockFile{Version: 1}
+	lock.Repos, err = m.lockEntries(targets, workers)
+	if err != nil {
+		return err
+	}
+	for _, e := range lock.Repos {
+		repoPath, warnings := m.unreproducible(e)
+		for _, w := range warnings {
+			m.logEvent(slog.LevelWarn, "warn", repoPath, "%s", w)
+		}
+		if m.DryRun {
+			m.printPlan(repoPath, "would-lock", e.Commit)
+		}
+	}
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Lock dry run: %d repos for %s", len(lock.Repos), path)
+		return nil
+	}
+	if len(targetNames) > 0 {
+		old, err := ReadLockFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		selected := make(map[string]bool)
+		for _, t := range targets {
+			selected[t.Name] = true
+		}
+		for _, e := range old.Repos {
+			if !selected[e.Target] {
+				lock.Repos = append(lock.Repos, e)
+			}
+		}
+	}
+	sortLockEntries(lock.Repos)
+	if lock.Repos == nil {
+		lock.Repos = []LockEntry{}
+	}
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	m.logf(slog.LevelInfo, "Locked %d repos in %s", len(lock.Repos), path)
+	return nil
+}
+
+// unreproducible returns the path of the repo of e and why checking e out
+// elsewhere would not reproduce it: local changes, or a commit origin lacks.
+func (m *Manager) unreproducible(e LockEntry) (string, []string) {
+	repoPath := filepath.Join(m.config.GetTargetByName(e.Target).Path, filepath.FromSlash(e.Path))
+	var warnings []string
+	if dirty, _ := m.git.IsDirty(repoPath); dirty {
+		warnings = append(warnings, "dirty; recording HEAD without the local changes")
+	}
+	if remotes, err := gitOutput(repoPath, "branch", "--remotes", "--contains", e.Commit); err == nil && strings.TrimSpace(remotes) == "" {
+		warnings = append(warnings, e.Commit[:12]+" is not on origin; push it so it can be restored elsewhere")
+	}
+	return repoPath, warnings
+}
+
+// lockEntries reads the HEAD commit and branch of every local repo of
+// targets, mirrors excepted.
+func (m *Manager) lockEntries(targets []config.Target, workers int) ([]LockEntry, error) {
+	jobs, _, err := m.localRepos(targets)
+	if err != nil {
+		return nil, err
+	}
+	var lockJobs []lockJob
+	for _, job := range jobs {
+		if isBareRepo(job.path) {
+			m.logEvent(slog.LevelDebug, "skip", job.path, "mirror clone")
+			continue
+		}
+		t := m.config.GetTargetByName(job.target)
+		rel, err := filepath.Rel(t.Path, job.path)
+		if err != nil {
+			return nil, err
+		}
+		lockJobs = append(lockJobs, lockJob{statusJob: job, rel: filepath.ToSlash(rel)})
+	}
+
+	type lockResult struct {
+		entry LockEntry
+		err   error
+	}
+	results := pool.Run(lockJobs, workers, func(job lockJob) lockResult {
+		commit, err := gitOutput(job.path, "rev-parse", "--verify", "HEAD")
+		if err != nil {
+			return lockResult{err: fmt.Errorf("%s: no HEAD commit", job.path)}
+		}
+		e := LockEntry{Target: job.target, Path: job.rel, Repo: job.org + "/" + job.name, Commit: strings.TrimSpace(commit)}
+		if branch, err := gitOutput(job.path, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+			e.Branch = strings.TrimSpace(branch)
+		}
+		return lockResult{entry: e}
+	})
+
+	var entries []LockEntry
+	var errs []error
+	for _, r := range results {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		entries = append(entries, r.entry)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	sortLockEntries(entries)
+	return entries, nil
+}
+
+func sortLockEntries(entries []LockEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Target != entries[j].Target {
+			return entries[i].Target < entries[j].Target
+		}
+		return entries[i].Path < entries[j].Path
+	})
+}
+
+// ReadLockFile reads the lock file at path.
+func ReadLockFile(path string) (*LockFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return &LockFile{}, err
+	}
+	var lock LockFile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if lock.Version != 1 {
+		return nil, fmt.Errorf("%s: unsupported lock file version %d", path, lock.Version)
+	}
+	return &lock, nil
+}
+
+// Restore checks out the commits the lock file at path records for the repos
+// of the named targets, fetching commits the clones lack. A recorded branch
+// is checked out when its tip is the locked commit; otherwise the commit is
+// checked out detached. Dirty repos are skipped; locked repos that are not
+// cloned fail, and local repos missing from the lock are left alone.
+func (m *Manager) Restore(path string, targetNames []string, workers int) error {
+	lock, err := ReadLockFile(path)
+	if err != nil {
+		return err
+	}
+	return m.restoreLock(lock, targetNames, workers)
+}
+
+// restoreLock is Restore for a lock already read.
+func (m *Manager) restoreLock(lock *LockFile, targetNames []string, workers int) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	statuses, _, err := m.getAllStatuses(targets, false, workers)
+	if err != nil {
+		return err
+	}
+	selected := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		selected[t.Name] = true
+	}
+	byPath := make(map[string]RepoStatus, len(statuses))
+	for _, s := range statuses {
+		byPath[s.Path] = s
+	}
+
+	var results []RepoResult
+	type restoreJob struct {
+		status RepoStatus
+		entry  LockEntry
+	}
+	var jobs []restoreJob
+	for _, e := range lock.Repos {
+		t := m.config.GetTargetByName(e.Target)
+		if t == nil && len(targetNames) == 0 {
+			r := RepoResult{Path: e.Path, Target: e.Target, Name: e.Repo, Result: "failed", Message: "target not in the config"}
+			m.logEvent(slog.LevelError, "error", e.Repo, "target %q not in the config", e.Target)
+			m.emit(r)
+			results = append(results, r)
+			continue
+		}
+		if !selected[e.Target] {
+			continue
+		}
+		repoPath := filepath.Join(t.Path, filepath.FromSlash(e.Path))
+		s, ok := byPath[repoPath]
+		if !ok {
+			r := RepoResult{Path: repoPath, Target: e.Target, Name: e.Repo, Result: "failed", Message: "not cloned"}
+			m.logEvent(slog.LevelError, "error", repoPath, "not cloned")
+			m.emit(r)
+			results = append(results, r)
+			continue
+		}
+		jobs = append(jobs, restoreJob{status: s, entry: e})
+	}
+
+	results = append(results, emitJobs(m, jobs, workers, func(m *Manager, job restoreJob) RepoResult {
+		return m.restoreRepo(job.status, job.entry)
+	})...)
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+
+	if m.JSON {
+		if results == nil {
+			results = []RepoResult{}
+		}
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	restored, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Restore dry run: %d to restore, %d skipped, %d failed", restored, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Restore complete: %d restored, %d skipped, %d failed", restored, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
+
+// restoreRepo checks out the locked commit of e in the repo of s.
+func (m *Manager) restoreRepo(s RepoStatus, e LockEntry) RepoResult {
+	if s.Error != "" {
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", s.Error)
+		return newResult(s, "failed", s.Error)
+	}
+	if s.Mirror {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "mirror clone")
+		return newResult(s, "skipped", "mirror clone")
+	}
+	short := e.Commit
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	head, _ := gitOutput(s.Path, "rev-parse", "--verify", "--quiet", "HEAD")
+	onBranch := e.Branch != "" && s.Branch == e.Branch
+	if strings.TrimSpace(head) == e.Commit && (onBranch || e.Branch == "") {
+		m.logEvent(slog.LevelDebug, "ok", s.Path, "at %s", short)
+		return newResult(s, "unchanged", "at "+short)
+	}
+	if s.Dirty {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "dirty")
+		return newResult(s, "skipped", "dirty")
+	}
+	reason := "check out " + short
+	if m.DryRun {
+		m.printPlan(s.Path, "would-restore", reason)
+		return newResult(s, "would-restore", reason)
+	}
+
+	run := func(env []string, args ...string) error {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = s.Path
+		cmd.Env = env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	fail := func(err error) RepoResult {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+	if gitRun(s.Path, "cat-file", "-e", e.Commit+"^{commit}") != nil {
+		if err := run(authFor(m.config.Providers[s.Provider]).env(), "fetch", "--quiet", "origin", e.Commit); err != nil {
+			return fail(fmt.Errorf("%s is not on origin: %w", short, err))
+		}
+	}
+	args := []string{"checkout", "--quiet", "--detach", e.Commit}
+	if e.Branch != "" {
+		if tip, err := gitOutput(s.Path, "rev-parse", "--verify", "--quiet", "refs/heads/"+e.Branch); err == nil && strings.TrimSpace(tip) == e.Commit {
+			args = []string{"checkout", "--quiet", e.Branch}
+			reason += " on " + e.Branch
+		}
+	}
+	if err := run(gitEnvNoPrompt(), args...); err != nil {
+		return fail(err)
+	}
+	m.logEvent(slog.LevelInfo, "restore", s.Path, "%s", reason)
+	return newResult(s, "restored", reason)
+}
@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// logger returns m.Logger, or a logger that renders records as the classic
+// "  [TAG] repo: detail" lines through printf.
+func (m *Manager) logger() *slog.Logger {
+	if m.Logger != nil {
+		return m.Logger
+	}
+	return slog.New(consoleHandler{m: m})
+}
+
+// logEvent reports what happened to one repo. event is a short lowercase verb
+// ("pull", "skip", "error") that becomes the tag in text output.
+func (m *Manager) logEvent(level slog.Level, event, repo, format string, args ...any) {
+	l := m.logger()
+	if !l.Enabled(context.Background(), level) {
+		return
+	}
+	attrs := []any{"repo", repo}
+	if format != "" {
+		attrs = append(attrs, "detail", fmt.Sprintf(format, args...))
+	}
+	l.Log(context.Background(), level, event, attrs...)
+}
+
+// logf reports progress that is not about a single repo, such as summaries.
+func (m *Manager) logf(level slog.Level, format string, args ...any) {
+	l := m.logger()
+	if !l.Enabled(context.Background(), level) {
+		return
+	}
+	l.Log(context.Background(), level, fmt.Sprintf(format, args...))
+}
+
+// consoleHandler is the default slog handler: records at or above
+// m.LogLevel are printed as plain lines, and records with a repo attribute
+// as tagged repo lines.
+type consoleHandler struct {
+	m *Manager
+}
+
+func (h consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return !h.m.machineOutput() && level >= h.m.LogLevel
+}
+
+func (h consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var repo, detail string
+	var hasRepo bool
+	r.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case "repo":
+			repo, hasRepo = a.Value.String(), true
+		case "detail":
+			detail = a.Value.String()
+		}
+		return true
+	})
+	if !hasRepo {
+		h.m.printf("%s\n", r.Message)
+		return nil
+	}
+	line := fmt.Sprintf("  %-7s %s", "["+strings.ToUpper(r.Message)+"]", repo)
+	if detail != "" {
+		line += ": " + detail
+	}
+	h.m.printf("%s\n", line)
+	return nil
+}
+
+func (h consoleHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h consoleHandler) WithGroup(string) slog.Handler      { return h }
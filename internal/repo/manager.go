@@ -5,16 +5,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/notify"
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/state"
 )
 
 type RepoTiming struct {
@@ -28,36 +36,102 @@ type RepoTiming struct {
 }
 
 type RepoStatus struct {
-	Path           string
-	Target         string
-	Provider       string
-	Org            string
-	Name           string
-	Branch         string
-	DefaultBranch  string
-	Dirty          bool
-	Ahead          int
-	Behind         int
-	CanFastForward bool
-	UpstreamGone   bool
-	Archived       bool
-	Orphan         bool
-	RemoteError    string
-	Error          string
+	Path             string           `json:"path"`
+	Target           string           `json:"target"`
+	Provider         string           `json:"provider"`
+	Org              string           `json:"org"`
+	Name             string           `json:"name"`
+	Branch           string           `json:"branch"`
+	DefaultBranch    string           `json:"default_branch,omitempty"`
+	DefaultDrift     string           `json:"default_drift,omitempty"` // stale local default (origin/HEAD) when the remote default moved
+	Dirty            bool             `json:"dirty"`
+	Staged           int              `json:"staged,omitempty"` // status --detail: changed path counts of dirty repos
+	Modified         int              `json:"modified,omitempty"`
+	Untracked        int              `json:"untracked,omitempty"`
+	Changes          []FileChange     `json:"changes,omitempty"` // status --detail: the changed paths
+	Ahead            int              `json:"ahead"`
+	Behind           int              `json:"behind"`
+	UpstreamBehind   int              `json:"upstream_behind,omitempty"` // forks: parent commits missing from origin's default branch
+	CanFastForward   bool             `json:"can_fast_forward"`
+	UpstreamGone     bool             `json:"upstream_gone"`
+	Archived         bool             `json:"archived"`
+	Orphan           bool             `json:"orphan"`
+	Mirror           bool             `json:"mirror,omitempty"`            // bare --mirror clone; no working tree
+	SubmoduleDrift   []string         `json:"submodule_drift,omitempty"`   // submodule paths not at the recorded commit
+	Stashes          int              `json:"stashes,omitempty"`           // stash entries
+	UnpushedBranches []UnpushedBranch `json:"unpushed_branches,omitempty"` // status --all-branches: local branches with commits on no origin ref
+	LastModified     *time.Time       `json:"last_modified,omitempty"`     // status --sort mtime: newest local change
+	Offline          bool             `json:"offline,omitempty"`           // remote refs are from the last fetch; archived/orphan unknown
+	Ref              string           `json:"ref,omitempty"`               // foldouts: the pinned branch, tag or commit
+	LastFetch        *time.Time       `json:"last_fetch,omitempty"`        // from the state store: last successful fetch
+	LastSync         *time.Time       `json:"last_sync,omitempty"`         // from the state store: last pull, push or sync
+	LastSyncCommand  string           `json:"last_sync_command,omitempty"`
+	LastSyncResult   string           `json:"last_sync_result,omitempty"`
+	RemoteError      string           `json:"remote_error,omitempty"`
+	Error            string           `json:"error,omitempty"`
+}
+
+// RepoResult is the outcome of a pull, push or sync for a single repo.
+type RepoResult struct {
+	Path         string `json:"path"`
+	Target       string `json:"target"`
+	Name         string `json:"name"`
+	Branch       string `json:"branch,omitempty"`
+	Result       string `json:"result"` // cloned | pulled | rebased | pushed | synced | updated | unshallowed | switched | tagged | opened | maintained | reset | restored | unchanged | skipped | failed | would-clone | would-pull | would-rebase | would-push | would-sync | would-update | would-unshallow | would-switch | would-tag | would-open | would-gc | would-reset | would-restore
+	SwitchedFrom string `json:"switched_from,omitempty"`
+	Ahead        int    `json:"ahead,omitempty"`
+	Behind       int    `json:"behind,omitempty"`
+	Message      string `json:"message,omitempty"`
+}
+
+// ListEntry is one repo line of the list command.
+type ListEntry struct {
+	Target   string `json:"target"`
+	Name     string `json:"name"` // repo name, or org/repo for foldouts
+	Path     string `json:"path"`
+	Local    bool   `json:"local"`
+	Archived bool   `json:"archived"`
+	Orphan   bool   `json:"orphan"`
 }
 
 type foldoutRepo struct {
-	Name   string `json:"name"`
+	Name   string `json:"name"` // org/repo, or provider:org/repo for a repo on another provider
 	Target string `json:"target,omitempty"`
+	// Sparse limits the checkout to these directories with a cone-mode
+	// `git sparse-checkout` once the repo is cloned.
+	Sparse []string `json:"sparse,omitempty"`
+	// Ref pins the checkout to a branch, tag or commit: clone checks it out
+	// and pull and sync fetch and check it out again instead of pulling.
+	Ref string `json:"ref,omitempty"`
+}
+
+// source splits the foldout's name into provider, owner and repo name. The
+// provider defaults to inherited, that of the repo listing the foldout.
+func (fr foldoutRepo) source(inherited string) (provider, owner, name string) {
+	provider, rest, ok := strings.Cut(fr.Name, ":")
+	if !ok {
+		provider, rest = inherited, fr.Name
+	}
+	owner, name, _ = strings.Cut(rest, "/")
+	return provider, owner, name
 }
 
 type foldoutConfig struct {
 	Repos []foldoutRepo `json:"repos"`
+	// Hooks are the repo's own post_clone and post_sync commands, run in it
+	// only when the config's allow_repo_hooks or --allow-hooks allows them.
+	Hooks map[string][]config.Hook `json:"hooks,omitempty"`
 }
 
+// repoHookEvents are the events a .tugboat.json may declare hooks for.
+var repoHookEvents = []string{"post_clone", "post_sync"}
+
+// orgKey identifies a repo owner on a provider. user marks personal accounts,
+// which are listed through a different API endpoint than organizations.
 type orgKey struct {
 	provider string
 	org      string
+	user     bool
 }
 
 func (k orgKey) string() string { return k.provider + "|" + k.org }
@@ -65,18 +139,402 @@ func (k orgKey) string() string { return k.provider + "|" + k.org }
 type Manager struct {
 	providers map[string]remote.Client
 	config    *config.Config
+	git       gitBackend
+
+	// JSON reports status/list/pull/push/sync results as a JSON array on
+	// stdout instead of human-readable lines.
+	JSON bool
+
+	// DryRun makes clone/pull/push/sync report what they would do without
+	// cloning, switching branches, pulling or pushing. Nothing is fetched
+	// either: ahead/behind counts come from the remote-tracking refs of the
+	// last fetch.
+	DryRun bool
+
+	// Out receives human-readable output and JSON, --format and --output
+	// results; nil means os.Stdout. Err receives warnings; nil means
+	// os.Stderr.
+	Out io.Writer
+	Err io.Writer
+
+	// OnResult, when set, is called with the result of each repo as soon as
+	// a bulk command is done with it, for programs embedding the manager.
+	OnResult func(RepoResult)
+
+	// Mirror makes clone create bare --mirror clones regardless of the
+	// configured clone mode.
+	Mirror bool
+
+	// Forks, when "exclude" or "only", overrides the forks setting of every
+	// org and user target for clone and list, Visibility, when "public" or
+	// "private", their visibility setting, and Languages, when set, their
+	// languages.
+	Forks      string
+	Visibility string
+	Languages  []string
+
+	// ExcludeTemplates leaves template repos of org and user targets out of
+	// clone and list.
+	ExcludeTemplates bool
+
+	// ForceWithLease makes push overwrite diverged branches with
+	// --force-with-lease, for targets that set allow_force.
+	ForceWithLease bool
+
+	// Color highlights status flags and line tags with ANSI colors.
+	Color bool
+
+	// LogLevel filters progress lines in the default text output: debug adds
+	// repos that needed nothing, warn keeps only problems.
+	LogLevel slog.Level
+	// Logger, when set, receives progress as structured records instead of the
+	// default text output. Status and list tables are printed either way.
+	Logger *slog.Logger
+
+	// Notifier, when set, is told about failed pulls, pushes and syncs and
+	// about repos that are diverged or orphaned.
+	Notifier *notify.Notifier
+
+	// NoFetch makes status compare against the remote refs of the last
+	// fetch, without fetching or asking the provider API. Archived and
+	// orphan flags and topic filters are then left out.
+	NoFetch bool
+
+	// Detail makes status list the staged, modified and untracked paths of
+	// dirty repos.
+	Detail bool
+
+	// AllBranches makes status check every local branch, not only the
+	// checked-out one, for commits that are on no origin ref.
+	AllBranches bool
+
+	// Sort orders status output: target (the default), name, behind, ahead
+	// or mtime. Counts and mtime sort the most first.
+	Sort string
+
+	// ProblemsOnly leaves repos that need no attention out of status
+	// output; the summary still counts them.
+	ProblemsOnly bool
+
+	// Format, when set, renders every status or list entry through the
+	// template instead of printing the text or JSON output.
+	Format *template.Template
+
+	// Output, when "csv" or "tsv", writes status, list and report entries as
+	// delimited rows for spreadsheets instead of text. "ndjson" writes one
+	// JSON object per line, and bulk commands write each repo's result as
+	// soon as it is done instead of all of them at the end.
+	Output string
+	emitMu *sync.Mutex
+
+	// Deadline, when set, bounds the whole run: clone, pull, push and sync
+	// skip the repos they have not started by then, git commands still
+	// running are stopped, and the command returns ErrDeadline.
+	Deadline time.Time
+
+	// Ordered makes commands that work on repos in parallel print each
+	// repo's lines and results in the order of the repos instead of as they
+	// finish, holding a repo back until those before it are reported, so
+	// two runs can be diffed.
+	Ordered bool
+
+	// TargetTags limits every command to the targets carrying at least one of
+	// these tags, among the named ones or all of them.
+	TargetTags []string
+
+	// ExcludeTargets names targets and groups every command leaves out.
+	ExcludeTargets []string
+
+	// Offline is NoFetch for every command that reads repo status, with the
+	// statuses marked offline. It is also switched on for one run when the
+	// provider API cannot be reached.
+	Offline bool
+
+	// AllowRepoHooks runs the hooks of every repo's .tugboat.json, not only
+	// those of the repos the config's allow_repo_hooks lists.
+	AllowRepoHooks bool
+	hookWarned     *sync.Map // repo paths warned about for not-allowed hooks
+
+	// State, when set, remembers fetches and pull, push and sync results
+	// across runs; status shows when each repo was last synced. Clone, pull,
+	// push and sync runs are also kept in its journal for history.
+	State *state.Store
+	run   *state.Run // the journal entry being recorded, guarded by emitMu
+
+	retryPaths map[string]bool // when set, the only repo paths clone clones
 }
 
 func NewManager(providers map[string]remote.Client, cfg *config.Config) *Manager {
-	return &Manager{providers: providers, config: cfg}
+	m := &Manager{
+		providers:  providers,
+		config:     cfg,
+		git:        limitPerHost(execBackend{}, cfg.NetworkWorkers),
+		emitMu:     &sync.Mutex{},
+		hookWarned: &sync.Map{},
+	}
+	m.git = deadlineBackend{gitBackend: m.git, m: m}
+	if n := cfg.Notifications; n != nil {
+		m.Notifier = &notify.Notifier{URL: n.URL, Kinds: n.Events}
+		if path, err := notify.DefaultStatePath(); err == nil {
+			m.Notifier.StatePath = path
+		}
+	}
+	return m
+}
+
+// ------------ output helpers --------------
+
+// printf writes human-readable output; it is silenced in JSON, --format and
+// --output mode so stdout stays machine-parseable.
+func (m *Manager) printf(format string, args ...any) {
+	if m.machineOutput() {
+		return
+	}
+	text := fmt.Sprintf(format, args...)
+	if m.Color {
+		for tag, code := range tagColors {
+			text = strings.ReplaceAll(text, tag, m.paint(code, tag))
+		}
+	}
+	fmt.Fprint(m.out(), text)
+}
+
+// out is where output goes: m.Out, else stdout.
+func (m *Manager) out() io.Writer {
+	if m.Out != nil {
+		return m.Out
+	}
+	return os.Stdout
+}
+
+// errOut is where warnings go: m.Err, else stderr.
+func (m *Manager) errOut() io.Writer {
+	if m.Err != nil {
+		return m.Err
+	}
+	return os.Stderr
+}
+
+const (
+	colorRed     = "31"
+	colorGreen   = "32"
+	colorYellow  = "33"
+	colorMagenta = "35"
+	colorCyan    = "36"
+)
+
+// tagColors are applied by printf to line tags wherever they appear.
+var tagColors = map[string]string{
+	"[ERROR]":   colorRed,
+	"[SKIP]":    colorYellow,
+	"[CLEAN]":   colorGreen,
+	"[DRY-RUN]": colorCyan,
+	"[DRIFT]":   colorYellow,
+}
+
+// paint wraps text in an ANSI color when m.Color is set.
+func (m *Manager) paint(code, text string) string {
+	if !m.Color {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+// machineOutput reports whether stdout carries JSON, --format or --output
+// rows instead of text.
+func (m *Manager) machineOutput() bool {
+	return m.JSON || m.Format != nil || m.Output != ""
+}
+
+// emit writes a finished repo result as one NDJSON line when the output is
+// ndjson, so wrappers see progress while the other repos still run. Repo
+// results are also added to the run being journaled and passed to OnResult.
+func (m *Manager) emit(v any) {
+	if r, ok := v.(RepoResult); ok {
+		m.journal(r)
+		if m.OnResult != nil {
+			m.emitMu.Lock()
+			m.OnResult(r)
+			m.emitMu.Unlock()
+		}
+	}
+	if m.Output != "ndjson" {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	m.emitMu.Lock()
+	defer m.emitMu.Unlock()
+	m.out().Write(append(b, '\n'))
+}
+
+// runJobs runs fn on items with the worker pool, hands each result to
+// report (when set) as soon as it is done, and returns the results in the
+// order of items. fn reports through the manager it is given: m itself, or
+// with m.Ordered a copy writing into a buffer of its own, which is printed
+// right before its result is reported, in the order of items.
+func runJobs[T, R any](m *Manager, items []T, workers int, fn func(*Manager, T) R, report func(R)) []R {
+	results := make([]R, 0, len(items))
+	if m.Ordered {
+		type held struct {
+			r   R
+			out bytes.Buffer
+		}
+		for h := range pool.StreamOrdered(items, workers, func(item T) *held {
+			h := &held{}
+			h.r = fn(m.buffered(&h.out), item)
+			return h
+		}) {
+			m.emitMu.Lock()
+			m.out().Write(h.out.Bytes())
+			m.emitMu.Unlock()
+			if report != nil {
+				report(h.r)
+			}
+			results = append(results, h.r)
+		}
+		return results
+	}
+
+	type indexed struct {
+		i int
+		r R
+	}
+	indices := make([]int, len(items))
+	for i := range indices {
+		indices[i] = i
+	}
+	results = results[:len(items)]
+	for d := range pool.Stream(indices, workers, func(i int) indexed {
+		return indexed{i, fn(m, items[i])}
+	}) {
+		if report != nil {
+			report(d.r)
+		}
+		results[d.i] = d.r
+	}
+	return results
+}
+
+// emitJobs is runJobs emitting each repo result as soon as it is done.
+func emitJobs[T any](m *Manager, items []T, workers int, fn func(*Manager, T) RepoResult) []RepoResult {
+	return runJobs(m, items, workers, fn, func(r RepoResult) { m.emit(r) })
+}
+
+// buffered returns a copy of m whose output goes to buf instead.
+func (m *Manager) buffered(buf *bytes.Buffer) *Manager {
+	jm := *m
+	jm.Out = buf
+	return &jm
+}
+
+// runRepos runs command on every repo of statuses with the worker pool,
+// between the command's hooks, and returns the results in the order of
+// statuses. Each result is emitted as soon as it is done.
+func (m *Manager) runRepos(command string, statuses []RepoStatus, workers int, fn func(*Manager, RepoStatus) RepoResult) []RepoResult {
+	return emitJobs(m, statuses, workers, func(m *Manager, s RepoStatus) RepoResult {
+		return m.withHooks(command, s, func(s RepoStatus) RepoResult { return fn(m, s) })
+	})
+}
+
+// writeJSON encodes v as indented JSON on the output.
+func (m *Manager) writeJSON(v any) error {
+	enc := json.NewEncoder(m.out())
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// newResult builds a RepoResult from the repo status it was computed for.
+func newResult(s RepoStatus, result, message string) RepoResult {
+	return RepoResult{
+		Path:    s.Path,
+		Target:  s.Target,
+		Name:    s.Name,
+		Branch:  s.Branch,
+		Result:  result,
+		Ahead:   s.Ahead,
+		Behind:  s.Behind,
+		Message: message,
+	}
+}
+
+// planUpdate describes what pull/sync would do to a prepared repo in dry-run
+// mode. A repo that still needs switching is assumed to pull afterwards.
+func planUpdate(s RepoStatus, switchedFrom string, ffOnly bool) (result, reason string) {
+	switch {
+	case switchedFrom != "":
+		return "would-pull", fmt.Sprintf("after switching %s -> %s", switchedFrom, s.DefaultBranch)
+	case s.Behind == 0:
+		return "unchanged", "up to date"
+	case !s.CanFastForward && ffOnly:
+		return "would-rebase", fmt.Sprintf("%d behind, %d ahead (diverged)", s.Behind, s.Ahead)
+	default:
+		return "would-pull", fmt.Sprintf("%d behind", s.Behind)
+	}
+}
+
+// printPlan prints a dry-run line for a repo.
+func (m *Manager) printPlan(path, result, reason string) {
+	m.printf("  [DRY-RUN] %s: %s (%s)\n", path, strings.ReplaceAll(result, "-", " "), reason)
+}
+
+// switchedResult is newResult for a repo that may have been switched onto its
+// default branch first.
+func switchedResult(s RepoStatus, switchedFrom, result, message string) RepoResult {
+	r := newResult(s, result, message)
+	r.SwitchedFrom = switchedFrom
+	return r
 }
 
 // ------------ selection helpers --------------
 
+// targetsFor returns the targets names select, narrowed to those carrying
+// one of m.TargetTags and without m.ExcludeTargets.
 func (m *Manager) targetsFor(names []string) ([]config.Target, error) {
+	res, err := m.namedTargets(names)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.TargetTags) > 0 {
+		var tagged []config.Target
+		for _, t := range res {
+			if t.HasTag(m.TargetTags...) {
+				tagged = append(tagged, t)
+			}
+		}
+		if len(tagged) == 0 {
+			return nil, fmt.Errorf("no targets tagged %s", strings.Join(m.TargetTags, " or "))
+		}
+		res = tagged
+	}
+	if len(m.ExcludeTargets) > 0 {
+		excluded, err := m.namedTargets(m.ExcludeTargets)
+		if err != nil {
+			return nil, fmt.Errorf("--exclude: %w", err)
+		}
+		res = slices.DeleteFunc(slices.Clone(res), func(t config.Target) bool {
+			return slices.ContainsFunc(excluded, func(e config.Target) bool { return e.Name == t.Name })
+		})
+		if len(res) == 0 {
+			return nil, errors.New("every selected target is excluded")
+		}
+	}
+	return res, nil
+}
+
+// ErrUnknownTargets is returned, wrapped, when a command is given target
+// names or patterns that match no target in the config.
+var ErrUnknownTargets = errors.New("unknown targets")
+
+// namedTargets returns the targets and groups called names, with target name
+// patterns expanded, or every target when names is empty.
+func (m *Manager) namedTargets(names []string) ([]config.Target, error) {
 	if len(names) == 0 {
 		return m.config.Targets, nil
 	}
+	names = m.config.ExpandGroups(names)
 	nameSet := make(map[string]config.Target, len(m.config.Targets))
 	for _, t := range m.config.Targets {
 		nameSet[t.Name] = t
@@ -85,23 +543,69 @@ func (m *Manager) targetsFor(names []string) ([]config.Target, error) {
 	var missing []string
 	seen := make(map[string]bool)
 	for _, n := range names {
-		t, ok := nameSet[n]
-		if !ok {
-			missing = append(missing, n)
-			continue
+		matched := []string{n}
+		if _, ok := nameSet[n]; !ok {
+			patternMatches, isPattern, err := m.config.MatchTargetNames(n)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrUnknownTargets, err)
+			}
+			if !isPattern || len(patternMatches) == 0 {
+				missing = append(missing, n)
+				continue
+			}
+			matched = patternMatches
 		}
-		if seen[n] {
-			continue
+		for _, name := range matched {
+			if !seen[name] {
+				res = append(res, nameSet[name])
+				seen[name] = true
+			}
 		}
-		res = append(res, t)
-		seen[n] = true
 	}
 	if len(missing) > 0 {
-		return nil, fmt.Errorf("unknown targets: %s", strings.Join(missing, ", "))
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTargets, strings.Join(missing, ", "))
 	}
 	return res, nil
 }
 
+// listOwnerRepos lists the repos owned by an org or user account.
+func listOwnerRepos(client remote.Client, owner string, user bool) ([]remote.Repository, error) {
+	if user {
+		return client.ListUserRepos(owner)
+	}
+	return client.ListOrgRepos(owner)
+}
+
+// listTargetRepos lists all remote repos for an org or user target.
+func (m *Manager) listTargetRepos(t config.Target) ([]remote.Repository, error) {
+	client, ok := m.providers[t.Provider]
+	if !ok {
+		return nil, fmt.Errorf("no client for provider %s", t.Provider)
+	}
+	return listOwnerRepos(client, t.Owner(), t.IsUser())
+}
+
+// targetRepos lists the remote repos clone and list work on for an org or
+// user target: those its search query finds or its team has access to, or
+// else all of the owner's.
+func (m *Manager) targetRepos(t config.Target) ([]remote.Repository, error) {
+	switch {
+	case t.Search != "":
+		searcher, ok := m.providers[t.Provider].(remote.Searcher)
+		if !ok {
+			return nil, fmt.Errorf("provider %s cannot search repositories", t.Provider)
+		}
+		return searcher.SearchRepos(t.Owner(), t.IsUser(), t.Search)
+	case t.Team != "":
+		lister, ok := m.providers[t.Provider].(remote.TeamLister)
+		if !ok {
+			return nil, fmt.Errorf("provider %s has no teams", t.Provider)
+		}
+		return lister.ListTeamRepos(t.Org, t.Team)
+	}
+	return m.listTargetRepos(t)
+}
+
 // buildRepoIndex fetches remote repo metadata for the requested orgs (per provider).
 // Key is provider|org, value is map[name]Repository.
 func (m *Manager) buildRepoIndex(orgs []orgKey) (map[string]map[string]remote.Repository, error) {
@@ -111,7 +615,7 @@ func (m *Manager) buildRepoIndex(orgs []orgKey) (map[string]map[string]remote.Re
 		if !ok {
 			return nil, fmt.Errorf("no client for provider %s", k.provider)
 		}
-		repos, err := client.ListOrgRepos(k.org)
+		repos, err := listOwnerRepos(client, k.org, k.user)
 		if err != nil {
 			return nil, fmt.Errorf("listing repos for %s/%s: %w", k.provider, k.org, err)
 		}
@@ -135,22 +639,116 @@ func loadFoldout(path string) (*foldoutConfig, error) {
 		}
 		return nil, err
 	}
+	return parseFoldout(data)
+}
+
+// fetchFoldout reads the .tugboat.json of owner/name from its remote default
+// branch through the provider API, so its foldouts are known before the repo
+// is cloned. It returns nil when the provider cannot read files or the repo
+// has no foldout file.
+func fetchFoldout(client remote.Client, owner, name string) (*foldoutConfig, error) {
+	reader, ok := client.(remote.FileReader)
+	if !ok {
+		return nil, nil
+	}
+	data, err := reader.GetFile(owner, name, ".tugboat.json")
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return parseFoldout(data)
+}
+
+// parseFoldout decodes a .tugboat.json, defaulting each target to the repo
+// name.
+func parseFoldout(data []byte) (*foldoutConfig, error) {
 	var fc foldoutConfig
 	if err := json.Unmarshal(data, &fc); err != nil {
 		return nil, fmt.Errorf("parsing .tugboat.json: %w", err)
 	}
 	for i := range fc.Repos {
-		parts := strings.Split(fc.Repos[i].Name, "/")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid repo name %q in .tugboat.json (expected org/repo)", fc.Repos[i].Name)
+		provider, owner, name := fc.Repos[i].source("-")
+		if provider == "" || owner == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid repo name %q in .tugboat.json (expected org/repo or provider:org/repo)", fc.Repos[i].Name)
 		}
 		if fc.Repos[i].Target == "" {
-			fc.Repos[i].Target = parts[len(parts)-1]
+			fc.Repos[i].Target = name
+		}
+	}
+	for event, hooks := range fc.Hooks {
+		if !slices.Contains(repoHookEvents, event) {
+			return nil, fmt.Errorf("unsupported hook event %q in .tugboat.json (want %s)", event, strings.Join(repoHookEvents, " or "))
+		}
+		for _, h := range hooks {
+			if strings.TrimSpace(h.Run) == "" || h.Once {
+				return nil, fmt.Errorf("invalid %s hook in .tugboat.json: repo hooks need a command and cannot be once", event)
+			}
 		}
 	}
 	return &fc, nil
 }
 
+// maxFoldoutDepth is how deep foldouts may nest: a foldout's own
+// .tugboat.json is followed down to this many levels below the repo target.
+const maxFoldoutDepth = 3
+
+// foldoutEntry is a foldout somewhere in the tree of nested foldouts of a
+// repo target.
+type foldoutEntry struct {
+	foldoutRepo
+	provider    string
+	owner, name string
+	path        string // checkout path
+	depth       int    // 1 for the repo target's own foldouts
+}
+
+// foldoutTree returns the foldouts of repo target t and, for cloned foldouts
+// with a .tugboat.json of their own, theirs, each followed by its children.
+func foldoutTree(t config.Target) ([]foldoutEntry, error) {
+	var entries []foldoutEntry
+	var walk func(dir, provider string, chain []string) error
+	walk = func(dir, provider string, chain []string) error {
+		fc, err := loadFoldout(dir)
+		if err != nil || fc == nil {
+			return err
+		}
+		for _, fr := range fc.Repos {
+			e := foldoutEntry{foldoutRepo: fr, path: filepath.Join(dir, fr.Target), depth: len(chain)}
+			e.provider, e.owner, e.name = fr.source(provider)
+			key := foldoutKey(e.provider, e.owner, e.name)
+			if err := checkFoldoutChain(chain, key); err != nil {
+				return err
+			}
+			entries = append(entries, e)
+			if isGitRepo(e.path) {
+				if err := walk(e.path, e.provider, append(chain[:len(chain):len(chain)], key)); err != nil {
+					return fmt.Errorf("foldout %s: %w", fr.Name, err)
+				}
+			}
+		}
+		return nil
+	}
+	return entries, walk(t.Path, t.Provider, []string{foldoutKey(t.Provider, t.Owner(), t.Repo)})
+}
+
+// foldoutKey names a repo across providers for cycle detection.
+func foldoutKey(provider, owner, name string) string {
+	return provider + ":" + owner + "/" + name
+}
+
+// checkFoldoutChain rejects foldout name below the repos of chain (the repo
+// target first) when it is one of them or nests too deep.
+func checkFoldoutChain(chain []string, name string) error {
+	for _, c := range chain {
+		if strings.EqualFold(c, name) {
+			return fmt.Errorf("foldout cycle: %s -> %s", strings.Join(chain, " -> "), name)
+		}
+	}
+	if len(chain) > maxFoldoutDepth {
+		return fmt.Errorf("foldouts nest deeper than %d levels: %s -> %s", maxFoldoutDepth, strings.Join(chain, " -> "), name)
+	}
+	return nil
+}
+
 func cleanFoldoutTargets(base string, repos []foldoutRepo) error {
 	seen := make(map[string]bool)
 	for _, r := range repos {
@@ -164,6 +762,14 @@ func cleanFoldoutTargets(base string, repos []foldoutRepo) error {
 			return fmt.Errorf("duplicate foldout target %s", r.Target)
 		}
 		seen[r.Target] = true
+		for _, dir := range r.Sparse {
+			if dir == "" || filepath.IsAbs(dir) || strings.HasPrefix(dir, "-") || strings.Contains(dir, "..") {
+				return fmt.Errorf("foldout %s has invalid sparse directory %q", r.Name, dir)
+			}
+		}
+		if strings.HasPrefix(r.Ref, "-") {
+			return fmt.Errorf("foldout %s has invalid ref %q", r.Name, r.Ref)
+		}
 	}
 	return nil
 }
@@ -174,6 +780,10 @@ type cloneJob struct {
 	cloneURL string
 	repoPath string
 	repoName string
+	sparse   []string // foldout sparse-checkout directories
+	ref      string   // foldout pinned ref
+	auth     gitAuth  // foldouts: that of the foldout's provider
+	hook     hookRepo // foldouts: the repo as its post_clone hooks see it
 }
 
 type cloneResult struct {
@@ -189,204 +799,421 @@ type updateSkipError struct {
 
 func (e *updateSkipError) Error() string { return e.reason }
 
-func (m *Manager) Clone(targetNames []string, excludeEmpty, includeArchived bool, workers int) error {
+// emitClone runs the post_clone hooks of a cloned repo, then emits the
+// outcome of clone job of target t as a RepoResult and returns r.
+func (m *Manager) emitClone(t config.Target, job cloneJob, r cloneResult) cloneResult {
+	if r.err == nil {
+		hr := job.hook
+		if hr.path == "" {
+			hr = hookRepo{path: job.repoPath, name: job.repoName, target: t.Name, org: t.Owner(), provider: t.Provider}
+		}
+		if err := m.runRepoHooks("post_clone", hr, "cloned"); err != nil {
+			r.status, r.err = "error", fmt.Errorf("cloned, but %w", err)
+		}
+	}
+	result := RepoResult{Path: job.repoPath, Target: t.Name, Name: job.repoName, Result: "cloned"}
+	switch {
+	case r.status == "skipped":
+		result.Result, result.Message = "skipped", r.err.Error()
+	case r.err != nil:
+		result.Result, result.Message = "failed", r.err.Error()
+	}
+	m.emit(result)
+	return r
+}
+
+func (m *Manager) Clone(targetNames []string, excludeEmpty, includeArchived bool, workers int) (err error) {
+	finish := m.startRun("clone", targetNames)
+	defer func() {
+		err = m.deadlineOutcome(err)
+		finish(err)
+	}()
+	finishHooks, err := m.startHooks("clone", targetNames)
+	if err != nil {
+		return err
+	}
+	defer finishHooks(&err)
 	targets, err := m.targetsFor(targetNames)
 	if err != nil {
 		return err
 	}
 
+	// Failed repos do not stop the remaining targets; they are totalled at
+	// the end.
+	var failures RepoFailures
 	for _, t := range targets {
+		var err error
 		if t.Repo == "" {
-			if err := m.cloneOrg(t, excludeEmpty, includeArchived, workers); err != nil {
-				return err
-			}
+			err = m.cloneOrg(t, excludeEmpty, includeArchived, workers)
 		} else {
-			if err := m.cloneRepoWithFoldout(t, excludeEmpty, includeArchived, workers); err != nil {
-				return err
-			}
+			err = m.cloneRepoWithFoldout(t, excludeEmpty, includeArchived, workers)
+		}
+		var rf *RepoFailures
+		if errors.As(err, &rf) {
+			failures.Failed += rf.Failed
+			failures.Total += rf.Total
+			continue
+		}
+		if err != nil {
+			return err
 		}
 	}
 
+	if failures.Failed > 0 {
+		return &failures
+	}
 	return nil
 }
 
 func (m *Manager) cloneOrg(t config.Target, excludeEmpty, includeArchived bool, workers int) error {
-	client, ok := m.providers[t.Provider]
-	if !ok {
-		return fmt.Errorf("no client for provider %s", t.Provider)
-	}
-
-	repos, err := client.ListOrgRepos(t.Org)
+	t = m.withFilters(t)
+	repos, err := m.targetRepos(t)
 	if err != nil {
-		return fmt.Errorf("listing repos for %s: %w", t.Org, err)
+		return fmt.Errorf("listing repos for %s: %w", t.Owner(), err)
 	}
 
 	// Build index for archived/orphan marking later (during status)
 
-	if err := os.MkdirAll(t.Path, 0755); err != nil {
-		return fmt.Errorf("creating directory %s: %w", t.Path, err)
+	if !m.DryRun {
+		if err := os.MkdirAll(t.Path, 0755); err != nil {
+			return fmt.Errorf("creating directory %s: %w", t.Path, err)
+		}
 	}
 
 	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
 
-	token := m.config.Providers[t.Provider].Token
+	auth := authFor(m.config.Providers[t.Provider])
+	cloneOpts := m.cloneOptionsFor(t)
+	// Recurse and layout targets keep repos in subdirectories; a repo found
+	// anywhere there is not cloned again where the layout puts it.
+	nested := make(map[string]string)
+	if t.ScanDepth() > 1 {
+		repoPaths, _ := targetRepoDirs(t)
+		for _, p := range repoPaths {
+			nested[filepath.Base(p)] = p
+		}
+	}
 	var jobs []cloneJob
+	var forks []forkClone
 	for _, r := range repos {
+		if !selectsRepo(t, r) {
+			continue
+		}
 		if r.Empty && excludeEmpty {
+			if m.DryRun {
+				m.logEvent(slog.LevelInfo, "skip", r.Name, "empty")
+			}
 			continue
 		}
 		if r.Archived && !includeArchived {
+			if m.DryRun {
+				m.logEvent(slog.LevelInfo, "skip", r.Name, "archived")
+			}
 			continue
 		}
-		dest := filepath.Join(t.Path, r.Name)
+		if r.Template && m.ExcludeTemplates {
+			if m.DryRun {
+				m.logEvent(slog.LevelInfo, "skip", r.Name, "template")
+			}
+			continue
+		}
+		dest, err := t.RepoDir(r.Name, r.Topics)
+		if err != nil {
+			m.logEvent(slog.LevelError, "error", r.Name, "%v", err)
+			continue
+		}
+		if p, ok := nested[r.Name]; ok {
+			dest = p
+		}
+		if !m.cloneSelected(dest) {
+			continue
+		}
+		if r.Fork {
+			forks = append(forks, forkClone{path: dest, owner: t.Owner(), repo: r})
+		}
 		if isGitRepo(dest) {
+			m.logEvent(slog.LevelDebug, "exists", dest, "")
 			continue
 		}
 		jobs = append(jobs, cloneJob{
-			cloneURL: pickCloneURL(&r, m.config.Providers[t.Provider].Options.Clone.Protocol),
+			cloneURL: pickCloneURL(&r, cloneOpts.Protocol),
 			repoPath: dest,
 			repoName: r.Name,
 		})
 	}
 
+	scope := "Org"
+	if t.IsUser() {
+		scope = "User"
+	}
+
 	if len(jobs) == 0 {
-		fmt.Printf("Org %s: nothing to clone\n", t.Org)
+		m.logf(slog.LevelInfo, "%s %s: nothing to clone", scope, t.Owner())
+		m.ensureUpstreams(t, forks, workers)
 		return nil
 	}
 
-	fmt.Printf("Org %s: cloning %d repositories...\n", t.Org, len(jobs))
-
-	results := pool.Run(jobs, workers, func(job cloneJob) cloneResult {
-		cmd := exec.Command("git", "clone", job.cloneURL, job.repoPath)
-		cmd.Env = gitEnvWithAuth(token)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return cloneResult{repoName: job.repoName, status: "error", err: fmt.Errorf("%v: %s", err, output)}
+	if m.DryRun {
+		for _, job := range jobs {
+			m.printPlan(job.repoPath, "would-clone", "missing locally, from "+job.cloneURL)
+			m.emit(RepoResult{Path: job.repoPath, Target: t.Name, Name: job.repoName, Result: "would-clone", Message: "from " + job.cloneURL})
 		}
-		return cloneResult{repoName: job.repoName, status: "cloned"}
-	})
+		m.logf(slog.LevelInfo, "%s %s: dry run (%d to clone)", scope, t.Owner(), len(jobs))
+		return nil
+	}
 
+	m.logf(slog.LevelInfo, "%s %s: cloning %d repositories...", scope, t.Owner(), len(jobs))
+
+	// Report each clone as it finishes; large orgs take long enough that
+	// waiting for the whole pool looks like a hang.
 	var cloned, failed int
-	for _, r := range results {
-		if r.status == "cloned" {
-			fmt.Printf("  [CLONED] %s\n", r.repoName)
+	runJobs(m, jobs, workers, func(m *Manager, job cloneJob) cloneResult {
+		if m.pastDeadline() {
+			return m.emitClone(t, job, cloneResult{repoName: job.repoName, status: "skipped", err: ErrDeadline})
+		}
+		if err := m.git.Clone(job.cloneURL, job.repoPath, auth, cloneOpts); err != nil {
+			return m.emitClone(t, job, cloneResult{repoName: job.repoName, status: "error", err: err})
+		}
+		if err := m.cloneReleases(t, t.Owner(), job.repoName, job.repoPath, cloneOpts); err != nil {
+			return m.emitClone(t, job, cloneResult{repoName: job.repoName, status: "error", err: err})
+		}
+		return m.emitClone(t, job, cloneResult{repoName: job.repoName, status: "cloned"})
+	}, func(r cloneResult) {
+		switch r.status {
+		case "cloned":
+			m.logEvent(slog.LevelInfo, "cloned", r.repoName, "")
 			cloned++
-		} else {
-			fmt.Printf("  [ERROR]  %s: %v\n", r.repoName, r.err)
+		case "skipped":
+			m.logEvent(slog.LevelWarn, "skip", r.repoName, "%v", r.err)
+		default:
+			m.logEvent(slog.LevelError, "error", r.repoName, "%v", r.err)
 			failed++
 		}
+	})
+	m.logf(slog.LevelInfo, "%s %s: clone complete (%d cloned, %d failed)", scope, t.Owner(), cloned, failed)
+	m.ensureUpstreams(t, forks, workers)
+	if failed > 0 {
+		return &RepoFailures{Failed: failed, Total: len(jobs)}
 	}
-	fmt.Printf("Org %s: clone complete (%d cloned, %d failed)\n", t.Org, cloned, failed)
 	return nil
 }
 
 func (m *Manager) cloneRepoWithFoldout(t config.Target, excludeEmpty, includeArchived bool, workers int) error {
+	if !isGitRepo(t.Path) && !m.cloneSelected(t.Path) {
+		return nil
+	}
 	client, ok := m.providers[t.Provider]
 	if !ok {
 		return fmt.Errorf("no client for provider %s", t.Provider)
 	}
-	repo, err := client.GetRepo(t.Org, t.Repo)
+	repo, err := client.GetRepo(t.Owner(), t.Repo)
 	if err != nil {
-		return fmt.Errorf("fetching repo %s/%s: %w", t.Org, t.Repo, err)
+		return fmt.Errorf("fetching repo %s/%s: %w", t.Owner(), t.Repo, err)
 	}
 	if repo == nil {
-		return fmt.Errorf("repo %s/%s not found (check that the repo exists and your token has access)", t.Org, t.Repo)
+		return fmt.Errorf("repo %s/%s not found (check that the repo exists and your token has access)", t.Owner(), t.Repo)
 	}
 
 	if repo.Empty && excludeEmpty {
-		fmt.Printf("Skipping empty repo: %s/%s\n", t.Org, t.Repo)
+		m.logf(slog.LevelInfo, "Skipping empty repo: %s/%s", t.Owner(), t.Repo)
 		return nil
 	}
 	if repo.Archived && !includeArchived {
-		fmt.Printf("Skipping archived repo: %s/%s\n", t.Org, t.Repo)
+		m.logf(slog.LevelInfo, "Skipping archived repo: %s/%s", t.Owner(), t.Repo)
 		return nil
 	}
 
-	if err := os.MkdirAll(filepath.Dir(t.Path), 0755); err != nil {
-		return fmt.Errorf("creating parent dir: %w", err)
+	if !m.DryRun {
+		if err := os.MkdirAll(filepath.Dir(t.Path), 0755); err != nil {
+			return fmt.Errorf("creating parent dir: %w", err)
+		}
 	}
 
-	token := m.config.Providers[t.Provider].Token
+	auth := authFor(m.config.Providers[t.Provider])
+	cloneOpts := m.cloneOptionsFor(t)
+	// The remote foldout file plans the foldouts of a dry run, and stands in
+	// for a clone that has none on disk (e.g. a mirror clone).
+	var remoteFoldout *foldoutConfig
 	if !isGitRepo(t.Path) {
-		cloneURL := pickCloneURL(repo, m.config.Providers[t.Provider].Options.Clone.Protocol)
-		fmt.Printf("Cloning %s/%s -> %s\n", t.Org, t.Repo, t.Path)
-		cmd := exec.Command("git", "clone", cloneURL, t.Path)
-		cmd.Env = gitEnvWithAuth(token)
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			os.Stderr.Write(out)
-			return err
+		if remoteFoldout, err = fetchFoldout(client, t.Owner(), t.Repo); err != nil {
+			m.logEvent(slog.LevelWarn, "warn", t.Path, "cannot read remote .tugboat.json: %v", err)
+		}
+		cloneURL := pickCloneURL(repo, cloneOpts.Protocol)
+		if m.DryRun {
+			m.printPlan(t.Path, "would-clone", "missing locally, from "+cloneURL)
+			m.emit(RepoResult{Path: t.Path, Target: t.Name, Name: t.Repo, Result: "would-clone", Message: "from " + cloneURL})
+			if remoteFoldout == nil {
+				return nil
+			}
+		} else {
+			m.logf(slog.LevelInfo, "Cloning %s/%s -> %s", t.Owner(), t.Repo, t.Path)
+			job := cloneJob{cloneURL: cloneURL, repoPath: t.Path, repoName: t.Repo}
+			err := m.git.Clone(cloneURL, t.Path, auth, cloneOpts)
+			if err == nil {
+				err = m.cloneReleases(t, t.Owner(), t.Repo, t.Path, cloneOpts)
+			}
+			if err != nil {
+				m.emitClone(t, job, cloneResult{repoName: t.Repo, status: "error", err: err})
+				return err
+			}
+			m.emitClone(t, job, cloneResult{repoName: t.Repo, status: "cloned"})
 		}
 	} else {
-		fmt.Printf("Exists: %s\n", t.Path)
+		m.logf(slog.LevelDebug, "Exists: %s", t.Path)
+	}
+	if repo.Fork && isGitRepo(t.Path) {
+		m.ensureUpstreams(t, []forkClone{{path: t.Path, owner: t.Owner(), repo: *repo}}, 1)
 	}
 
-	// foldout
 	fc, err := loadFoldout(t.Path)
 	if err != nil {
 		return err
 	}
 	if fc == nil {
-		return nil // no foldout
-	}
-	if err := cleanFoldoutTargets(t.Path, fc.Repos); err != nil {
-		return err
+		fc = remoteFoldout
 	}
 
-	// Build clone jobs
-	var jobs []cloneJob
-	for _, fr := range fc.Repos {
-		dest := filepath.Join(t.Path, fr.Target)
-		if isGitRepo(dest) {
-			continue
+	// Foldouts are cloned one level at a time, since nested foldouts are
+	// listed in the .tugboat.json of their (just cloned) parent foldout.
+	var failed, total int
+	var cloneLevel func(dir, provider string, fc *foldoutConfig, chain []string) error
+	cloneLevel = func(dir, provider string, fc *foldoutConfig, chain []string) error {
+		if fc == nil {
+			return nil
 		}
-		parts := strings.Split(fr.Name, "/")
-		org := parts[0]
-		repoName := parts[1]
-		r, err := client.GetRepo(org, repoName)
-		if err != nil {
-			return fmt.Errorf("fetching foldout repo %s: %w", fr.Name, err)
-		}
-		if r == nil {
-			fmt.Printf("  [MISS] %s not found\n", fr.Name)
-			continue
-		}
-		if r.Empty && excludeEmpty {
-			continue
+		if err := cleanFoldoutTargets(dir, fc.Repos); err != nil {
+			return err
 		}
-		if r.Archived && !includeArchived {
-			continue
+		var jobs []cloneJob
+		for _, fr := range fc.Repos {
+			p, owner, name := fr.source(provider)
+			if err := checkFoldoutChain(chain, foldoutKey(p, owner, name)); err != nil {
+				return err
+			}
+			dest := filepath.Join(dir, fr.Target)
+			if isGitRepo(dest) || !m.cloneSelected(dest) {
+				continue
+			}
+			frClient, ok := m.providers[p]
+			if !ok {
+				return fmt.Errorf("foldout %s: no client for provider %s", fr.Name, p)
+			}
+			r, err := frClient.GetRepo(owner, name)
+			if err != nil {
+				return fmt.Errorf("fetching foldout repo %s: %w", fr.Name, err)
+			}
+			if r == nil {
+				m.logEvent(slog.LevelWarn, "miss", fr.Name, "not found")
+				continue
+			}
+			if r.Empty && excludeEmpty {
+				if m.DryRun {
+					m.logEvent(slog.LevelInfo, "skip", fr.Name, "empty")
+				}
+				continue
+			}
+			if r.Archived && !includeArchived {
+				if m.DryRun {
+					m.logEvent(slog.LevelInfo, "skip", fr.Name, "archived")
+				}
+				continue
+			}
+			jobs = append(jobs, cloneJob{
+				cloneURL: pickCloneURL(r, cloneOpts.Protocol),
+				repoPath: dest,
+				repoName: fr.Name,
+				sparse:   fr.Sparse,
+				ref:      fr.Ref,
+				auth:     authFor(m.config.Providers[p]),
+				hook:     hookRepo{path: dest, name: name, target: t.Name, org: owner, provider: p},
+			})
+		}
+
+		if m.DryRun {
+			for _, job := range jobs {
+				reason := "foldout " + job.repoName + ", from " + job.cloneURL
+				if len(job.sparse) > 0 {
+					reason += ", sparse " + strings.Join(job.sparse, ", ")
+				}
+				if job.ref != "" {
+					reason += ", at " + job.ref
+				}
+				m.printPlan(job.repoPath, "would-clone", reason)
+				m.emit(RepoResult{Path: job.repoPath, Target: t.Name, Name: job.repoName, Result: "would-clone", Message: reason})
+			}
+		} else if len(jobs) > 0 {
+			m.logf(slog.LevelInfo, "Foldout: cloning %d repos under %s", len(jobs), dir)
+			total += len(jobs)
+			runJobs(m, jobs, workers, func(m *Manager, job cloneJob) cloneResult {
+				if m.pastDeadline() {
+					return m.emitClone(t, job, cloneResult{repoName: job.repoName, status: "skipped", err: ErrDeadline})
+				}
+				if err := m.git.Clone(job.cloneURL, job.repoPath, job.auth, cloneOpts); err != nil {
+					return m.emitClone(t, job, cloneResult{repoName: job.repoName, status: "error", err: err})
+				}
+				if len(job.sparse) > 0 && cloneOpts.Mode != "mirror" {
+					if err := gitSparseCheckout(job.repoPath, job.sparse, job.auth); err != nil {
+						return m.emitClone(t, job, cloneResult{repoName: job.repoName, status: "error", err: fmt.Errorf("cloned, but sparse-checkout failed: %w", err)})
+					}
+				}
+				if job.ref != "" && cloneOpts.Mode != "mirror" {
+					if err := checkoutPinnedRef(job.repoPath, job.ref, job.auth); err != nil {
+						return m.emitClone(t, job, cloneResult{repoName: job.repoName, status: "error", err: fmt.Errorf("cloned, but checking out %s failed: %w", job.ref, err)})
+					}
+				}
+				return m.emitClone(t, job, cloneResult{repoName: job.repoName, status: "cloned"})
+			}, func(r cloneResult) {
+				switch r.status {
+				case "cloned":
+					m.logEvent(slog.LevelInfo, "cloned", r.repoName, "")
+				case "skipped":
+					m.logEvent(slog.LevelWarn, "skip", r.repoName, "%v", r.err)
+				default:
+					m.logEvent(slog.LevelError, "error", r.repoName, "%v", r.err)
+					failed++
+				}
+			})
+		}
+
+		for _, fr := range fc.Repos {
+			p, owner, name := fr.source(provider)
+			dest := filepath.Join(dir, fr.Target)
+			var child *foldoutConfig
+			var err error
+			switch {
+			case isGitRepo(dest):
+				child, err = loadFoldout(dest)
+			case m.DryRun:
+				child, err = fetchFoldout(m.providers[p], owner, name)
+			}
+			if err != nil {
+				return fmt.Errorf("foldout %s: %w", fr.Name, err)
+			}
+			if err := cloneLevel(dest, p, child, append(chain[:len(chain):len(chain)], foldoutKey(p, owner, name))); err != nil {
+				return err
+			}
 		}
-		jobs = append(jobs, cloneJob{
-			cloneURL: pickCloneURL(r, m.config.Providers[t.Provider].Options.Clone.Protocol),
-			repoPath: dest,
-			repoName: fr.Name,
-		})
-	}
-
-	if len(jobs) == 0 {
 		return nil
 	}
-	fmt.Printf("Foldout: cloning %d repos under %s\n", len(jobs), t.Path)
-	results := pool.Run(jobs, workers, func(job cloneJob) cloneResult {
-		cmd := exec.Command("git", "clone", job.cloneURL, job.repoPath)
-		cmd.Env = gitEnvWithAuth(token)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return cloneResult{repoName: job.repoName, status: "error", err: fmt.Errorf("%v: %s", err, output)}
-		}
-		return cloneResult{repoName: job.repoName, status: "cloned"}
-	})
-	for _, r := range results {
-		if r.status == "cloned" {
-			fmt.Printf("  [CLONED] %s\n", r.repoName)
-		} else {
-			fmt.Printf("  [ERROR]  %s: %v\n", r.repoName, r.err)
-		}
+	if err := cloneLevel(t.Path, t.Provider, fc, []string{foldoutKey(t.Provider, t.Owner(), t.Repo)}); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return &RepoFailures{Failed: failed, Total: total}
 	}
 	return nil
 }
 
+// cloneOptionsFor resolves a target's clone options, applying the Mirror
+// override.
+func (m *Manager) cloneOptionsFor(t config.Target) config.CloneOptions {
+	opts := m.config.CloneOptionsFor(t)
+	if m.Mirror {
+		opts.Mode = "mirror"
+	}
+	return opts
+}
+
 func pickCloneURL(r *remote.Repository, protocol string) string {
 	switch protocol {
 	case "ssh":
@@ -409,7 +1236,8 @@ type statusJob struct {
 	name     string
 	org      string
 	provider string
-	token    string
+	ref      string // foldouts: the pinned ref
+	auth     gitAuth
 }
 
 type statusResult struct {
@@ -418,6 +1246,9 @@ type statusResult struct {
 }
 
 func (m *Manager) Status(targetNames []string, debug bool, workers int) error {
+	if !validStatusSort(m.Sort) {
+		return fmt.Errorf("unknown sort %q (want name, target, behind, ahead or mtime)", m.Sort)
+	}
 	targets, err := m.targetsFor(targetNames)
 	if err != nil {
 		return err
@@ -426,50 +1257,145 @@ func (m *Manager) Status(targetNames []string, debug bool, workers int) error {
 	if err != nil {
 		return err
 	}
+	if m.Detail {
+		for i, s := range statuses {
+			if !s.Dirty || s.Error != "" {
+				continue
+			}
+			changes, err := dirtyFiles(s.Path)
+			if err != nil {
+				statuses[i].Error = fmt.Sprintf("listing changes: %v", err)
+				continue
+			}
+			statuses[i].Changes = changes
+			statuses[i].Staged, statuses[i].Modified, statuses[i].Untracked = countChanges(changes)
+		}
+	}
+	if m.AllBranches {
+		for i, s := range statuses {
+			if s.Error != "" || s.Mirror {
+				continue
+			}
+			unpushed, err := unpushedBranches(s.Path)
+			if err != nil {
+				statuses[i].Error = err.Error()
+				continue
+			}
+			statuses[i].UnpushedBranches = unpushed
+		}
+	}
+	sortStatuses(statuses, m.Sort)
+	shown := statuses
+	if m.ProblemsOnly {
+		shown = problemStatuses(statuses)
+	}
+	if m.Format != nil {
+		if err := writeFormatted(m.out(), m.Format, shown); err != nil {
+			return err
+		}
+		return statusOutcome(statuses)
+	}
+	if m.Output != "" {
+		if err := writeRows(m.out(), shown, m.Output); err != nil {
+			return err
+		}
+		return statusOutcome(statuses)
+	}
+	if m.JSON {
+		if shown == nil {
+			shown = []RepoStatus{}
+		}
+		if err := m.writeJSON(shown); err != nil {
+			return err
+		}
+		return statusOutcome(statuses)
+	}
 
 	var clean, dirty, ahead, behind, diverged, errored int
 	for _, s := range statuses {
 		if s.Error != "" {
-			fmt.Printf("  [ERROR]    %s: %s\n", s.Path, s.Error)
+			m.printf("  [ERROR]    %s: %s\n", s.Path, s.Error)
 			errored++
 			continue
 		}
 
 		var flags []string
 		if s.Dirty {
-			flags = append(flags, "dirty")
+			if s.Changes != nil {
+				flags = append(flags, m.paint(colorYellow, fmt.Sprintf("dirty: %d staged, %d modified, %d untracked", s.Staged, s.Modified, s.Untracked)))
+			} else {
+				flags = append(flags, m.paint(colorYellow, "dirty"))
+			}
 			dirty++
 		}
 		if s.Ahead > 0 {
-			flags = append(flags, fmt.Sprintf("%d ahead", s.Ahead))
+			flags = append(flags, m.paint(colorCyan, fmt.Sprintf("%d ahead", s.Ahead)))
 			ahead++
 		}
 		if s.Behind > 0 {
-			flags = append(flags, fmt.Sprintf("%d behind", s.Behind))
+			flags = append(flags, m.paint(colorRed, fmt.Sprintf("%d behind", s.Behind)))
 			behind++
 			if !s.CanFastForward {
-				flags = append(flags, "diverged")
+				flags = append(flags, m.paint(colorRed, "diverged"))
 				diverged++
 			}
 		}
+		if s.UpstreamBehind > 0 {
+			flags = append(flags, m.paint(colorYellow, fmt.Sprintf("%d upstream-behind", s.UpstreamBehind)))
+		}
 		if s.RemoteError != "" {
-			flags = append(flags, "remote: "+s.RemoteError)
+			flags = append(flags, m.paint(colorRed, "remote: "+s.RemoteError))
+		}
+		if len(s.SubmoduleDrift) > 0 {
+			flags = append(flags, m.paint(colorYellow, fmt.Sprintf("%d submodules drifted", len(s.SubmoduleDrift))))
+		}
+		if n := len(s.UnpushedBranches); n == 1 {
+			flags = append(flags, m.paint(colorCyan, "1 branch unpushed"))
+		} else if n > 1 {
+			flags = append(flags, m.paint(colorCyan, fmt.Sprintf("%d branches unpushed", n)))
+		}
+		if s.Stashes > 0 {
+			flags = append(flags, m.paint(colorYellow, fmt.Sprintf("%d stashed", s.Stashes)))
+		}
+		if s.DefaultDrift != "" {
+			flags = append(flags, m.paint(colorYellow, fmt.Sprintf("default moved %s -> %s", s.DefaultDrift, s.DefaultBranch)))
 		}
 		if s.Archived {
 			flags = append(flags, "archived")
 		}
 		if s.Orphan {
-			flags = append(flags, "orphan")
+			flags = append(flags, m.paint(colorMagenta, "orphan"))
 		}
 		if len(flags) > 0 {
-			fmt.Printf("  %s (%s) [%s]\n", s.Path, s.Branch, strings.Join(flags, ", "))
+			if s.Mirror {
+				flags = append(flags, "mirror")
+			}
+			m.printf("  %s (%s) [%s]%s\n", s.Path, s.Branch, strings.Join(flags, ", "), syncNote(s))
+			for i, c := range s.Changes {
+				if i == maxDetailFiles {
+					m.printf("      ... and %d more\n", len(s.Changes)-i)
+					break
+				}
+				m.printf("      %-10s %s\n", c.State, c.Path)
+			}
+			for _, b := range s.UnpushedBranches {
+				m.printf("      %s: %s\n", b.Name, m.paint(colorCyan, fmt.Sprintf("%d unpushed", b.Commits)))
+			}
+		} else if m.ProblemsOnly {
+			clean++
+		} else if s.Mirror {
+			m.printf("  %s %s\n", m.paint(colorGreen, "[MIRROR]"), s.Path)
+			clean++
 		} else {
-			fmt.Printf("  [CLEAN]  %s\n", s.Path)
+			m.printf("  [CLEAN]  %s%s\n", s.Path, syncNote(s))
 			clean++
 		}
 	}
 
-	fmt.Printf("\nSummary: %d clean, %d dirty, %d ahead, %d behind, %d diverged, %d errors\n",
+	if len(statuses) > 0 && statuses[0].Offline {
+		m.printf("\nOffline: ahead/behind as of the last fetch; archived and orphan repos not marked\n")
+	}
+	m.printf("\nSummary: %d clean, %d dirty, %d ahead, %d behind, %d diverged, %d errors\n",
 		clean, dirty, ahead, behind, diverged, errored)
 
 	if debug && len(timings) > 0 {
@@ -477,37 +1403,188 @@ func (m *Manager) Status(targetNames []string, debug bool, workers int) error {
 		for _, t := range timings {
 			totalTime += t.Total
 		}
-		fmt.Printf("\nDebug: %d repos, total time %v\n", len(timings), totalTime)
+		m.printf("\nDebug: %d repos, total time %v\n", len(timings), totalTime)
+	}
+	return statusOutcome(statuses)
+}
+
+// problemStatuses returns the statuses that status flags in some way, the
+// ones --problems keeps.
+func problemStatuses(statuses []RepoStatus) []RepoStatus {
+	var problems []RepoStatus
+	for _, s := range statuses {
+		if s.Error != "" || s.RemoteError != "" || s.Dirty || s.Ahead > 0 || s.Behind > 0 ||
+			s.UpstreamBehind > 0 || len(s.SubmoduleDrift) > 0 || s.DefaultDrift != "" || s.Archived ||
+			s.Orphan || s.Stashes > 0 || len(s.UnpushedBranches) > 0 {
+			problems = append(problems, s)
+		}
+	}
+	return problems
+}
+
+// ErrNotClean is returned by Status when every repo was read but some are
+// dirty, ahead or behind their upstream, or (with AllBranches) have local
+// branches with unpushed commits.
+var ErrNotClean = errors.New("some repositories are not clean")
+
+// RepoFailures is returned by bulk commands when some repos failed. Each
+// failure has already been reported on its own line.
+type RepoFailures struct {
+	Failed int
+	Total  int
+}
+
+func (e *RepoFailures) Error() string {
+	return fmt.Sprintf("%d of %d repositories failed", e.Failed, e.Total)
+}
+
+// statusOutcome returns the error Status reports for statuses: failures
+// (including failed fetches) take precedence over repos that are not clean.
+func statusOutcome(statuses []RepoStatus) error {
+	var failed, unclean int
+	for _, s := range statuses {
+		switch {
+		case s.Error != "" || s.RemoteError != "":
+			failed++
+		case s.Dirty || s.Ahead > 0 || s.Behind > 0 || len(s.UnpushedBranches) > 0:
+			unclean++
+		}
+	}
+	if failed > 0 {
+		return &RepoFailures{Failed: failed, Total: len(statuses)}
+	}
+	if unclean > 0 {
+		return ErrNotClean
 	}
 	return nil
 }
 
+// Statuses returns the status of every local repo of the named targets (all
+// targets when none are named). Targets whose path does not exist yet are
+// skipped.
+func (m *Manager) Statuses(targetNames []string, workers int) ([]RepoStatus, error) {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return nil, err
+	}
+	var existingTargets []config.Target
+	for _, t := range targets {
+		if _, err := os.Stat(t.Path); err == nil {
+			existingTargets = append(existingTargets, t)
+		}
+	}
+	statuses, _, err := m.getAllStatuses(existingTargets, false, workers)
+	return statuses, err
+}
+
+// RefreshStatus re-reads the status of a single repo, keeping the remote
+// metadata (default branch, archived, orphan) from s.
+func (m *Manager) RefreshStatus(s RepoStatus) RepoStatus {
+	refreshed := getRepoStatus(m.git, s.Path, s.Target, s.Org, s.Name, s.Provider, authFor(m.providerFor(s.Target)), true, nil)
+	refreshed.DefaultBranch = s.DefaultBranch
+	refreshed.Archived = s.Archived
+	refreshed.Orphan = s.Orphan
+	return refreshed
+}
+
 func (m *Manager) getAllStatuses(targets []config.Target, debug bool, workers int) ([]RepoStatus, []RepoTiming, error) {
+	jobs, orgKeys, err := m.localRepos(targets)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil, nil
+	}
+
+	// The remote index drives archived/orphan marking and target filters.
+	offline := m.Offline
+	var index map[string]map[string]remote.Repository
+	if len(orgKeys) > 0 && !m.NoFetch && !offline {
+		idx, err := m.buildRepoIndex(orgKeys)
+		switch {
+		case err == nil:
+			index = idx
+			jobs = m.selectStatusJobs(jobs, index)
+		case isNetworkError(err):
+			m.logf(slog.LevelWarn, "Working offline, the provider API is unreachable: %v", err)
+			offline = true
+		}
+	}
+
+	// Dry runs plan from the remote-tracking refs of the last fetch, so they
+	// run no git command that changes the repo.
+	fetch := !m.NoFetch && !offline && !m.DryRun
+	results := pool.Run(jobs, workers, func(job statusJob) statusResult {
+		var timing RepoTiming
+		status := getRepoStatus(m.git, job.path, job.target, job.org, job.name, job.provider, job.auth, fetch, &timing)
+		status.Ref = job.ref
+		m.recordStatus(&status, fetch)
+		return statusResult{status: status, timing: timing}
+	})
+
+	m.saveState()
+
+	statuses := make([]RepoStatus, len(results))
+	timings := make([]RepoTiming, len(results))
+	for i, r := range results {
+		statuses[i] = r.status
+		statuses[i].Offline = offline
+		timings[i] = r.timing
+	}
+
+	// mark archived/orphan
+	if index != nil {
+		markRemoteState(statuses, index)
+		markDefaultDrift(statuses)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Target == statuses[j].Target {
+			return statuses[i].Name < statuses[j].Name
+		}
+		return statuses[i].Target < statuses[j].Target
+	})
+
+	if debug {
+		sort.Slice(timings, func(i, j int) bool {
+			return timings[i].Total > timings[j].Total
+		})
+	}
+
+	return statuses, timings, nil
+}
+
+// isNetworkError reports whether err comes from failing to reach a host at
+// all (DNS, connect, timeout) rather than from an API response.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// localRepos finds the local checkouts of targets (org/user repos matching
+// the target's name filters, repo targets and their foldouts) without touching
+// the network, and the owners whose remote listing describes them.
+func (m *Manager) localRepos(targets []config.Target) ([]statusJob, []orgKey, error) {
 	var jobs []statusJob
 	var orgKeys []orgKey
 	orgKeySet := make(map[string]bool)
 
 	for _, t := range targets {
-		tok := m.config.Providers[t.Provider].Token
+		auth := authFor(m.config.Providers[t.Provider])
 		if t.Repo == "" {
 			if _, err := os.Stat(t.Path); os.IsNotExist(err) {
 				return nil, nil, fmt.Errorf("target %q path does not exist: %s", t.Name, t.Path)
 			}
-			entries, err := os.ReadDir(t.Path)
+			repoPaths, err := targetRepoDirs(t)
 			if err != nil {
 				continue
 			}
-			for _, entry := range entries {
-				if !entry.IsDir() {
-					continue
-				}
-				repoPath := filepath.Join(t.Path, entry.Name())
-				if !isGitRepo(repoPath) {
-					continue
-				}
-				jobs = append(jobs, statusJob{path: repoPath, target: t.Name, name: entry.Name(), org: t.Org, provider: t.Provider, token: tok})
+			for _, repoPath := range repoPaths {
+				jobs = append(jobs, statusJob{path: repoPath, target: t.Name, name: filepath.Base(repoPath), org: t.Owner(), provider: t.Provider, auth: auth})
 			}
-			okey := orgKey{provider: t.Provider, org: t.Org}
+			okey := orgKey{provider: t.Provider, org: t.Owner(), user: t.IsUser()}
 			if !orgKeySet[okey.string()] {
 				orgKeys = append(orgKeys, okey)
 				orgKeySet[okey.string()] = true
@@ -517,34 +1594,26 @@ func (m *Manager) getAllStatuses(targets []config.Target, debug bool, workers in
 				return nil, nil, fmt.Errorf("target %q path does not exist: %s", t.Name, t.Path)
 			}
 			if isGitRepo(t.Path) {
-				jobs = append(jobs, statusJob{path: t.Path, target: t.Name, name: t.Repo, org: t.Org, provider: t.Provider, token: tok})
+				jobs = append(jobs, statusJob{path: t.Path, target: t.Name, name: t.Repo, org: t.Owner(), provider: t.Provider, auth: auth})
 			}
-			// foldout
-			fc, err := loadFoldout(t.Path)
+			// foldouts, nested ones included
+			foldouts, err := foldoutTree(t)
 			if err != nil {
 				return nil, nil, err
 			}
-			if fc != nil {
-				for _, fr := range fc.Repos {
-					dest := filepath.Join(t.Path, fr.Target)
-					if isGitRepo(dest) {
-						parts := strings.Split(fr.Name, "/")
-						repoName := parts[len(parts)-1]
-						frOrg := t.Org
-						if len(parts) == 2 {
-							frOrg = parts[0]
-						}
-						jobs = append(jobs, statusJob{path: dest, target: t.Name, name: repoName, org: frOrg, provider: t.Provider, token: tok})
-						okey := orgKey{provider: t.Provider, org: frOrg}
-						if !orgKeySet[okey.string()] {
-							orgKeys = append(orgKeys, okey)
-							orgKeySet[okey.string()] = true
-						}
+			for _, fr := range foldouts {
+				if isGitRepo(fr.path) {
+					frAuth := authFor(m.config.Providers[fr.provider])
+					jobs = append(jobs, statusJob{path: fr.path, target: t.Name, name: fr.name, org: fr.owner, provider: fr.provider, ref: fr.Ref, auth: frAuth})
+					okey := orgKey{provider: fr.provider, org: fr.owner, user: fr.provider == t.Provider && t.IsUser() && fr.owner == t.User}
+					if !orgKeySet[okey.string()] {
+						orgKeys = append(orgKeys, okey)
+						orgKeySet[okey.string()] = true
 					}
 				}
 			}
 			// Collect orgKey for single-repo targets too (for orphan/archived detection)
-			okey := orgKey{provider: t.Provider, org: t.Org}
+			okey := orgKey{provider: t.Provider, org: t.Owner(), user: t.IsUser()}
 			if !orgKeySet[okey.string()] {
 				orgKeys = append(orgKeys, okey)
 				orgKeySet[okey.string()] = true
@@ -552,44 +1621,46 @@ func (m *Manager) getAllStatuses(targets []config.Target, debug bool, workers in
 		}
 	}
 
-	if len(jobs) == 0 {
-		return nil, nil, nil
-	}
-
-	results := pool.Run(jobs, workers, func(job statusJob) statusResult {
-		var timing RepoTiming
-		status := getRepoStatus(job.path, job.target, job.org, job.name, job.provider, job.token, &timing)
-		return statusResult{status: status, timing: timing}
-	})
+	return jobs, orgKeys, nil
+}
 
-	statuses := make([]RepoStatus, len(results))
-	timings := make([]RepoTiming, len(results))
-	for i, r := range results {
-		statuses[i] = r.status
-		timings[i] = r.timing
-	}
+// targetRepoDirs returns the paths of the local repos of org or user target
+// t that pass its name filters: the repos directly under its path or, for
+// recurse targets, in subdirectories down to its scan depth. Repos are not
+// searched for nested repos, and hidden and <repo>.worktrees directories are
+// not descended into.
+func targetRepoDirs(t config.Target) ([]string, error) {
+	return findRepoDirs(t.Path, t.ScanDepth(), t.MatchesName)
+}
 
-	// mark archived/orphan
-	if len(orgKeys) > 0 {
-		if index, err := m.buildRepoIndex(orgKeys); err == nil {
-			markRemoteState(statuses, index)
+// findRepoDirs returns the git repos in root and its subdirectories down to
+// depth levels whose directory name passes match.
+func findRepoDirs(root string, depth int, match func(name string) bool) ([]string, error) {
+	var repos []string
+	var scan func(dir string, level int) error
+	scan = func(dir string, level int) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
 		}
-	}
-
-	sort.Slice(statuses, func(i, j int) bool {
-		if statuses[i].Target == statuses[j].Target {
-			return statuses[i].Name < statuses[j].Name
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			p := filepath.Join(dir, e.Name())
+			if isGitRepo(p) {
+				if match(e.Name()) {
+					repos = append(repos, p)
+				}
+				continue
+			}
+			if level < depth && !strings.HasPrefix(e.Name(), ".") && !strings.HasSuffix(e.Name(), ".worktrees") {
+				_ = scan(p, level+1) // unreadable subdirectories are skipped
+			}
 		}
-		return statuses[i].Target < statuses[j].Target
-	})
-
-	if debug {
-		sort.Slice(timings, func(i, j int) bool {
-			return timings[i].Total > timings[j].Total
-		})
+		return nil
 	}
-
-	return statuses, timings, nil
+	return repos, scan(root, 1)
 }
 
 // ------------ auth helpers --------------
@@ -620,15 +1691,85 @@ func gitEnvWithAuth(token string) []string {
 	return env
 }
 
+// gitAuth is how git commands reach and authenticate to one provider: the
+// token for HTTPS remotes, an optional ssh command for SSH remotes, and the
+// proxy and TLS settings of the provider.
+type gitAuth struct {
+	token      string
+	sshCommand string
+	proxy      string
+	caFile     string
+	insecure   bool
+	timeouts   config.TimeoutOptions
+	deadline   time.Time // the run's Deadline, set by deadlineBackend
+}
+
+// authFor returns the git authentication of provider p.
+func authFor(p config.Provider) gitAuth {
+	return gitAuth{
+		token:      p.Token,
+		sshCommand: p.GitSSHCommand(),
+		proxy:      p.Proxy,
+		caFile:     p.CACertFile,
+		insecure:   p.InsecureSkipVerify,
+		timeouts:   p.Options.Timeouts,
+	}
+}
+
+// env returns gitEnvWithAuth(a.token), plus GIT_SSH_COMMAND when the
+// provider sets an ssh key or command, and the variables git's HTTP
+// transport reads for the provider's proxy and TLS settings. Later entries
+// win over inherited ones.
+func (a gitAuth) env() []string {
+	env := gitEnvWithAuth(a.token)
+	if a.sshCommand != "" {
+		env = append(env, "GIT_SSH_COMMAND="+a.sshCommand)
+	}
+	if a.proxy != "" {
+		env = append(env, "http_proxy="+a.proxy, "https_proxy="+a.proxy, "HTTPS_PROXY="+a.proxy)
+	}
+	if a.caFile != "" {
+		env = append(env, "GIT_SSL_CAINFO="+a.caFile)
+	}
+	if a.insecure {
+		env = append(env, "GIT_SSL_NO_VERIFY=true")
+	}
+	return env
+}
+
 // ------------ git helpers --------------
 
+// isGitRepo reports whether path is a working tree or a bare repository.
+// Besides a .git directory, a working tree may have a .git file pointing at
+// the repository elsewhere, as linked worktrees and submodule checkouts do.
 func isGitRepo(path string) bool {
 	gitDir := filepath.Join(path, ".git")
 	info, err := os.Stat(gitDir)
-	return err == nil && info.IsDir()
+	switch {
+	case err == nil && info.IsDir():
+		return true
+	case err == nil && info.Mode().IsRegular():
+		data, err := os.ReadFile(gitDir)
+		return err == nil && strings.HasPrefix(string(data), "gitdir: ")
+	}
+	return isBareRepo(path)
 }
 
-func getRepoStatus(path, target, org, name, provider, token string, timing *RepoTiming) RepoStatus {
+// isBareRepo reports whether path is a repository without a working tree,
+// such as a --mirror clone.
+func isBareRepo(path string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			return false
+		}
+	}
+	_, err := os.Stat(filepath.Join(path, ".git"))
+	return os.IsNotExist(err)
+}
+
+// getRepoStatus reads the state of one local repo. Without fetch it compares
+// against the remote refs of the last fetch.
+func getRepoStatus(git gitBackend, path, target, org, name, provider string, auth gitAuth, fetch bool, timing *RepoTiming) RepoStatus {
 	totalStart := time.Now()
 	status := RepoStatus{
 		Path:     path,
@@ -640,7 +1781,7 @@ func getRepoStatus(path, target, org, name, provider, token string, timing *Repo
 
 	// Get current branch
 	branchStart := time.Now()
-	branch, err := gitOutput(path, "rev-parse", "--abbrev-ref", "HEAD")
+	branch, err := git.CurrentBranch(path)
 	if timing != nil {
 		timing.Branch = time.Since(branchStart)
 	}
@@ -648,12 +1789,33 @@ func getRepoStatus(path, target, org, name, provider, token string, timing *Repo
 		status.Error = fmt.Sprintf("getting branch: %v", err)
 		return status
 	}
-	status.Branch = strings.TrimSpace(branch)
+	status.Branch = branch
+
+	// Mirrors have no working tree or upstream to compare against; they are
+	// only updated by sync and pull.
+	if isBareRepo(path) {
+		status.Mirror = true
+		status.CanFastForward = true
+		if timing != nil {
+			timing.Total = time.Since(totalStart)
+			timing.Path = path
+		}
+		return status
+	}
 
 	// Fetch from remote
 	fetchStart := time.Now()
-	if fetchErr := gitFetchWithStderr(path, token); fetchErr != "" {
-		status.RemoteError = fetchErr
+	if fetch {
+		if fetchErr := git.Fetch(path, auth); fetchErr != nil {
+			status.RemoteError = fetchErr.Error()
+		}
+	}
+	if hasRemote(path, "upstream") {
+		behind, err := upstreamBehind(git, path, auth, fetch)
+		if err != nil && status.RemoteError == "" {
+			status.RemoteError = "upstream: " + err.Error()
+		}
+		status.UpstreamBehind = behind
 	}
 	if timing != nil {
 		timing.Fetch = time.Since(fetchStart)
@@ -661,7 +1823,7 @@ func getRepoStatus(path, target, org, name, provider, token string, timing *Repo
 
 	// Check for uncommitted changes
 	statusStart := time.Now()
-	dirtyOutput, err := gitOutput(path, "status", "--porcelain")
+	dirty, err := git.IsDirty(path)
 	if timing != nil {
 		timing.Status = time.Since(statusStart)
 	}
@@ -669,21 +1831,27 @@ func getRepoStatus(path, target, org, name, provider, token string, timing *Repo
 		status.Error = fmt.Sprintf("checking status: %v", err)
 		return status
 	}
-	status.Dirty = strings.TrimSpace(dirtyOutput) != ""
+	status.Dirty = dirty
+
+	if _, err := os.Stat(filepath.Join(path, ".gitmodules")); err == nil {
+		if drift, err := git.SubmoduleDrift(path); err == nil {
+			status.SubmoduleDrift = drift
+		}
+	}
+	if stashes, err := git.StashCount(path); err == nil {
+		status.Stashes = stashes
+	}
 
 	// Get ahead/behind counts
 	revListStart := time.Now()
 	upstream := fmt.Sprintf("origin/%s", status.Branch)
-	revList, err := gitOutput(path, "rev-list", "--left-right", "--count", fmt.Sprintf("%s...%s", status.Branch, upstream))
+	ahead, behind, err := git.AheadBehind(path, status.Branch, upstream)
 	if timing != nil {
 		timing.RevList = time.Since(revListStart)
 	}
 	if err == nil {
-		parts := strings.Fields(strings.TrimSpace(revList))
-		if len(parts) == 2 {
-			fmt.Sscanf(parts[0], "%d", &status.Ahead)
-			fmt.Sscanf(parts[1], "%d", &status.Behind)
-		}
+		status.Ahead = ahead
+		status.Behind = behind
 	} else if status.RemoteError == "" {
 		// rev-list failed after a successful fetch — the upstream ref is gone.
 		status.UpstreamGone = true
@@ -691,8 +1859,7 @@ func getRepoStatus(path, target, org, name, provider, token string, timing *Repo
 
 	mergeBaseStart := time.Now()
 	if status.Behind > 0 {
-		err := gitRun(path, "merge-base", "--is-ancestor", status.Branch, upstream)
-		status.CanFastForward = (err == nil) || (status.Ahead == 0)
+		status.CanFastForward = git.IsAncestor(path, status.Branch, upstream) || (status.Ahead == 0)
 	} else {
 		status.CanFastForward = true
 	}
@@ -720,67 +1887,62 @@ func gitRun(repoPath string, args ...string) error {
 	return cmd.Run()
 }
 
-func gitFetchWithStderr(repoPath, token string) string {
-	cmd := exec.Command("git", "fetch", "--quiet")
+// gitSparseCheckout limits the working tree of repoPath to dirs (plus the
+// files at the top level) with a cone-mode sparse checkout. Partial clones
+// fetch the blobs of dirs here, hence auth.
+func gitSparseCheckout(repoPath string, dirs []string, auth gitAuth) error {
+	cmd := exec.Command("git", append([]string{"sparse-checkout", "set", "--cone"}, dirs...)...)
 	cmd.Dir = repoPath
-	cmd.Env = gitEnvWithAuth(token)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		output := strings.TrimSpace(stderr.String())
-		if idx := strings.Index(output, "\n"); idx > 0 {
-			output = output[:idx]
-		}
-		return output
+	cmd.Env = auth.env()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
 	}
-	return ""
+	return nil
 }
 
-// Pull/Push helpers used by sync-like commands
-func gitPull(repoPath string, ffOnly bool, token string) error {
-	args := []string{"pull"}
+// Pull/Push helpers used by sync-like commands. They go through the git
+// backend so the per-host network limit applies, and write git's output to
+// the error output when they fail.
+func (m *Manager) gitPull(repoPath string, ffOnly bool, auth gitAuth) error {
+	var args []string
 	if ffOnly {
 		args = append(args, "--ff-only")
 	}
-	cmd := exec.Command("git", args...)
-	cmd.Dir = repoPath
-	cmd.Env = gitEnvWithAuth(token)
-	out, err := cmd.CombinedOutput()
+	out, err := m.git.Pull(repoPath, auth, args...)
 	if err != nil {
-		os.Stderr.Write(out)
+		m.errOut().Write(out)
 	}
 	return err
 }
 
-func gitPullRebase(repoPath string, token string) error {
-	cmd := exec.Command("git", "pull", "--rebase=merges")
-	cmd.Dir = repoPath
-	cmd.Env = gitEnvWithAuth(token)
-	out, err := cmd.CombinedOutput()
+func (m *Manager) gitPullRebase(repoPath string, auth gitAuth) error {
+	out, err := m.git.Pull(repoPath, auth, "--rebase=merges")
 	if err != nil {
-		// Abort the rebase so the repo is not left in a broken mid-rebase state.
-		abort := exec.Command("git", "rebase", "--abort")
-		abort.Dir = repoPath
-		abort.Env = gitEnvNoPrompt()
-		abort.Run() // best-effort
-		os.Stderr.Write(out)
+		abortRebase(repoPath)
+		m.errOut().Write(out)
 	}
 	return err
 }
 
+// abortRebase aborts a failed rebase so the repo is not left in a broken
+// mid-rebase state. It is best-effort.
+func abortRebase(repoPath string) {
+	abort := exec.Command("git", "rebase", "--abort")
+	abort.Dir = repoPath
+	abort.Env = gitEnvNoPrompt()
+	abort.Run()
+}
+
 // gitPullWithFallback tries a normal pull (ff-only when requested) and, if
 // that fails because the branch has diverged, falls back to a rebase pull.
 // Returns (true, nil) when the fallback rebase succeeded.  If the rebase
 // itself fails (e.g. conflicts) it is aborted so the repo stays clean.
-func gitPullWithFallback(repoPath string, ffOnly bool, token string) (rebased bool, err error) {
-	args := []string{"pull"}
+func (m *Manager) gitPullWithFallback(repoPath string, ffOnly bool, auth gitAuth) (rebased bool, err error) {
+	var args []string
 	if ffOnly {
 		args = append(args, "--ff-only")
 	}
-	cmd := exec.Command("git", args...)
-	cmd.Dir = repoPath
-	cmd.Env = gitEnvWithAuth(token)
-	out, err := cmd.CombinedOutput()
+	out, err := m.git.Pull(repoPath, auth, args...)
 	if err == nil {
 		return false, nil
 	}
@@ -788,42 +1950,57 @@ func gitPullWithFallback(repoPath string, ffOnly bool, token string) (rebased bo
 	// specifically because branches have diverged.  Other failures (auth,
 	// network, missing remote, etc.) must not trigger a rebase attempt.
 	if !ffOnly || !strings.Contains(string(out), "Not possible to fast-forward") {
-		os.Stderr.Write(out)
+		m.errOut().Write(out)
 		return false, err
 	}
 	// Fallback: rebase with merge preservation.
-	cmd2 := exec.Command("git", "pull", "--rebase=merges")
-	cmd2.Dir = repoPath
-	cmd2.Env = gitEnvWithAuth(token)
-	out2, err2 := cmd2.CombinedOutput()
-	if err2 != nil {
-		// Abort the rebase so the repo is not left in a broken mid-rebase state.
-		abort := exec.Command("git", "rebase", "--abort")
-		abort.Dir = repoPath
-		abort.Env = gitEnvNoPrompt()
-		abort.Run() // best-effort
-		os.Stderr.Write(out2)
-		return false, err2
+	if err := m.gitPullRebase(repoPath, auth); err != nil {
+		return false, err
 	}
 	return true, nil
 }
 
-func gitPush(repoPath, token string) error {
-	cmd := exec.Command("git", "push")
+func (m *Manager) gitPush(repoPath string, auth gitAuth, args ...string) error {
+	out, err := m.git.Push(repoPath, auth, args...)
+	if err != nil {
+		m.errOut().Write(out)
+	}
+	return err
+}
+
+// isShallowRepo reports whether the repo was cloned with limited history.
+func isShallowRepo(repoPath string) (bool, error) {
+	out, err := gitOutput(repoPath, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) == "true", nil
+}
+
+// gitUnshallow fetches the full history. Shallow clones made with
+// --single-branch only fetch their one branch, so the fetch refspec is widened
+// to all branches first.
+func gitUnshallow(repoPath string, auth gitAuth) error {
+	if !isBareRepo(repoPath) {
+		if err := gitRun(repoPath, "config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+			return fmt.Errorf("widening the fetch refspec: %w", err)
+		}
+	}
+	cmd := exec.Command("git", "fetch", "--quiet", "--unshallow")
 	cmd.Dir = repoPath
-	cmd.Env = gitEnvWithAuth(token)
+	cmd.Env = auth.env()
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		os.Stderr.Write(out)
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
 	}
-	return err
+	return nil
 }
 
 // hasUpstreamRef fetches from origin and checks whether the current branch
 // has a corresponding remote-tracking ref. Returns (exists, branchName, error).
 // Returns an error if fetch fails, so callers can distinguish "verified missing"
 // from "could not verify".
-func hasUpstreamRef(repoPath, token string) (bool, string, error) {
+func hasUpstreamRef(repoPath string, auth gitAuth) (bool, string, error) {
 	branch, err := gitOutput(repoPath, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return false, "", fmt.Errorf("getting branch: %w", err)
@@ -832,7 +2009,7 @@ func hasUpstreamRef(repoPath, token string) (bool, string, error) {
 	// Fetch with auth so HTTPS repos can authenticate.
 	cmd := exec.Command("git", "fetch", "--quiet")
 	cmd.Dir = repoPath
-	cmd.Env = gitEnvWithAuth(token)
+	cmd.Env = auth.env()
 	if err := cmd.Run(); err != nil {
 		return false, branch, fmt.Errorf("fetch failed: %w", err)
 	}
@@ -864,6 +2041,12 @@ func localBranchExists(repoPath, branch string) bool {
 	return gitRun(repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch) == nil
 }
 
+// hasConfiguredUpstream reports whether branch has an upstream configured,
+// which tells a branch whose remote was deleted from one never pushed.
+func hasConfiguredUpstream(repoPath, branch string) bool {
+	return gitRun(repoPath, "config", "--get", "branch."+branch+".remote") == nil
+}
+
 func remoteTrackingRefExists(repoPath, branch string) bool {
 	return gitRun(repoPath, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch) == nil
 }
@@ -911,7 +2094,22 @@ func switchToDefaultBranch(repoPath, branch, defaultBranch string) error {
 	if branch == defaultBranch {
 		return nil
 	}
+	if err := checkSwitchToDefaultBranch(repoPath, branch, defaultBranch); err != nil {
+		return err
+	}
+
+	if err := ensureLocalBranch(repoPath, defaultBranch); err != nil {
+		return err
+	}
+	if err := gitRun(repoPath, "switch", defaultBranch); err != nil {
+		return fmt.Errorf("git switch %s: %w", defaultBranch, err)
+	}
+	return nil
+}
 
+// checkSwitchToDefaultBranch runs the safety checks of switchToDefaultBranch
+// without touching the repo.
+func checkSwitchToDefaultBranch(repoPath, branch, defaultBranch string) error {
 	dirtyOutput, err := gitOutput(repoPath, "status", "--porcelain")
 	if err != nil {
 		return fmt.Errorf("checking status: %w", err)
@@ -937,17 +2135,47 @@ func switchToDefaultBranch(repoPath, branch, defaultBranch string) error {
 			return &updateSkipError{reason: fmt.Sprintf("on %s, commits are not on %s; not switching", branch, defaultBranch)}
 		}
 	}
+	return nil
+}
 
-	if err := ensureLocalBranch(repoPath, defaultBranch); err != nil {
-		return err
+// markRemoteState annotates archived/orphan based on remote index.
+// selectsRepo reports whether an org/user target's filters include r.
+func selectsRepo(t config.Target, r remote.Repository) bool {
+	return t.MatchesName(r.Name) && t.MatchesTopics(r.Topics) && t.MatchesFork(r.Fork) && t.MatchesVisibility(r.Private) && t.MatchesLanguage(r.Language)
+}
+
+// withFilters returns t with the Forks, Visibility and Languages overrides
+// applied.
+func (m *Manager) withFilters(t config.Target) config.Target {
+	if m.Forks != "" {
+		t.Forks = m.Forks
 	}
-	if err := gitRun(repoPath, "switch", defaultBranch); err != nil {
-		return fmt.Errorf("git switch %s: %w", defaultBranch, err)
+	if m.Visibility != "" {
+		t.Visibility = m.Visibility
 	}
-	return nil
+	if len(m.Languages) > 0 {
+		t.Languages = m.Languages
+	}
+	return t
+}
+
+// selectStatusJobs drops repos of org/user targets that the target's filters
+// exclude. Repos missing from the index are kept so they show up as orphans.
+func (m *Manager) selectStatusJobs(jobs []statusJob, index map[string]map[string]remote.Repository) []statusJob {
+	selected := jobs[:0]
+	for _, job := range jobs {
+		t := m.config.GetTargetByName(job.target)
+		if t != nil && t.Repo == "" {
+			key := orgKey{provider: job.provider, org: job.org}.string()
+			if r, ok := index[key][job.name]; ok && !selectsRepo(*t, r) {
+				continue
+			}
+		}
+		selected = append(selected, job)
+	}
+	return selected
 }
 
-// markRemoteState annotates archived/orphan based on remote index.
 func markRemoteState(statuses []RepoStatus, index map[string]map[string]remote.Repository) {
 	for i := range statuses {
 		key := orgKey{provider: statuses[i].Provider, org: statuses[i].Org}.string()
@@ -965,7 +2193,7 @@ func markRemoteState(statuses []RepoStatus, index map[string]map[string]remote.R
 	}
 }
 
-func (m *Manager) prepareRepoForDefaultBranch(s RepoStatus, token string) (RepoStatus, bool, error) {
+func (m *Manager) prepareRepoForDefaultBranch(s RepoStatus, auth gitAuth) (RepoStatus, bool, error) {
 	defaultBranch := strings.TrimSpace(s.DefaultBranch)
 	if defaultBranch != "" && s.Branch == defaultBranch {
 		return s, false, nil
@@ -991,103 +2219,159 @@ func (m *Manager) prepareRepoForDefaultBranch(s RepoStatus, token string) (RepoS
 		return s, false, &updateSkipError{reason: fmt.Sprintf("on %s, %d ahead; not updating non-default branch", s.Branch, s.Ahead)}
 	}
 
+	if m.DryRun {
+		// Report the switch as planned; the repo stays on its current branch.
+		if err := checkSwitchToDefaultBranch(s.Path, s.Branch, defaultBranch); err != nil {
+			return s, false, err
+		}
+		s.DefaultBranch = defaultBranch
+		return s, true, nil
+	}
+
 	if err := switchToDefaultBranch(s.Path, s.Branch, defaultBranch); err != nil {
 		return s, false, err
 	}
 
-	refreshed := getRepoStatus(s.Path, s.Target, s.Org, s.Name, s.Provider, token, nil)
+	refreshed := getRepoStatus(m.git, s.Path, s.Target, s.Org, s.Name, s.Provider, auth, true, nil)
 	refreshed.DefaultBranch = defaultBranch
 	refreshed.Archived = s.Archived
 	refreshed.Orphan = s.Orphan
 	return refreshed, true, nil
 }
 
-// TODO: implement sync/pull/push/list using the new target model.
-func (m *Manager) Pull(targetNames []string, workers int) error {
-	targets, err := m.targetsFor(targetNames)
+func (m *Manager) Pull(targetNames []string, workers int) (err error) {
+	finish := m.startRun("pull", targetNames)
+	defer func() {
+		err = m.deadlineOutcome(err)
+		finish(err)
+	}()
+	finishHooks, err := m.startHooks("pull", targetNames)
 	if err != nil {
 		return err
 	}
-
-	var existingTargets []config.Target
-	for _, t := range targets {
-		if _, err := os.Stat(t.Path); err == nil {
-			existingTargets = append(existingTargets, t)
-		}
-	}
-
-	statuses, _, err := m.getAllStatuses(existingTargets, false, workers)
+	defer finishHooks(&err)
+	statuses, err := m.Statuses(targetNames, workers)
 	if err != nil {
 		return err
 	}
 	if len(statuses) == 0 {
-		fmt.Println("Pull: no repositories found.")
+		if m.JSON {
+			return m.writeJSON([]RepoResult{})
+		}
+		m.logf(slog.LevelInfo, "Pull: no repositories found.")
 		return nil
 	}
 
-	optMap := make(map[string]config.ProviderOptions)
-	tokenMap := make(map[string]string)
-	for _, t := range targets {
-		optMap[t.Name] = m.config.Providers[t.Provider].Options
-		tokenMap[t.Name] = m.config.Providers[t.Provider].Token
+	results := make([]RepoResult, 0, len(statuses))
+	for _, s := range statuses {
+		r := m.withHooks("pull", s, m.PullRepo)
+		m.emit(r)
+		results = append(results, r)
 	}
+	m.notify("pull", statuses, results)
+	m.recordResults("pull", results)
 
-	var pulled, skipped, failed int
-	for _, s := range statuses {
-		opts := optMap[s.Target]
-		tok := tokenMap[s.Target]
+	if m.JSON {
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	pulled, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Pull dry run: %d to pull, %d skipped, %d failed", pulled, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Pull complete: %d pulled, %d skipped, %d failed", pulled, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
 
-		if s.Error != "" {
-			fmt.Printf("  [ERROR] %s: %s\n", s.Path, s.Error)
-			failed++
-			continue
+// PullRepo updates the default branch of one repo, switching onto it first
+// when that is safe. Foldouts pinned to a ref are moved to it instead.
+func (m *Manager) PullRepo(s RepoStatus) RepoResult {
+	if s.Mirror && s.Error == "" {
+		return m.updateMirror(s)
+	}
+	if s.Ref != "" {
+		return m.updatePinned(s)
+	}
+	p := m.providerFor(s.Target)
+	prepared, switchedFrom, done := m.beginUpdate(s, authFor(p), p.Options.Sync.Autostash)
+	if done != nil {
+		return *done
+	}
+
+	if m.DryRun {
+		result, reason := planUpdate(prepared, switchedFrom, p.Options.Sync.GetFFOnly())
+		if prepared.Dirty && prepared.Behind > 0 {
+			reason += ", autostash"
 		}
+		m.printPlan(prepared.Path, result, reason)
+		return switchedResult(prepared, switchedFrom, result, reason)
+	}
 
-		prepared, switched, err := m.prepareRepoForDefaultBranch(s, tok)
+	return m.withAutostash(prepared, switchedFrom, p.Options.Sync.Autostash, func() RepoResult {
+		rebased, err := m.gitPullWithFallback(prepared.Path, p.Options.Sync.GetFFOnly(), authFor(p))
 		if err != nil {
-			var skipErr *updateSkipError
-			if errors.As(err, &skipErr) {
-				fmt.Printf("  [SKIP]  %s: %s\n", s.Path, skipErr.reason)
-				skipped++
-				continue
-			}
-			fmt.Printf("  [ERROR] %s: %v\n", s.Path, err)
-			failed++
-			continue
+			m.logEvent(slog.LevelError, "error", prepared.Path, "%v", err)
+			return switchedResult(prepared, switchedFrom, "failed", err.Error())
 		}
-		if switched {
-			fmt.Printf("  [SWITCH] %s: %s -> %s\n", s.Path, s.Branch, prepared.DefaultBranch)
+		if err := m.updateSubmodules(prepared); err != nil {
+			m.logEvent(slog.LevelError, "error", prepared.Path, "%v", err)
+			return switchedResult(prepared, switchedFrom, "failed", err.Error())
 		}
-		if prepared.Error != "" {
-			fmt.Printf("  [ERROR] %s: %s\n", prepared.Path, prepared.Error)
-			failed++
-			continue
-		}
-		if prepared.Dirty {
-			fmt.Printf("  [SKIP]  %s: dirty\n", prepared.Path)
-			skipped++
-			continue
+		if rebased {
+			m.logEvent(slog.LevelInfo, "rebase", prepared.Path, "")
+			return switchedResult(prepared, switchedFrom, "rebased", "")
 		}
+		m.logEvent(slog.LevelInfo, "pull", prepared.Path, "")
+		return switchedResult(prepared, switchedFrom, "pulled", "")
+	})
+}
 
-		rebased, err := gitPullWithFallback(prepared.Path, opts.Sync.GetFFOnly(), tok)
-		if err != nil {
-			fmt.Printf("  [ERROR] %s: %v\n", prepared.Path, err)
-			failed++
-			continue
+// PullRemoteRepo pulls the local checkouts of the remote repo fullName
+// (owner/name) on provider, e.g. after a push webhook. defaultBranch, when
+// known, saves resolving it locally. It returns no results when the repo is
+// not managed.
+func (m *Manager) PullRemoteRepo(provider, fullName, defaultBranch string) ([]RepoResult, error) {
+	owner, name, ok := strings.Cut(fullName, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository name %q", fullName)
+	}
+	var targets []config.Target
+	for _, t := range m.config.Targets {
+		if _, err := os.Stat(t.Path); err == nil && t.Provider == provider {
+			targets = append(targets, t)
 		}
-		if rebased {
-			fmt.Printf("  [REBASE] %s\n", prepared.Path)
-		} else {
-			fmt.Printf("  [PULL]  %s\n", prepared.Path)
+	}
+	jobs, _, err := m.localRepos(targets)
+	if err != nil {
+		return nil, err
+	}
+	var results []RepoResult
+	for _, job := range jobs {
+		if !strings.EqualFold(job.org, owner) || !strings.EqualFold(job.name, name) {
+			continue
 		}
-		pulled++
+		s := getRepoStatus(m.git, job.path, job.target, job.org, job.name, job.provider, job.auth, true, nil)
+		s.DefaultBranch = defaultBranch
+		s.Ref = job.ref
+		results = append(results, m.PullRepo(s))
 	}
-
-	fmt.Printf("Pull complete: %d pulled, %d skipped, %d failed\n", pulled, skipped, failed)
-	return nil
+	return results, nil
 }
 
-func (m *Manager) Push(targetNames []string, workers int) error {
+func (m *Manager) Push(targetNames []string, workers int) (err error) {
+	finish := m.startRun("push", targetNames)
+	defer func() {
+		err = m.deadlineOutcome(err)
+		finish(err)
+	}()
+	finishHooks, err := m.startHooks("push", targetNames)
+	if err != nil {
+		return err
+	}
+	defer finishHooks(&err)
 	targets, err := m.targetsFor(targetNames)
 	if err != nil {
 		return err
@@ -1098,40 +2382,108 @@ func (m *Manager) Push(targetNames []string, workers int) error {
 		return err
 	}
 
-	// Build target -> token map for push authentication.
-	tokenMap := make(map[string]string)
-	for _, t := range targets {
-		tokenMap[t.Name] = m.config.Providers[t.Provider].Token
-	}
+	results := m.runRepos("push", statuses, workers, (*Manager).PushRepo)
+	m.notify("push", statuses, results)
+	m.recordResults("push", results)
 
-	var pushed, skipped, failed int
-	for _, s := range statuses {
-		if s.Error != "" {
-			fmt.Printf("  [ERROR] %s: %s\n", s.Path, s.Error)
-			failed++
-			continue
-		}
-		if s.Behind > 0 {
-			fmt.Printf("  [SKIP]  %s: behind remote, pull first\n", s.Path)
-			skipped++
-			continue
-		}
-		if s.Ahead == 0 {
-			continue
-		}
-		if err := gitPush(s.Path, tokenMap[s.Target]); err != nil {
-			fmt.Printf("  [ERROR] %s: %v\n", s.Path, err)
-			failed++
-		} else {
-			fmt.Printf("  [PUSH]  %s: %d commits\n", s.Path, s.Ahead)
-			pushed++
+	if m.JSON {
+		if err := m.writeJSON(results); err != nil {
+			return err
 		}
+		return resultsOutcome(results)
 	}
-	fmt.Printf("Push complete: %d pushed, %d skipped, %d failed\n", pushed, skipped, failed)
-	return nil
+	pushed, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Push dry run: %d to push, %d skipped, %d failed", pushed, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Push complete: %d pushed, %d skipped, %d failed", pushed, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
+
+// PushRepo pushes one repo when it is ahead and not behind its upstream.
+func (m *Manager) PushRepo(s RepoStatus) RepoResult {
+	if s.Error != "" {
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", s.Error)
+		return newResult(s, "failed", s.Error)
+	}
+	if s.Mirror {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "mirror clone")
+		return newResult(s, "skipped", "mirror clone")
+	}
+	if s.UpstreamGone && s.Branch != "HEAD" && !hasConfiguredUpstream(s.Path, s.Branch) {
+		return m.pushNewBranch(s)
+	}
+	force := s.Behind > 0 && s.Ahead > 0 && m.ForceWithLease
+	if force {
+		if t := m.config.GetTargetByName(s.Target); t == nil || !t.AllowForce {
+			m.logEvent(slog.LevelInfo, "skip", s.Path, "diverged; target does not set allow_force")
+			return newResult(s, "skipped", "diverged; target does not set allow_force")
+		}
+	} else if s.Behind > 0 {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "behind remote, pull first")
+		return newResult(s, "skipped", "behind remote, pull first")
+	}
+	if s.Ahead == 0 {
+		m.logEvent(slog.LevelDebug, "ok", s.Path, "nothing to push")
+		return newResult(s, "unchanged", "")
+	}
+	reason := fmt.Sprintf("%d commits", s.Ahead)
+	var args []string
+	if force {
+		reason = fmt.Sprintf("%d commits, replacing %d remote (force-with-lease)", s.Ahead, s.Behind)
+		args = append(args, "--force-with-lease")
+	}
+	if m.DryRun {
+		m.printPlan(s.Path, "would-push", reason)
+		return newResult(s, "would-push", reason)
+	}
+	if err := m.gitPush(s.Path, authFor(m.providerFor(s.Target)), args...); err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+	m.logEvent(slog.LevelInfo, "push", s.Path, "%s", reason)
+	return newResult(s, "pushed", "")
+}
+
+// pushNewBranch publishes a branch that was never pushed, when the provider
+// sets push.set_upstream.
+func (m *Manager) pushNewBranch(s RepoStatus) RepoResult {
+	p := m.providerFor(s.Target)
+	if !p.Options.Push.SetUpstream {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "%s has no upstream (set options.push.set_upstream to publish it)", s.Branch)
+		return newResult(s, "skipped", "no upstream")
+	}
+	ahead, err := unpushedCommits(s.Path, s.Branch)
+	if err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+	s.Ahead = ahead
+	reason := fmt.Sprintf("new branch %s, %d commits", s.Branch, s.Ahead)
+	if m.DryRun {
+		m.printPlan(s.Path, "would-push", reason)
+		return newResult(s, "would-push", reason)
+	}
+	if err := m.gitPush(s.Path, authFor(p), "-u", "origin", s.Branch); err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+	m.logEvent(slog.LevelInfo, "push", s.Path, "%s", reason)
+	return newResult(s, "pushed", "")
 }
 
-func (m *Manager) Sync(targetNames []string, workers int) error {
+func (m *Manager) Sync(targetNames []string, workers int) (err error) {
+	finish := m.startRun("sync", targetNames)
+	defer func() {
+		err = m.deadlineOutcome(err)
+		finish(err)
+	}()
+	finishHooks, err := m.startHooks("sync", targetNames)
+	if err != nil {
+		return err
+	}
+	defer finishHooks(&err)
 	targets, err := m.targetsFor(targetNames)
 	if err != nil {
 		return err
@@ -1141,112 +2493,381 @@ func (m *Manager) Sync(targetNames []string, workers int) error {
 		return err
 	}
 
-	// map target -> options and tokens
-	optMap := make(map[string]config.ProviderOptions)
-	tokenMap := make(map[string]string)
-	for _, t := range targets {
-		optMap[t.Name] = m.config.Providers[t.Provider].Options
-		tokenMap[t.Name] = m.config.Providers[t.Provider].Token
+	results := m.runRepos("sync", statuses, workers, (*Manager).SyncRepo)
+	m.notify("sync", statuses, results)
+	m.recordResults("sync", results)
+
+	if m.JSON {
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	synced, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Sync dry run: %d to sync, %d skipped, %d failed", synced, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Sync complete: %d synced, %d skipped, %d failed", synced, skipped, failed)
 	}
+	return resultsOutcome(results)
+}
 
-	var synced, skipped, failed int
-	for _, s := range statuses {
-		opts := optMap[s.Target]
-		tok := tokenMap[s.Target]
+// SyncRepo brings one repo's default branch level with its upstream: it pulls
+// (or rebases when diverged) and then pushes local commits. Foldouts pinned to
+// a ref are moved to it instead, and nothing is pushed.
+func (m *Manager) SyncRepo(s RepoStatus) RepoResult {
+	if s.Mirror && s.Error == "" {
+		return m.updateMirror(s)
+	}
+	if s.Ref != "" {
+		return m.updatePinned(s)
+	}
+	p := m.providerFor(s.Target)
+	opts, auth := p.Options, authFor(p)
+	prepared, switchedFrom, done := m.beginUpdate(s, auth, opts.Sync.Autostash)
+	if done != nil {
+		return *done
+	}
 
-		if s.Error != "" {
-			fmt.Printf("  [ERROR] %s: %s\n", s.Path, s.Error)
-			failed++
-			continue
-		}
-		prepared, switched, err := m.prepareRepoForDefaultBranch(s, tok)
-		if err != nil {
-			var skipErr *updateSkipError
-			if errors.As(err, &skipErr) {
-				fmt.Printf("  [SKIP]  %s: %s\n", s.Path, skipErr.reason)
-				skipped++
-				continue
+	if m.DryRun {
+		result, reason := planUpdate(prepared, switchedFrom, opts.Sync.GetFFOnly())
+		if prepared.Ahead > 0 {
+			push := fmt.Sprintf("push %d ahead", prepared.Ahead)
+			if result == "unchanged" {
+				reason = push
+			} else {
+				reason += ", then " + push
 			}
-			fmt.Printf("  [ERROR] %s: %v\n", s.Path, err)
-			failed++
-			continue
-		}
-		if switched {
-			fmt.Printf("  [SWITCH] %s: %s -> %s\n", s.Path, s.Branch, prepared.DefaultBranch)
+			result = "would-sync"
+		} else if result != "unchanged" {
+			result = "would-sync"
 		}
-		if prepared.Error != "" {
-			fmt.Printf("  [ERROR] %s: %s\n", prepared.Path, prepared.Error)
-			failed++
-			continue
-		}
-		if prepared.Dirty {
-			fmt.Printf("  [SKIP]  %s: dirty\n", prepared.Path)
-			skipped++
-			continue
+		if prepared.Dirty && prepared.Behind > 0 {
+			reason += ", autostash"
 		}
+		m.printPlan(prepared.Path, result, reason)
+		return switchedResult(prepared, switchedFrom, result, reason)
+	}
 
+	return m.withAutostash(prepared, switchedFrom, opts.Sync.Autostash, func() RepoResult {
 		if prepared.Behind > 0 {
 			if !prepared.CanFastForward && opts.Sync.GetFFOnly() {
 				// Diverged: ff-only would fail, go straight to rebase.
-				fmt.Printf("  [REBASE] %s: %d behind, %d ahead (diverged)\n", prepared.Path, prepared.Behind, prepared.Ahead)
-				if err := gitPullRebase(prepared.Path, tok); err != nil {
-					fmt.Printf("    error: %v\n", err)
-					failed++
-					continue
+				m.logEvent(slog.LevelInfo, "rebase", prepared.Path, "%d behind, %d ahead (diverged)", prepared.Behind, prepared.Ahead)
+				if err := m.gitPullRebase(prepared.Path, auth); err != nil {
+					m.logEvent(slog.LevelError, "error", prepared.Path, "%v", err)
+					return switchedResult(prepared, switchedFrom, "failed", err.Error())
 				}
 			} else {
-				fmt.Printf("  [PULL]  %s: %d behind\n", prepared.Path, prepared.Behind)
-				if err := gitPull(prepared.Path, opts.Sync.GetFFOnly(), tok); err != nil {
-					fmt.Printf("    error: %v\n", err)
-					failed++
-					continue
+				m.logEvent(slog.LevelInfo, "pull", prepared.Path, "%d behind", prepared.Behind)
+				if err := m.gitPull(prepared.Path, opts.Sync.GetFFOnly(), auth); err != nil {
+					m.logEvent(slog.LevelError, "error", prepared.Path, "%v", err)
+					return switchedResult(prepared, switchedFrom, "failed", err.Error())
 				}
 			}
 		}
+		if err := m.updateSubmodules(prepared); err != nil {
+			m.logEvent(slog.LevelError, "error", prepared.Path, "%v", err)
+			return switchedResult(prepared, switchedFrom, "failed", err.Error())
+		}
 		if prepared.Ahead > 0 {
-			fmt.Printf("  [PUSH]  %s: %d ahead\n", prepared.Path, prepared.Ahead)
-			if err := gitPush(prepared.Path, tok); err != nil {
-				fmt.Printf("    error: %v\n", err)
-				failed++
-				continue
+			m.logEvent(slog.LevelInfo, "push", prepared.Path, "%d ahead", prepared.Ahead)
+			if err := m.gitPush(prepared.Path, auth); err != nil {
+				m.logEvent(slog.LevelError, "error", prepared.Path, "%v", err)
+				return switchedResult(prepared, switchedFrom, "failed", err.Error())
 			}
 		}
-		synced++
+		if prepared.Behind == 0 && prepared.Ahead == 0 {
+			m.logEvent(slog.LevelDebug, "ok", prepared.Path, "up to date")
+		}
+		return switchedResult(prepared, switchedFrom, "synced", "")
+	})
+}
+
+// beginUpdate runs the checks shared by pull and sync: errored and dirty repos
+// are refused (dirty ones on the default branch are let through when autostash
+// is set), and repos on another branch are moved onto the default branch when
+// safe. A non-nil result means the repo is finished and must not be updated;
+// otherwise the returned status reflects the (possibly switched) repo.
+func (m *Manager) beginUpdate(s RepoStatus, auth gitAuth, autostash bool) (RepoStatus, string, *RepoResult) {
+	finish := func(r RepoResult) (RepoStatus, string, *RepoResult) { return s, "", &r }
+
+	if s.Error != "" {
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", s.Error)
+		return finish(newResult(s, "failed", s.Error))
+	}
+
+	prepared, switched, err := m.prepareRepoForDefaultBranch(s, auth)
+	if err != nil {
+		var skipErr *updateSkipError
+		if errors.As(err, &skipErr) {
+			m.logEvent(slog.LevelInfo, "skip", s.Path, "%s", skipErr.reason)
+			return finish(newResult(s, "skipped", skipErr.reason))
+		}
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return finish(newResult(s, "failed", err.Error()))
+	}
+	var switchedFrom string
+	if switched {
+		if !m.DryRun {
+			m.logEvent(slog.LevelInfo, "switch", s.Path, "%s -> %s", s.Branch, prepared.DefaultBranch)
+		}
+		switchedFrom = s.Branch
+	}
+	if prepared.Error != "" {
+		m.logEvent(slog.LevelError, "error", prepared.Path, "%s", prepared.Error)
+		return finish(switchedResult(prepared, switchedFrom, "failed", prepared.Error))
+	}
+	if prepared.Dirty && !autostash {
+		m.logEvent(slog.LevelInfo, "skip", prepared.Path, "dirty")
+		return finish(switchedResult(prepared, switchedFrom, "skipped", "dirty"))
+	}
+	return prepared, switchedFrom, nil
+}
+
+// withAutostash runs update for a prepared repo. When autostash is set and the
+// repo is dirty and behind, local changes are stashed first and popped back
+// afterwards; a conflicting pop fails the repo and leaves the changes in the
+// stash.
+func (m *Manager) withAutostash(s RepoStatus, switchedFrom string, autostash bool, update func() RepoResult) RepoResult {
+	if !autostash || !s.Dirty || s.Behind == 0 {
+		return update()
+	}
+	before, _ := gitOutput(s.Path, "rev-parse", "--quiet", "--verify", "refs/stash")
+	cmd := exec.Command("git", "stash", "push", "--include-untracked", "-m", "tugboat autostash")
+	cmd.Dir = s.Path
+	cmd.Env = gitEnvNoPrompt()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		msg := fmt.Sprintf("git stash: %v: %s", err, strings.TrimSpace(string(out)))
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", msg)
+		return switchedResult(s, switchedFrom, "failed", msg)
+	}
+	after, _ := gitOutput(s.Path, "rev-parse", "--quiet", "--verify", "refs/stash")
+	if after == before {
+		// Nothing was stashed (e.g. only submodule changes); do not pop an
+		// older, unrelated stash entry.
+		return update()
+	}
+	m.logEvent(slog.LevelInfo, "stash", s.Path, "")
+
+	r := update()
+
+	pop := exec.Command("git", "stash", "pop")
+	pop.Dir = s.Path
+	pop.Env = gitEnvNoPrompt()
+	if out, err := pop.CombinedOutput(); err != nil {
+		msg := "stash pop conflicted; local changes are kept in the stash (see git stash list)"
+		m.logEvent(slog.LevelError, "error", s.Path, "%s: %s", msg, strings.TrimSpace(string(out)))
+		if r.Result == "failed" {
+			msg = r.Message + "; " + msg
+		}
+		r.Result = "failed"
+		r.Message = msg
+		return r
+	}
+	m.logEvent(slog.LevelDebug, "unstash", s.Path, "")
+	return r
+}
+
+// updateSubmodules checks out the recorded submodule commits when the
+// target's clone options enable submodules. Repos without submodules are left
+// alone.
+func (m *Manager) updateSubmodules(s RepoStatus) error {
+	t := m.config.GetTargetByName(s.Target)
+	if t == nil || !m.config.CloneOptionsFor(*t).GetSubmodules() {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(s.Path, ".gitmodules")); err != nil {
+		return nil
+	}
+	cmd := exec.Command("git", "submodule", "update", "--init", "--recursive")
+	cmd.Dir = s.Path
+	cmd.Env = authFor(m.providerFor(s.Target)).env()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("updating submodules: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// updateMirror refreshes a bare mirror from its remote, pruning refs that were
+// deleted upstream, and backs up new release assets when the target's clone
+// options ask for it. Mirrors are never pushed.
+func (m *Manager) updateMirror(s RepoStatus) RepoResult {
+	if m.DryRun {
+		m.printPlan(s.Path, "would-update", "mirror")
+		return newResult(s, "would-update", "mirror")
+	}
+	cmd := exec.Command("git", "remote", "update", "--prune")
+	cmd.Dir = s.Path
+	cmd.Env = authFor(m.providerFor(s.Target)).env()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		msg := fmt.Sprintf("%v: %s", err, strings.TrimSpace(string(out)))
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", msg)
+		return newResult(s, "failed", msg)
+	}
+	result := newResult(s, "updated", "")
+	if t := m.config.GetTargetByName(s.Target); t != nil && m.config.CloneOptionsFor(*t).Releases {
+		n, err := m.backupReleases(s.Provider, s.Org, s.Name, s.Path)
+		if err != nil {
+			msg := fmt.Sprintf("updated, but backing up releases failed: %v", err)
+			m.logEvent(slog.LevelError, "error", s.Path, "%s", msg)
+			return newResult(s, "failed", msg)
+		}
+		if n > 0 {
+			result.Message = fmt.Sprintf("%d release assets", n)
+		}
+	}
+	m.logEvent(slog.LevelInfo, "update", s.Path, "%s", result.Message)
+	return result
+}
+
+// Unshallow fetches the full history of repos that were cloned with a depth.
+func (m *Manager) Unshallow(targetNames []string, workers int) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+
+	statuses, _, err := m.getAllStatuses(targets, false, workers)
+	if err != nil {
+		return err
+	}
+
+	results := emitJobs(m, statuses, workers, (*Manager).UnshallowRepo)
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+
+	if m.JSON {
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	deepened, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Unshallow dry run: %d to unshallow, %d skipped, %d failed", deepened, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Unshallow complete: %d unshallowed, %d skipped, %d failed", deepened, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
+
+// UnshallowRepo fetches the full history of a single repo if it is shallow.
+func (m *Manager) UnshallowRepo(s RepoStatus) RepoResult {
+	if s.Error != "" {
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", s.Error)
+		return newResult(s, "failed", s.Error)
+	}
+	shallow, err := isShallowRepo(s.Path)
+	if err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+	if !shallow {
+		m.logEvent(slog.LevelDebug, "ok", s.Path, "not shallow")
+		return newResult(s, "unchanged", "")
+	}
+	if m.DryRun {
+		m.printPlan(s.Path, "would-unshallow", "shallow clone")
+		return newResult(s, "would-unshallow", "shallow clone")
+	}
+	if err := gitUnshallow(s.Path, authFor(m.providerFor(s.Target))); err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+	m.logEvent(slog.LevelInfo, "unshallow", s.Path, "")
+	return newResult(s, "unshallowed", "")
+}
+
+// countResults tallies results into repos that were (or would be) changed,
+// skipped and failed. Unchanged repos are not counted.
+func countResults(results []RepoResult) (done, skipped, failed int) {
+	for _, r := range results {
+		switch r.Result {
+		case "unchanged":
+		case "skipped":
+			skipped++
+		case "failed":
+			failed++
+		default:
+			done++
+		}
+	}
+	return done, skipped, failed
+}
+
+// ErrDeadline is the error of a command that reached its run deadline.
+var ErrDeadline = errors.New("run deadline reached")
+
+// pastDeadline reports whether the run deadline has passed.
+func (m *Manager) pastDeadline() bool {
+	return !m.Deadline.IsZero() && !time.Now().Before(m.Deadline)
+}
+
+// deadlineOutcome adds ErrDeadline to the error of a command that ran into
+// the deadline, keeping err for errors.As.
+func (m *Manager) deadlineOutcome(err error) error {
+	if !m.pastDeadline() {
+		return err
+	}
+	if err == nil {
+		return ErrDeadline
+	}
+	if errors.Is(err, ErrDeadline) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrDeadline, err)
+}
+
+// resultsOutcome returns a *RepoFailures when any result failed.
+func resultsOutcome(results []RepoResult) error {
+	if _, _, failed := countResults(results); failed > 0 {
+		return &RepoFailures{Failed: failed, Total: len(results)}
 	}
-	fmt.Printf("Sync complete: %d synced, %d skipped, %d failed\n", synced, skipped, failed)
 	return nil
 }
 
+// providerFor returns the provider config of the named target.
+func (m *Manager) providerFor(targetName string) config.Provider {
+	if t := m.config.GetTargetByName(targetName); t != nil {
+		return m.config.Providers[t.Provider]
+	}
+	return config.Provider{}
+}
+
 func (m *Manager) List(targetNames []string, includeArchived bool, workers int) error {
 	targets, err := m.targetsFor(targetNames)
 	if err != nil {
 		return err
 	}
 
+	entries := []ListEntry{}
 	for _, t := range targets {
-		fmt.Printf("Target: %s (%s/%s) path=%s\n", t.Name, t.Provider, t.Org, t.Path)
+		m.printf("Target: %s (%s/%s) path=%s\n", t.Name, t.Provider, t.Owner(), t.Path)
 		if t.Repo == "" {
-			client, ok := m.providers[t.Provider]
-			if !ok {
-				fmt.Printf("  [ERROR] no client for provider %s\n\n", t.Provider)
+			if _, ok := m.providers[t.Provider]; !ok {
+				if m.machineOutput() {
+					fmt.Fprintf(m.errOut(), "Error: target %s: no client for provider %s\n", t.Name, t.Provider)
+				}
+				m.printf("  [ERROR] no client for provider %s\n\n", t.Provider)
 				continue
 			}
 
 			remoteMap := make(map[string]remote.Repository)
-			if repos, err := client.ListOrgRepos(t.Org); err == nil {
+			if repos, err := m.targetRepos(t); err == nil {
 				for _, r := range repos {
 					remoteMap[r.Name] = r
 				}
 			} else {
-				fmt.Printf("  [ERROR] listing org: %v\n", err)
+				if m.machineOutput() {
+					fmt.Fprintf(m.errOut(), "Error: target %s: listing org: %v\n", t.Name, err)
+				}
+				m.printf("  [ERROR] listing org: %v\n", err)
 			}
 
-			local := make(map[string]bool)
-			entries, _ := os.ReadDir(t.Path)
-			for _, e := range entries {
-				if e.IsDir() && isGitRepo(filepath.Join(t.Path, e.Name())) {
-					local[e.Name()] = true
-				}
+			local := make(map[string]string) // name -> path
+			repoPaths, _ := targetRepoDirs(t)
+			for _, p := range repoPaths {
+				local[filepath.Base(p)] = p
 			}
 
 			names := make([]string, 0, len(remoteMap))
@@ -1257,23 +2878,39 @@ func (m *Manager) List(targetNames []string, includeArchived bool, workers int)
 
 			for _, n := range names {
 				r := remoteMap[n]
+				if !selectsRepo(m.withFilters(t), r) {
+					continue
+				}
 				// Skip archived repos unless --include-archived is set
 				if r.Archived && !includeArchived {
 					continue
 				}
+				if r.Template && m.ExcludeTemplates {
+					continue
+				}
 				mark := "[ ]"
-				if local[n] {
+				localPath, isLocal := local[n]
+				if isLocal {
 					mark = "[x]"
+				} else {
+					localPath = filepath.Join(t.Path, n)
 				}
 				flags := []string{}
 				if r.Archived {
 					flags = append(flags, "archived")
 				}
-				fmt.Printf("  %s %s", mark, n)
+				if r.Fork {
+					flags = append(flags, "fork")
+				}
+				if r.Template {
+					flags = append(flags, "template")
+				}
+				m.printf("  %s %s", mark, n)
 				if len(flags) > 0 {
-					fmt.Printf(" (%s)", strings.Join(flags, ", "))
+					m.printf(" (%s)", strings.Join(flags, ", "))
 				}
-				fmt.Println()
+				m.printf("\n")
+				entries = append(entries, ListEntry{Target: t.Name, Name: n, Path: localPath, Local: isLocal, Archived: r.Archived})
 			}
 
 			// local only -> orphan
@@ -1285,7 +2922,8 @@ func (m *Manager) List(targetNames []string, includeArchived bool, workers int)
 			}
 			sort.Strings(orphans)
 			for _, n := range orphans {
-				fmt.Printf("  [x] %s (orphan)\n", n)
+				m.printf("  [x] %s (%s)\n", n, m.paint(colorMagenta, "orphan"))
+				entries = append(entries, ListEntry{Target: t.Name, Name: n, Path: local[n], Local: true, Orphan: true})
 			}
 
 		} else {
@@ -1293,23 +2931,31 @@ func (m *Manager) List(targetNames []string, includeArchived bool, workers int)
 			if isGitRepo(t.Path) {
 				mark = "[x]"
 			}
-			fmt.Printf("  %s %s\n", mark, t.Repo)
-			fc, err := loadFoldout(t.Path)
+			m.printf("  %s %s\n", mark, t.Repo)
+			entries = append(entries, ListEntry{Target: t.Name, Name: t.Repo, Path: t.Path, Local: mark == "[x]"})
+			foldouts, err := foldoutTree(t)
 			if err != nil {
 				return err
 			}
-			if fc != nil {
-				for _, fr := range fc.Repos {
-					dest := filepath.Join(t.Path, fr.Target)
-					m := "[ ]"
-					if isGitRepo(dest) {
-						m = "[x]"
-					}
-					fmt.Printf("  %s %s -> %s\n", m, fr.Name, fr.Target)
+			for _, fr := range foldouts {
+				mark := "[ ]"
+				if isGitRepo(fr.path) {
+					mark = "[x]"
 				}
+				m.printf("  %s%s %s -> %s\n", strings.Repeat("  ", fr.depth-1), mark, fr.Name, fr.Target)
+				entries = append(entries, ListEntry{Target: t.Name, Name: fr.Name, Path: fr.path, Local: mark == "[x]"})
 			}
 		}
-		fmt.Println()
+		m.printf("\n")
+	}
+	if m.Format != nil {
+		return writeFormatted(m.out(), m.Format, entries)
+	}
+	if m.Output != "" {
+		return writeRows(m.out(), entries, m.Output)
+	}
+	if m.JSON {
+		return m.writeJSON(entries)
 	}
 	return nil
 }
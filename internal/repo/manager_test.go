@@ -1,28 +1,50 @@
 package repo
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
 	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/state"
 )
 
 type fakeClient struct {
 	repos map[string]map[string]remote.Repository
+	users map[string]map[string]remote.Repository
+	files map[string]string // "owner/name/path" -> content on the default branch
 }
 
 func (c fakeClient) ListOrgRepos(orgName string) ([]remote.Repository, error) {
-	reposByName := c.repos[orgName]
+	return reposOf(c.repos[orgName]), nil
+}
+
+func (c fakeClient) ListUserRepos(userName string) ([]remote.Repository, error) {
+	return reposOf(c.users[userName]), nil
+}
+
+func reposOf(reposByName map[string]remote.Repository) []remote.Repository {
 	repos := make([]remote.Repository, 0, len(reposByName))
 	for _, repo := range reposByName {
 		repos = append(repos, repo)
 	}
-	return repos, nil
+	return repos
 }
 
 func (c fakeClient) GetRepo(owner, repoName string) (*remote.Repository, error) {
@@ -34,6 +56,14 @@ func (c fakeClient) GetRepo(owner, repoName string) (*remote.Repository, error)
 	return &copy, nil
 }
 
+func (c fakeClient) GetFile(owner, name, path string) ([]byte, error) {
+	content, ok := c.files[owner+"/"+name+"/"+path]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(content), nil
+}
+
 type testRepo struct {
 	org           string
 	name          string
@@ -335,6 +365,491 @@ func TestPullSkipsMissingRepoTargetPathButContinues(t *testing.T) {
 	}
 }
 
+func TestSyncDryRunDoesNotChangeRepo(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+
+	runGit(t, repo.workPath, "switch", "-c", "feature/clean")
+	runGit(t, repo.workPath, "push", "-u", "origin", "feature/clean")
+
+	other := cloneRepo(t, repo.remotePath, filepath.Join(base, "other"))
+	commitFile(t, other, "remote.txt", "from remote\n", "remote update")
+	runGit(t, other, "push", "origin", "main")
+
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	manager.DryRun = true
+	output := captureStdout(t, func() {
+		if err := manager.Sync(nil, 1); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+
+	if branch := currentBranch(t, repo.workPath); branch != "feature/clean" {
+		t.Fatalf("current branch = %q, want dry run to stay on %q", branch, "feature/clean")
+	}
+	if _, err := os.Stat(filepath.Join(repo.workPath, "remote.txt")); !os.IsNotExist(err) {
+		t.Fatalf("remote.txt should not be pulled in dry run (stat err = %v)", err)
+	}
+	if !strings.Contains(output, "[DRY-RUN] "+repo.workPath+": would sync (after switching feature/clean -> main)") {
+		t.Fatalf("expected dry-run plan output, got:\n%s", output)
+	}
+}
+
+func TestPullJSONOutput(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+
+	runGit(t, repo.workPath, "switch", "-c", "feature/clean")
+	runGit(t, repo.workPath, "push", "-u", "origin", "feature/clean")
+
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	manager.JSON = true
+	output := captureStdout(t, func() {
+		if err := manager.Pull(nil, 1); err != nil {
+			t.Fatalf("Pull() error = %v", err)
+		}
+	})
+
+	var results []RepoResult
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, output)
+	}
+	if len(results) != 1 {
+		t.Fatalf("len(results) = %d, want 1", len(results))
+	}
+	got := results[0]
+	if got.Path != repo.workPath || got.Result != "pulled" || got.SwitchedFrom != "feature/clean" {
+		t.Fatalf("result = %+v, want pulled after switching from feature/clean", got)
+	}
+}
+
+func TestCloneUserTargetListsUserRepos(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "alice", "dotfiles", "main", filepath.Join(base, "seed"))
+
+	userRepo := remoteRepo(repo)
+	userRepo.CloneURL = repo.remotePath
+	client := fakeClient{
+		users: map[string]map[string]remote.Repository{
+			"alice": {repo.name: userRepo},
+		},
+	}
+	target := config.Target{
+		Name:     "alice",
+		Provider: "fake",
+		User:     "alice",
+		Path:     filepath.Join(base, "alice"),
+	}
+	manager := newTestManager([]config.Target{target}, client)
+	output := captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+
+	if !isGitRepo(filepath.Join(target.Path, repo.name)) {
+		t.Fatalf("expected %s to be cloned, got:\n%s", repo.name, output)
+	}
+	if !strings.Contains(output, "User alice: cloning 1 repositories...") {
+		t.Fatalf("expected user clone output, got:\n%s", output)
+	}
+}
+
+func TestCloneWithDepthThenUnshallow(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "seed"))
+	commitFile(t, repo.workPath, "CHANGELOG.md", "v2\n", "second commit")
+	runGit(t, repo.workPath, "push")
+	runGit(t, repo.workPath, "push", "origin", "main:feature")
+
+	orgRepo := remoteRepo(repo)
+	// Local-path clones ignore --depth; a file:// URL honours it.
+	orgRepo.CloneURL = "file://" + repo.remotePath
+	client := fakeClient{repos: map[string]map[string]remote.Repository{"acme": {repo.name: orgRepo}}}
+	target := config.Target{
+		Name:     "acme",
+		Provider: "fake",
+		Org:      "acme",
+		Path:     filepath.Join(base, "acme"),
+		Clone:    &config.CloneOptions{Depth: 1},
+	}
+	manager := newTestManager([]config.Target{target}, client)
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+
+	clonePath := filepath.Join(target.Path, repo.name)
+	if got := strings.TrimSpace(runGit(t, clonePath, "rev-list", "--count", "HEAD")); got != "1" {
+		t.Fatalf("commits after shallow clone = %s, want 1", got)
+	}
+	runGit(t, clonePath, "rev-parse", "--verify", "refs/remotes/origin/feature")
+	// Shallow clones made before --no-single-branch only fetch one branch.
+	runGit(t, clonePath, "config", "remote.origin.fetch", "+refs/heads/main:refs/remotes/origin/main")
+	runGit(t, clonePath, "update-ref", "-d", "refs/remotes/origin/feature")
+
+	output := captureStdout(t, func() {
+		if err := manager.Unshallow(nil, 1); err != nil {
+			t.Fatalf("Unshallow() error = %v", err)
+		}
+	})
+	if got := strings.TrimSpace(runGit(t, clonePath, "rev-list", "--count", "HEAD")); got != "2" {
+		t.Fatalf("commits after unshallow = %s, want 2\n%s", got, output)
+	}
+	runGit(t, clonePath, "rev-parse", "--verify", "refs/remotes/origin/feature")
+	if !strings.Contains(output, "Unshallow complete: 1 unshallowed, 0 skipped, 0 failed") {
+		t.Fatalf("unexpected unshallow output:\n%s", output)
+	}
+}
+
+func TestCloneWithBlobFilterIsPartial(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "seed"))
+	runGit(t, repo.remotePath, "config", "uploadpack.allowFilter", "true")
+
+	orgRepo := remoteRepo(repo)
+	orgRepo.CloneURL = "file://" + repo.remotePath
+	client := fakeClient{repos: map[string]map[string]remote.Repository{"acme": {repo.name: orgRepo}}}
+	target := config.Target{
+		Name:     "acme",
+		Provider: "fake",
+		Org:      "acme",
+		Path:     filepath.Join(base, "acme"),
+		Clone:    &config.CloneOptions{Filter: "blob:none"},
+	}
+	manager := newTestManager([]config.Target{target}, client)
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+
+	clonePath := filepath.Join(target.Path, repo.name)
+	if got := strings.TrimSpace(runGit(t, clonePath, "config", "remote.origin.partialclonefilter")); got != "blob:none" {
+		t.Fatalf("partialclonefilter = %q, want blob:none", got)
+	}
+	if _, err := os.Stat(filepath.Join(clonePath, "README.md")); err != nil {
+		t.Fatalf("checkout missing after partial clone: %v", err)
+	}
+}
+
+func TestMirrorCloneIsUpdatedBySync(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "seed"))
+
+	orgRepo := remoteRepo(repo)
+	orgRepo.CloneURL = repo.remotePath
+	client := fakeClient{repos: map[string]map[string]remote.Repository{"acme": {repo.name: orgRepo}}}
+	target := config.Target{
+		Name:     "acme",
+		Provider: "fake",
+		Org:      "acme",
+		Path:     filepath.Join(base, "backup"),
+		Clone:    &config.CloneOptions{Mode: "mirror"},
+	}
+	manager := newTestManager([]config.Target{target}, client)
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+
+	mirrorPath := filepath.Join(target.Path, repo.name)
+	if !isBareRepo(mirrorPath) {
+		t.Fatalf("expected bare mirror at %s", mirrorPath)
+	}
+
+	commitFile(t, repo.workPath, "CHANGELOG.md", "v2\n", "second commit")
+	runGit(t, repo.workPath, "push")
+	runGit(t, repo.workPath, "push", "origin", "main:release")
+
+	output := captureStdout(t, func() {
+		if err := manager.Sync(nil, 1); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+	if !strings.Contains(output, "[UPDATE] "+mirrorPath) {
+		t.Fatalf("expected mirror update, got:\n%s", output)
+	}
+	want := strings.TrimSpace(runGit(t, repo.workPath, "rev-parse", "HEAD"))
+	if got := strings.TrimSpace(runGit(t, mirrorPath, "rev-parse", "release")); got != want {
+		t.Fatalf("mirror release = %s, want %s", got, want)
+	}
+}
+
+func TestSubmodulesClonedAndUpdatedBySync(t *testing.T) {
+	// Local file:// submodules are blocked by default since git 2.38.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	base := t.TempDir()
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-seed"))
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-seed"))
+	runGit(t, app.workPath, "submodule", "add", lib.remotePath, "vendor/lib")
+	runGit(t, app.workPath, "commit", "-m", "add lib submodule")
+	runGit(t, app.workPath, "push")
+
+	orgRepo := remoteRepo(app)
+	orgRepo.CloneURL = app.remotePath
+	client := fakeClient{repos: map[string]map[string]remote.Repository{"acme": {app.name: orgRepo}}}
+	submodules := true
+	target := config.Target{
+		Name:     "acme",
+		Provider: "fake",
+		Org:      "acme",
+		Path:     filepath.Join(base, "acme"),
+		Clone:    &config.CloneOptions{Submodules: &submodules},
+	}
+	manager := newTestManager([]config.Target{target}, client)
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+
+	clonePath := filepath.Join(target.Path, app.name)
+	if _, err := os.Stat(filepath.Join(clonePath, "vendor", "lib", "README.md")); err != nil {
+		t.Fatalf("expected submodule to be checked out: %v", err)
+	}
+
+	// Move the submodule pointer upstream.
+	commitFile(t, lib.workPath, "lib.go", "package lib\n", "add lib.go")
+	runGit(t, lib.workPath, "push")
+	runGit(t, filepath.Join(app.workPath, "vendor", "lib"), "pull")
+	runGit(t, app.workPath, "commit", "-am", "bump lib")
+	runGit(t, app.workPath, "push")
+
+	output := captureStdout(t, func() {
+		if err := manager.Sync(nil, 1); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+	if _, err := os.Stat(filepath.Join(clonePath, "vendor", "lib", "lib.go")); err != nil {
+		t.Fatalf("expected submodule update after sync: %v\n%s", err, output)
+	}
+
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if len(statuses) != 1 || len(statuses[0].SubmoduleDrift) != 0 || statuses[0].Dirty {
+		t.Fatalf("expected clean repo without submodule drift, got %+v", statuses)
+	}
+
+	runGit(t, filepath.Join(clonePath, "vendor", "lib"), "checkout", "--quiet", "HEAD~1")
+	statuses, err = manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if got := statuses[0].SubmoduleDrift; len(got) != 1 || got[0] != "vendor/lib" {
+		t.Fatalf("SubmoduleDrift = %v, want [vendor/lib]", got)
+	}
+}
+
+func TestTopicFilterLimitsOrgTargetRepos(t *testing.T) {
+	base := t.TempDir()
+	orgPath := filepath.Join(base, "acme")
+	if err := os.MkdirAll(orgPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	payments := createTestRepo(t, base, "acme", "payments", "main", filepath.Join(orgPath, "payments"))
+	search := createTestRepo(t, base, "acme", "search", "main", filepath.Join(orgPath, "search"))
+	billing := createTestRepo(t, base, "acme", "billing", "main", filepath.Join(base, "billing-seed"))
+
+	paymentsRemote := remoteRepo(payments)
+	paymentsRemote.Topics = []string{"team-payments"}
+	billingRemote := remoteRepo(billing)
+	billingRemote.Topics = []string{"Team-Payments", "go"}
+	billingRemote.CloneURL = billing.remotePath
+	client := fakeClient{repos: map[string]map[string]remote.Repository{"acme": {
+		payments.name: paymentsRemote,
+		search.name:   remoteRepo(search),
+		billing.name:  billingRemote,
+	}}}
+	target := config.Target{
+		Name:     "acme",
+		Provider: "fake",
+		Org:      "acme",
+		Path:     orgPath,
+		Topics:   []string{"team-payments"},
+	}
+	manager := newTestManager([]config.Target{target}, client)
+
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+	if !isGitRepo(filepath.Join(orgPath, billing.name)) {
+		t.Fatalf("expected topic-matching repo %s to be cloned", billing.name)
+	}
+
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	var names []string
+	for _, s := range statuses {
+		names = append(names, s.Name)
+	}
+	if strings.Join(names, ",") != "billing,payments" {
+		t.Fatalf("status repos = %v, want [billing payments]", names)
+	}
+}
+
+func TestExcludePatternSkipsRepoInPullAndList(t *testing.T) {
+	base := t.TempDir()
+	orgPath := filepath.Join(base, "acme")
+	if err := os.MkdirAll(orgPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(orgPath, "api"))
+	legacy := createTestRepo(t, base, "acme", "api-deprecated", "main", filepath.Join(orgPath, "api-deprecated"))
+
+	// Put both repos one commit behind origin.
+	for _, r := range []testRepo{api, legacy} {
+		upstream := cloneRepo(t, r.remotePath, filepath.Join(base, r.name+"-upstream"))
+		commitFile(t, upstream, "CHANGELOG.md", "v2\n", "second commit")
+		runGit(t, upstream, "push")
+	}
+
+	target := config.Target{
+		Name:     "acme",
+		Provider: "fake",
+		Org:      "acme",
+		Path:     orgPath,
+		Exclude:  []string{"*-deprecated"},
+	}
+	manager := newTestManager([]config.Target{target}, fakeClientForRepos(api, legacy))
+	output := captureStdout(t, func() {
+		if err := manager.Pull(nil, 1); err != nil {
+			t.Fatalf("Pull() error = %v", err)
+		}
+		if err := manager.List(nil, false, 1); err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+	})
+
+	if !strings.Contains(output, "Pull complete: 1 pulled") {
+		t.Fatalf("expected only one repo pulled, got:\n%s", output)
+	}
+	if strings.Contains(output, "api-deprecated") {
+		t.Fatalf("excluded repo should not appear, got:\n%s", output)
+	}
+	if _, err := os.Stat(filepath.Join(legacy.workPath, "CHANGELOG.md")); !os.IsNotExist(err) {
+		t.Fatalf("excluded repo should not have been pulled")
+	}
+}
+
+func TestPruneRemovesCleanOrphansOnly(t *testing.T) {
+	base := t.TempDir()
+	orgPath := filepath.Join(base, "acme")
+	if err := os.MkdirAll(orgPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(orgPath, "api"))
+	gone := createTestRepo(t, base, "acme", "gone", "main", filepath.Join(orgPath, "gone"))
+	wip := createTestRepo(t, base, "acme", "wip", "main", filepath.Join(orgPath, "wip"))
+	writeFile(t, filepath.Join(wip.workPath, "notes.txt"), "unsaved\n")
+
+	target := config.Target{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath}
+	manager := newTestManager([]config.Target{target}, fakeClientForRepos(api))
+
+	output := captureStdout(t, func() {
+		if err := manager.Prune(nil, "", false, func(int) bool { return false }); err != nil {
+			t.Fatalf("Prune() error = %v", err)
+		}
+	})
+	if !strings.Contains(output, "Prune cancelled") || !isGitRepo(gone.workPath) {
+		t.Fatalf("declined prune should keep repos, got:\n%s", output)
+	}
+
+	archive := filepath.Join(base, "archive")
+	output = captureStdout(t, func() {
+		if err := manager.Prune(nil, archive, false, func(n int) bool { return n == 1 }); err != nil {
+			t.Fatalf("Prune() error = %v", err)
+		}
+	})
+	if !strings.Contains(output, "Prune complete: 1 pruned, 1 skipped, 0 failed") {
+		t.Fatalf("unexpected prune output:\n%s", output)
+	}
+	if isGitRepo(gone.workPath) || !isGitRepo(filepath.Join(archive, "acme", "gone")) {
+		t.Fatalf("expected orphan to be moved to the archive")
+	}
+	if !isGitRepo(wip.workPath) || !isGitRepo(api.workPath) {
+		t.Fatalf("dirty orphan and remote repo must be kept")
+	}
+}
+
+func TestAdoptRecordsForeignCloneAsTarget(t *testing.T) {
+	base := t.TempDir()
+	stray := createTestRepo(t, base, "acme", "tools", "main", filepath.Join(base, "tools"))
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-seed"))
+	if err := os.MkdirAll(filepath.Join(app.workPath, "deps"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cloneRepo(t, lib.remotePath, filepath.Join(app.workPath, "deps", "lib"))
+
+	configPath := filepath.Join(base, "config.json")
+	writeFile(t, configPath, `{"workers": 2, "providers": {"fake": {"type": "github", "token": "t"}}, "targets": []}`)
+
+	manager := newTestManager([]config.Target{repoTarget(app)}, fakeClientForRepos(stray, app, lib))
+	for name, r := range map[string]testRepo{"tools": stray, "lib": lib} {
+		rr := manager.providers["fake"].(fakeClient).repos["acme"][name]
+		rr.CloneURL = r.remotePath
+		manager.providers["fake"].(fakeClient).repos["acme"][name] = rr
+	}
+
+	output := captureStdout(t, func() {
+		if err := manager.Adopt([]string{stray.workPath, filepath.Join(app.workPath, "deps", "lib")}, configPath); err != nil {
+			t.Errorf("Adopt() error = %v", err)
+		}
+	})
+	if t.Failed() {
+		t.Fatalf("adopt output:\n%s", output)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadFromBytes(data)
+	if err != nil {
+		t.Fatalf("adopted config does not load: %v\n%s", err, data)
+	}
+	if len(cfg.Targets) != 1 || cfg.Targets[0].Repo != "tools" || cfg.Targets[0].Org != "acme" || cfg.Targets[0].Path != stray.workPath {
+		t.Fatalf("unexpected targets after adopt: %+v", cfg.Targets)
+	}
+	if cfg.Workers != 2 {
+		t.Fatalf("adopt should keep other settings, got workers = %d", cfg.Workers)
+	}
+
+	fc, err := loadFoldout(app.workPath)
+	if err != nil || fc == nil {
+		t.Fatalf("expected foldout file: %v", err)
+	}
+	if len(fc.Repos) != 1 || fc.Repos[0].Name != "acme/lib" || fc.Repos[0].Target != "deps/lib" {
+		t.Fatalf("unexpected foldout entries: %+v", fc.Repos)
+	}
+}
+
+func TestNormalizeGitURL(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/acme/api.git":            "github.com/acme/api",
+		"https://x-access-token@GitHub.com/acme/api": "github.com/acme/api",
+		"git@github.com:acme/api.git":                "github.com/acme/api",
+		"ssh://git@gitlab.com:2222/acme/sub/api.git": "gitlab.com/acme/sub/api",
+	}
+	for raw, want := range tests {
+		if got := normalizeGitURL(raw); got != want {
+			t.Errorf("normalizeGitURL(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
 func newTestManager(targets []config.Target, client fakeClient) *Manager {
 	cfg := &config.Config{
 		Providers: map[string]config.Provider{
@@ -434,6 +949,15 @@ func writeFile(t *testing.T, path, contents string) {
 	}
 }
 
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", path, err)
+	}
+	return string(data)
+}
+
 func currentBranch(t *testing.T, dir string) string {
 	t.Helper()
 	return strings.TrimSpace(runGit(t, dir, "branch", "--show-current"))
@@ -476,3 +1000,2746 @@ func runGit(t *testing.T, dir string, args ...string) string {
 	}
 	return string(output)
 }
+
+func TestStatusColorsFlagsOnlyWhenEnabled(t *testing.T) {
+	base := t.TempDir()
+	orgPath := filepath.Join(base, "acme")
+	if err := os.MkdirAll(orgPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(orgPath, "api"))
+	writeFile(t, filepath.Join(api.workPath, "scratch.txt"), "wip\n")
+
+	target := config.Target{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath}
+	manager := newTestManager([]config.Target{target}, fakeClientForRepos(api))
+
+	plain := captureStdout(t, func() {
+		if err := manager.Status(nil, false, 1); !errors.Is(err, ErrNotClean) {
+			t.Fatalf("Status() error = %v, want ErrNotClean", err)
+		}
+	})
+	if strings.Contains(plain, "\x1b[") {
+		t.Fatalf("expected no ANSI codes without Color, got:\n%q", plain)
+	}
+
+	manager.Color = true
+	colored := captureStdout(t, func() {
+		if err := manager.Status(nil, false, 1); !errors.Is(err, ErrNotClean) {
+			t.Fatalf("Status() error = %v, want ErrNotClean", err)
+		}
+	})
+	if !strings.Contains(colored, "\x1b[33mdirty\x1b[0m") {
+		t.Fatalf("expected dirty flag in yellow, got:\n%q", colored)
+	}
+}
+
+func TestQuietLogLevelKeepsOnlyProblems(t *testing.T) {
+	base := t.TempDir()
+	clean := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	dirty := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-work"))
+	writeFile(t, filepath.Join(dirty.workPath, "scratch.txt"), "wip\n")
+
+	targets := []config.Target{repoTarget(clean), repoTarget(dirty)}
+	manager := newTestManager(targets, fakeClientForRepos(clean, dirty))
+	manager.LogLevel = slog.LevelWarn
+	output := captureStdout(t, func() {
+		if err := manager.Pull(nil, 1); err != nil {
+			t.Fatalf("Pull() error = %v", err)
+		}
+	})
+	if output != "" {
+		t.Fatalf("expected no output at warn level, got:\n%s", output)
+	}
+}
+
+func TestStructuredLoggerReceivesRepoEvents(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	writeFile(t, filepath.Join(repo.workPath, "scratch.txt"), "wip\n")
+
+	var logs strings.Builder
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	manager.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	output := captureStdout(t, func() {
+		if err := manager.Pull(nil, 1); err != nil {
+			t.Fatalf("Pull() error = %v", err)
+		}
+	})
+	if output != "" {
+		t.Fatalf("expected progress on the logger only, got stdout:\n%s", output)
+	}
+
+	var record struct {
+		Level  string `json:"level"`
+		Msg    string `json:"msg"`
+		Repo   string `json:"repo"`
+		Detail string `json:"detail"`
+	}
+	first, _, _ := strings.Cut(logs.String(), "\n")
+	if err := json.Unmarshal([]byte(first), &record); err != nil {
+		t.Fatalf("log line is not JSON: %v\n%s", err, logs.String())
+	}
+	if record.Level != "INFO" || record.Msg != "skip" || record.Repo != repo.workPath || record.Detail != "dirty" {
+		t.Fatalf("record = %+v, want skip of %s (dirty)", record, repo.workPath)
+	}
+}
+
+func TestPullReportsFailedReposInError(t *testing.T) {
+	base := t.TempDir()
+	ok := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	broken := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-work"))
+	if err := os.RemoveAll(broken.remotePath); err != nil {
+		t.Fatal(err)
+	}
+
+	targets := []config.Target{repoTarget(ok), repoTarget(broken)}
+	manager := newTestManager(targets, fakeClientForRepos(ok, broken))
+	var err error
+	captureStdout(t, func() {
+		err = manager.Pull(nil, 1)
+	})
+
+	var failures *RepoFailures
+	if !errors.As(err, &failures) {
+		t.Fatalf("Pull() error = %v, want *RepoFailures", err)
+	}
+	if failures.Failed != 1 || failures.Total != 2 {
+		t.Fatalf("failures = %+v, want 1 of 2", failures)
+	}
+}
+
+func TestDiscoverGroupsCheckoutsByOwner(t *testing.T) {
+	base := t.TempDir()
+	work := filepath.Join(base, "work")
+	for _, dir := range []string{"acme", "misc", "managed"} {
+		if err := os.MkdirAll(filepath.Join(work, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkouts := map[string]string{
+		"acme/api":     "https://gitea.acme.com/acme/api.git",
+		"acme/web":     "git@gitea.acme.com:acme/web.git",
+		"misc/tool":    "https://github.com/someone/tool.git",
+		"managed/kept": "https://gitea.acme.com/acme/kept.git",
+	}
+	for dir, origin := range checkouts {
+		r := createTestRepo(t, base, "x", strings.ReplaceAll(dir, "/", "-"), "main", filepath.Join(work, dir))
+		runGit(t, r.workPath, "remote", "set-url", "origin", origin)
+	}
+
+	existing := &config.Config{
+		Providers: map[string]config.Provider{"corp": {Type: "gitea", APIURL: "https://gitea.acme.com"}},
+		Targets:   []config.Target{{Name: "kept", Provider: "corp", Org: "acme", Repo: "kept", Path: filepath.Join(work, "managed", "kept")}},
+	}
+	d, err := Discover(work, existing)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	if len(d.Providers) != 1 || d.Providers["github"].Type != "github" {
+		t.Fatalf("providers = %+v, want only a new github provider", d.Providers)
+	}
+	if len(d.Targets) != 2 {
+		t.Fatalf("targets = %+v, want an org target and a repo target", d.Targets)
+	}
+	org, repo := d.Targets[0], d.Targets[1]
+	if org.Provider != "corp" || org.Org != "acme" || org.Repo != "" || org.Path != filepath.Join(work, "acme") ||
+		strings.Join(org.Include, ",") != "api,web" {
+		t.Errorf("org target = %+v, want corp/acme limited to api and web", org)
+	}
+	if repo.Provider != "github" || repo.Org != "someone" || repo.Repo != "tool" || repo.Name != "tool" {
+		t.Errorf("repo target = %+v, want github someone/tool", repo)
+	}
+	if len(d.Skipped) != 1 || !strings.Contains(d.Skipped[0], "already managed by target kept") {
+		t.Errorf("skipped = %v, want the managed checkout", d.Skipped)
+	}
+}
+
+func TestBranchesFlagsFeatureBranchesAndUnpushedCommits(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-work"))
+
+	runGit(t, app.workPath, "switch", "-c", "feature/x")
+	commitFile(t, app.workPath, "x.txt", "x\n", "feature commit")
+	commitFile(t, app.workPath, "y.txt", "y\n", "another feature commit")
+
+	manager := newTestManager([]config.Target{repoTarget(app), repoTarget(lib)}, fakeClientForRepos(app, lib))
+	output := captureStdout(t, func() {
+		if err := manager.Branches(nil, 1); err != nil {
+			t.Fatalf("Branches() error = %v", err)
+		}
+	})
+
+	if !strings.Contains(output, app.workPath+" (feature/x) [not on main]") {
+		t.Errorf("expected app flagged as off its default branch, got:\n%s", output)
+	}
+	if !strings.Contains(output, "feature/x: 2 unpushed") {
+		t.Errorf("expected unpushed count for feature/x, got:\n%s", output)
+	}
+	if !strings.Contains(output, lib.workPath+" (main)\n") {
+		t.Errorf("expected lib listed on main without flags, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Summary: 2 repos, 1 not on default branch, 1 with unpushed commits") {
+		t.Errorf("unexpected summary:\n%s", output)
+	}
+}
+
+func TestCheckoutSwitchesReposAndReportsMissingBranch(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-work"))
+	dirty := createTestRepo(t, base, "acme", "web", "main", filepath.Join(base, "web-work"))
+
+	// release/1.0 exists only on app's remote and as a local branch in web.
+	other := cloneRepo(t, app.remotePath, filepath.Join(base, "app-other"))
+	runGit(t, other, "switch", "-c", "release/1.0")
+	commitFile(t, other, "release.txt", "1.0\n", "release commit")
+	runGit(t, other, "push", "-u", "origin", "release/1.0")
+	runGit(t, dirty.workPath, "branch", "release/1.0")
+	writeFile(t, filepath.Join(dirty.workPath, "README.md"), "local edit\n")
+
+	manager := newTestManager([]config.Target{repoTarget(app), repoTarget(lib), repoTarget(dirty)}, fakeClientForRepos(app, lib, dirty))
+	output := captureStdout(t, func() {
+		if err := manager.Checkout("release/1.0", nil, 1); err != nil {
+			t.Fatalf("Checkout() error = %v", err)
+		}
+	})
+
+	if branch := currentBranch(t, app.workPath); branch != "release/1.0" {
+		t.Errorf("app branch = %q, want release/1.0 tracking origin", branch)
+	}
+	if branch := currentBranch(t, lib.workPath); branch != "main" {
+		t.Errorf("lib branch = %q, want main", branch)
+	}
+	if branch := currentBranch(t, dirty.workPath); branch != "main" {
+		t.Errorf("dirty web branch = %q, want main (skipped)", branch)
+	}
+	if !strings.Contains(output, "No branch release/1.0 in 1 repos:\n  "+lib.workPath) {
+		t.Errorf("expected lib reported as missing the branch, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Checkout complete: 1 switched, 2 skipped, 0 failed") {
+		t.Errorf("unexpected summary:\n%s", output)
+	}
+}
+
+func TestSwitchDefaultMovesRenamedDefaultBranch(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "master", filepath.Join(base, "app-work"))
+
+	// Rename master -> main on the remote, as a hosting UI would.
+	runGit(t, repo.remotePath, "branch", "-m", "master", "main")
+	repo.defaultBranch = "main"
+
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].DefaultDrift != "master" {
+		t.Fatalf("statuses = %+v, want default drift from master", statuses)
+	}
+
+	output := captureStdout(t, func() {
+		if err := manager.SwitchDefault(nil, 1); err != nil {
+			t.Fatalf("SwitchDefault() error = %v", err)
+		}
+	})
+	if branch := currentBranch(t, repo.workPath); branch != "main" {
+		t.Fatalf("current branch = %q, want main\n%s", branch, output)
+	}
+	if head := strings.TrimSpace(runGit(t, repo.workPath, "symbolic-ref", "refs/remotes/origin/HEAD")); head != "refs/remotes/origin/main" {
+		t.Errorf("origin/HEAD = %q, want refs/remotes/origin/main", head)
+	}
+	statuses, err = manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if statuses[0].DefaultDrift != "" {
+		t.Errorf("drift still reported after switch: %+v", statuses[0])
+	}
+}
+
+func TestSyncAutostashKeepsLocalChangesAndReportsConflicts(t *testing.T) {
+	base := t.TempDir()
+	clean := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	clash := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-work"))
+
+	for _, repo := range []testRepo{clean, clash} {
+		other := cloneRepo(t, repo.remotePath, filepath.Join(base, repo.name+"-other"))
+		commitFile(t, other, "README.md", "from remote\n", "remote update")
+		runGit(t, other, "push", "origin", "main")
+	}
+	writeFile(t, filepath.Join(clean.workPath, "scratch.txt"), "notes\n")
+	writeFile(t, filepath.Join(clash.workPath, "README.md"), "local edit\n")
+
+	manager := newTestManager([]config.Target{repoTarget(clean), repoTarget(clash)}, fakeClientForRepos(clean, clash))
+	p := manager.config.Providers["fake"]
+	p.Options.Sync.Autostash = true
+	manager.config.Providers["fake"] = p
+
+	var err error
+	output := captureStdout(t, func() {
+		err = manager.Sync(nil, 1)
+	})
+
+	var failures *RepoFailures
+	if !errors.As(err, &failures) || failures.Failed != 1 {
+		t.Fatalf("Sync() error = %v, want the conflicting repo reported as failed", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(clean.workPath, "README.md")); string(data) != "from remote\n" {
+		t.Errorf("app README = %q, want pulled content", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(clean.workPath, "scratch.txt")); string(data) != "notes\n" {
+		t.Errorf("app scratch.txt = %q, want local file restored", data)
+	}
+	if !strings.Contains(output, "[ERROR] "+clash.workPath+": stash pop conflicted") {
+		t.Errorf("expected stash pop conflict reported, got:\n%s", output)
+	}
+	if stashes := runGit(t, clash.workPath, "stash", "list"); !strings.Contains(stashes, "tugboat autostash") {
+		t.Errorf("conflicting changes should stay in the stash, got %q", stashes)
+	}
+}
+
+func TestPushForceWithLeaseOnlyForAllowForceTargets(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+
+	other := cloneRepo(t, repo.remotePath, filepath.Join(base, "other"))
+	commitFile(t, other, "remote.txt", "remote\n", "remote commit")
+	runGit(t, other, "push", "origin", "main")
+	commitFile(t, repo.workPath, "local.txt", "local\n", "rewritten commit")
+
+	target := repoTarget(repo)
+	manager := newTestManager([]config.Target{target}, fakeClientForRepos(repo))
+	manager.ForceWithLease = true
+	captureStdout(t, func() {
+		if err := manager.Push(nil, 1); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	})
+	local := strings.TrimSpace(runGit(t, repo.workPath, "rev-parse", "HEAD"))
+	if remote := strings.TrimSpace(runGit(t, repo.remotePath, "rev-parse", "main")); remote == local {
+		t.Fatal("diverged repo was force-pushed although the target does not set allow_force")
+	}
+
+	target.AllowForce = true
+	manager = newTestManager([]config.Target{target}, fakeClientForRepos(repo))
+	manager.ForceWithLease = true
+	output := captureStdout(t, func() {
+		if err := manager.Push(nil, 1); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	})
+	if remote := strings.TrimSpace(runGit(t, repo.remotePath, "rev-parse", "main")); remote != local {
+		t.Fatalf("remote main = %s, want force-pushed %s\n%s", remote, local, output)
+	}
+}
+
+func TestPushSetsUpstreamForNewBranchWhenEnabled(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	runGit(t, repo.workPath, "switch", "-c", "feature/new")
+	commitFile(t, repo.workPath, "new.txt", "new\n", "new branch commit")
+
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	output := captureStdout(t, func() {
+		if err := manager.Push(nil, 1); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	})
+	if !strings.Contains(output, "feature/new has no upstream") {
+		t.Errorf("expected new branch reported as skipped, got:\n%s", output)
+	}
+
+	p := manager.config.Providers["fake"]
+	p.Options.Push.SetUpstream = true
+	manager.config.Providers["fake"] = p
+	output = captureStdout(t, func() {
+		if err := manager.Push(nil, 1); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	})
+	if !strings.Contains(output, "new branch feature/new, 1 commits") {
+		t.Errorf("expected push of the new branch, got:\n%s", output)
+	}
+	if upstream := strings.TrimSpace(runGit(t, repo.workPath, "rev-parse", "--abbrev-ref", "feature/new@{upstream}")); upstream != "origin/feature/new" {
+		t.Errorf("upstream = %q, want origin/feature/new", upstream)
+	}
+}
+
+func TestCreateTagTagsCleanReposAndPushes(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	dirty := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-work"))
+	writeFile(t, filepath.Join(dirty.workPath, "README.md"), "local edit\n")
+
+	manager := newTestManager([]config.Target{repoTarget(app), repoTarget(dirty)}, fakeClientForRepos(app, dirty))
+	output := captureStdout(t, func() {
+		if err := manager.CreateTag("v1.0.0", TagOptions{Message: "Release 1.0.0", Push: true}, nil, 1); err != nil {
+			t.Fatalf("CreateTag() error = %v", err)
+		}
+	})
+
+	if tags := strings.TrimSpace(runGit(t, app.remotePath, "tag", "--list")); tags != "v1.0.0" {
+		t.Errorf("remote tags = %q, want v1.0.0 pushed", tags)
+	}
+	if kind := strings.TrimSpace(runGit(t, app.workPath, "cat-file", "-t", "v1.0.0")); kind != "tag" {
+		t.Errorf("v1.0.0 is a %s, want an annotated tag", kind)
+	}
+	if tags := strings.TrimSpace(runGit(t, dirty.workPath, "tag", "--list")); tags != "" {
+		t.Errorf("dirty repo was tagged: %q", tags)
+	}
+	if !strings.Contains(output, "Tag complete: 1 tagged, 1 skipped, 0 failed") {
+		t.Errorf("unexpected summary:\n%s", output)
+	}
+
+	output = captureStdout(t, func() {
+		if err := manager.Tags([]string{"app"}, 1); err != nil {
+			t.Fatalf("Tags() error = %v", err)
+		}
+	})
+	if !strings.Contains(output, app.workPath+": v1.0.0") {
+		t.Errorf("expected tag listed, got:\n%s", output)
+	}
+}
+
+func TestGrepAggregatesMatchesAcrossRepos(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-work"))
+	commitFile(t, app.workPath, "config/app.yaml", "name: app\ntimeout: 30\n", "add config")
+	commitFile(t, lib.workPath, "lib.go", "package lib\n", "add code")
+
+	manager := newTestManager([]config.Target{repoTarget(app), repoTarget(lib)}, fakeClientForRepos(app, lib))
+	output := captureStdout(t, func() {
+		if err := manager.Grep("TIMEOUT", GrepOptions{IgnoreCase: true}, nil, 2); err != nil {
+			t.Fatalf("Grep() error = %v", err)
+		}
+	})
+	if !strings.Contains(output, app.workPath+"\n  config/app.yaml:2: timeout: 30\n") {
+		t.Errorf("expected match with repo-relative path, got:\n%s", output)
+	}
+	if strings.Contains(output, lib.workPath+"\n") {
+		t.Errorf("lib has no match but was listed:\n%s", output)
+	}
+
+	captureStdout(t, func() {
+		if err := manager.Grep("no-such-text", GrepOptions{}, nil, 2); !errors.Is(err, ErrNoMatches) {
+			t.Errorf("Grep() error = %v, want ErrNoMatches", err)
+		}
+	})
+}
+
+func TestWatchRoundLogsStateChanges(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+
+	var states map[string]string
+	output := captureStdout(t, func() {
+		states = manager.watchRound(nil, false, 1, nil)
+	})
+	if states[repo.workPath] != "clean" || strings.Contains(output, "[CHANGE]") {
+		t.Fatalf("first round states = %v, output:\n%s", states, output)
+	}
+
+	writeFile(t, filepath.Join(repo.workPath, "README.md"), "local edit\n")
+	output = captureStdout(t, func() {
+		states = manager.watchRound(nil, false, 1, states)
+	})
+	if !strings.Contains(output, "[CHANGE] "+repo.workPath+": clean -> dirty") {
+		t.Errorf("expected change to dirty logged, got:\n%s", output)
+	}
+	if !strings.Contains(output, "1 repos: 0 clean, 1 dirty") {
+		t.Errorf("expected round summary, got:\n%s", output)
+	}
+}
+
+func TestPullRemoteRepoPullsOnlyMatchingCheckout(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-work"))
+	for _, repo := range []testRepo{app, lib} {
+		other := cloneRepo(t, repo.remotePath, filepath.Join(base, repo.name+"-other"))
+		commitFile(t, other, "remote.txt", "remote\n", "remote update")
+		runGit(t, other, "push", "origin", "main")
+	}
+
+	manager := newTestManager([]config.Target{repoTarget(app), repoTarget(lib)}, fakeClientForRepos(app, lib))
+	var results []RepoResult
+	captureStdout(t, func() {
+		var err error
+		results, err = manager.PullRemoteRepo("fake", "ACME/app", "main")
+		if err != nil {
+			t.Fatalf("PullRemoteRepo() error = %v", err)
+		}
+	})
+	if len(results) != 1 || results[0].Path != app.workPath || results[0].Result != "pulled" {
+		t.Fatalf("results = %+v, want app pulled", results)
+	}
+	if _, err := os.Stat(filepath.Join(lib.workPath, "remote.txt")); err == nil {
+		t.Error("lib was pulled although the push was for app")
+	}
+}
+
+// creatingClient is a fakeClient that creates bare repos under dir.
+type creatingClient struct {
+	fakeClient
+	dir string
+}
+
+func (c creatingClient) CreateRepo(owner, name string, opts remote.CreateOptions) (*remote.Repository, error) {
+	path := filepath.Join(c.dir, name+".git")
+	if out, err := exec.Command("git", "init", "--bare", path).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, out)
+	}
+	return &remote.Repository{Name: name, FullName: owner + "/" + name, CloneURL: path, Empty: true}, nil
+}
+
+func TestCloneFoldoutWithSparseCheckout(t *testing.T) {
+	base := t.TempDir()
+	parent := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	mono := createTestRepo(t, base, "acme", "mono", "main", filepath.Join(base, "mono-seed"))
+	commitFile(t, mono.workPath, "docs/guide.md", "guide\n", "add docs")
+	commitFile(t, mono.workPath, "assets/big.bin", "binary\n", "add assets")
+	runGit(t, mono.workPath, "push")
+	writeFile(t, filepath.Join(parent.workPath, ".tugboat.json"), `{"repos": [{"name": "acme/mono", "sparse": ["docs"]}]}`)
+
+	monoRepo := remoteRepo(mono)
+	monoRepo.CloneURL = mono.remotePath
+	client := fakeClientForRepos(parent)
+	client.repos["acme"]["mono"] = monoRepo
+	manager := newTestManager([]config.Target{repoTarget(parent)}, client)
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+
+	clonePath := filepath.Join(parent.workPath, "mono")
+	if _, err := os.Stat(filepath.Join(clonePath, "docs", "guide.md")); err != nil {
+		t.Errorf("sparse directory missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(clonePath, "README.md")); err != nil {
+		t.Errorf("top-level file missing in cone mode: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(clonePath, "assets")); !os.IsNotExist(err) {
+		t.Errorf("assets checked out despite sparse list: %v", err)
+	}
+
+	writeFile(t, filepath.Join(parent.workPath, ".tugboat.json"), `{"repos": [{"name": "acme/mono", "sparse": ["../etc"]}]}`)
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err == nil {
+			t.Error("Clone() accepted a sparse directory outside the repo")
+		}
+	})
+}
+
+func TestCreateClonesIntoOrgTargetOrFoldout(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	orgPath := filepath.Join(base, "acme")
+	targets := []config.Target{
+		repoTarget(app),
+		{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath},
+	}
+	manager := newTestManager(targets, fakeClientForRepos(app))
+	manager.providers["fake"] = creatingClient{fakeClient: manager.providers["fake"].(fakeClient), dir: t.TempDir()}
+
+	captureStdout(t, func() {
+		if err := manager.Create("fake", "acme", "svc", remote.CreateOptions{DefaultBranch: "trunk"}, ""); err != nil {
+			t.Errorf("Create() error = %v", err)
+		}
+		if err := manager.Create("fake", "acme", "plugin", remote.CreateOptions{}, "app"); err != nil {
+			t.Errorf("Create() with foldout error = %v", err)
+		}
+	})
+
+	svc := filepath.Join(orgPath, "svc")
+	if head := strings.TrimSpace(runGit(t, svc, "symbolic-ref", "HEAD")); head != "refs/heads/trunk" {
+		t.Errorf("HEAD of %s = %q, want refs/heads/trunk", svc, head)
+	}
+	if !isGitRepo(filepath.Join(app.workPath, "plugin")) {
+		t.Error("foldout repo was not cloned into the repo target")
+	}
+	fc, err := loadFoldout(app.workPath)
+	if err != nil || fc == nil || len(fc.Repos) != 1 || fc.Repos[0].Name != "acme/plugin" {
+		t.Errorf("foldout config = %+v, %v; want acme/plugin", fc, err)
+	}
+
+	if err := manager.Create("fake", "other", "svc", remote.CreateOptions{}, ""); err == nil {
+		t.Error("Create() for an owner without a target succeeded")
+	}
+}
+
+func TestCloneForkAddsUpstreamAndStatusCountsUpstreamBehind(t *testing.T) {
+	manager, parent, clonePath, output := cloneTestFork(t)
+	if got := strings.TrimSpace(runGit(t, clonePath, "remote", "get-url", "upstream")); got != parent.remotePath {
+		t.Fatalf("upstream remote = %q, want %q\n%s", got, parent.remotePath, output)
+	}
+	if !strings.Contains(output, "[UPSTREAM] "+clonePath+": fork of oss/lib") {
+		t.Errorf("expected upstream output, got:\n%s", output)
+	}
+
+	commitFile(t, parent.workPath, "new.txt", "new\n", "upstream change")
+	runGit(t, parent.workPath, "push", "origin", "main")
+
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].UpstreamBehind != 1 || statuses[0].RemoteError != "" {
+		t.Fatalf("statuses = %+v, want 1 upstream-behind", statuses)
+	}
+}
+
+func TestForkSyncFastForwardsOriginFromUpstream(t *testing.T) {
+	manager, parent, clonePath, _ := cloneTestFork(t)
+	commitFile(t, parent.workPath, "new.txt", "new\n", "upstream change")
+	runGit(t, parent.workPath, "push", "origin", "main")
+	want := strings.TrimSpace(runGit(t, parent.workPath, "rev-parse", "HEAD"))
+
+	output := captureStdout(t, func() {
+		if err := manager.ForkSync(nil, false, 1); err != nil {
+			t.Errorf("ForkSync() error = %v", err)
+		}
+	})
+	if got := strings.TrimSpace(runGit(t, clonePath, "rev-parse", "origin/main")); got != want {
+		t.Errorf("origin/main = %s, want upstream %s\n%s", got, want, output)
+	}
+	if got := strings.TrimSpace(runGit(t, clonePath, "rev-parse", "HEAD")); got != want {
+		t.Errorf("local main = %s, want fast-forwarded to %s", got, want)
+	}
+	if !strings.Contains(output, "Fork sync complete: 1 synced, 0 skipped, 0 failed") {
+		t.Errorf("expected summary, got:\n%s", output)
+	}
+
+	// A fork with its own commits on the default branch is left alone.
+	configureGitIdentity(t, clonePath)
+	commitFile(t, clonePath, "local.txt", "local\n", "fork change")
+	runGit(t, clonePath, "push", "origin", "main")
+	commitFile(t, parent.workPath, "more.txt", "more\n", "another upstream change")
+	runGit(t, parent.workPath, "push", "origin", "main")
+	output = captureStdout(t, func() {
+		if err := manager.ForkSync(nil, false, 1); err != nil {
+			t.Errorf("ForkSync() error = %v", err)
+		}
+	})
+	if !strings.Contains(output, "origin/main has commits upstream lacks") {
+		t.Errorf("expected diverged fork to be skipped, got:\n%s", output)
+	}
+}
+
+// cloneTestFork clones a fork of a parent repo through an org target whose
+// listing, like GitHub's, omits the fork's parent.
+func cloneTestFork(t *testing.T) (manager *Manager, parent testRepo, clonePath, output string) {
+	t.Helper()
+	base := t.TempDir()
+	parent = createTestRepo(t, base, "oss", "lib", "main", filepath.Join(base, "parent-work"))
+	forkPath := filepath.Join(base, "fork.git")
+	runGit(t, base, "clone", "--bare", parent.remotePath, forkPath)
+
+	fork := remote.Repository{
+		Name:          "lib",
+		FullName:      "acme/lib",
+		CloneURL:      forkPath,
+		DefaultBranch: "main",
+		Fork:          true,
+	}
+	listed := fakeClient{repos: map[string]map[string]remote.Repository{"acme": {"lib": fork}}}
+	parentRepo := remoteRepo(parent)
+	parentRepo.CloneURL = parent.remotePath
+	fork.Parent = &parentRepo
+	withParent := fakeClient{repos: map[string]map[string]remote.Repository{"acme": {"lib": fork}}}
+
+	target := config.Target{Name: "acme", Provider: "fake", Org: "acme", Path: filepath.Join(base, "acme")}
+	manager = newTestManager([]config.Target{target}, withParent)
+	manager.providers["fake"] = listingClient{fakeClient: withParent, listed: listed}
+	output = captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+	return manager, parent, filepath.Join(target.Path, "lib"), output
+}
+
+// listingClient answers listings from listed and single-repo lookups from the
+// embedded client.
+type listingClient struct {
+	fakeClient
+	listed fakeClient
+}
+
+func (c listingClient) ListOrgRepos(orgName string) ([]remote.Repository, error) {
+	return c.listed.ListOrgRepos(orgName)
+}
+
+func TestMigrateReposPushesBranchesAndSkipsExisting(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "old", "app", "main", filepath.Join(base, "app-work"))
+	runGit(t, app.workPath, "push", "origin", "main:release")
+	lib := createTestRepo(t, base, "old", "lib", "main", filepath.Join(base, "lib-work"))
+
+	source := fakeClientForRepos(app, lib)
+	for name, r := range map[string]testRepo{"app": app, "lib": lib} {
+		rr := source.repos["old"][name]
+		rr.CloneURL = r.remotePath
+		source.repos["old"][name] = rr
+	}
+	manager := newTestManager(nil, source)
+	destDir := t.TempDir()
+	existing := map[string]map[string]remote.Repository{"new": {"lib": {Name: "lib", FullName: "new/lib"}}}
+	manager.config.Providers["dest"] = config.Provider{Type: "github"}
+	manager.providers["dest"] = creatingClient{fakeClient: fakeClient{repos: existing}, dir: destDir}
+
+	opts := MigrateOptions{Rename: map[string]string{"app": "app-svc"}}
+	output := captureStdout(t, func() {
+		if err := manager.MigrateRepos("fake", "old", "dest", "new", opts); err != nil {
+			t.Errorf("MigrateRepos() error = %v", err)
+		}
+	})
+
+	dest := filepath.Join(destDir, "app-svc.git")
+	branches := runGit(t, dest, "branch", "--format=%(refname:short)")
+	if !strings.Contains(branches, "main") || !strings.Contains(branches, "release") {
+		t.Errorf("destination branches = %q, want main and release\n%s", branches, output)
+	}
+	if !strings.Contains(output, "[1/2] old/app -> new/app-svc") {
+		t.Errorf("expected progress line, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Migration complete: 1 migrated, 1 skipped, 0 failed") {
+		t.Errorf("expected existing lib to be skipped, got:\n%s", output)
+	}
+}
+
+type prClient struct {
+	fakeClient
+	prs     map[string][]remote.PullRequest // by repo name
+	created map[string]remote.NewPullRequest
+}
+
+func (c prClient) ListPullRequests(owner, name string) ([]remote.PullRequest, error) {
+	return c.prs[name], nil
+}
+
+func (c prClient) CreatePullRequest(owner, name string, pr remote.NewPullRequest) (*remote.PullRequest, error) {
+	c.created[name] = pr
+	return &remote.PullRequest{Number: 1, Title: pr.Title, URL: "https://example.com/" + owner + "/" + name + "/pulls/1"}, nil
+}
+
+func TestPullRequestsListsOpenPRsOldestFirst(t *testing.T) {
+	base := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(base, "acme"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "acme", "app"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "acme", "lib"))
+
+	now := time.Now()
+	client := prClient{fakeClient: fakeClientForRepos(app, lib), prs: map[string][]remote.PullRequest{
+		"app": {
+			{Number: 7, Title: "Newer", Author: "bob", Created: now.Add(-3 * time.Hour), Review: remote.ReviewPending},
+			{Number: 4, Title: "Older", Author: "ann", Created: now.Add(-72 * time.Hour), Review: remote.ReviewApproved},
+		},
+	}}
+	manager := newTestManager([]config.Target{{Name: "acme", Provider: "fake", Org: "acme", Path: filepath.Join(base, "acme")}}, client.fakeClient)
+	manager.providers["fake"] = client
+
+	output := captureStdout(t, func() {
+		if err := manager.PullRequests(nil, 2); err != nil {
+			t.Errorf("PullRequests() error = %v", err)
+		}
+	})
+
+	older := strings.Index(output, "#4     Older  ann, 3d  approved")
+	newer := strings.Index(output, "#7     Newer  bob, 3h  pending")
+	if older < 0 || newer < 0 || older > newer {
+		t.Errorf("expected both PRs oldest first, got:\n%s", output)
+	}
+	if strings.Contains(output, lib.workPath) {
+		t.Errorf("repo without open PRs should not be listed, got:\n%s", output)
+	}
+	if !strings.Contains(output, "2 open pull requests in 1 repos (2 checked)") {
+		t.Errorf("expected summary line, got:\n%s", output)
+	}
+}
+
+func TestCreatePullRequestsPushesAheadBranchesAndSkipsOpen(t *testing.T) {
+	base := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(base, "acme"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "acme", "app"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "acme", "lib"))
+	web := createTestRepo(t, base, "acme", "web", "main", filepath.Join(base, "acme", "web"))
+	for _, r := range []testRepo{app, web} {
+		runGit(t, r.workPath, "switch", "-c", "bump")
+		commitFile(t, r.workPath, "go.mod", "require x v2\n", "Bump x to v2")
+	}
+
+	client := prClient{
+		fakeClient: fakeClientForRepos(app, lib, web),
+		prs:        map[string][]remote.PullRequest{"web": {{Number: 9, Head: "bump", URL: "https://example.com/acme/web/pulls/9"}}},
+		created:    map[string]remote.NewPullRequest{},
+	}
+	manager := newTestManager([]config.Target{{Name: "acme", Provider: "fake", Org: "acme", Path: filepath.Join(base, "acme")}}, client.fakeClient)
+	manager.providers["fake"] = client
+
+	opts := PROptions{Title: "{{.Repo}}: {{.Subject}}", Body: "{{range .Commits}}- {{.}}\n{{end}}"}
+	output := captureStdout(t, func() {
+		if err := manager.CreatePullRequests(nil, opts, 2); err != nil {
+			t.Errorf("CreatePullRequests() error = %v", err)
+		}
+	})
+
+	want := remote.NewPullRequest{Title: "app: Bump x to v2", Body: "- Bump x to v2\n", Head: "bump", Base: "main"}
+	if got := client.created["app"]; got != want {
+		t.Errorf("app pull request = %+v, want %+v", got, want)
+	}
+	if len(client.created) != 1 {
+		t.Errorf("opened pull requests for %v, want only app", client.created)
+	}
+	if runGit(t, app.remotePath, "rev-parse", "refs/heads/bump") == "" {
+		t.Error("bump branch was not pushed to origin")
+	}
+	if !strings.Contains(output, "#9 already open") {
+		t.Errorf("expected web to be skipped, got:\n%s", output)
+	}
+	if !strings.Contains(output, "PR create complete: 1 opened, 1 skipped, 0 failed") {
+		t.Errorf("expected summary line, got:\n%s", output)
+	}
+}
+
+type issueClient struct {
+	fakeClient
+	open map[string]int // by repo name
+}
+
+func (c issueClient) CountOpenIssues(owner, name string, filter remote.IssueFilter) (int, error) {
+	return c.open[name], nil
+}
+
+func TestIssuesListsReposWithOpenIssuesMostFirst(t *testing.T) {
+	base := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(base, "acme"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "acme", "app"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "acme", "lib"))
+	web := createTestRepo(t, base, "acme", "web", "main", filepath.Join(base, "acme", "web"))
+
+	client := issueClient{fakeClient: fakeClientForRepos(app, lib, web), open: map[string]int{"app": 2, "web": 5}}
+	manager := newTestManager([]config.Target{{Name: "acme", Provider: "fake", Org: "acme", Path: filepath.Join(base, "acme")}}, client.fakeClient)
+	manager.providers["fake"] = client
+
+	output := captureStdout(t, func() {
+		if err := manager.Issues(nil, remote.IssueFilter{Labels: []string{"bug"}}, 2); err != nil {
+			t.Errorf("Issues() error = %v", err)
+		}
+	})
+
+	webLine := strings.Index(output, "5  "+web.workPath)
+	appLine := strings.Index(output, "2  "+app.workPath)
+	if webLine < 0 || appLine < 0 || webLine > appLine {
+		t.Errorf("expected web before app, got:\n%s", output)
+	}
+	if strings.Contains(output, lib.workPath) {
+		t.Errorf("repo without issues should not be listed, got:\n%s", output)
+	}
+	if !strings.Contains(output, "7 open issues labeled bug in 2 repos (3 checked)") {
+		t.Errorf("expected summary line, got:\n%s", output)
+	}
+}
+
+type settingsClient struct {
+	fakeClient
+	settings map[string]remote.RepoSettings // by repo name
+}
+
+func (c settingsClient) GetRepoSettings(owner, name string) (*remote.RepoSettings, error) {
+	s := c.settings[name]
+	return &s, nil
+}
+
+func TestAuditReportsDriftFromPolicy(t *testing.T) {
+	yes, no := true, false
+	client := settingsClient{
+		fakeClient: fakeClient{repos: map[string]map[string]remote.Repository{"acme": {
+			"app": {Name: "app"},
+			"lib": {Name: "lib"},
+			"old": {Name: "old", Archived: true},
+		}}},
+		settings: map[string]remote.RepoSettings{
+			"app": {DefaultBranch: "main", Private: true, BranchProtected: true, AllowSquashMerge: &yes},
+			"lib": {DefaultBranch: "master", Private: true, AllowSquashMerge: &no},
+		},
+	}
+	manager := newTestManager([]config.Target{{Name: "acme", Provider: "fake", Org: "acme", Path: t.TempDir()}}, client.fakeClient)
+	manager.providers["fake"] = client
+	manager.config.Audit = &config.AuditPolicy{DefaultBranch: "main", Visibility: "private", BranchProtection: &yes, AllowSquashMerge: &yes, DeleteBranchOnMerge: &yes}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = manager.Audit(nil, 2)
+	})
+	if !errors.Is(err, ErrDrift) {
+		t.Errorf("Audit() error = %v, want ErrDrift", err)
+	}
+
+	want := "[DRIFT]  fake/acme/lib: default_branch is master, want main; branch_protection is false, want true; allow_squash_merge is false, want true"
+	if !strings.Contains(output, want) {
+		t.Errorf("expected lib drift line, got:\n%s", output)
+	}
+	if strings.Contains(output, "acme/app:") || strings.Contains(output, "acme/old") {
+		t.Errorf("app matches the policy and old is archived; got:\n%s", output)
+	}
+	if !strings.Contains(output, "1 of 2 repos drift from the policy") {
+		t.Errorf("expected summary line, got:\n%s", output)
+	}
+}
+
+func TestStatusNoFetchUsesLastFetchedRefs(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	other := cloneRepo(t, repo.remotePath, filepath.Join(base, "other"))
+	commitFile(t, other, "remote.txt", "from remote\n", "remote update")
+	runGit(t, other, "push", "origin", "main")
+
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	manager.NoFetch = true
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Behind != 0 {
+		t.Fatalf("no-fetch statuses = %+v, want one repo not behind", statuses)
+	}
+	if out := runGit(t, repo.workPath, "rev-parse", "origin/main"); strings.TrimSpace(out) == strings.TrimSpace(runGit(t, other, "rev-parse", "HEAD")) {
+		t.Error("no-fetch status fetched origin")
+	}
+
+	manager.NoFetch = false
+	statuses, err = manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if statuses[0].Behind != 1 {
+		t.Errorf("fetched status behind = %d, want 1", statuses[0].Behind)
+	}
+}
+
+func TestStatusCountsStashes(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	configureGitIdentity(t, repo.workPath)
+	for _, contents := range []string{"first\n", "second\n"} {
+		writeFile(t, filepath.Join(repo.workPath, "README.md"), contents)
+		runGit(t, repo.workPath, "stash", "push", "--quiet")
+	}
+
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if statuses[0].Stashes != 2 || statuses[0].Dirty {
+		t.Fatalf("status = %+v, want 2 stashes and a clean tree", statuses[0])
+	}
+	output := captureStdout(t, func() {
+		if err := manager.Status(nil, false, 1); err != nil {
+			t.Fatalf("Status() error = %v, stashes alone should not fail it", err)
+		}
+	})
+	if !strings.Contains(output, "2 stashed") {
+		t.Fatalf("status output missing stash flag:\n%s", output)
+	}
+}
+
+func TestStatusAllBranchesFlagsUnpushedBranches(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	configureGitIdentity(t, repo.workPath)
+	runGit(t, repo.workPath, "switch", "--quiet", "-c", "feature")
+	commitFile(t, repo.workPath, "feature.txt", "wip\n", "feature work")
+	runGit(t, repo.workPath, "switch", "--quiet", "main")
+
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	captureStdout(t, func() {
+		if err := manager.Status(nil, false, 1); err != nil {
+			t.Fatalf("Status() error = %v, want nil without --all-branches", err)
+		}
+	})
+
+	manager.AllBranches = true
+	output := captureStdout(t, func() {
+		if err := manager.Status(nil, false, 1); !errors.Is(err, ErrNotClean) {
+			t.Fatalf("Status() error = %v, want ErrNotClean", err)
+		}
+	})
+	if !strings.Contains(output, "1 branch unpushed") || !strings.Contains(output, "feature: 1 unpushed") {
+		t.Fatalf("status output missing unpushed branch:\n%s", output)
+	}
+}
+
+func TestStatusSortOrders(t *testing.T) {
+	base := t.TempDir()
+	alpha := createTestRepo(t, base, "acme", "alpha", "main", filepath.Join(base, "alpha"))
+	beta := createTestRepo(t, base, "acme", "beta", "main", filepath.Join(base, "beta"))
+	other := cloneRepo(t, beta.remotePath, filepath.Join(base, "other"))
+	commitFile(t, other, "one.txt", "1\n", "one")
+	commitFile(t, other, "two.txt", "2\n", "two")
+	runGit(t, other, "push", "origin", "main")
+	writeFile(t, filepath.Join(alpha.workPath, "README.md"), "edited\n")
+
+	manager := newTestManager([]config.Target{repoTarget(alpha), repoTarget(beta)}, fakeClientForRepos(alpha, beta))
+	manager.JSON = true
+	order := func(by string) []string {
+		t.Helper()
+		manager.Sort = by
+		output := captureStdout(t, func() { _ = manager.Status(nil, false, 1) })
+		var statuses []RepoStatus
+		if err := json.Unmarshal([]byte(output), &statuses); err != nil {
+			t.Fatalf("decoding %q: %v", output, err)
+		}
+		var names []string
+		for _, s := range statuses {
+			names = append(names, s.Name)
+		}
+		return names
+	}
+	for by, want := range map[string][]string{
+		"":       {"alpha", "beta"},
+		"behind": {"beta", "alpha"},
+		"mtime":  {"alpha", "beta"},
+	} {
+		if got := order(by); !reflect.DeepEqual(got, want) {
+			t.Errorf("sort %q = %v, want %v", by, got, want)
+		}
+	}
+
+	manager.Sort = "size"
+	if err := manager.Status(nil, false, 1); err == nil || !strings.Contains(err.Error(), "unknown sort") {
+		t.Fatalf("Status() with sort size error = %v", err)
+	}
+}
+
+func TestStatusAndListFormatTemplate(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	writeFile(t, filepath.Join(repo.workPath, "README.md"), "edited\n")
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+
+	tmpl, err := ParseFormat(`{{.Name}}\t{{.Branch}}\t{{if .Dirty}}dirty{{end}}`)
+	if err != nil {
+		t.Fatalf("ParseFormat() error = %v", err)
+	}
+	manager.Format = tmpl
+	output := captureStdout(t, func() {
+		if err := manager.Status(nil, false, 1); !errors.Is(err, ErrNotClean) {
+			t.Fatalf("Status() error = %v, want ErrNotClean", err)
+		}
+	})
+	if output != "app\tmain\tdirty\n" {
+		t.Fatalf("formatted status = %q", output)
+	}
+
+	if manager.Format, err = ParseFormat(`{{.Target}} {{.Local}}`); err != nil {
+		t.Fatalf("ParseFormat() error = %v", err)
+	}
+	output = captureStdout(t, func() {
+		if err := manager.List(nil, false, 1); err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+	})
+	if output != "app true\n" {
+		t.Fatalf("formatted list = %q", output)
+	}
+
+	if _, err := ParseFormat("{{.Name"); err == nil {
+		t.Fatal("ParseFormat() accepted an unterminated action")
+	}
+}
+
+func TestStatusAndListDelimitedOutput(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	writeFile(t, filepath.Join(repo.workPath, "README.md"), "edited, twice\n")
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+
+	manager.Output = "csv"
+	output := captureStdout(t, func() {
+		if err := manager.Status(nil, false, 1); !errors.Is(err, ErrNotClean) {
+			t.Fatalf("Status() error = %v, want ErrNotClean", err)
+		}
+	})
+	rows, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing %q: %v", output, err)
+	}
+	if len(rows) != 2 || rows[0][0] != "path" || rows[1][0] != repo.workPath {
+		t.Fatalf("status csv = %q", rows)
+	}
+	row := make(map[string]string)
+	for i, name := range rows[0] {
+		row[name] = rows[1][i]
+	}
+	if row["dirty"] != "true" || row["branch"] != "main" || row["behind"] != "0" {
+		t.Fatalf("status csv row = %v", row)
+	}
+
+	manager.Output = "tsv"
+	output = captureStdout(t, func() {
+		if err := manager.List(nil, false, 1); err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+	})
+	want := "target\tname\tpath\tlocal\tarchived\torphan\napp\tapp\t" + repo.workPath + "\ttrue\tfalse\tfalse\n"
+	if output != want {
+		t.Fatalf("list tsv = %q, want %q", output, want)
+	}
+}
+
+func TestNDJSONOutputWritesOneResultPerLine(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "seed"))
+	orgRepo := remoteRepo(repo)
+	orgRepo.CloneURL = repo.remotePath
+	client := fakeClient{repos: map[string]map[string]remote.Repository{"acme": {repo.name: orgRepo}}}
+	target := config.Target{Name: "acme", Provider: "fake", Org: "acme", Path: filepath.Join(base, "acme")}
+	manager := newTestManager([]config.Target{target}, client)
+	manager.Output = "ndjson"
+
+	decode := func(output string) []RepoResult {
+		t.Helper()
+		var results []RepoResult
+		for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+			var r RepoResult
+			if err := json.Unmarshal([]byte(line), &r); err != nil {
+				t.Fatalf("line %q is not a JSON result: %v", line, err)
+			}
+			results = append(results, r)
+		}
+		return results
+	}
+	output := captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+	if got := decode(output); len(got) != 1 || got[0].Result != "cloned" || got[0].Path != filepath.Join(target.Path, "api") {
+		t.Fatalf("clone ndjson = %+v", got)
+	}
+
+	output = captureStdout(t, func() {
+		if err := manager.Pull(nil, 1); err != nil {
+			t.Fatalf("Pull() error = %v", err)
+		}
+	})
+	if got := decode(output); len(got) != 1 || got[0].Name != "api" || got[0].Result == "failed" {
+		t.Fatalf("pull ndjson = %+v", got)
+	}
+}
+
+func TestStatusProblemsOnlyHidesCleanRepos(t *testing.T) {
+	base := t.TempDir()
+	clean := createTestRepo(t, base, "acme", "clean", "main", filepath.Join(base, "clean"))
+	dirty := createTestRepo(t, base, "acme", "dirty", "main", filepath.Join(base, "dirty"))
+	writeFile(t, filepath.Join(dirty.workPath, "README.md"), "edited\n")
+	manager := newTestManager([]config.Target{repoTarget(clean), repoTarget(dirty)}, fakeClientForRepos(clean, dirty))
+	manager.ProblemsOnly = true
+
+	output := captureStdout(t, func() {
+		if err := manager.Status(nil, false, 1); !errors.Is(err, ErrNotClean) {
+			t.Fatalf("Status() error = %v, want ErrNotClean", err)
+		}
+	})
+	if strings.Contains(output, clean.workPath) || !strings.Contains(output, dirty.workPath) {
+		t.Fatalf("problems output:\n%s", output)
+	}
+	if !strings.Contains(output, "Summary: 1 clean, 1 dirty") {
+		t.Fatalf("summary should still count clean repos:\n%s", output)
+	}
+
+	manager.JSON = true
+	output = captureStdout(t, func() { _ = manager.Status(nil, false, 1) })
+	var statuses []RepoStatus
+	if err := json.Unmarshal([]byte(output), &statuses); err != nil {
+		t.Fatalf("decoding %q: %v", output, err)
+	}
+	if len(statuses) != 1 || statuses[0].Name != "dirty" {
+		t.Fatalf("problems JSON = %+v, want only the dirty repo", statuses)
+	}
+}
+
+func TestStatusDetailListsChangedFiles(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	writeFile(t, filepath.Join(repo.workPath, "new.go"), "package app\n")
+	runGit(t, repo.workPath, "add", "new.go")
+	writeFile(t, filepath.Join(repo.workPath, "README.md"), "edited\n")
+	writeFile(t, filepath.Join(repo.workPath, "scratch", "notes.txt"), "todo\n")
+
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	manager.Detail = true
+	manager.JSON = true
+	output := captureStdout(t, func() {
+		if err := manager.Status(nil, false, 1); !errors.Is(err, ErrNotClean) {
+			t.Fatalf("Status() error = %v, want ErrNotClean", err)
+		}
+	})
+	var statuses []RepoStatus
+	if err := json.Unmarshal([]byte(output), &statuses); err != nil {
+		t.Fatalf("decoding %q: %v", output, err)
+	}
+	want := []FileChange{
+		{Path: "README.md", State: "modified"},
+		{Path: "new.go", State: "staged"},
+		{Path: "scratch/notes.txt", State: "untracked"},
+	}
+	s := statuses[0]
+	if s.Staged != 1 || s.Modified != 1 || s.Untracked != 1 || !reflect.DeepEqual(s.Changes, want) {
+		t.Fatalf("detail = %d staged, %d modified, %d untracked, %+v; want one each, %+v", s.Staged, s.Modified, s.Untracked, s.Changes, want)
+	}
+
+	manager.JSON = false
+	output = captureStdout(t, func() { _ = manager.Status(nil, false, 1) })
+	if !strings.Contains(output, "dirty: 1 staged, 1 modified, 1 untracked") || !strings.Contains(output, "scratch/notes.txt") {
+		t.Fatalf("text detail missing:\n%s", output)
+	}
+}
+
+type unreachableClient struct {
+	fakeClient
+}
+
+func (unreachableClient) ListOrgRepos(orgName string) ([]remote.Repository, error) {
+	return nil, fmt.Errorf("fetching repos: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("network is unreachable")})
+}
+
+func TestStatusGoesOfflineWhenProviderUnreachable(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	// A remote git cannot reach either.
+	runGit(t, repo.workPath, "remote", "set-url", "origin", filepath.Join(base, "missing.git"))
+
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	manager.providers["fake"] = unreachableClient{}
+	var err error
+	output := captureStdout(t, func() {
+		err = manager.Status(nil, false, 1)
+	})
+	if err != nil {
+		t.Errorf("Status() error = %v, want clean offline status", err)
+	}
+	if !strings.Contains(output, "Working offline") || !strings.Contains(output, "[CLEAN]") {
+		t.Errorf("expected offline warning and clean repo, got:\n%s", output)
+	}
+
+	manager.JSON = true
+	manager.Offline = true
+	manager.providers["fake"] = fakeClientForRepos(repo)
+	output = captureStdout(t, func() {
+		err = manager.Status(nil, false, 1)
+	})
+	if err != nil || !strings.Contains(output, `"offline": true`) {
+		t.Errorf("Status() with Offline = %v, output:\n%s", err, output)
+	}
+}
+
+type slowCloneBackend struct {
+	gitBackend
+	mu         sync.Mutex
+	running    map[string]int
+	maxRunning map[string]int
+}
+
+func (b *slowCloneBackend) Clone(cloneURL, dest string, auth gitAuth, opts config.CloneOptions) error {
+	host := urlHost(cloneURL)
+	b.mu.Lock()
+	b.running[host]++
+	if b.running[host] > b.maxRunning[host] {
+		b.maxRunning[host] = b.running[host]
+	}
+	b.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	b.mu.Lock()
+	b.running[host]--
+	b.mu.Unlock()
+	return nil
+}
+
+func TestLimitPerHostCapsConcurrentClonesPerHost(t *testing.T) {
+	inner := &slowCloneBackend{running: map[string]int{}, maxRunning: map[string]int{}}
+	limited := limitPerHost(inner, 2)
+
+	var urls []string
+	for i := 0; i < 8; i++ {
+		urls = append(urls, fmt.Sprintf("https://git.example.com/acme/r%d.git", i), fmt.Sprintf("git@other.example.com:acme/r%d.git", i))
+	}
+	pool.Run(urls, 16, func(u string) error {
+		return limited.Clone(u, "", gitAuth{}, config.CloneOptions{})
+	})
+
+	for _, host := range []string{"git.example.com", "other.example.com"} {
+		if got := inner.maxRunning[host]; got != 2 {
+			t.Errorf("max concurrent clones on %s = %d, want 2", host, got)
+		}
+	}
+}
+
+func TestAddWorktreeAndWorktreesAreRepos(t *testing.T) {
+	base := t.TempDir()
+	orgPath := filepath.Join(base, "acme")
+	if err := os.MkdirAll(orgPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(orgPath, "api"))
+	target := config.Target{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath}
+	manager := newTestManager([]config.Target{target}, fakeClientForRepos(api))
+
+	output := captureStdout(t, func() {
+		if err := manager.AddWorktree("acme/api", "feature/login"); err != nil {
+			t.Fatalf("AddWorktree() error = %v", err)
+		}
+	})
+	worktree := filepath.Join(orgPath, "api.worktrees", "feature-login")
+	if strings.TrimSpace(output) != worktree {
+		t.Fatalf("AddWorktree() printed %q, want %q", output, worktree)
+	}
+	if got := strings.TrimSpace(runGit(t, worktree, "branch", "--show-current")); got != "feature/login" {
+		t.Errorf("worktree branch = %q, want feature/login", got)
+	}
+	if !isGitRepo(worktree) {
+		t.Error("isGitRepo() = false for a linked worktree")
+	}
+	if err := manager.AddWorktree("api", "feature/login"); err == nil {
+		t.Error("AddWorktree() succeeded over an existing worktree")
+	}
+
+	review := filepath.Join(orgPath, "api-review")
+	runGit(t, api.workPath, "worktree", "add", "--quiet", "-b", "review", review)
+	var statuses []RepoStatus
+	captureStdout(t, func() {
+		var err error
+		if statuses, err = manager.Statuses(nil, 1); err != nil {
+			t.Fatalf("Statuses() error = %v", err)
+		}
+	})
+	var found bool
+	for _, s := range statuses {
+		if s.Path == review {
+			found = s.Branch == "review"
+		}
+	}
+	if !found {
+		t.Errorf("worktree %s missing from statuses: %+v", review, statuses)
+	}
+}
+
+func TestReferenceCacheIsSharedByLaterClones(t *testing.T) {
+	base := t.TempDir()
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "seed"))
+	cacheDir := filepath.Join(base, "cache")
+
+	orgRepo := remoteRepo(api)
+	orgRepo.CloneURL = "file://" + api.remotePath
+	client := fakeClient{repos: map[string]map[string]remote.Repository{"acme": {api.name: orgRepo}}}
+	clone := &config.CloneOptions{ReferenceDir: cacheDir}
+	targets := []config.Target{
+		{Name: "work", Provider: "fake", Org: "acme", Path: filepath.Join(base, "work"), Clone: clone},
+		{Name: "review", Provider: "fake", Org: "acme", Path: filepath.Join(base, "review"), Clone: clone},
+	}
+	manager := newTestManager(targets, client)
+	captureStdout(t, func() {
+		if err := manager.Clone([]string{"work"}, false, false, 1); err != nil {
+			t.Fatalf("Clone(work) error = %v", err)
+		}
+		if err := manager.UpdateCache([]string{"work"}, 1); err != nil {
+			t.Fatalf("UpdateCache() error = %v", err)
+		}
+	})
+	cached := referencePath(cacheDir, orgRepo.CloneURL)
+	if !isBareRepo(cached) {
+		t.Fatalf("no cache repo at %s", cached)
+	}
+	if got := strings.TrimSpace(runGit(t, cached, "config", "remote.origin.url")); got != orgRepo.CloneURL {
+		t.Errorf("cache origin = %q, want %q", got, orgRepo.CloneURL)
+	}
+
+	output := captureStdout(t, func() {
+		if err := manager.Clone([]string{"review"}, false, false, 1); err != nil {
+			t.Fatalf("Clone(review) error = %v", err)
+		}
+		if err := manager.UpdateCache(nil, 1); err != nil {
+			t.Fatalf("second UpdateCache() error = %v", err)
+		}
+	})
+	alternates, err := os.ReadFile(filepath.Join(base, "review", "api", ".git", "objects", "info", "alternates"))
+	if err != nil || !strings.Contains(string(alternates), cached) {
+		t.Errorf("review clone alternates = %q, %v; want the cache", alternates, err)
+	}
+	if !strings.Contains(output, "Cache update complete: 0 added, 1 updated, 0 failed") {
+		t.Errorf("second update did not fetch the one shared cache repo:\n%s", output)
+	}
+}
+
+func TestReferencePathIgnoresProtocol(t *testing.T) {
+	want := filepath.Join("/cache", "github.com", "acme", "api.git")
+	for _, u := range []string{
+		"https://github.com/acme/api.git",
+		"ssh://git@github.com:22/acme/api.git",
+		"git@github.com:acme/api.git",
+		"https://github.com/acme/../acme/api",
+	} {
+		if got := referencePath("/cache", u); got != want {
+			t.Errorf("referencePath(%q) = %q, want %q", u, got, want)
+		}
+	}
+}
+
+func TestGCRunsMaintenanceTasks(t *testing.T) {
+	base := t.TempDir()
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api"))
+	manager := newTestManager([]config.Target{repoTarget(api)}, fakeClientForRepos(api))
+
+	output := captureStdout(t, func() {
+		if err := manager.GC(nil, nil, 1); err != nil {
+			t.Fatalf("GC() error = %v", err)
+		}
+		if err := manager.GC(nil, []string{"commit-graph"}, 1); err != nil {
+			t.Fatalf("GC(commit-graph) error = %v", err)
+		}
+	})
+	if strings.Count(output, "GC complete: 1 maintained, 0 failed") != 2 {
+		t.Errorf("unexpected gc output:\n%s", output)
+	}
+	objects := filepath.Join(api.workPath, ".git", "objects", "info")
+	_, single := os.Stat(filepath.Join(objects, "commit-graph"))
+	_, chain := os.Stat(filepath.Join(objects, "commit-graphs"))
+	if single != nil && chain != nil {
+		t.Error("commit-graph task wrote no commit graph")
+	}
+}
+
+func TestCleanRemovesUntrackedPathsAfterConfirmation(t *testing.T) {
+	base := t.TempDir()
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api"))
+	writeFile(t, filepath.Join(api.workPath, "build", "out.bin"), "artifact\n")
+	writeFile(t, filepath.Join(api.workPath, "notes.txt"), "scratch\n")
+	writeFile(t, filepath.Join(api.workPath, ".env"), "SECRET=1\n")
+	target := repoTarget(api)
+	target.Clean = &config.CleanPolicy{Deny: []string{".env"}}
+	manager := newTestManager([]config.Target{target}, fakeClientForRepos(api))
+
+	var asked int
+	output := captureStdout(t, func() {
+		err := manager.Clean(nil, false, func(files, repos int) bool {
+			asked = files
+			return false
+		}, 1)
+		if err != nil {
+			t.Fatalf("Clean() error = %v", err)
+		}
+	})
+	if asked != 2 || !strings.Contains(output, "build/") || strings.Contains(output, ".env") {
+		t.Fatalf("Clean() asked about %d paths, listed:\n%s", asked, output)
+	}
+	if _, err := os.Stat(filepath.Join(api.workPath, "notes.txt")); err != nil {
+		t.Fatal("declined clean removed files")
+	}
+
+	captureStdout(t, func() {
+		if err := manager.Clean(nil, false, func(int, int) bool { return true }, 1); err != nil {
+			t.Fatalf("Clean() error = %v", err)
+		}
+	})
+	for _, gone := range []string{"build", "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(api.workPath, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s survived the clean", gone)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(api.workPath, ".env")); err != nil {
+		t.Errorf("denied .env was removed: %v", err)
+	}
+}
+
+func TestResetMovesReposOntoOriginDefault(t *testing.T) {
+	base := t.TempDir()
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api"))
+	web := createTestRepo(t, base, "acme", "web", "main", filepath.Join(base, "web"))
+	runGit(t, api.workPath, "switch", "-c", "feature")
+	commitFile(t, api.workPath, "feature.txt", "wip\n", "feature work")
+	writeFile(t, filepath.Join(api.workPath, "README.md"), "edited\n")
+	configureGitIdentity(t, web.workPath)
+	commitFile(t, web.workPath, "local.txt", "local\n", "unpushed")
+	manager := newTestManager([]config.Target{repoTarget(api), repoTarget(web)}, fakeClientForRepos(api, web))
+
+	captureStdout(t, func() {
+		if err := manager.Reset(nil, false, func(int) bool { t.Fatal("asked with nothing to reset"); return false }, 1); err != nil {
+			t.Fatalf("Reset() error = %v", err)
+		}
+	})
+	if branch := strings.TrimSpace(runGit(t, api.workPath, "branch", "--show-current")); branch != "feature" {
+		t.Fatalf("dirty repo was reset without --dirty-too, on %s", branch)
+	}
+
+	var asked int
+	captureStdout(t, func() {
+		if err := manager.Reset(nil, true, func(count int) bool { asked = count; return true }, 1); err != nil {
+			t.Fatalf("Reset() error = %v", err)
+		}
+	})
+	if asked != 1 {
+		t.Fatalf("Reset() asked about %d repos, want 1", asked)
+	}
+	if branch := strings.TrimSpace(runGit(t, api.workPath, "branch", "--show-current")); branch != "main" {
+		t.Fatalf("api on %s after reset, want main", branch)
+	}
+	if status := runGit(t, api.workPath, "status", "--porcelain"); status != "" {
+		t.Fatalf("api still has changes after reset:\n%s", status)
+	}
+	if ahead := strings.TrimSpace(runGit(t, web.workPath, "rev-list", "--count", "origin/main..main")); ahead != "1" {
+		t.Fatalf("web lost its unpushed commit (ahead %s)", ahead)
+	}
+	captureStdout(t, func() {
+		if err := manager.Reset([]string{"api"}, false, func(int) bool { t.Fatal("asked to reset a pristine repo"); return false }, 1); err != nil {
+			t.Fatalf("second Reset() error = %v", err)
+		}
+	})
+}
+
+func TestRecurseTargetFindsNestedRepos(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "work")
+	for _, dir := range []string{"team-a", "team-b/backend", "deep/a/b/c"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(root, "team-a", "api"))
+	web := createTestRepo(t, base, "acme", "web", "main", filepath.Join(root, "team-b", "backend", "web"))
+	createTestRepo(t, base, "acme", "lost", "main", filepath.Join(root, "deep", "a", "b", "c", "lost"))
+
+	target := config.Target{Name: "acme", Provider: "fake", Org: "acme", Path: root}
+	manager := newTestManager([]config.Target{target}, fakeClientForRepos(api, web))
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if len(statuses) != 0 {
+		t.Fatalf("plain target found %d nested repos, want 0", len(statuses))
+	}
+
+	target.Recurse = true
+	manager = newTestManager([]config.Target{target}, fakeClientForRepos(api, web))
+	statuses, err = manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	var paths []string
+	for _, s := range statuses {
+		paths = append(paths, s.Path)
+	}
+	sort.Strings(paths)
+	if want := []string{api.workPath, web.workPath}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("recurse statuses = %v, want %v (lost is below max_depth)", paths, want)
+	}
+
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+	if _, err := os.Stat(filepath.Join(root, "api")); !os.IsNotExist(err) {
+		t.Fatal("clone cloned api again although team-a/api exists")
+	}
+}
+
+func TestLayoutClonesReposByTopic(t *testing.T) {
+	base := t.TempDir()
+	orgPath := filepath.Join(base, "acme")
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api-seed"))
+	tools := createTestRepo(t, base, "acme", "tools", "main", filepath.Join(base, "tools-seed"))
+
+	apiRemote := remoteRepo(api)
+	apiRemote.Topics = []string{"payments"}
+	apiRemote.CloneURL = api.remotePath
+	toolsRemote := remoteRepo(tools)
+	toolsRemote.CloneURL = tools.remotePath
+	client := fakeClient{repos: map[string]map[string]remote.Repository{"acme": {
+		api.name:   apiRemote,
+		tools.name: toolsRemote,
+	}}}
+	target := config.Target{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath, Layout: "{{.Topic}}/{{.Name}}"}
+	manager := newTestManager([]config.Target{target}, client)
+
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+	for _, p := range []string{filepath.Join(orgPath, "payments", "api"), filepath.Join(orgPath, "tools")} {
+		if !isGitRepo(p) {
+			t.Fatalf("expected a clone at %s", p)
+		}
+	}
+
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	var names []string
+	for _, s := range statuses {
+		names = append(names, s.Name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "api,tools" {
+		t.Fatalf("status repos = %v, want [api tools]", names)
+	}
+}
+
+func TestTargetTagsSelectTargets(t *testing.T) {
+	base := t.TempDir()
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api"))
+	docs := createTestRepo(t, base, "acme", "docs", "main", filepath.Join(base, "docs"))
+	apiTarget := repoTarget(api)
+	apiTarget.Tags = []string{"work", "critical"}
+	docsTarget := repoTarget(docs)
+	docsTarget.Tags = []string{"oss"}
+	manager := newTestManager([]config.Target{apiTarget, docsTarget}, fakeClientForRepos(api, docs))
+
+	manager.TargetTags = []string{"critical"}
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Name != "api" {
+		t.Fatalf("statuses = %+v, want only api", statuses)
+	}
+
+	if _, err := manager.Statuses([]string{docsTarget.Name}, 1); err == nil {
+		t.Fatal("Statuses() should fail when no named target carries the tag")
+	}
+}
+
+func TestExcludeTargetsLeavesTargetsOut(t *testing.T) {
+	base := t.TempDir()
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api"))
+	mono := createTestRepo(t, base, "acme", "mono", "main", filepath.Join(base, "mono"))
+	manager := newTestManager([]config.Target{repoTarget(api), repoTarget(mono)}, fakeClientForRepos(api, mono))
+
+	manager.ExcludeTargets = []string{"mono"}
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Name != "api" {
+		t.Fatalf("statuses = %+v, want only api", statuses)
+	}
+
+	if _, err := manager.Statuses([]string{"mono"}, 1); err == nil {
+		t.Error("Statuses() should fail when every named target is excluded")
+	}
+	manager.ExcludeTargets = []string{"missing"}
+	if _, err := manager.Statuses(nil, 1); err == nil {
+		t.Error("Statuses() should reject an unknown excluded target")
+	}
+}
+
+func TestInitFoldoutRecordsClonesAndRemoteRepos(t *testing.T) {
+	base := t.TempDir()
+	workspace := filepath.Join(base, "workspace")
+	if err := os.MkdirAll(filepath.Join(workspace, "services"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(workspace, "services", "api"))
+	web := createTestRepo(t, base, "acme", "web", "main", filepath.Join(workspace, "web"))
+	client := fakeClientForRepos(api, web)
+	for name, r := range map[string]testRepo{"api": api, "web": web} {
+		rr := client.repos["acme"][name]
+		rr.CloneURL = r.remotePath
+		client.repos["acme"][name] = rr
+	}
+	manager := newTestManager([]config.Target{{Name: "acme", Provider: "fake", Org: "acme", Path: filepath.Join(base, "acme")}}, client)
+
+	if err := manager.InitFoldout(workspace, FoldoutInitOptions{}); err != nil {
+		t.Fatalf("InitFoldout() error = %v", err)
+	}
+	fc, err := loadFoldout(workspace)
+	if err != nil || fc == nil {
+		t.Fatalf("expected foldout file: %v", err)
+	}
+	want := []foldoutRepo{{Name: "acme/api", Target: "services/api"}, {Name: "acme/web", Target: "web"}}
+	if !reflect.DeepEqual(fc.Repos, want) {
+		t.Fatalf("foldout entries = %+v, want %+v", fc.Repos, want)
+	}
+	if err := manager.InitFoldout(workspace, FoldoutInitOptions{}); err == nil {
+		t.Fatal("InitFoldout() should not replace an existing file without Force")
+	}
+
+	rr := client.repos["acme"]["web"]
+	rr.Topics = []string{"frontend"}
+	client.repos["acme"]["web"] = rr
+	opts := FoldoutInitOptions{From: "fake/acme", Topics: []string{"frontend"}, Force: true}
+	if err := manager.InitFoldout(workspace, opts); err != nil {
+		t.Fatalf("InitFoldout(--from) error = %v", err)
+	}
+	fc, err = loadFoldout(workspace)
+	if err != nil || fc == nil || len(fc.Repos) != 1 || fc.Repos[0].Name != "acme/web" || fc.Repos[0].Target != "web" {
+		t.Fatalf("foldout entries from remote = %+v, %v", fc, err)
+	}
+}
+
+func TestCloneDryRunPlansFoldoutsFromRemoteFile(t *testing.T) {
+	base := t.TempDir()
+	parent := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-seed"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-seed"))
+
+	client := fakeClientForRepos(parent, lib)
+	for name, r := range map[string]testRepo{"app": parent, "lib": lib} {
+		rr := client.repos["acme"][name]
+		rr.CloneURL = r.remotePath
+		client.repos["acme"][name] = rr
+	}
+	client.files = map[string]string{"acme/app/.tugboat.json": `{"repos": [{"name": "acme/lib", "target": "deps/lib"}]}`}
+	target := repoTarget(parent)
+	target.Path = filepath.Join(base, "app")
+	manager := newTestManager([]config.Target{target}, client)
+	manager.DryRun = true
+
+	output := captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+	for _, want := range []string{target.Path + ": would clone (missing locally", filepath.Join(target.Path, "deps", "lib") + ": would clone (foldout acme/lib"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("dry run output lacks %q:\n%s", want, output)
+		}
+	}
+	if _, err := os.Stat(target.Path); !os.IsNotExist(err) {
+		t.Fatalf("dry run created %s", target.Path)
+	}
+}
+
+func TestNestedFoldoutsCloneAndStatus(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	meta := createTestRepo(t, base, "acme", "meta", "main", filepath.Join(base, "meta-seed"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-seed"))
+	commitFile(t, meta.workPath, ".tugboat.json", `{"repos": [{"name": "acme/lib", "target": "libs/lib"}]}`, "add foldouts")
+	runGit(t, meta.workPath, "push")
+	writeFile(t, filepath.Join(app.workPath, ".tugboat.json"), `{"repos": [{"name": "acme/meta"}]}`)
+
+	client := fakeClientForRepos(app, meta, lib)
+	for name, r := range map[string]testRepo{"meta": meta, "lib": lib} {
+		rr := client.repos["acme"][name]
+		rr.CloneURL = r.remotePath
+		client.repos["acme"][name] = rr
+	}
+	manager := newTestManager([]config.Target{repoTarget(app)}, client)
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+	libPath := filepath.Join(app.workPath, "meta", "libs", "lib")
+	if !isGitRepo(libPath) {
+		t.Fatalf("nested foldout not cloned at %s", libPath)
+	}
+
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	var paths []string
+	for _, s := range statuses {
+		paths = append(paths, s.Path)
+	}
+	sort.Strings(paths)
+	if want := []string{app.workPath, filepath.Join(app.workPath, "meta"), libPath}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("status paths = %v, want %v", paths, want)
+	}
+
+	writeFile(t, filepath.Join(libPath, ".tugboat.json"), `{"repos": [{"name": "acme/app"}]}`)
+	if _, err := manager.Statuses(nil, 1); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("Statuses() error = %v, want a foldout cycle", err)
+	}
+}
+
+func TestForeignProviderFoldoutCloneAndStatus(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	sdk := createTestRepo(t, base, "acme", "sdk", "main", filepath.Join(base, "sdk-seed"))
+	writeFile(t, filepath.Join(app.workPath, ".tugboat.json"), `{"repos": [{"name": "other:acme/sdk"}]}`)
+
+	other := fakeClientForRepos(sdk)
+	rr := other.repos["acme"]["sdk"]
+	rr.CloneURL = sdk.remotePath
+	other.repos["acme"]["sdk"] = rr
+	manager := newTestManager([]config.Target{repoTarget(app)}, fakeClientForRepos(app))
+	manager.config.Providers["other"] = config.Provider{Type: "gitea", APIURL: "https://other.invalid"}
+	manager.providers["other"] = other
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+	sdkPath := filepath.Join(app.workPath, "sdk")
+	if !isGitRepo(sdkPath) {
+		t.Fatalf("foldout of provider other not cloned at %s", sdkPath)
+	}
+
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	var found bool
+	for _, s := range statuses {
+		if s.Path == sdkPath {
+			found = true
+			if s.Provider != "other" {
+				t.Fatalf("foldout provider = %q, want other", s.Provider)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("status has no entry for %s", sdkPath)
+	}
+
+	writeFile(t, filepath.Join(app.workPath, ".tugboat.json"), `{"repos": [{"name": "missing:acme/lib"}]}`)
+	if err := manager.Clone(nil, false, false, 1); err == nil || !strings.Contains(err.Error(), "no client for provider missing") {
+		t.Fatalf("Clone() error = %v, want an unknown provider", err)
+	}
+}
+
+func TestPinnedFoldoutRefCloneAndPull(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-seed"))
+	runGit(t, lib.workPath, "tag", "v1")
+	commitFile(t, lib.workPath, "later.txt", "later", "after v1")
+	runGit(t, lib.workPath, "push", "--tags", "origin", "main")
+	tagged := strings.TrimSpace(runGit(t, lib.workPath, "rev-parse", "v1^{commit}"))
+	tip := strings.TrimSpace(runGit(t, lib.workPath, "rev-parse", "HEAD"))
+	writeFile(t, filepath.Join(app.workPath, ".tugboat.json"), `{"repos": [{"name": "acme/lib", "ref": "v1"}]}`)
+
+	client := fakeClientForRepos(app, lib)
+	rr := client.repos["acme"]["lib"]
+	rr.CloneURL = lib.remotePath
+	client.repos["acme"]["lib"] = rr
+	manager := newTestManager([]config.Target{repoTarget(app)}, client)
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+	libPath := filepath.Join(app.workPath, "lib")
+	if head := strings.TrimSpace(runGit(t, libPath, "rev-parse", "HEAD")); head != tagged {
+		t.Fatalf("cloned foldout HEAD = %s, want v1 (%s)", head, tagged)
+	}
+
+	// Pull leaves the pinned foldout at its tag rather than pulling main.
+	captureStdout(t, func() {
+		if err := manager.Pull(nil, 1); err != nil {
+			t.Fatalf("Pull() error = %v", err)
+		}
+	})
+	if head := strings.TrimSpace(runGit(t, libPath, "rev-parse", "HEAD")); head != tagged {
+		t.Fatalf("pulled foldout HEAD = %s, want v1 (%s)", head, tagged)
+	}
+
+	writeFile(t, filepath.Join(app.workPath, ".tugboat.json"), `{"repos": [{"name": "acme/lib", "ref": "main"}]}`)
+	captureStdout(t, func() {
+		if err := manager.Sync(nil, 1); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+	if head := strings.TrimSpace(runGit(t, libPath, "rev-parse", "HEAD")); head != tip {
+		t.Fatalf("synced foldout HEAD = %s, want main (%s)", head, tip)
+	}
+	if branch := strings.TrimSpace(runGit(t, libPath, "rev-parse", "--abbrev-ref", "HEAD")); branch != "main" {
+		t.Fatalf("synced foldout branch = %s, want main", branch)
+	}
+}
+
+func TestLockAndRestore(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(app.workPath, "lib"))
+	writeFile(t, filepath.Join(app.workPath, ".tugboat.json"), `{"repos": [{"name": "acme/lib"}]}`)
+	manager := newTestManager([]config.Target{repoTarget(app)}, fakeClientForRepos(app, lib))
+
+	lockPath := filepath.Join(base, "tugboat.lock")
+	captureStdout(t, func() {
+		if err := manager.Lock(lockPath, nil, 1); err != nil {
+			t.Fatalf("Lock() error = %v", err)
+		}
+	})
+	lock, err := ReadLockFile(lockPath)
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	locked := strings.TrimSpace(runGit(t, lib.workPath, "rev-parse", "HEAD"))
+	want := []LockEntry{
+		{Target: "app", Path: ".", Repo: "acme/app", Commit: strings.TrimSpace(runGit(t, app.workPath, "rev-parse", "HEAD")), Branch: "main"},
+		{Target: "app", Path: "lib", Repo: "acme/lib", Commit: locked, Branch: "main"},
+	}
+	if !reflect.DeepEqual(lock.Repos, want) {
+		t.Fatalf("lock repos = %+v, want %+v", lock.Repos, want)
+	}
+
+	commitFile(t, lib.workPath, "later.txt", "later", "after the lock")
+	captureStdout(t, func() {
+		if err := manager.Restore(lockPath, nil, 1); err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+	})
+	if head := strings.TrimSpace(runGit(t, lib.workPath, "rev-parse", "HEAD")); head != locked {
+		t.Fatalf("restored HEAD = %s, want %s", head, locked)
+	}
+	if branch := strings.TrimSpace(runGit(t, lib.workPath, "rev-parse", "--abbrev-ref", "HEAD")); branch != "HEAD" {
+		t.Fatalf("restored branch = %s, want a detached HEAD since main moved on", branch)
+	}
+}
+
+func TestSnapshotSaveAndRestore(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(app.workPath, "lib"))
+	writeFile(t, filepath.Join(app.workPath, ".tugboat.json"), `{"repos": [{"name": "acme/lib"}]}`)
+	runGit(t, app.workPath, "add", ".tugboat.json")
+	runGit(t, app.workPath, "commit", "-q", "-m", "foldouts")
+	writeFile(t, filepath.Join(app.workPath, ".gitignore"), "lib/\n")
+	runGit(t, app.workPath, "add", ".gitignore")
+	runGit(t, app.workPath, "commit", "-q", "-m", "ignore foldouts")
+	manager := newTestManager([]config.Target{repoTarget(app)}, fakeClientForRepos(app, lib))
+	dir := filepath.Join(base, "snapshots")
+
+	saved := strings.TrimSpace(runGit(t, lib.workPath, "rev-parse", "HEAD"))
+	writeFile(t, filepath.Join(lib.workPath, "README.md"), "work in progress\n")
+	writeFile(t, filepath.Join(lib.workPath, "notes.txt"), "untracked\n")
+	captureStdout(t, func() {
+		if err := manager.SaveSnapshot(dir, "before", nil, true, false, 1); err != nil {
+			t.Fatalf("SaveSnapshot() error = %v", err)
+		}
+	})
+	if got := readFile(t, filepath.Join(lib.workPath, "notes.txt")); got != "untracked\n" {
+		t.Fatalf("saving a snapshot changed the working tree: notes.txt = %q", got)
+	}
+	if err := manager.SaveSnapshot(dir, "before", nil, true, false, 1); err == nil {
+		t.Fatal("SaveSnapshot() replaced an existing snapshot without force")
+	}
+
+	runGit(t, lib.workPath, "add", "-A")
+	runGit(t, lib.workPath, "commit", "-q", "-m", "refactor")
+	writeFile(t, filepath.Join(lib.workPath, "later.txt"), "later\n")
+	captureStdout(t, func() {
+		if err := manager.RestoreSnapshot(dir, "before", 1); err != nil {
+			t.Fatalf("RestoreSnapshot() error = %v", err)
+		}
+	})
+	if head := strings.TrimSpace(runGit(t, lib.workPath, "rev-parse", "HEAD")); head != saved {
+		t.Fatalf("restored HEAD = %s, want %s", head, saved)
+	}
+	if branch := strings.TrimSpace(runGit(t, lib.workPath, "rev-parse", "--abbrev-ref", "HEAD")); branch != "main" {
+		t.Fatalf("restored branch = %s, want main", branch)
+	}
+	if got := readFile(t, filepath.Join(lib.workPath, "README.md")); got != "work in progress\n" {
+		t.Fatalf("restored README.md = %q, want the saved change", got)
+	}
+	if _, err := os.Stat(filepath.Join(lib.workPath, "later.txt")); !os.IsNotExist(err) {
+		t.Fatalf("later.txt still in the working tree, want it stashed")
+	}
+	if stashes := runGit(t, lib.workPath, "stash", "list"); !strings.Contains(stashes, "before restoring snapshot before") {
+		t.Fatalf("stash list = %q, want the changes made since the snapshot", stashes)
+	}
+}
+
+func TestStateRecordsFetchAndSync(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	manager := newTestManager([]config.Target{repoTarget(app)}, fakeClientForRepos(app))
+	store, err := state.Open(filepath.Join(base, "state.json"))
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	manager.State = store
+
+	captureStdout(t, func() {
+		if err := manager.Pull(nil, 1); err != nil {
+			t.Fatalf("Pull() error = %v", err)
+		}
+	})
+	reopened, err := state.Open(store.Path)
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	r, ok := reopened.Repo(app.workPath)
+	if !ok || r.LastFetch.IsZero() || r.SyncCommand != "pull" || r.RemoteRef != "origin/main" {
+		t.Fatalf("state = %+v, %v; want a fetch and a pull", r, ok)
+	}
+	if want := strings.TrimSpace(runGit(t, app.workPath, "rev-parse", "origin/main")); r.RemoteSHA != want {
+		t.Fatalf("remote sha = %s, want %s", r.RemoteSHA, want)
+	}
+
+	statuses, err := manager.Statuses(nil, 1)
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if s := statuses[0]; s.LastSync == nil || s.LastSyncResult != r.SyncResult {
+		t.Fatalf("status last sync = %v %q, want the recorded pull", s.LastSync, s.LastSyncResult)
+	}
+	if note := syncNote(statuses[0]); !strings.Contains(note, "last synced 0m ago") {
+		t.Fatalf("syncNote() = %q", note)
+	}
+}
+
+func TestHistoryJournalsRuns(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	manager := newTestManager([]config.Target{repoTarget(app)}, fakeClientForRepos(app))
+	store, err := state.Open(filepath.Join(base, "state.json"))
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	manager.State = store
+
+	captureStdout(t, func() {
+		if err := manager.Pull(nil, 1); err != nil {
+			t.Fatalf("Pull() error = %v", err)
+		}
+		manager.DryRun = true
+		if err := manager.Push(nil, 1); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+		manager.DryRun = false
+		if err := manager.Sync(nil, 1); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+	runs, err := store.Runs()
+	if err != nil {
+		t.Fatalf("Runs() error = %v", err)
+	}
+	if len(runs) != 2 || runs[0].Command != "pull" || runs[1].Command != "sync" {
+		t.Fatalf("runs = %+v, want the pull and the sync but not the dry run", runs)
+	}
+	if runs[0].ID != 1 || runs[1].ID != 2 || runs[0].Started.IsZero() {
+		t.Fatalf("runs = %+v, want IDs 1 and 2 with start times", runs)
+	}
+	if repos := runs[0].Repos; len(repos) != 1 || repos[0].Path != app.workPath || repos[0].Result == "" {
+		t.Fatalf("pull repos = %+v, want the app's result", repos)
+	}
+}
+
+func TestHooksRunAroundCloneAndPull(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	sdk := createTestRepo(t, base, "acme", "sdk", "main", filepath.Join(base, "sdk-seed"))
+	writeFile(t, filepath.Join(app.workPath, ".tugboat.json"), `{"repos": [{"name": "acme/sdk"}]}`)
+	client := fakeClientForRepos(app, sdk)
+	rr := client.repos["acme"]["sdk"]
+	rr.CloneURL = sdk.remotePath
+	client.repos["acme"]["sdk"] = rr
+	manager := newTestManager([]config.Target{repoTarget(app)}, client)
+
+	logPath := filepath.Join(base, "hooks.log")
+	record := `echo "$TUGBOAT_EVENT $TUGBOAT_REPO_NAME $TUGBOAT_RESULT" >> ` + logPath
+	manager.config.Hooks = map[string][]config.Hook{
+		"post_clone": {{Run: record}},
+		"pre_pull":   {{Run: record, Once: true}, {Run: `test "$TUGBOAT_REPO_NAME" != app`}},
+		"post_pull":  {{Run: record}},
+	}
+	captureStdout(t, func() {
+		if err := manager.Clone(nil, false, false, 1); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+	})
+	if got := readFile(t, logPath); got != "post_clone sdk cloned\n" {
+		t.Fatalf("hooks after clone logged %q", got)
+	}
+
+	var results []RepoResult
+	manager.JSON = true
+	out := captureStdout(t, func() {
+		if err := manager.Pull(nil, 1); err != nil {
+			t.Fatalf("Pull() error = %v", err)
+		}
+	})
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatalf("parsing pull output %q: %v", out, err)
+	}
+	for _, r := range results {
+		if r.Name == "app" && (r.Result != "skipped" || !strings.Contains(r.Message, "pre_pull hook")) {
+			t.Fatalf("app result = %+v, want skipped by its pre_pull hook", r)
+		}
+	}
+	want := "post_clone sdk cloned\npre_pull  \npost_pull sdk "
+	if got := readFile(t, logPath); !strings.HasPrefix(got, want) {
+		t.Fatalf("hooks after pull logged %q, want it to start with %q", got, want)
+	}
+}
+
+func TestRepoHooksNeedAllowing(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	logPath := filepath.Join(base, "hooks.log")
+	commitFile(t, app.workPath, ".tugboat.json", `{"hooks": {"post_sync": ["echo \"$TUGBOAT_REPO_NAME $TUGBOAT_RESULT\" >> `+logPath+`"]}}`, "add repo hooks")
+	runGit(t, app.workPath, "push", "origin", "main")
+	manager := newTestManager([]config.Target{repoTarget(app)}, fakeClientForRepos(app))
+
+	sync := func() {
+		t.Helper()
+		captureStdout(t, func() {
+			if err := manager.Sync(nil, 1); err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+		})
+	}
+	sync()
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Fatalf("repo hook ran without being allowed (stat error %v)", err)
+	}
+
+	manager.config.AllowRepoHooks = []string{"acme/*"}
+	sync()
+	if got := readFile(t, logPath); !strings.HasPrefix(got, "app ") {
+		t.Fatalf("repo hook logged %q, want a line for app", got)
+	}
+
+	if _, err := parseFoldout([]byte(`{"hooks": {"pre_sync": ["true"]}}`)); err == nil {
+		t.Fatal("parseFoldout() accepted a pre_sync repo hook")
+	}
+}
+
+func TestQueryAnswersPerLine(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	manager := newTestManager([]config.Target{repoTarget(app)}, fakeClientForRepos(app))
+
+	in := strings.NewReader("roots\nrepo " + filepath.Join(app.workPath, "src", "main.go") + "\nrepo " + base + "\nstatus " + app.workPath + "\nbogus\nquit\nroots\n")
+	var out bytes.Buffer
+	if err := manager.Query(nil, in, &out); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Query() answered %d lines, want 5 (stopping at quit):\n%s", len(lines), out.String())
+	}
+	var roots []QueryRepo
+	if err := json.Unmarshal([]byte(lines[0]), &roots); err != nil || len(roots) != 1 || roots[0].Path != app.workPath || roots[0].Name != "app" {
+		t.Fatalf("roots = %s (%v)", lines[0], err)
+	}
+	var found QueryRepo
+	if err := json.Unmarshal([]byte(lines[1]), &found); err != nil || found.Path != app.workPath {
+		t.Fatalf("repo of a file = %s (%v)", lines[1], err)
+	}
+	if lines[2] != "null" {
+		t.Fatalf("repo outside all repos = %s, want null", lines[2])
+	}
+	var s RepoStatus
+	if err := json.Unmarshal([]byte(lines[3]), &s); err != nil || s.Path != app.workPath || s.Branch != "main" {
+		t.Fatalf("status = %s (%v)", lines[3], err)
+	}
+	if !strings.Contains(lines[4], `"error"`) {
+		t.Fatalf("unknown query answered %s", lines[4])
+	}
+}
+
+func TestPushRunsReposInParallelInOrder(t *testing.T) {
+	base := t.TempDir()
+	var repos []testRepo
+	var targets []config.Target
+	for _, name := range []string{"api", "cli", "web"} {
+		r := createTestRepo(t, base, "acme", name, "main", filepath.Join(base, name))
+		commitFile(t, r.workPath, "local.txt", name+"\n", "local commit")
+		repos = append(repos, r)
+		targets = append(targets, repoTarget(r))
+	}
+	manager := newTestManager(targets, fakeClientForRepos(repos...))
+	manager.config.NetworkWorkers = 2
+	manager.git = limitPerHost(execBackend{}, 2)
+	manager.JSON = true
+	out := captureStdout(t, func() {
+		if err := manager.Push(nil, 3); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	})
+	var results []RepoResult
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatalf("parsing push output %q: %v", out, err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("results = %+v, want three", results)
+	}
+	for i, r := range results {
+		if r.Path != repos[i].workPath || r.Result != "pushed" {
+			t.Fatalf("result %d = %+v, want %s pushed", i, r, repos[i].workPath)
+		}
+		local := strings.TrimSpace(runGit(t, r.Path, "rev-parse", "HEAD"))
+		if remote := strings.TrimSpace(runGit(t, repos[i].remotePath, "rev-parse", "main")); remote != local {
+			t.Fatalf("%s: remote main = %s, want pushed %s", r.Name, remote, local)
+		}
+	}
+}
+
+func TestOrderedReportsReposInOrder(t *testing.T) {
+	base := t.TempDir()
+	var repos []testRepo
+	var targets []config.Target
+	for _, name := range []string{"api", "cli", "docs", "web"} {
+		r := createTestRepo(t, base, "acme", name, "main", filepath.Join(base, name))
+		commitFile(t, r.workPath, "local.txt", name+"\n", "local commit")
+		repos = append(repos, r)
+		targets = append(targets, repoTarget(r))
+	}
+	manager := newTestManager(targets, fakeClientForRepos(repos...))
+	manager.Ordered = true
+	manager.Output = "ndjson"
+	var out bytes.Buffer
+	manager.Out = &out
+	if err := manager.Push(nil, 4); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(repos) {
+		t.Fatalf("output = %q, want a line per repo", out.String())
+	}
+	for i, repo := range repos {
+		var r RepoResult
+		if err := json.Unmarshal([]byte(lines[i]), &r); err != nil {
+			t.Fatalf("line %d %q: %v", i, lines[i], err)
+		}
+		if r.Path != repo.workPath || r.Result != "pushed" {
+			t.Fatalf("line %d = %+v, want %s pushed", i, r, repo.workPath)
+		}
+	}
+}
+
+func TestFetchTimesOut(t *testing.T) {
+	base := t.TempDir()
+	r := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api"))
+	runGit(t, r.workPath, "remote", "set-url", "origin", "ssh://git@git.example.com/acme/api.git")
+	// The ssh command stands in for a wedged connection.
+	auth := gitAuth{sshCommand: "sleep 5; :", timeouts: config.TimeoutOptions{Fetch: config.Duration(200 * time.Millisecond)}}
+	start := time.Now()
+	err := execBackend{}.Fetch(r.workPath, auth)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Fatalf("Fetch() error = %v, want a timeout", err)
+	}
+	if took := time.Since(start); took > 4*time.Second {
+		t.Errorf("Fetch() took %s, want it stopped soon after the timeout", took)
+	}
+}
+
+func TestDeadlineSkipsRemainingRepos(t *testing.T) {
+	base := t.TempDir()
+	var repos []testRepo
+	var targets []config.Target
+	for _, name := range []string{"api", "web"} {
+		r := createTestRepo(t, base, "acme", name, "main", filepath.Join(base, name))
+		commitFile(t, r.workPath, "local.txt", name+"\n", "local commit")
+		repos = append(repos, r)
+		targets = append(targets, repoTarget(r))
+	}
+	manager := newTestManager(targets, fakeClientForRepos(repos...))
+	manager.Deadline = time.Now().Add(-time.Second)
+	var results []RepoResult
+	manager.OnResult = func(r RepoResult) { results = append(results, r) }
+	manager.Out = io.Discard
+	err := manager.Push(nil, 2)
+	if !errors.Is(err, ErrDeadline) {
+		t.Fatalf("Push() error = %v, want ErrDeadline", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v, want both repos", results)
+	}
+	for _, r := range results {
+		if r.Result != "skipped" || r.Message != ErrDeadline.Error() {
+			t.Errorf("%s: result = %s %q, want skipped at the deadline", r.Name, r.Result, r.Message)
+		}
+	}
+
+	// A git command still running at the deadline is stopped.
+	r := repos[0]
+	runGit(t, r.workPath, "remote", "set-url", "origin", "ssh://git@git.example.com/acme/api.git")
+	auth := gitAuth{sshCommand: "sleep 5; :", deadline: time.Now().Add(200 * time.Millisecond)}
+	if err := (execBackend{}).Fetch(r.workPath, auth); !errors.Is(err, ErrDeadline) {
+		t.Fatalf("Fetch() error = %v, want ErrDeadline", err)
+	}
+}
+
+func TestRetryFailedClonesClonesOnlyFailedRepos(t *testing.T) {
+	base := t.TempDir()
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api-seed"))
+	web := createTestRepo(t, base, "acme", "web", "main", filepath.Join(base, "web-seed"))
+	client := fakeClientForRepos(api, web)
+	apiRepo := client.repos["acme"]["api"]
+	apiRepo.CloneURL = api.remotePath
+	client.repos["acme"]["api"] = apiRepo
+	webRepo := client.repos["acme"]["web"]
+	webRepo.CloneURL = filepath.Join(base, "missing.git")
+	client.repos["acme"]["web"] = webRepo
+	orgPath := filepath.Join(base, "acme")
+	manager := newTestManager([]config.Target{{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath}}, client)
+	store, err := state.Open(filepath.Join(base, "state.json"))
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	manager.State = store
+	manager.Out = io.Discard
+
+	var rf *RepoFailures
+	if err := manager.Clone(nil, false, false, 2); !errors.As(err, &rf) || rf.Failed != 1 {
+		t.Fatalf("Clone() error = %v, want one failed repo", err)
+	}
+	if _, err := os.Stat(filepath.Join(orgPath, "web")); !os.IsNotExist(err) {
+		t.Fatalf("failed clone left %s behind: %v", filepath.Join(orgPath, "web"), err)
+	}
+
+	// Fix the failing repo, and remove the good clone to see it is left
+	// alone by the retry.
+	webRepo.CloneURL = web.remotePath
+	client.repos["acme"]["web"] = webRepo
+	if err := os.RemoveAll(filepath.Join(orgPath, "api")); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.RetryFailedClones(nil, false, false, 2); err != nil {
+		t.Fatalf("RetryFailedClones() error = %v", err)
+	}
+	if !isGitRepo(filepath.Join(orgPath, "web")) {
+		t.Error("failed repo was not cloned again")
+	}
+	if _, err := os.Stat(filepath.Join(orgPath, "api")); !os.IsNotExist(err) {
+		t.Errorf("retry cloned a repo that had not failed: %v", err)
+	}
+
+	// The retry succeeded, so there is nothing left to retry.
+	if err := manager.RetryFailedClones(nil, false, false, 2); err != nil {
+		t.Fatalf("second RetryFailedClones() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(orgPath, "api")); !os.IsNotExist(err) {
+		t.Errorf("second retry cloned %s", filepath.Join(orgPath, "api"))
+	}
+}
+
+func TestForksFilterCloneAndList(t *testing.T) {
+	base := t.TempDir()
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api-seed"))
+	lib := createTestRepo(t, base, "acme", "lib", "main", filepath.Join(base, "lib-seed"))
+	client := fakeClientForRepos(api, lib)
+	for name, r := range map[string]testRepo{"api": api, "lib": lib} {
+		rr := client.repos["acme"][name]
+		rr.CloneURL = r.remotePath
+		rr.Fork = name == "lib"
+		client.repos["acme"][name] = rr
+	}
+	orgPath := filepath.Join(base, "acme")
+	manager := newTestManager([]config.Target{{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath, Forks: "exclude"}}, client)
+	manager.Out = io.Discard
+	if err := manager.Clone(nil, false, false, 2); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if !isGitRepo(filepath.Join(orgPath, "api")) {
+		t.Error("api was not cloned")
+	}
+	if _, err := os.Stat(filepath.Join(orgPath, "lib")); !os.IsNotExist(err) {
+		t.Errorf("fork lib was cloned despite forks: exclude: %v", err)
+	}
+
+	var out bytes.Buffer
+	manager.Out = &out
+	manager.JSON = true
+	manager.Forks = "only"
+	if err := manager.List(nil, false, 1); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var entries []ListEntry
+	if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+		t.Fatalf("parsing list output %q: %v", out.String(), err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.Orphan {
+			names = append(names, e.Name)
+		}
+	}
+	if len(names) != 1 || names[0] != "lib" {
+		t.Errorf("listed %v with --only-forks, want [lib]", names)
+	}
+}
+
+func TestVisibilityFilterClone(t *testing.T) {
+	base := t.TempDir()
+	site := createTestRepo(t, base, "acme", "site", "main", filepath.Join(base, "site-seed"))
+	vault := createTestRepo(t, base, "acme", "vault", "main", filepath.Join(base, "vault-seed"))
+	client := fakeClientForRepos(site, vault)
+	for name, r := range map[string]testRepo{"site": site, "vault": vault} {
+		rr := client.repos["acme"][name]
+		rr.CloneURL = r.remotePath
+		rr.Private = name == "vault"
+		client.repos["acme"][name] = rr
+	}
+	orgPath := filepath.Join(base, "acme")
+	manager := newTestManager([]config.Target{{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath}}, client)
+	manager.Out = io.Discard
+	manager.Visibility = "public"
+	if err := manager.Clone(nil, false, false, 2); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if !isGitRepo(filepath.Join(orgPath, "site")) {
+		t.Error("public repo was not cloned")
+	}
+	if _, err := os.Stat(filepath.Join(orgPath, "vault")); !os.IsNotExist(err) {
+		t.Errorf("private repo was cloned with --only-public: %v", err)
+	}
+}
+
+func TestLanguageFilterClone(t *testing.T) {
+	base := t.TempDir()
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api-seed"))
+	web := createTestRepo(t, base, "acme", "web", "main", filepath.Join(base, "web-seed"))
+	client := fakeClientForRepos(api, web)
+	for name, r := range map[string]testRepo{"api": api, "web": web} {
+		rr := client.repos["acme"][name]
+		rr.CloneURL = r.remotePath
+		rr.Language = map[string]string{"api": "Go", "web": "TypeScript"}[name]
+		client.repos["acme"][name] = rr
+	}
+	orgPath := filepath.Join(base, "acme")
+	manager := newTestManager([]config.Target{{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath, Languages: []string{"typescript"}}}, client)
+	manager.Out = io.Discard
+	manager.Languages = []string{"go"}
+	if err := manager.Clone(nil, false, false, 2); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if !isGitRepo(filepath.Join(orgPath, "api")) {
+		t.Error("Go repo was not cloned with --language go")
+	}
+	if _, err := os.Stat(filepath.Join(orgPath, "web")); !os.IsNotExist(err) {
+		t.Errorf("TypeScript repo was cloned with --language go: %v", err)
+	}
+}
+
+// searchClient is a fakeClient whose search finds the repos named in found.
+type searchClient struct {
+	fakeClient
+	found   []string
+	queries []string
+}
+
+func (c *searchClient) SearchRepos(owner string, user bool, query string) ([]remote.Repository, error) {
+	c.queries = append(c.queries, owner+" "+query)
+	var repos []remote.Repository
+	for _, name := range c.found {
+		repos = append(repos, c.repos[owner][name])
+	}
+	return repos, nil
+}
+
+func TestSearchTargetClonesFoundRepos(t *testing.T) {
+	base := t.TempDir()
+	sdk := createTestRepo(t, base, "acme", "sdk", "main", filepath.Join(base, "sdk-seed"))
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-seed"))
+	fake := fakeClientForRepos(sdk, app)
+	for name, r := range map[string]testRepo{"sdk": sdk, "app": app} {
+		rr := fake.repos["acme"][name]
+		rr.CloneURL = r.remotePath
+		fake.repos["acme"][name] = rr
+	}
+	client := &searchClient{fakeClient: fake, found: []string{"sdk"}}
+	orgPath := filepath.Join(base, "acme")
+	target := config.Target{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath, Search: "topic:sdk archived:false"}
+	manager := newTestManager([]config.Target{target}, fake)
+	manager.providers["fake"] = client
+	manager.Out = io.Discard
+	if err := manager.Clone(nil, false, false, 2); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if want := []string{"acme topic:sdk archived:false"}; !reflect.DeepEqual(client.queries, want) {
+		t.Errorf("searches = %q, want %q", client.queries, want)
+	}
+	if !isGitRepo(filepath.Join(orgPath, "sdk")) {
+		t.Error("repo found by the search was not cloned")
+	}
+	if _, err := os.Stat(filepath.Join(orgPath, "app")); !os.IsNotExist(err) {
+		t.Errorf("repo the search did not find was cloned: %v", err)
+	}
+
+	// Providers that cannot search fail the target rather than cloning all.
+	manager = newTestManager([]config.Target{target}, fake)
+	manager.Out, manager.Err = io.Discard, io.Discard
+	if err := manager.Clone(nil, false, false, 2); err == nil || !strings.Contains(err.Error(), "cannot search") {
+		t.Errorf("Clone() with a provider that cannot search: error = %v", err)
+	}
+}
+
+// teamClient is a fakeClient whose teams have access to the repos named in
+// teams.
+type teamClient struct {
+	fakeClient
+	teams map[string][]string // "org/team" -> repo names
+}
+
+func (c teamClient) ListTeamRepos(orgName, team string) ([]remote.Repository, error) {
+	var repos []remote.Repository
+	for _, name := range c.teams[orgName+"/"+team] {
+		repos = append(repos, c.repos[orgName][name])
+	}
+	return repos, nil
+}
+
+func TestTeamTargetClonesTeamRepos(t *testing.T) {
+	base := t.TempDir()
+	api := createTestRepo(t, base, "acme", "api", "main", filepath.Join(base, "api-seed"))
+	billing := createTestRepo(t, base, "acme", "billing", "main", filepath.Join(base, "billing-seed"))
+	fake := fakeClientForRepos(api, billing)
+	for name, r := range map[string]testRepo{"api": api, "billing": billing} {
+		rr := fake.repos["acme"][name]
+		rr.CloneURL = r.remotePath
+		fake.repos["acme"][name] = rr
+	}
+	orgPath := filepath.Join(base, "acme")
+	manager := newTestManager([]config.Target{{Name: "platform", Provider: "fake", Org: "acme", Team: "platform", Path: orgPath}}, fake)
+	manager.providers["fake"] = teamClient{fakeClient: fake, teams: map[string][]string{"acme/platform": {"api"}}}
+	manager.Out = io.Discard
+	if err := manager.Clone(nil, false, false, 2); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if !isGitRepo(filepath.Join(orgPath, "api")) {
+		t.Error("team repo was not cloned")
+	}
+	if _, err := os.Stat(filepath.Join(orgPath, "billing")); !os.IsNotExist(err) {
+		t.Errorf("repo outside the team was cloned: %v", err)
+	}
+}
+
+func TestExcludeTemplatesSkipsTemplateRepos(t *testing.T) {
+	base := t.TempDir()
+	svc := createTestRepo(t, base, "acme", "svc", "main", filepath.Join(base, "svc-seed"))
+	tmpl := createTestRepo(t, base, "acme", "service-template", "main", filepath.Join(base, "tmpl-seed"))
+	client := fakeClientForRepos(svc, tmpl)
+	for name, r := range map[string]testRepo{"svc": svc, "service-template": tmpl} {
+		rr := client.repos["acme"][name]
+		rr.CloneURL = r.remotePath
+		rr.Template = name == "service-template"
+		client.repos["acme"][name] = rr
+	}
+	orgPath := filepath.Join(base, "acme")
+	manager := newTestManager([]config.Target{{Name: "acme", Provider: "fake", Org: "acme", Path: orgPath}}, client)
+	manager.Out = io.Discard
+	manager.ExcludeTemplates = true
+	if err := manager.Clone(nil, false, false, 2); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if !isGitRepo(filepath.Join(orgPath, "svc")) {
+		t.Error("repo was not cloned")
+	}
+	if _, err := os.Stat(filepath.Join(orgPath, "service-template")); !os.IsNotExist(err) {
+		t.Errorf("template repo was cloned: %v", err)
+	}
+
+	var out bytes.Buffer
+	manager.Out = &out
+	manager.ExcludeTemplates = false
+	if err := manager.List(nil, false, 2); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "service-template (template)") {
+		t.Errorf("List() output does not mark the template repo:\n%s", out.String())
+	}
+}
+
+// releaseClient is a fakeClient whose repos have the given releases, with
+// each asset's URL as its content.
+type releaseClient struct {
+	fakeClient
+	releases  []remote.Release
+	downloads int
+}
+
+func (c *releaseClient) ListReleases(owner, name string) ([]remote.Release, error) {
+	return c.releases, nil
+}
+
+func (c *releaseClient) DownloadAsset(asset remote.ReleaseAsset, w io.Writer) error {
+	c.downloads++
+	_, err := io.WriteString(w, asset.URL)
+	return err
+}
+
+func TestBackupReleasesWritesAssetsAndManifest(t *testing.T) {
+	mirror := t.TempDir()
+	client := &releaseClient{releases: []remote.Release{{
+		Tag:  "v1.0.0",
+		Name: "First",
+		Assets: []remote.ReleaseAsset{
+			{Name: "tool-linux.tar.gz", Size: 5, URL: "linux"},
+			{Name: "tool-darwin.tar.gz", Size: 6, URL: "darwin"},
+		},
+	}}}
+	manager := newTestManager(nil, fakeClient{})
+	manager.providers["fake"] = client
+
+	n, err := manager.backupReleases("fake", "acme", "tool", mirror)
+	if err != nil || n != 2 {
+		t.Fatalf("backupReleases() = %d, %v; want 2, nil", n, err)
+	}
+	data, err := os.ReadFile(filepath.Join(mirror, releasesDir, "v1.0.0", "tool-darwin.tar.gz"))
+	if err != nil || string(data) != "darwin" {
+		t.Errorf("backed-up asset = %q, %v; want %q", data, err, "darwin")
+	}
+	var manifest releaseManifest
+	data, err = os.ReadFile(filepath.Join(mirror, releasesDir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("reading manifest: %v", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("decoding manifest: %v", err)
+	}
+	if manifest.Repo != "acme/tool" || len(manifest.Releases) != 1 || len(manifest.Releases[0].Assets) != 2 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	if a := manifest.Releases[0].Assets[0]; a.Path != "v1.0.0/tool-linux.tar.gz" || a.Size != 5 || a.SHA256 == "" {
+		t.Errorf("manifest asset = %+v", a)
+	}
+
+	// A second run only downloads assets that are new.
+	client.releases = append([]remote.Release{{Tag: "v1.1.0", Assets: []remote.ReleaseAsset{{Name: "tool-linux.tar.gz", Size: 3, URL: "new"}}}}, client.releases...)
+	client.downloads = 0
+	if n, err := manager.backupReleases("fake", "acme", "tool", mirror); err != nil || n != 1 || client.downloads != 1 {
+		t.Errorf("second backupReleases() = %d, %v with %d downloads; want 1, nil with 1", n, err, client.downloads)
+	}
+}
+
+func TestBackupIncrementalAndRestore(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	manager := newTestManager([]config.Target{repoTarget(app)}, fakeClientForRepos(app))
+	manager.Out = io.Discard
+	dir := filepath.Join(base, "backup")
+
+	if err := manager.Backup(dir, nil, false, 1); err != nil {
+		t.Fatalf("first Backup() error = %v", err)
+	}
+	commitFile(t, app.workPath, "later.txt", "later\n", "later work")
+	runGit(t, app.workPath, "branch", "feature")
+	if err := manager.Backup(dir, nil, false, 1); err != nil {
+		t.Fatalf("second Backup() error = %v", err)
+	}
+	var results []RepoResult
+	manager.OnResult = func(r RepoResult) { results = append(results, r) }
+	if err := manager.Backup(dir, nil, false, 1); err != nil {
+		t.Fatalf("third Backup() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Result != "unchanged" {
+		t.Fatalf("backup of an unchanged repo = %+v, want unchanged", results)
+	}
+	manifest, err := readBackupManifest(dir)
+	if err != nil {
+		t.Fatalf("reading manifest: %v", err)
+	}
+	if len(manifest.Repos) != 1 || len(manifest.Repos[0].Bundles) != 2 {
+		t.Fatalf("manifest = %+v, want one repo with a full and an incremental bundle", manifest.Repos)
+	}
+
+	head := strings.TrimSpace(runGit(t, app.workPath, "rev-parse", "HEAD"))
+	if err := os.RemoveAll(app.workPath); err != nil {
+		t.Fatal(err)
+	}
+	results = nil
+	if err := manager.RestoreBackup(dir, nil, 1); err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Result != "restored" {
+		t.Fatalf("restore results = %+v, want restored", results)
+	}
+	if got := strings.TrimSpace(runGit(t, app.workPath, "rev-parse", "HEAD")); got != head {
+		t.Errorf("restored HEAD = %s, want %s", got, head)
+	}
+	if branch := strings.TrimSpace(runGit(t, app.workPath, "rev-parse", "--abbrev-ref", "HEAD")); branch != "main" {
+		t.Errorf("restored branch = %s, want main", branch)
+	}
+	if got := readFile(t, filepath.Join(app.workPath, "later.txt")); got != "later\n" {
+		t.Errorf("restored later.txt = %q", got)
+	}
+	if got := strings.TrimSpace(runGit(t, app.workPath, "remote", "get-url", "origin")); got != app.remotePath {
+		t.Errorf("restored origin = %s, want %s", got, app.remotePath)
+	}
+	runGit(t, app.workPath, "rev-parse", "--verify", "refs/heads/feature")
+	if status := runGit(t, app.workPath, "status", "--porcelain"); status != "" {
+		t.Errorf("restored working tree is not clean:\n%s", status)
+	}
+}
+
+func TestExportAndApplyWorkspace(t *testing.T) {
+	base := t.TempDir()
+	app := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app"))
+	manager := newTestManager([]config.Target{repoTarget(app)}, fakeClientForRepos(app))
+	p := manager.config.Providers["fake"]
+	p.Token = "secret-token"
+	manager.config.Providers["fake"] = p
+
+	exported := strings.TrimSpace(runGit(t, app.workPath, "rev-parse", "HEAD"))
+	commitFile(t, app.workPath, "later.txt", "later", "after the export")
+	runGit(t, app.workPath, "push", "-q", "origin", "main")
+	runGit(t, app.workPath, "reset", "-q", "--hard", exported)
+
+	var out bytes.Buffer
+	manager.Out = &out
+	if err := manager.Export(1); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if strings.Contains(out.String(), "secret-token") {
+		t.Fatalf("export contains the provider token:\n%s", out.String())
+	}
+	ws, err := ParseWorkspace(out.Bytes())
+	if err != nil {
+		t.Fatalf("ParseWorkspace() error = %v", err)
+	}
+	want := []LockEntry{{Target: "app", Path: ".", Repo: "acme/app", Commit: exported, Branch: "main"}}
+	if !reflect.DeepEqual(ws.Repos, want) {
+		t.Fatalf("exported repos = %+v, want %+v", ws.Repos, want)
+	}
+
+	// Apply on "another machine": the same target at a new path.
+	target := repoTarget(app)
+	target.Path = filepath.Join(base, "laptop", "app")
+	client := fakeClientForRepos(app)
+	rr := client.repos["acme"]["app"]
+	rr.CloneURL = app.remotePath
+	client.repos["acme"]["app"] = rr
+	laptop := newTestManager([]config.Target{target}, client)
+	captureStdout(t, func() {
+		if err := laptop.Apply(ws, 1); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+	})
+	if head := strings.TrimSpace(runGit(t, target.Path, "rev-parse", "HEAD")); head != exported {
+		t.Fatalf("applied HEAD = %s, want the exported %s", head, exported)
+	}
+}
+
+func TestDryRunDoesNotFetch(t *testing.T) {
+	base := t.TempDir()
+	repo := createTestRepo(t, base, "acme", "app", "main", filepath.Join(base, "app-work"))
+	other := cloneRepo(t, repo.remotePath, filepath.Join(base, "other"))
+	commitFile(t, other, "remote.txt", "from remote\n", "remote update")
+	runGit(t, other, "push", "origin", "main")
+
+	before := runGit(t, repo.workPath, "for-each-ref", "refs/remotes")
+	manager := newTestManager([]config.Target{repoTarget(repo)}, fakeClientForRepos(repo))
+	manager.DryRun = true
+	captureStdout(t, func() {
+		for name, run := range map[string]func([]string, int) error{"Pull": manager.Pull, "Push": manager.Push, "Sync": manager.Sync} {
+			if err := run(nil, 1); err != nil {
+				t.Fatalf("%s() error = %v", name, err)
+			}
+		}
+	})
+	if after := runGit(t, repo.workPath, "for-each-ref", "refs/remotes"); after != before {
+		t.Fatalf("remote-tracking refs changed by a dry run:\nbefore:\n%s\nafter:\n%s", before, after)
+	}
+}
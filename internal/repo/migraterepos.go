@@ -0,0 +1,216 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+// MigrateOptions selects and names the repos MigrateRepos moves.
+type MigrateOptions struct {
+	Include []string          // glob patterns on source repo names; all repos when empty
+	Rename  map[string]string // source name -> destination name
+}
+
+// MigrateResult is the outcome of moving one repo.
+type MigrateResult struct {
+	Source  string `json:"source"` // owner/name on the source provider
+	Dest    string `json:"dest"`   // owner/name on the destination provider
+	Result  string `json:"result"` // migrated | would-migrate | skipped | failed
+	Method  string `json:"method,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// MigrateRepos copies every repo of fromOwner on one provider to toOwner on
+// another. Destinations with a migration API import the repo server-side
+// (with issues, releases and the like); elsewhere the repo is created and
+// its branches and tags are pushed from a temporary mirror clone. Repos that
+// already exist at the destination are skipped, so an interrupted run can
+// simply be repeated.
+func (m *Manager) MigrateRepos(fromProvider, fromOwner, toProvider, toOwner string, opts MigrateOptions) error {
+	src, ok := m.providers[fromProvider]
+	if !ok {
+		return fmt.Errorf("unknown provider %q", fromProvider)
+	}
+	dst, ok := m.providers[toProvider]
+	if !ok {
+		return fmt.Errorf("unknown provider %q", toProvider)
+	}
+	for _, pattern := range opts.Include {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	var repos []remote.Repository
+	var err error
+	if m.ownerIsUser(fromProvider, fromOwner) {
+		repos, err = src.ListUserRepos(fromOwner)
+	} else {
+		repos, err = src.ListOrgRepos(fromOwner)
+	}
+	if err != nil {
+		return fmt.Errorf("listing repos for %s: %w", fromOwner, err)
+	}
+	var selected []remote.Repository
+	for _, r := range repos {
+		if matchesAny(opts.Include, r.Name) {
+			selected = append(selected, r)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Name < selected[j].Name })
+
+	results := make([]MigrateResult, 0, len(selected))
+	for i, r := range selected {
+		destName := r.Name
+		if renamed, ok := opts.Rename[r.Name]; ok {
+			destName = renamed
+		}
+		m.logf(slog.LevelInfo, "[%d/%d] %s/%s -> %s/%s", i+1, len(selected), fromOwner, r.Name, toOwner, destName)
+		results = append(results, m.migrateRepo(r, fromProvider, fromOwner, dst, toProvider, toOwner, destName))
+	}
+
+	var migrated, skipped, failed int
+	for _, r := range results {
+		switch r.Result {
+		case "skipped":
+			skipped++
+		case "failed":
+			failed++
+		default:
+			migrated++
+		}
+	}
+	if m.JSON {
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+	} else if m.DryRun {
+		m.logf(slog.LevelInfo, "Migration dry run: %d to migrate, %d skipped", migrated, skipped)
+	} else {
+		m.logf(slog.LevelInfo, "Migration complete: %d migrated, %d skipped, %d failed", migrated, skipped, failed)
+	}
+	if failed > 0 {
+		return &RepoFailures{Failed: failed, Total: len(results)}
+	}
+	return nil
+}
+
+func (m *Manager) migrateRepo(r remote.Repository, fromProvider, fromOwner string, dst remote.Client, toProvider, toOwner, destName string) MigrateResult {
+	res := MigrateResult{Source: fromOwner + "/" + r.Name, Dest: toOwner + "/" + destName}
+	fail := func(err error) MigrateResult {
+		m.logEvent(slog.LevelError, "error", res.Source, "%v", err)
+		res.Result, res.Message = "failed", err.Error()
+		return res
+	}
+
+	existing, err := dst.GetRepo(toOwner, destName)
+	if err != nil {
+		return fail(fmt.Errorf("checking destination: %w", err))
+	}
+	if existing != nil {
+		m.logEvent(slog.LevelInfo, "skip", res.Source, "%s already exists on %s", res.Dest, toProvider)
+		res.Result, res.Message, res.URL = "skipped", "destination exists", existing.HTMLURL
+		return res
+	}
+
+	migrator, canMigrate := dst.(remote.Migrator)
+	creator, canCreate := dst.(remote.Creator)
+	switch {
+	case canMigrate:
+		res.Method = "api"
+	case canCreate:
+		res.Method = "git"
+	default:
+		return fail(fmt.Errorf("provider %q cannot create repositories", toProvider))
+	}
+	if m.DryRun {
+		reason := fmt.Sprintf("to %s:%s via %s", toProvider, res.Dest, res.Method)
+		m.printPlan(res.Source, "would-migrate", reason)
+		res.Result, res.Message = "would-migrate", reason
+		return res
+	}
+
+	srcProvider := m.config.Providers[fromProvider]
+	var created *remote.Repository
+	if canMigrate {
+		created, err = migrator.MigrateRepo(toOwner, destName, remote.MigrateSource{
+			CloneURL:    r.CloneURL,
+			Service:     m.config.Providers[fromProvider].Type,
+			Token:       srcProvider.Token,
+			Description: r.Description,
+			Private:     r.Private,
+		})
+		if err != nil {
+			return fail(err)
+		}
+	} else {
+		created, err = creator.CreateRepo(toOwner, destName, remote.CreateOptions{Description: r.Description, Private: r.Private})
+		if err != nil {
+			return fail(err)
+		}
+		if !r.Empty {
+			if err := mirrorPush(r.CloneURL, authFor(srcProvider), created.CloneURL, authFor(m.config.Providers[toProvider]), r.DefaultBranch); err != nil {
+				return fail(fmt.Errorf("created %s but copying failed: %w", res.Dest, err))
+			}
+		}
+	}
+	m.logEvent(slog.LevelInfo, "migrate", res.Source, "%s (%s)", created.HTMLURL, res.Method)
+	res.Result, res.URL = "migrated", created.HTMLURL
+	return res
+}
+
+// mirrorPush copies all branches and tags of srcURL to destURL through a
+// temporary bare clone. Other refs (e.g. GitHub's refs/pull) are left out;
+// hosts reject pushes to them. defaultBranch is pushed first because hosts
+// make the first branch they receive the default.
+func mirrorPush(srcURL string, srcAuth gitAuth, destURL string, destAuth gitAuth, defaultBranch string) error {
+	tmp, err := os.MkdirTemp("", "tugboat-migrate-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	mirror := filepath.Join(tmp, "repo.git")
+
+	clone := exec.Command("git", "clone", "--mirror", "--quiet", srcURL, mirror)
+	clone.Env = srcAuth.env()
+	if out, err := clone.CombinedOutput(); err != nil {
+		return fmt.Errorf("cloning source: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	var pushes [][]string
+	if defaultBranch != "" {
+		pushes = append(pushes, []string{"refs/heads/" + defaultBranch})
+	}
+	pushes = append(pushes, []string{"refs/heads/*:refs/heads/*", "refs/tags/*:refs/tags/*"})
+	for _, refspecs := range pushes {
+		push := exec.Command("git", append([]string{"push", "--quiet", destURL}, refspecs...)...)
+		push.Dir = mirror
+		push.Env = destAuth.env()
+		if out, err := push.CombinedOutput(); err != nil {
+			return fmt.Errorf("pushing: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// matchesAny reports whether name matches one of patterns; an empty list
+// matches everything.
+func matchesAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
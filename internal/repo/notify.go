@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"fmt"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/notify"
+)
+
+// notify reports failed results and diverged or orphaned repos of one run to
+// the configured webhook. A failed notification is only a warning: it never
+// changes the outcome of the command.
+func (m *Manager) notify(command string, statuses []RepoStatus, results []RepoResult) {
+	if m.Notifier == nil || m.DryRun {
+		return
+	}
+	outcome := make(map[string]RepoResult, len(results))
+	for _, r := range results {
+		outcome[r.Path] = r
+	}
+
+	checked := make([]string, 0, len(statuses))
+	var events []notify.Event
+	for _, s := range statuses {
+		checked = append(checked, s.Path)
+		r, ok := outcome[s.Path]
+		if ok && r.Result == "failed" {
+			events = append(events, notify.Event{Kind: notify.Failed, Path: s.Path, Message: r.Message})
+		}
+		// Sync rebases diverged repos; only report the ones left diverged.
+		if s.Error == "" && s.Behind > 0 && !s.CanFastForward && (!ok || r.Result == "skipped") {
+			events = append(events, notify.Event{
+				Kind:    notify.Diverged,
+				Path:    s.Path,
+				Message: fmt.Sprintf("%d ahead, %d behind", s.Ahead, s.Behind),
+			})
+		}
+		if s.Orphan {
+			events = append(events, notify.Event{Kind: notify.Orphan, Path: s.Path, Message: "no longer exists on the provider"})
+		}
+	}
+	if err := m.Notifier.Send(command, checked, events); err != nil {
+		fmt.Fprintf(m.errOut(), "Warning: %v\n", err)
+	}
+}
@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+// updatePinned moves a foldout pinned to s.Ref onto it, for pull and sync:
+// tags are fetched, a branch ref is fast-forwarded to origin, and a tag or
+// commit is checked out detached. Dirty repos are skipped.
+func (m *Manager) updatePinned(s RepoStatus) RepoResult {
+	if s.Error != "" {
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", s.Error)
+		return newResult(s, "failed", s.Error)
+	}
+	if s.Dirty {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "dirty")
+		return newResult(s, "skipped", "dirty")
+	}
+	auth := authFor(m.config.Providers[s.Provider])
+	if !m.DryRun {
+		cmd := exec.Command("git", "fetch", "--quiet", "--tags", "origin")
+		cmd.Dir = s.Path
+		cmd.Env = auth.env()
+		if out, err := cmd.CombinedOutput(); err != nil {
+			msg := fmt.Sprintf("fetch: %v: %s", err, strings.TrimSpace(string(out)))
+			m.logEvent(slog.LevelError, "error", s.Path, "%s", msg)
+			return newResult(s, "failed", msg)
+		}
+	}
+	if atPinnedRef(s.Path, s.Branch, s.Ref) {
+		m.logEvent(slog.LevelDebug, "ok", s.Path, "at %s", s.Ref)
+		return newResult(s, "unchanged", "at "+s.Ref)
+	}
+	reason := "check out " + s.Ref
+	if m.DryRun {
+		m.printPlan(s.Path, "would-update", reason)
+		return newResult(s, "would-update", reason)
+	}
+	if err := checkoutPinnedRef(s.Path, s.Ref, auth); err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+	m.logEvent(slog.LevelInfo, "update", s.Path, "%s", reason)
+	return newResult(s, "updated", reason)
+}
+
+// atPinnedRef reports whether the checkout at repoPath, on branch, is at ref:
+// on branch ref at origin's tip for a branch, else detached at its commit.
+func atPinnedRef(repoPath, branch, ref string) bool {
+	head, err := gitOutput(repoPath, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		return false
+	}
+	if remoteTrackingRefExists(repoPath, ref) {
+		tip, err := gitOutput(repoPath, "rev-parse", "refs/remotes/origin/"+ref)
+		return err == nil && branch == ref && tip == head
+	}
+	commit, err := gitOutput(repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	return err == nil && branch == "HEAD" && commit == head
+}
+
+// checkoutPinnedRef checks out ref in repoPath. A branch of origin is checked
+// out as a local branch tracking it and fast-forwarded; a tag or commit is
+// checked out detached, fetched by name first when the clone lacks it (e.g.
+// a commit beyond a shallow clone's depth).
+func checkoutPinnedRef(repoPath, ref string, auth gitAuth) error {
+	run := func(env []string, args ...string) error {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		cmd.Env = env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	if remoteTrackingRefExists(repoPath, ref) {
+		if !localBranchExists(repoPath, ref) {
+			return run(gitEnvNoPrompt(), "checkout", "--quiet", "--track", "-b", ref, "origin/"+ref)
+		}
+		if err := run(gitEnvNoPrompt(), "checkout", "--quiet", ref); err != nil {
+			return err
+		}
+		return run(gitEnvNoPrompt(), "merge", "--quiet", "--ff-only", "origin/"+ref)
+	}
+	commit := ref + "^{commit}"
+	if gitRun(repoPath, "rev-parse", "--verify", "--quiet", commit) != nil {
+		if err := run(auth.env(), "fetch", "--quiet", "origin", ref); err != nil {
+			return err
+		}
+		commit = "FETCH_HEAD"
+	}
+	return run(gitEnvNoPrompt(), "checkout", "--quiet", "--detach", commit)
+}
@@ -0,0 +1,284 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+// PREntry is one repo of `pr list`.
+type PREntry struct {
+	Path         string               `json:"path"`
+	Target       string               `json:"target"`
+	Name         string               `json:"name"`
+	PullRequests []remote.PullRequest `json:"pull_requests"` // oldest first
+	Error        string               `json:"error,omitempty"`
+}
+
+// PullRequests prints the open pull requests of every local repo of the
+// named targets, with author, age and review state. Repos without open pull
+// requests are left out of the text output. It does not fetch.
+func (m *Manager) PullRequests(targetNames []string, workers int) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	jobs, _, err := m.localRepos(targets)
+	if err != nil {
+		return err
+	}
+
+	entries := pool.Run(jobs, workers, func(job statusJob) PREntry {
+		e := PREntry{Path: job.path, Target: job.target, Name: job.name, PullRequests: []remote.PullRequest{}}
+		lister, ok := m.providers[job.provider].(remote.PullRequestLister)
+		if !ok {
+			e.Error = fmt.Sprintf("provider %q cannot list pull requests", job.provider)
+			return e
+		}
+		prs, err := lister.ListPullRequests(job.org, job.name)
+		if err != nil {
+			e.Error = err.Error()
+			return e
+		}
+		sort.Slice(prs, func(i, j int) bool { return prs[i].Created.Before(prs[j].Created) })
+		e.PullRequests = append(e.PullRequests, prs...)
+		return e
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	var errored, open, withOpen int
+	for _, e := range entries {
+		if e.Error != "" {
+			errored++
+		}
+		if len(e.PullRequests) > 0 {
+			open += len(e.PullRequests)
+			withOpen++
+		}
+	}
+	if m.JSON {
+		if entries == nil {
+			entries = []PREntry{}
+		}
+		if err := m.writeJSON(entries); err != nil {
+			return err
+		}
+	} else {
+		now := time.Now()
+		for _, e := range entries {
+			if e.Error != "" {
+				m.printf("  [ERROR]  %s: %s\n", e.Path, e.Error)
+				continue
+			}
+			if len(e.PullRequests) == 0 {
+				continue
+			}
+			m.printf("%s\n", m.paint(colorMagenta, e.Path))
+			for _, pr := range e.PullRequests {
+				var flags []string
+				if pr.Draft {
+					flags = append(flags, "draft")
+				}
+				flags = append(flags, m.paint(reviewColor(pr.Review), pr.Review))
+				m.printf("  #%-5d %s  %s, %s  %s\n", pr.Number, pr.Title, pr.Author, age(now, pr.Created), strings.Join(flags, ", "))
+			}
+		}
+		m.printf("\n%d open pull requests in %d repos (%d checked)\n", open, withOpen, len(entries))
+	}
+
+	if errored > 0 {
+		return &RepoFailures{Failed: errored, Total: len(entries)}
+	}
+	return nil
+}
+
+// PROptions controls the pull requests CreatePullRequests opens. Title and
+// Body are text/template templates executed with a PRTemplateData.
+type PROptions struct {
+	Title  string // defaults to the subject of the branch's newest commit
+	Body   string
+	Branch string // only repos on this branch; any feature branch when empty
+	Draft  bool
+}
+
+// PRTemplateData is what the title and body templates of `pr create` see.
+type PRTemplateData struct {
+	Repo    string   // repo name
+	Org     string   // owner on the provider
+	Target  string   // config target
+	Branch  string   // head branch
+	Base    string   // default branch the PR goes into
+	Subject string   // subject of the newest commit on Branch
+	Commits []string // subjects of the commits on Branch, oldest first
+}
+
+// CreatePullRequests opens a pull request into the default branch for every
+// repo of the named targets whose checked-out branch has commits the default
+// branch lacks. The branch is pushed to origin first. Repos that already
+// have an open pull request from the branch are skipped, so a rerun only
+// fills the gaps.
+func (m *Manager) CreatePullRequests(targetNames []string, opts PROptions, workers int) error {
+	if opts.Title == "" {
+		opts.Title = "{{.Subject}}"
+	}
+	title, err := template.New("title").Parse(opts.Title)
+	if err != nil {
+		return fmt.Errorf("parsing title template: %w", err)
+	}
+	body, err := template.New("body").Parse(opts.Body)
+	if err != nil {
+		return fmt.Errorf("parsing body template: %w", err)
+	}
+
+	statuses, err := m.Statuses(targetNames, workers)
+	if err != nil {
+		return err
+	}
+	results := []RepoResult{}
+	for _, s := range statuses {
+		if s.Mirror || (opts.Branch != "" && s.Error == "" && s.Branch != opts.Branch) {
+			continue
+		}
+		results = append(results, m.createPullRequest(s, opts.Draft, title, body))
+	}
+
+	if m.JSON {
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	opened, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "PR dry run: %d to open, %d skipped, %d failed", opened, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "PR create complete: %d opened, %d skipped, %d failed", opened, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
+
+// createPullRequest pushes the checked-out branch of one repo and opens a
+// pull request from it into the default branch.
+func (m *Manager) createPullRequest(s RepoStatus, draft bool, title, body *template.Template) RepoResult {
+	if s.Error != "" {
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", s.Error)
+		return newResult(s, "failed", s.Error)
+	}
+	base, err := resolveDefaultBranch(s.Path, s.DefaultBranch)
+	if err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+	if s.Branch == "" || s.Branch == base {
+		m.logEvent(slog.LevelDebug, "ok", s.Path, "not on a feature branch")
+		return newResult(s, "unchanged", "")
+	}
+	out, err := gitOutput(s.Path, "log", "--reverse", "--format=%s", "refs/remotes/origin/"+base+"..HEAD")
+	if err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "cannot compare %s with origin/%s", s.Branch, base)
+		return newResult(s, "failed", fmt.Sprintf("cannot compare %s with origin/%s", s.Branch, base))
+	}
+	if strings.TrimSpace(out) == "" {
+		m.logEvent(slog.LevelDebug, "ok", s.Path, "%s has no commits ahead of origin/%s", s.Branch, base)
+		return newResult(s, "unchanged", "")
+	}
+	commits := strings.Split(strings.TrimSpace(out), "\n")
+
+	client := m.providers[s.Provider]
+	creator, ok := client.(remote.PullRequestCreator)
+	if !ok {
+		msg := fmt.Sprintf("provider %q cannot open pull requests", s.Provider)
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", msg)
+		return newResult(s, "failed", msg)
+	}
+	if lister, ok := client.(remote.PullRequestLister); ok {
+		open, err := lister.ListPullRequests(s.Org, s.Name)
+		if err != nil {
+			m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+			return newResult(s, "failed", err.Error())
+		}
+		for _, pr := range open {
+			if pr.Head == s.Branch {
+				reason := fmt.Sprintf("#%d already open: %s", pr.Number, pr.URL)
+				m.logEvent(slog.LevelInfo, "skip", s.Path, "%s", reason)
+				return newResult(s, "skipped", reason)
+			}
+		}
+	}
+
+	data := PRTemplateData{
+		Repo:    s.Name,
+		Org:     s.Org,
+		Target:  s.Target,
+		Branch:  s.Branch,
+		Base:    base,
+		Subject: commits[len(commits)-1],
+		Commits: commits,
+	}
+	var titleText, bodyText bytes.Buffer
+	if err := title.Execute(&titleText, data); err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "title template: %v", err)
+		return newResult(s, "failed", fmt.Sprintf("title template: %v", err))
+	}
+	if err := body.Execute(&bodyText, data); err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "body template: %v", err)
+		return newResult(s, "failed", fmt.Sprintf("body template: %v", err))
+	}
+	newPR := remote.NewPullRequest{
+		Title: strings.TrimSpace(titleText.String()),
+		Body:  bodyText.String(),
+		Head:  s.Branch,
+		Base:  base,
+		Draft: draft,
+	}
+
+	reason := fmt.Sprintf("%s -> %s (%d commits): %s", s.Branch, base, len(commits), newPR.Title)
+	if m.DryRun {
+		m.printPlan(s.Path, "would-open", reason)
+		return newResult(s, "would-open", reason)
+	}
+	if err := m.gitPush(s.Path, authFor(m.providerFor(s.Target)), "--quiet", "--set-upstream", "origin", s.Branch); err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "pushing %s failed", s.Branch)
+		return newResult(s, "failed", fmt.Sprintf("pushing %s failed", s.Branch))
+	}
+	pr, err := creator.CreatePullRequest(s.Org, s.Name, newPR)
+	if err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+	m.logEvent(slog.LevelInfo, "open", s.Path, "#%d %s", pr.Number, pr.URL)
+	return newResult(s, "opened", pr.URL)
+}
+
+// reviewColor picks the color of a pull request review state.
+func reviewColor(review string) string {
+	switch review {
+	case remote.ReviewApproved:
+		return colorGreen
+	case remote.ReviewChangesRequested:
+		return colorRed
+	default:
+		return colorYellow
+	}
+}
+
+// age formats how long ago t was in the largest whole unit: minutes, hours
+// or days.
+func age(now, t time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// orphanRepo is a local clone under an org/user target whose repo no longer
+// exists on the remote.
+type orphanRepo struct {
+	target string
+	name   string
+	path   string
+}
+
+// findOrphans lists local repos of the org/user targets that are missing from
+// the remote listing. Unlike status, a failed listing is an error: nothing may
+// be treated as deleted unless the remote confirmed it.
+func (m *Manager) findOrphans(targetNames []string) ([]orphanRepo, error) {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []orphanRepo
+	for _, t := range targets {
+		if t.Repo != "" {
+			continue
+		}
+		repoPaths, err := targetRepoDirs(t)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", t.Path, err)
+		}
+		repos, err := m.listTargetRepos(t)
+		if err != nil {
+			return nil, fmt.Errorf("listing repos for %s: %w", t.Owner(), err)
+		}
+		remoteNames := make(map[string]bool, len(repos))
+		for _, r := range repos {
+			remoteNames[r.Name] = true
+		}
+		for _, repoPath := range repoPaths {
+			if name := filepath.Base(repoPath); !remoteNames[name] {
+				orphans = append(orphans, orphanRepo{target: t.Name, name: name, path: repoPath})
+			}
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].path < orphans[j].path })
+	return orphans, nil
+}
+
+// Prune deletes local repos that were removed from their org or user account,
+// or moves them under moveTo/<target>/<name> when moveTo is set. Dirty repos
+// are kept unless force is set. confirm, when non-nil, is asked before
+// anything is removed and cancels the prune by returning false.
+func (m *Manager) Prune(targetNames []string, moveTo string, force bool, confirm func(count int) bool) error {
+	orphans, err := m.findOrphans(targetNames)
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		m.logf(slog.LevelInfo, "No orphan repos found")
+		return nil
+	}
+
+	var candidates []orphanRepo
+	var skipped int
+	for _, o := range orphans {
+		if !force {
+			dirty, err := m.git.IsDirty(o.path)
+			if err != nil {
+				m.logEvent(slog.LevelInfo, "skip", o.path, "checking status: %v", err)
+				skipped++
+				continue
+			}
+			if dirty {
+				m.logEvent(slog.LevelInfo, "skip", o.path, "dirty (use --force to prune anyway)")
+				skipped++
+				continue
+			}
+		}
+		candidates = append(candidates, o)
+	}
+
+	action, result := "delete", "would-prune"
+	if moveTo != "" {
+		action, result = "move to "+moveTo, "would-move"
+	}
+	if m.DryRun {
+		for _, o := range candidates {
+			m.printPlan(o.path, result, "missing on remote, "+action)
+		}
+		m.logf(slog.LevelInfo, "Prune dry run: %d to prune, %d skipped", len(candidates), skipped)
+		return nil
+	}
+	if len(candidates) == 0 {
+		m.logf(slog.LevelInfo, "Prune complete: 0 pruned, %d skipped, 0 failed", skipped)
+		return nil
+	}
+
+	for _, o := range candidates {
+		m.printf("  [ORPHAN] %s\n", o.path)
+	}
+	if confirm != nil && !confirm(len(candidates)) {
+		m.logf(slog.LevelInfo, "Prune cancelled")
+		return nil
+	}
+
+	var pruned, failed int
+	for _, o := range candidates {
+		if moveTo != "" {
+			dest := filepath.Join(moveTo, o.target, o.name)
+			if err := movePath(o.path, dest); err != nil {
+				m.logEvent(slog.LevelError, "error", o.path, "%v", err)
+				failed++
+				continue
+			}
+			m.logEvent(slog.LevelInfo, "moved", o.path, "to %s", dest)
+		} else {
+			if err := os.RemoveAll(o.path); err != nil {
+				m.logEvent(slog.LevelError, "error", o.path, "%v", err)
+				failed++
+				continue
+			}
+			m.logEvent(slog.LevelInfo, "pruned", o.path, "")
+		}
+		pruned++
+	}
+	m.logf(slog.LevelInfo, "Prune complete: %d pruned, %d skipped, %d failed", pruned, skipped, failed)
+	if failed > 0 {
+		return &RepoFailures{Failed: failed, Total: len(candidates)}
+	}
+	return nil
+}
+
+// movePath renames src to dest, refusing to overwrite an existing dest.
+func movePath(src, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("destination %s already exists", dest)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(dest), err)
+	}
+	return os.Rename(src, dest)
+}
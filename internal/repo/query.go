@@ -0,0 +1,159 @@
+package repo
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+)
+
+// QueryRepo is a local repo as query reports it.
+type QueryRepo struct {
+	Path     string `json:"path"`
+	Target   string `json:"target"`
+	Provider string `json:"provider"`
+	Org      string `json:"org"`
+	Name     string `json:"name"`
+}
+
+// queryRoot is a local repo known to query, with its path as the
+// filesystem resolves it so paths through symlinks still match.
+type queryRoot struct {
+	job      statusJob
+	resolved string
+}
+
+// Query answers queries read from in, one per line, with one JSON line each
+// on out, for editor plugins that cannot wait for a full status run. The
+// repos of targets are found once and again on "reload"; nothing is fetched
+// and no provider API is asked. Queries are
+//
+//	roots          all local repos, as a list of QueryRepo
+//	repo PATH      the innermost repo containing PATH, or null
+//	status PATH    the RepoStatus of the repo containing PATH, against the
+//	               remote refs of the last fetch
+//	reload         find the repos again; answers {"roots": N}
+//
+// A query that cannot be answered gets {"error": "..."}. Query returns at
+// the end of in or on "quit".
+func (m *Manager) Query(targetNames []string, in io.Reader, out io.Writer) error {
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	roots, err := m.queryRoots(targets)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		command, arg, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		arg = strings.TrimSpace(arg)
+		var answer any
+		switch {
+		case command == "":
+			continue
+		case command == "quit":
+			return nil
+		case command == "roots" && arg == "":
+			list := make([]QueryRepo, 0, len(roots))
+			for _, r := range roots {
+				list = append(list, r.job.queryRepo())
+			}
+			answer = list
+		case command == "repo" && arg != "":
+			if r := containingRoot(roots, arg); r != nil {
+				answer = r.job.queryRepo()
+			}
+		case command == "status" && arg != "":
+			r := containingRoot(roots, arg)
+			if r == nil {
+				answer = queryError("no repo contains %s", arg)
+				break
+			}
+			var timing RepoTiming
+			s := getRepoStatus(m.git, r.job.path, r.job.target, r.job.org, r.job.name, r.job.provider, r.job.auth, false, &timing)
+			s.Ref = r.job.ref
+			s.Offline = true
+			m.recordStatus(&s, false)
+			answer = s
+		case command == "reload" && arg == "":
+			if roots, err = m.queryRoots(targets); err != nil {
+				answer = queryError("%v", err)
+				break
+			}
+			answer = map[string]int{"roots": len(roots)}
+		default:
+			answer = queryError("unknown query %q (want roots, repo PATH, status PATH, reload or quit)", scanner.Text())
+		}
+		if err := enc.Encode(answer); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func queryError(format string, args ...any) map[string]string {
+	return map[string]string{"error": fmt.Sprintf(format, args...)}
+}
+
+func (job statusJob) queryRepo() QueryRepo {
+	return QueryRepo{Path: job.path, Target: job.target, Provider: job.provider, Org: job.org, Name: job.name}
+}
+
+// queryRoots finds the local repos of the targets whose paths exist.
+func (m *Manager) queryRoots(targets []config.Target) ([]queryRoot, error) {
+	var existing []config.Target
+	for _, t := range targets {
+		if _, err := os.Stat(t.Path); err == nil {
+			existing = append(existing, t)
+		}
+	}
+	jobs, _, err := m.localRepos(existing)
+	if err != nil {
+		return nil, err
+	}
+	roots := make([]queryRoot, 0, len(jobs))
+	for _, job := range jobs {
+		resolved, err := filepath.EvalSymlinks(job.path)
+		if err != nil {
+			resolved = job.path
+		}
+		roots = append(roots, queryRoot{job: job, resolved: resolved})
+	}
+	return roots, nil
+}
+
+// containingRoot returns the innermost root that path is in, so files of a
+// foldout belong to the foldout rather than to the repo around it.
+func containingRoot(roots []queryRoot, path string) *queryRoot {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	candidates := []string{path}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved != path {
+		candidates = append(candidates, resolved)
+	}
+	var best *queryRoot
+	for i, r := range roots {
+		for _, p := range candidates {
+			if (within(p, r.job.path) || within(p, r.resolved)) && (best == nil || len(r.job.path) > len(best.job.path)) {
+				best = &roots[i]
+			}
+		}
+	}
+	return best
+}
+
+// within reports whether path is dir or below it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,159 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+// releasesDir is where a mirror keeps the backed-up assets of its repo's
+// releases, one directory per release tag, next to the manifest.
+const releasesDir = ".tugboat-releases"
+
+// releaseManifest is releasesDir/manifest.json: the repo's releases as the
+// provider last listed them and where their assets are.
+type releaseManifest struct {
+	Repo     string            `json:"repo"` // owner/name
+	Updated  time.Time         `json:"updated"`
+	Releases []manifestRelease `json:"releases"`
+}
+
+type manifestRelease struct {
+	Tag       string          `json:"tag"`
+	Name      string          `json:"name,omitempty"`
+	Published time.Time       `json:"published"`
+	Assets    []manifestAsset `json:"assets"`
+}
+
+type manifestAsset struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"` // relative to releasesDir, slash-separated
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// cloneReleases backs up the releases of a mirror that was just cloned to
+// path, when the clone options ask for it.
+func (m *Manager) cloneReleases(t config.Target, owner, name, path string, opts config.CloneOptions) error {
+	if opts.Mode != "mirror" || !opts.Releases {
+		return nil
+	}
+	if _, err := m.backupReleases(t.Provider, owner, name, path); err != nil {
+		return fmt.Errorf("cloned, but backing up releases failed: %w", err)
+	}
+	return nil
+}
+
+// backupReleases downloads the release assets of owner/name into the mirror
+// at path and rewrites its manifest, returning how many assets were
+// downloaded. Assets the manifest already has with the same size are not
+// downloaded again, and files of releases deleted upstream are left on disk.
+// An asset that fails to download does not stop the others.
+func (m *Manager) backupReleases(provider, owner, name, path string) (int, error) {
+	lister, ok := m.providers[provider].(remote.ReleaseLister)
+	if !ok {
+		return 0, fmt.Errorf("provider %s has no releases", provider)
+	}
+	releases, err := lister.ListReleases(owner, name)
+	if err != nil {
+		return 0, err
+	}
+
+	dir := filepath.Join(path, releasesDir)
+	have := make(map[string]manifestAsset)
+	if data, err := os.ReadFile(filepath.Join(dir, "manifest.json")); err == nil {
+		var old releaseManifest
+		if json.Unmarshal(data, &old) == nil {
+			for _, r := range old.Releases {
+				for _, a := range r.Assets {
+					have[a.Path] = a
+				}
+			}
+		}
+	}
+
+	manifest := releaseManifest{Repo: owner + "/" + name, Updated: time.Now().UTC(), Releases: []manifestRelease{}}
+	downloaded := 0
+	var errs []error
+	for _, r := range releases {
+		mr := manifestRelease{Tag: r.Tag, Name: r.Name, Published: r.Published, Assets: []manifestAsset{}}
+		for _, a := range r.Assets {
+			rel := r.Tag + "/" + a.Name
+			if !filepath.IsLocal(filepath.FromSlash(rel)) {
+				errs = append(errs, fmt.Errorf("release %s: asset %q has an unsafe path", r.Tag, a.Name))
+				continue
+			}
+			dest := filepath.Join(dir, filepath.FromSlash(rel))
+			if prev, ok := have[rel]; ok && prev.Size == a.Size {
+				if info, err := os.Stat(dest); err == nil && info.Size() == a.Size {
+					mr.Assets = append(mr.Assets, prev)
+					continue
+				}
+			}
+			sum, err := downloadAsset(lister, a, dest)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("release %s: %w", r.Tag, err))
+				continue
+			}
+			downloaded++
+			mr.Assets = append(mr.Assets, manifestAsset{Name: a.Name, Path: rel, Size: a.Size, SHA256: sum})
+		}
+		manifest.Releases = append(manifest.Releases, mr)
+	}
+
+	if len(releases) > 0 || len(have) > 0 {
+		if err := writeReleaseManifest(dir, manifest); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return downloaded, errors.Join(errs...)
+}
+
+// downloadAsset writes asset to dest through a temporary file, so an
+// interrupted download never looks complete, and returns its SHA-256.
+func downloadAsset(lister remote.ReleaseLister, asset remote.ReleaseAsset, dest string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	hash := sha256.New()
+	err = lister.DownloadAsset(asset, io.MultiWriter(tmp, hash))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func writeReleaseManifest(dir string, manifest releaseManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, ".manifest.json.tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, "manifest.json"))
+}
@@ -0,0 +1,153 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Reset puts every repo of the named targets back on origin's default
+// branch, exactly at origin/<default>: the default branch is checked out and
+// hard-reset, discarding local changes to tracked files. Repos whose local
+// default branch has commits origin lacks are skipped, as are dirty repos
+// unless dirtyToo is set. Other branches are left as they are, and untracked
+// files stay (see Clean). confirm, when non-nil, is asked once the repos to
+// reset are listed and cancels the reset by returning false.
+func (m *Manager) Reset(targetNames []string, dirtyToo bool, confirm func(count int) bool, workers int) error {
+	statuses, err := m.Statuses(targetNames, workers)
+	if err != nil {
+		return err
+	}
+
+	var results []RepoResult
+	var planned []RepoStatus
+	reasons := make(map[string]string)
+	for _, s := range statuses {
+		if s.Mirror && s.Error == "" {
+			continue
+		}
+		reason, r := m.planReset(s, dirtyToo)
+		if r != nil {
+			m.emit(*r)
+			results = append(results, *r)
+			continue
+		}
+		planned = append(planned, s)
+		reasons[s.Path] = reason
+	}
+
+	if m.DryRun {
+		for _, s := range planned {
+			m.printPlan(s.Path, "would-reset", reasons[s.Path])
+			r := newResult(s, "would-reset", reasons[s.Path])
+			m.emit(r)
+			results = append(results, r)
+		}
+	} else if len(planned) > 0 {
+		if !m.JSON {
+			for _, s := range planned {
+				m.printf("  [RESET] %s: %s\n", s.Path, reasons[s.Path])
+			}
+		}
+		if confirm != nil && !confirm(len(planned)) {
+			m.logf(slog.LevelInfo, "Reset cancelled")
+			return nil
+		}
+		results = append(results, emitJobs(m, planned, workers, func(m *Manager, s RepoStatus) RepoResult {
+			return m.resetRepo(s, reasons[s.Path])
+		})...)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+
+	if m.JSON {
+		if results == nil {
+			results = []RepoResult{}
+		}
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	reset, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Reset dry run: %d to reset, %d skipped, %d failed", reset, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Reset complete: %d reset, %d skipped, %d failed", reset, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
+
+// planReset decides whether s needs a reset. It returns what the reset will
+// do, or the final result when the repo is left alone.
+func (m *Manager) planReset(s RepoStatus, dirtyToo bool) (string, *RepoResult) {
+	done := func(result, reason string, level slog.Level, event string) (string, *RepoResult) {
+		m.logEvent(level, event, s.Path, "%s", reason)
+		r := newResult(s, result, reason)
+		return "", &r
+	}
+	if s.Error != "" {
+		return done("failed", s.Error, slog.LevelError, "error")
+	}
+	base, err := resolveDefaultBranch(s.Path, s.DefaultBranch)
+	if err != nil {
+		return done("failed", err.Error(), slog.LevelError, "error")
+	}
+	if !remoteTrackingRefExists(s.Path, base) {
+		return done("failed", fmt.Sprintf("origin/%s does not exist", base), slog.LevelError, "error")
+	}
+	if localBranchExists(s.Path, base) {
+		out, err := gitOutput(s.Path, "rev-list", "--count", "refs/remotes/origin/"+base+"..refs/heads/"+base)
+		if err != nil {
+			return done("failed", fmt.Sprintf("cannot compare %s with origin/%s", base, base), slog.LevelError, "error")
+		}
+		if n, _ := strconv.Atoi(strings.TrimSpace(out)); n > 0 {
+			return done("skipped", fmt.Sprintf("%s has %d commits not on origin", base, n), slog.LevelInfo, "skip")
+		}
+	}
+	if s.Dirty && !dirtyToo {
+		return done("skipped", "dirty (use --dirty-too to discard the changes)", slog.LevelInfo, "skip")
+	}
+
+	var steps []string
+	if s.Branch != base {
+		steps = append(steps, "switch from "+s.Branch)
+	}
+	if s.Dirty {
+		steps = append(steps, "discard local changes")
+	}
+	at, err := gitOutput(s.Path, "rev-parse", "HEAD")
+	want, err2 := gitOutput(s.Path, "rev-parse", "refs/remotes/origin/"+base)
+	if err != nil || err2 != nil || at != want {
+		steps = append(steps, "reset to origin/"+base)
+	}
+	if len(steps) == 0 {
+		return done("unchanged", "already at origin/"+base, slog.LevelDebug, "ok")
+	}
+	return strings.Join(steps, ", "), nil
+}
+
+// resetRepo checks out the default branch of s at origin/<default>,
+// discarding local changes.
+func (m *Manager) resetRepo(s RepoStatus, reason string) RepoResult {
+	base, err := resolveDefaultBranch(s.Path, s.DefaultBranch)
+	if err != nil {
+		m.logEvent(slog.LevelError, "error", s.Path, "%v", err)
+		return newResult(s, "failed", err.Error())
+	}
+	cmd := exec.Command("git", "checkout", "--quiet", "--force", "-B", base, "refs/remotes/origin/"+base)
+	cmd.Dir = s.Path
+	cmd.Env = gitEnvNoPrompt()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		msg := fmt.Sprintf("%v: %s", err, strings.TrimSpace(string(out)))
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", msg)
+		return newResult(s, "failed", msg)
+	}
+	// checkout -B from a remote-tracking ref does not set up tracking
+	// when branch.autoSetupMerge is off, so set it explicitly.
+	_ = gitRun(s.Path, "branch", "--quiet", "--set-upstream-to=origin/"+base, base)
+	m.logEvent(slog.LevelInfo, "reset", s.Path, "%s", reason)
+	return newResult(s, "reset", reason)
+}
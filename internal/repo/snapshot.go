@@ -0,0 +1,342 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot is a saved workspace state: the branch and commit of every repo
+// of its targets and, when saved with dirty changes, a stash of them.
+type Snapshot struct {
+	Name    string          `json:"name"`
+	Created time.Time       `json:"created"`
+	Repos   []SnapshotEntry `json:"repos"`
+}
+
+// SnapshotEntry is one repo of a snapshot. Stash is a stash commit holding
+// the repo's uncommitted changes, untracked files included; the repo keeps it
+// reachable as refs/tugboat/snapshots/<name>.
+type SnapshotEntry struct {
+	LockEntry
+	Stash string `json:"stash,omitempty"`
+}
+
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// snapshotPath returns where the snapshot name is kept in dir.
+func snapshotPath(dir, name string) (string, error) {
+	if !snapshotNamePattern.MatchString(name) || strings.HasSuffix(name, ".lock") || strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid snapshot name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+func snapshotRef(name string) string {
+	return "refs/tugboat/snapshots/" + name
+}
+
+// SaveSnapshot records the branch and commit of every local repo of the
+// named targets as snapshot name in dir. With dirty, the uncommitted changes
+// of dirty repos are stashed into the snapshot and left in place; otherwise
+// they are not saved, with a warning. An existing snapshot is only replaced
+// with force.
+func (m *Manager) SaveSnapshot(dir, name string, targetNames []string, dirty, force bool, workers int) error {
+	path, err := snapshotPath(dir, name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("snapshot %q already exists (use --force to replace it)", name)
+	}
+	targets, err := m.targetsFor(targetNames)
+	if err != nil {
+		return err
+	}
+	entries, err := m.lockEntries(targets, workers)
+	if err != nil {
+		return err
+	}
+
+	snap := Snapshot{Name: name, Created: time.Now().UTC().Truncate(time.Second), Repos: []SnapshotEntry{}}
+	for _, e := range entries {
+		snap.Repos = append(snap.Repos, SnapshotEntry{LockEntry: e})
+	}
+	type stashResult struct {
+		entry SnapshotEntry
+		err   error
+	}
+	results := runJobs(m, snap.Repos, workers, func(m *Manager, e SnapshotEntry) stashResult {
+		repoPath := m.snapshotRepoPath(e)
+		if isDirty, _ := m.git.IsDirty(repoPath); !isDirty {
+			if !m.DryRun {
+				_ = gitRun(repoPath, "update-ref", "-d", snapshotRef(name))
+			}
+			return stashResult{entry: e}
+		}
+		if !dirty {
+			m.logEvent(slog.LevelWarn, "warn", repoPath, "dirty; the changes are not saved (use --dirty to stash them into the snapshot)")
+			return stashResult{entry: e}
+		}
+		if m.DryRun {
+			m.printPlan(repoPath, "would-stash", "uncommitted changes")
+			return stashResult{entry: e}
+		}
+		stash, err := stashAndKeep(repoPath, name)
+		if err != nil {
+			m.logEvent(slog.LevelError, "error", repoPath, "%v", err)
+			return stashResult{err: err}
+		}
+		m.logEvent(slog.LevelInfo, "stash", repoPath, "%s", stash[:12])
+		e.Stash = stash
+		return stashResult{entry: e}
+	}, nil)
+	var failed int
+	for i, r := range results {
+		if r.err != nil {
+			failed++
+		}
+		snap.Repos[i] = r.entry
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d repos could not stash their changes; snapshot %q not saved", failed, name)
+	}
+
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Snapshot dry run: %d repos for %s", len(snap.Repos), path)
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	m.logf(slog.LevelInfo, "Saved snapshot %s: %d repos", name, len(snap.Repos))
+	return nil
+}
+
+// stashAndKeep stashes the uncommitted changes of repoPath, untracked files
+// included, puts them back, and keeps the stash commit as the snapshot ref of
+// name. It returns the stash commit.
+func stashAndKeep(repoPath, name string) (string, error) {
+	before, _ := gitOutput(repoPath, "rev-parse", "--quiet", "--verify", "refs/stash")
+	cmd := exec.Command("git", "stash", "push", "--include-untracked", "-m", "tugboat snapshot "+name)
+	cmd.Dir = repoPath
+	cmd.Env = gitEnvNoPrompt()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("git stash: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	after, _ := gitOutput(repoPath, "rev-parse", "--quiet", "--verify", "refs/stash")
+	if after == before {
+		return "", errors.New("git stash saved nothing")
+	}
+	stash := strings.TrimSpace(after)
+	pop := exec.Command("git", "stash", "pop", "--index")
+	pop.Dir = repoPath
+	pop.Env = gitEnvNoPrompt()
+	if out, err := pop.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("git stash pop: %v: %s (the changes are kept in the stash)", err, strings.TrimSpace(string(out)))
+	}
+	if err := gitRun(repoPath, "update-ref", snapshotRef(name), stash); err != nil {
+		return "", fmt.Errorf("keeping the stash as %s: %w", snapshotRef(name), err)
+	}
+	return stash, nil
+}
+
+func (m *Manager) snapshotRepoPath(e SnapshotEntry) string {
+	if t := m.config.GetTargetByName(e.Target); t != nil {
+		return filepath.Join(t.Path, filepath.FromSlash(e.Path))
+	}
+	return ""
+}
+
+// ReadSnapshot reads snapshot name from dir.
+func ReadSnapshot(dir, name string) (*Snapshot, error) {
+	path, err := snapshotPath(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no snapshot %q", name)
+	} else if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &snap, nil
+}
+
+// ListSnapshots returns the snapshots in dir, oldest first.
+func ListSnapshots(dir string) ([]Snapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	snaps := []Snapshot{}
+	for _, p := range paths {
+		snap, err := ReadSnapshot(dir, strings.TrimSuffix(filepath.Base(p), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, *snap)
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Created.Before(snaps[j].Created) })
+	return snaps, nil
+}
+
+// DeleteSnapshot removes snapshot name from dir and its stash refs from the
+// repos.
+func (m *Manager) DeleteSnapshot(dir, name string) error {
+	snap, err := ReadSnapshot(dir, name)
+	if err != nil {
+		return err
+	}
+	for _, e := range snap.Repos {
+		if repoPath := m.snapshotRepoPath(e); e.Stash != "" && repoPath != "" {
+			_ = gitRun(repoPath, "update-ref", "-d", snapshotRef(name))
+		}
+	}
+	path, _ := snapshotPath(dir, name)
+	return os.Remove(path)
+}
+
+// RestoreSnapshot puts every repo of snapshot name back: its branch is
+// checked out and moved to the saved commit (the message names the commit it
+// was at), or the commit is checked out detached when none was, and saved
+// changes are applied on top. Changes made since are stashed first, so
+// nothing is lost. Repos of targets missing from the config fail, as do
+// repos that are no longer cloned.
+func (m *Manager) RestoreSnapshot(dir, name string, workers int) error {
+	snap, err := ReadSnapshot(dir, name)
+	if err != nil {
+		return err
+	}
+	results := emitJobs(m, snap.Repos, workers, func(m *Manager, e SnapshotEntry) RepoResult {
+		return m.restoreSnapshotRepo(name, e)
+	})
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+
+	if m.JSON {
+		if results == nil {
+			results = []RepoResult{}
+		}
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	restored, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Snapshot restore dry run: %d to restore, %d skipped, %d failed", restored, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Snapshot %s restored: %d restored, %d skipped, %d failed", name, restored, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
+
+func (m *Manager) restoreSnapshotRepo(name string, e SnapshotEntry) RepoResult {
+	r := RepoResult{Path: m.snapshotRepoPath(e), Target: e.Target, Name: e.Repo, Branch: e.Branch}
+	fail := func(err error) RepoResult {
+		m.logEvent(slog.LevelError, "error", e.Repo, "%v", err)
+		r.Result, r.Message = "failed", err.Error()
+		return r
+	}
+	if r.Path == "" {
+		r.Path = e.Path
+		return fail(fmt.Errorf("target %q not in the config", e.Target))
+	}
+	if !isGitRepo(r.Path) {
+		return fail(errors.New("not cloned"))
+	}
+
+	head, _ := gitOutput(r.Path, "rev-parse", "--verify", "--quiet", "HEAD")
+	head = strings.TrimSpace(head)
+	branch, _ := m.git.CurrentBranch(r.Path)
+	dirty, err := m.git.IsDirty(r.Path)
+	if err != nil {
+		return fail(err)
+	}
+	atBranch := branch == e.Branch || (e.Branch == "" && branch == "HEAD")
+	if head == e.Commit && atBranch && !dirty && e.Stash == "" {
+		m.logEvent(slog.LevelDebug, "ok", r.Path, "at snapshot")
+		r.Result = "unchanged"
+		return r
+	}
+
+	var steps []string
+	if dirty {
+		steps = append(steps, "stash current changes")
+	}
+	at := e.Commit[:min(12, len(e.Commit))]
+	if e.Branch != "" {
+		steps = append(steps, fmt.Sprintf("check out %s at %s", e.Branch, at))
+		if tip, err := gitOutput(r.Path, "rev-parse", "--verify", "--quiet", "refs/heads/"+e.Branch); err == nil && strings.TrimSpace(tip) != e.Commit {
+			steps[len(steps)-1] += fmt.Sprintf(" (was %s)", strings.TrimSpace(tip)[:12])
+		}
+	} else {
+		steps = append(steps, "check out "+at+" detached")
+	}
+	if e.Stash != "" {
+		steps = append(steps, "apply saved changes")
+	}
+	reason := strings.Join(steps, ", ")
+	if m.DryRun {
+		m.printPlan(r.Path, "would-restore", reason)
+		r.Result, r.Message = "would-restore", reason
+		return r
+	}
+
+	run := func(args ...string) error {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = r.Path
+		cmd.Env = gitEnvNoPrompt()
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	if dirty {
+		if err := run("stash", "push", "--include-untracked", "-m", "tugboat: before restoring snapshot "+name); err != nil {
+			return fail(err)
+		}
+	}
+	if gitRun(r.Path, "cat-file", "-e", e.Commit+"^{commit}") != nil {
+		cmd := exec.Command("git", "fetch", "--quiet", "origin", e.Commit)
+		cmd.Dir = r.Path
+		cmd.Env = authFor(m.config.Providers[m.config.GetTargetByName(e.Target).Provider]).env()
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fail(fmt.Errorf("%s is gone and not on origin: %v: %s", at, err, strings.TrimSpace(string(out))))
+		}
+	}
+	checkout := []string{"checkout", "--quiet", "--detach", e.Commit}
+	if e.Branch != "" {
+		checkout = []string{"checkout", "--quiet", "-B", e.Branch, e.Commit}
+	}
+	if err := run(checkout...); err != nil {
+		return fail(err)
+	}
+	if e.Stash != "" {
+		if err := run("stash", "apply", "--index", e.Stash); err != nil {
+			return fail(fmt.Errorf("applying the saved changes: %w", err))
+		}
+	}
+	m.logEvent(slog.LevelInfo, "restore", r.Path, "%s", reason)
+	r.Result, r.Message = "restored", reason
+	return r
+}
@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/state"
+)
+
+// recordStatus remembers a successful fetch of s in the state store, then
+// fills in s what this and earlier runs recorded about the repo.
+func (m *Manager) recordStatus(s *RepoStatus, fetched bool) {
+	if m.State == nil {
+		return
+	}
+	if fetched && s.Error == "" && s.RemoteError == "" && !s.Mirror {
+		ref := "origin/" + s.Branch
+		sha, err := gitOutput(s.Path, "rev-parse", "--verify", "--quiet", "refs/remotes/"+ref)
+		m.State.Update(s.Path, func(r *state.Repo) {
+			r.LastFetch = time.Now().UTC()
+			if err == nil {
+				r.RemoteRef, r.RemoteSHA = ref, strings.TrimSpace(sha)
+			}
+		})
+	}
+	r, ok := m.State.Repo(s.Path)
+	if !ok {
+		return
+	}
+	if !r.LastFetch.IsZero() {
+		s.LastFetch = &r.LastFetch
+	}
+	if !r.LastSync.IsZero() {
+		s.LastSync = &r.LastSync
+		s.LastSyncCommand, s.LastSyncResult = r.SyncCommand, r.SyncResult
+	}
+}
+
+// recordResults remembers the outcome of a pull, push or sync of each repo.
+func (m *Manager) recordResults(command string, results []RepoResult) {
+	if m.State == nil || m.DryRun {
+		return
+	}
+	now := time.Now().UTC()
+	for _, r := range results {
+		m.State.Update(r.Path, func(s *state.Repo) {
+			s.LastSync, s.SyncCommand, s.SyncResult = now, command, r.Result
+		})
+	}
+	m.saveState()
+}
+
+// startRun begins journaling a clone, pull, push or sync; the returned
+// function records how it ended. Dry runs are not journaled.
+func (m *Manager) startRun(command string, targetNames []string) func(error) {
+	if m.State == nil || m.DryRun {
+		return func(error) {}
+	}
+	started := time.Now()
+	m.emitMu.Lock()
+	m.run = &state.Run{Command: command, Targets: targetNames, Started: started.UTC(), Repos: []state.RunRepo{}}
+	m.emitMu.Unlock()
+	return func(err error) {
+		m.emitMu.Lock()
+		run := *m.run
+		m.run = nil
+		m.emitMu.Unlock()
+		run.DurationMS = time.Since(started).Milliseconds()
+		if err != nil {
+			run.Error = err.Error()
+		}
+		m.State.AddRun(run)
+		m.saveState()
+	}
+}
+
+// journal adds a repo result to the run being journaled, if any.
+func (m *Manager) journal(r RepoResult) {
+	m.emitMu.Lock()
+	defer m.emitMu.Unlock()
+	if m.run != nil {
+		m.run.Repos = append(m.run.Repos, state.RunRepo{Path: r.Path, Target: r.Target, Result: r.Result, Message: r.Message})
+	}
+}
+
+// saveState writes the state store. Failing to is only a warning: it never
+// changes the outcome of the command.
+func (m *Manager) saveState() {
+	if m.State == nil {
+		return
+	}
+	if err := m.State.Save(); err != nil {
+		fmt.Fprintf(m.errOut(), "Warning: saving state: %v\n", err)
+	}
+}
+
+// syncNote tells when s was last pulled, pushed or synced, for status lines;
+// it is empty when no run recorded one.
+func syncNote(s RepoStatus) string {
+	if s.LastSync == nil {
+		return ""
+	}
+	ago := age(time.Now(), *s.LastSync)
+	switch s.LastSyncResult {
+	case "failed", "skipped":
+		return fmt.Sprintf("  (last %s %s %s ago)", s.LastSyncCommand, s.LastSyncResult, ago)
+	default:
+		return fmt.Sprintf("  (last synced %s ago)", ago)
+	}
+}
+
+// RetryFailedClones clones the repos that failed in the last clone run of
+// the journal again, and nothing else. With targetNames, only failed repos
+// of those targets are retried.
+func (m *Manager) RetryFailedClones(targetNames []string, excludeEmpty, includeArchived bool, workers int) error {
+	if m.State == nil {
+		return errors.New("retrying failed clones needs the state store")
+	}
+	runs, err := m.State.Runs()
+	if err != nil {
+		return err
+	}
+	i := len(runs) - 1
+	for i >= 0 && runs[i].Command != "clone" {
+		i--
+	}
+	if i < 0 {
+		return errors.New("no clone run recorded yet")
+	}
+	run := runs[i]
+
+	var selected map[string]bool
+	if len(targetNames) > 0 {
+		targets, err := m.targetsFor(targetNames)
+		if err != nil {
+			return err
+		}
+		selected = make(map[string]bool)
+		for _, t := range targets {
+			selected[t.Name] = true
+		}
+	}
+	paths := make(map[string]bool)
+	var retry []string
+	for _, r := range run.Repos {
+		if r.Result != "failed" || (selected != nil && !selected[r.Target]) || m.config.GetTargetByName(r.Target) == nil {
+			continue
+		}
+		paths[r.Path] = true
+		if !slices.Contains(retry, r.Target) {
+			retry = append(retry, r.Target)
+		}
+	}
+	if len(paths) == 0 {
+		m.logf(slog.LevelInfo, "Clone run %d has no failed repos to retry", run.ID)
+		return nil
+	}
+	m.logf(slog.LevelInfo, "Retrying %d failed clones of run %d", len(paths), run.ID)
+	m.retryPaths = paths
+	defer func() { m.retryPaths = nil }()
+	return m.Clone(retry, excludeEmpty, includeArchived, workers)
+}
+
+// cloneSelected reports whether clone may clone a repo at path: any repo,
+// unless failed clones are being retried.
+func (m *Manager) cloneSelected(path string) bool {
+	return m.retryPaths == nil || m.retryPaths[path]
+}
@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validStatusSort reports whether by is a sort order of Manager.Sort.
+func validStatusSort(by string) bool {
+	switch by {
+	case "", "target", "name", "behind", "ahead", "mtime":
+		return true
+	}
+	return false
+}
+
+// sortStatuses reorders statuses, which come sorted by target and name, by
+// the given order. Ties keep the target and name order.
+func sortStatuses(statuses []RepoStatus, by string) {
+	var less func(a, b RepoStatus) bool
+	switch by {
+	case "name":
+		less = func(a, b RepoStatus) bool { return a.Name < b.Name }
+	case "behind":
+		less = func(a, b RepoStatus) bool { return a.Behind > b.Behind }
+	case "ahead":
+		less = func(a, b RepoStatus) bool { return a.Ahead > b.Ahead }
+	case "mtime":
+		for i, s := range statuses {
+			if s.Error == "" {
+				t := lastModified(s.Path)
+				statuses[i].LastModified = &t
+			}
+		}
+		less = func(a, b RepoStatus) bool {
+			if a.LastModified == nil || b.LastModified == nil {
+				return a.LastModified != nil
+			}
+			return a.LastModified.After(*b.LastModified)
+		}
+	default:
+		return
+	}
+	sort.SliceStable(statuses, func(i, j int) bool { return less(statuses[i], statuses[j]) })
+}
+
+// lastModified returns when the repo at repoPath was last changed locally:
+// the newest entry of the HEAD reflog (commits, checkouts, merges), or the
+// modification time of a changed file in the working tree when that is newer.
+func lastModified(repoPath string) time.Time {
+	var newest time.Time
+	out, err := gitOutput(repoPath, "log", "-g", "-1", "--format=%ct", "HEAD")
+	if err != nil || strings.TrimSpace(out) == "" {
+		out, _ = gitOutput(repoPath, "log", "-1", "--format=%ct", "HEAD")
+	}
+	if sec, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64); err == nil {
+		newest = time.Unix(sec, 0)
+	}
+	changes, _ := dirtyFiles(repoPath)
+	for _, c := range changes {
+		if info, err := os.Lstat(filepath.Join(repoPath, c.Path)); err == nil && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest
+}
@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
+)
+
+// TagEntry is one repo line of `tag list`.
+type TagEntry struct {
+	Path   string   `json:"path"`
+	Target string   `json:"target"`
+	Name   string   `json:"name"`
+	Tags   []string `json:"tags"` // newest version first
+	Error  string   `json:"error,omitempty"`
+}
+
+// TagOptions controls how CreateTag tags each repo.
+type TagOptions struct {
+	Message string // annotation; defaults to the tag name
+	Sign    bool   // GPG/SSH-sign the tag (git tag -s)
+	Push    bool   // push the tag to origin afterwards
+}
+
+// Tags prints the tags of every repo of the named targets, highest version
+// first.
+func (m *Manager) Tags(targetNames []string, workers int) error {
+	statuses, err := m.Statuses(targetNames, workers)
+	if err != nil {
+		return err
+	}
+
+	entries := pool.Run(statuses, workers, func(s RepoStatus) TagEntry {
+		e := TagEntry{Path: s.Path, Target: s.Target, Name: s.Name, Tags: []string{}, Error: s.Error}
+		if s.Error != "" {
+			return e
+		}
+		out, err := gitOutput(s.Path, "tag", "--list", "--sort=-v:refname")
+		if err != nil {
+			e.Error = fmt.Sprintf("listing tags: %v", err)
+			return e
+		}
+		e.Tags = append(e.Tags, strings.Fields(out)...)
+		return e
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	if m.JSON {
+		if entries == nil {
+			entries = []TagEntry{}
+		}
+		return m.writeJSON(entries)
+	}
+	var errored int
+	for _, e := range entries {
+		switch {
+		case e.Error != "":
+			m.printf("  [ERROR]  %s: %s\n", e.Path, e.Error)
+			errored++
+		case len(e.Tags) == 0:
+			m.printf("  %s: (no tags)\n", e.Path)
+		default:
+			m.printf("  %s: %s\n", e.Path, strings.Join(e.Tags, ", "))
+		}
+	}
+	if errored > 0 {
+		return &RepoFailures{Failed: errored, Total: len(entries)}
+	}
+	return nil
+}
+
+// CreateTag creates the annotated tag name at HEAD of every repo of the named
+// targets. Dirty repos and repos that already have the tag are skipped.
+func (m *Manager) CreateTag(name string, opts TagOptions, targetNames []string, workers int) error {
+	if err := gitRun(".", "check-ref-format", "refs/tags/"+name); err != nil {
+		return fmt.Errorf("invalid tag name %q", name)
+	}
+	statuses, err := m.Statuses(targetNames, workers)
+	if err != nil {
+		return err
+	}
+
+	results := make([]RepoResult, 0, len(statuses))
+	for _, s := range statuses {
+		results = append(results, m.TagRepo(s, name, opts))
+	}
+
+	if m.JSON {
+		if err := m.writeJSON(results); err != nil {
+			return err
+		}
+		return resultsOutcome(results)
+	}
+	tagged, skipped, failed := countResults(results)
+	if m.DryRun {
+		m.logf(slog.LevelInfo, "Tag dry run: %d to tag, %d skipped, %d failed", tagged, skipped, failed)
+	} else {
+		m.logf(slog.LevelInfo, "Tag complete: %d tagged, %d skipped, %d failed", tagged, skipped, failed)
+	}
+	return resultsOutcome(results)
+}
+
+// TagRepo tags HEAD of one repo and optionally pushes the tag.
+func (m *Manager) TagRepo(s RepoStatus, name string, opts TagOptions) RepoResult {
+	if s.Error != "" {
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", s.Error)
+		return newResult(s, "failed", s.Error)
+	}
+	if s.Mirror {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "mirror clone")
+		return newResult(s, "skipped", "mirror clone")
+	}
+	if gitRun(s.Path, "rev-parse", "--verify", "--quiet", "refs/tags/"+name) == nil {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "tag %s already exists", name)
+		return newResult(s, "skipped", "tag exists")
+	}
+	if s.Dirty {
+		m.logEvent(slog.LevelInfo, "skip", s.Path, "dirty")
+		return newResult(s, "skipped", "dirty")
+	}
+
+	reason := fmt.Sprintf("%s at %s", name, s.Branch)
+	if opts.Push {
+		reason += ", then push"
+	}
+	if m.DryRun {
+		m.printPlan(s.Path, "would-tag", reason)
+		return newResult(s, "would-tag", reason)
+	}
+
+	message := opts.Message
+	if message == "" {
+		message = name
+	}
+	flag := "-a"
+	if opts.Sign {
+		flag = "-s"
+	}
+	cmd := exec.Command("git", "tag", flag, name, "-m", message)
+	cmd.Dir = s.Path
+	cmd.Env = gitEnvNoPrompt()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		msg := fmt.Sprintf("git tag %s: %v: %s", name, err, strings.TrimSpace(string(out)))
+		m.logEvent(slog.LevelError, "error", s.Path, "%s", msg)
+		return newResult(s, "failed", msg)
+	}
+	if opts.Push {
+		if err := m.gitPush(s.Path, authFor(m.providerFor(s.Target)), "origin", "refs/tags/"+name); err != nil {
+			msg := fmt.Sprintf("tagged locally, push failed: %v", err)
+			m.logEvent(slog.LevelError, "error", s.Path, "%s", msg)
+			return newResult(s, "failed", msg)
+		}
+	}
+	if opts.Push {
+		m.logEvent(slog.LevelInfo, "tag", s.Path, "%s at %s, pushed", name, s.Branch)
+	} else {
+		m.logEvent(slog.LevelInfo, "tag", s.Path, "%s at %s", name, s.Branch)
+	}
+	return newResult(s, "tagged", "")
+}
@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+)
+
+// forkClone is a local clone of a fork, for which clone adds an upstream
+// remote.
+type forkClone struct {
+	path  string
+	owner string
+	repo  remote.Repository
+}
+
+// ensureUpstreams adds an `upstream` remote pointing at the parent repo to
+// every clone of a fork that does not have one yet. Failures are warnings:
+// the clone itself succeeded.
+func (m *Manager) ensureUpstreams(t config.Target, forks []forkClone, workers int) {
+	opts := m.cloneOptionsFor(t)
+	if m.DryRun || opts.Mode == "mirror" {
+		return
+	}
+	var todo []forkClone
+	for _, f := range forks {
+		if isGitRepo(f.path) && !hasRemote(f.path, "upstream") {
+			todo = append(todo, f)
+		}
+	}
+	client := m.providers[t.Provider]
+	auth := authFor(m.config.Providers[t.Provider])
+
+	type upstreamResult struct {
+		fork   forkClone
+		parent string
+		err    error
+	}
+	runJobs(m, todo, workers, func(_ *Manager, f forkClone) upstreamResult {
+		parent := f.repo.Parent
+		if parent == nil && client != nil {
+			// Listings may omit the parent; the single-repo lookup has it.
+			full, err := client.GetRepo(f.owner, f.repo.Name)
+			if err != nil {
+				return upstreamResult{fork: f, err: fmt.Errorf("looking up parent: %w", err)}
+			}
+			if full != nil {
+				parent = full.Parent
+			}
+		}
+		if parent == nil {
+			return upstreamResult{fork: f, err: errors.New("provider did not report the parent repo")}
+		}
+		return upstreamResult{fork: f, parent: parent.FullName, err: addUpstream(f.path, parent, opts.Protocol, auth)}
+	}, func(r upstreamResult) {
+		if r.err != nil {
+			m.logEvent(slog.LevelWarn, "upstream", r.fork.path, "%v", r.err)
+			return
+		}
+		m.logEvent(slog.LevelInfo, "upstream", r.fork.path, "fork of %s", r.parent)
+	})
+}
+
+// addUpstream adds and fetches the upstream remote of a fork clone and
+// points upstream/HEAD at the parent's default branch.
+func addUpstream(repoPath string, parent *remote.Repository, protocol string, auth gitAuth) error {
+	if err := gitRun(repoPath, "remote", "add", "upstream", pickCloneURL(parent, protocol)); err != nil {
+		return fmt.Errorf("adding upstream remote: %w", err)
+	}
+	if err := fetchRemote(repoPath, "upstream", auth); err != nil {
+		return fmt.Errorf("fetching upstream: %w", err)
+	}
+	if parent.DefaultBranch != "" {
+		return gitRun(repoPath, "remote", "set-head", "upstream", parent.DefaultBranch)
+	}
+	return gitRun(repoPath, "remote", "set-head", "upstream", "--auto")
+}
+
+// hasRemote reports whether the repo has a remote called name.
+func hasRemote(repoPath, name string) bool {
+	return gitRun(repoPath, "remote", "get-url", name) == nil
+}
+
+// fetchRemote fetches one remote; the error is the first line git reported.
+func fetchRemote(repoPath, name string, auth gitAuth) error {
+	cmd := exec.Command("git", "fetch", "--quiet", name)
+	cmd.Dir = repoPath
+	cmd.Env = auth.env()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		output, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		if output == "" {
+			return err
+		}
+		return errors.New(output)
+	}
+	return nil
+}
+
+// upstreamBehind fetches the upstream remote of a fork clone (when fetch is
+// set) and counts the commits on the parent's default branch that origin's
+// default branch lacks.
+func upstreamBehind(git gitBackend, repoPath string, auth gitAuth, fetch bool) (int, error) {
+	if fetch {
+		if err := fetchRemote(repoPath, "upstream", auth); err != nil {
+			return 0, err
+		}
+	}
+	if gitRun(repoPath, "rev-parse", "--verify", "--quiet", "refs/remotes/upstream/HEAD") != nil {
+		// Remotes added by hand have no HEAD until asked for it.
+		if err := gitRun(repoPath, "remote", "set-head", "upstream", "--auto"); err != nil {
+			return 0, errors.New("cannot resolve upstream default branch")
+		}
+	}
+	if gitRun(repoPath, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/HEAD") != nil {
+		return 0, nil
+	}
+	_, behind, err := git.AheadBehind(repoPath, "refs/remotes/origin/HEAD", "refs/remotes/upstream/HEAD")
+	return behind, err
+}
@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Watch re-checks the named targets every interval until ctx is done,
+// syncing them first when sync is set. After each round it logs the repos
+// whose state changed since the previous round and a one-line summary.
+func (m *Manager) Watch(ctx context.Context, targetNames []string, interval time.Duration, sync bool, workers int) error {
+	if _, err := m.targetsFor(targetNames); err != nil {
+		return err
+	}
+	m.logf(slog.LevelInfo, "Watching every %s (Ctrl-C to stop)", interval)
+
+	var states map[string]string
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		states = m.watchRound(targetNames, sync, workers, states)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchRound runs one round of Watch and returns the state of every repo,
+// keyed by path, for comparison with the next round.
+func (m *Manager) watchRound(targetNames []string, sync bool, workers int, previous map[string]string) map[string]string {
+	if sync {
+		if err := m.Sync(targetNames, workers); err != nil {
+			m.logf(slog.LevelError, "Sync failed: %v", err)
+		}
+	}
+	statuses, err := m.Statuses(targetNames, workers)
+	if err != nil {
+		m.logf(slog.LevelError, "Status failed: %v", err)
+		return previous
+	}
+
+	states := make(map[string]string, len(statuses))
+	var clean, dirty, ahead, behind, errored int
+	for _, s := range statuses {
+		state := describeState(s)
+		states[s.Path] = state
+		switch {
+		case s.Error != "" || s.RemoteError != "":
+			errored++
+		case state == "clean":
+			clean++
+		}
+		if s.Dirty {
+			dirty++
+		}
+		if s.Ahead > 0 {
+			ahead++
+		}
+		if s.Behind > 0 {
+			behind++
+		}
+		if previous == nil {
+			continue
+		}
+		if old, ok := previous[s.Path]; !ok {
+			m.logEvent(slog.LevelInfo, "new", s.Path, "%s", state)
+		} else if old != state {
+			level := slog.LevelInfo
+			if s.Error != "" || s.RemoteError != "" {
+				level = slog.LevelWarn
+			}
+			m.logEvent(level, "change", s.Path, "%s -> %s", old, state)
+		}
+	}
+	for path := range previous {
+		if _, ok := states[path]; !ok {
+			m.logEvent(slog.LevelWarn, "gone", path, "")
+		}
+	}
+
+	m.logf(slog.LevelInfo, "[%s] %d repos: %d clean, %d dirty, %d ahead, %d behind, %d errors",
+		time.Now().Format("15:04:05"), len(statuses), clean, dirty, ahead, behind, errored)
+	return states
+}
+
+// describeState condenses a status into the flags watch compares between
+// rounds.
+func describeState(s RepoStatus) string {
+	if s.Error != "" {
+		return "error"
+	}
+	var flags []string
+	if s.Dirty {
+		flags = append(flags, "dirty")
+	}
+	if s.Ahead > 0 {
+		flags = append(flags, fmt.Sprintf("%d ahead", s.Ahead))
+	}
+	if s.Behind > 0 {
+		flags = append(flags, fmt.Sprintf("%d behind", s.Behind))
+	}
+	if s.RemoteError != "" {
+		flags = append(flags, "fetch failed")
+	}
+	if s.Orphan {
+		flags = append(flags, "orphan")
+	}
+	if len(flags) == 0 {
+		return "clean"
+	}
+	return strings.Join(flags, ", ")
+}
@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+)
+
+// Workspace is what `tugboat export` writes and `tugboat apply` reads: the
+// config without its secrets and the checked-out commit of every repo, so a
+// workspace can be reproduced on another machine.
+type Workspace struct {
+	Version  int            `json:"version"`
+	Exported time.Time      `json:"exported"`
+	Config   *config.Config `json:"config"`
+	Repos    []LockEntry    `json:"repos"`
+}
+
+// Export writes the workspace of all targets to the manager's output as
+// JSON. Repos are recorded like lock records them; targets not cloned yet
+// have none. Warnings about repos that cannot be reproduced go to stderr so
+// the output can be redirected to a file.
+func (m *Manager) Export(workers int) error {
+	var existing []config.Target
+	for _, t := range m.config.Targets {
+		if _, err := os.Stat(t.Path); err == nil {
+			existing = append(existing, t)
+		}
+	}
+	repos, err := m.lockEntries(existing, workers)
+	if err != nil {
+		return err
+	}
+	for _, e := range repos {
+		repoPath, warnings := m.unreproducible(e)
+		for _, w := range warnings {
+			fmt.Fprintf(m.errOut(), "Warning: %s: %s\n", repoPath, w)
+		}
+	}
+	if repos == nil {
+		repos = []LockEntry{}
+	}
+	ws := Workspace{Version: 1, Exported: time.Now().UTC(), Config: m.config.Portable(), Repos: repos}
+	return m.writeJSON(ws)
+}
+
+// ParseWorkspace parses an exported workspace.
+func ParseWorkspace(data []byte) (*Workspace, error) {
+	var ws Workspace
+	if err := json.Unmarshal(data, &ws); err != nil {
+		return nil, fmt.Errorf("parsing workspace: %w", err)
+	}
+	if ws.Version != 1 {
+		return nil, fmt.Errorf("unsupported workspace version %d", ws.Version)
+	}
+	if ws.Config == nil {
+		return nil, fmt.Errorf("workspace has no config")
+	}
+	return &ws, nil
+}
+
+// Apply reproduces ws with the manager's config, which must already have
+// ws's targets: it clones their repos and then checks out the exported
+// commits like restore does. Clone failures do not stop the checkouts.
+func (m *Manager) Apply(ws *Workspace, workers int) error {
+	var targetNames []string
+	for _, t := range ws.Config.Targets {
+		if m.config.GetTargetByName(t.Name) == nil {
+			return fmt.Errorf("target %q is not in the config", t.Name)
+		}
+		targetNames = append(targetNames, t.Name)
+	}
+	if len(targetNames) == 0 {
+		return fmt.Errorf("workspace has no targets")
+	}
+	cloneErr := m.Clone(targetNames, false, false, workers)
+	var rf *RepoFailures
+	if cloneErr != nil && !errors.As(cloneErr, &rf) {
+		return cloneErr
+	}
+	if m.DryRun {
+		return cloneErr
+	}
+	restoreErr := m.restoreLock(&LockFile{Version: 1, Repos: ws.Repos}, targetNames, workers)
+	return errors.Join(cloneErr, restoreErr)
+}
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AddWorktree creates a linked worktree of the local repo repoName (its name,
+// owner/name or path) with branch checked out, and prints its path. The
+// worktree goes to <worktree_dir>/<repo>/<branch>, or to <repo>.worktrees/
+// <branch> next to the repo when worktree_dir is not configured; slashes in
+// the branch become dashes. Branches that exist locally or on origin are
+// checked out; others are created from origin's default branch.
+func (m *Manager) AddWorktree(repoName, branch string) error {
+	targets, err := m.targetsFor(nil)
+	if err != nil {
+		return err
+	}
+	jobs, _, err := m.localRepos(targets)
+	if err != nil {
+		return err
+	}
+	abs, _ := filepath.Abs(repoName)
+	var matches []statusJob
+	for _, job := range jobs {
+		if job.name == repoName || job.org+"/"+job.name == repoName || job.path == abs {
+			matches = append(matches, job)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return fmt.Errorf("no local repo %q", repoName)
+	case 1:
+	default:
+		var paths []string
+		for _, job := range matches {
+			paths = append(paths, job.path)
+		}
+		sort.Strings(paths)
+		return fmt.Errorf("%q matches several repos, use owner/name or the path: %s", repoName, strings.Join(paths, ", "))
+	}
+	job := matches[0]
+	if isBareRepo(job.path) {
+		return fmt.Errorf("%s is a bare repository", job.path)
+	}
+
+	dir := m.config.WorktreeDir
+	if dir == "" {
+		dir = job.path + ".worktrees"
+	} else {
+		dir = filepath.Join(dir, job.name)
+	}
+	dest := filepath.Join(dir, strings.ReplaceAll(branch, "/", "-"))
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("%s already exists", dest)
+	}
+
+	args := []string{"worktree", "add", "--quiet"}
+	switch {
+	case localBranchExists(job.path, branch):
+		args = append(args, dest, branch)
+	case remoteTrackingRefExists(job.path, branch):
+		args = append(args, "--track", "-b", branch, dest, "origin/"+branch)
+	default:
+		base, err := defaultBranchFromOriginHead(job.path)
+		if err != nil {
+			return err
+		}
+		args = append(args, "--no-track", "-b", branch, dest, "origin/"+base)
+	}
+	if m.DryRun {
+		m.printPlan(dest, "would-add", fmt.Sprintf("worktree of %s on %s", job.path, branch))
+		return nil
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = job.path
+	cmd.Env = gitEnvNoPrompt()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git worktree add: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	m.logEvent(slog.LevelDebug, "worktree", dest, "%s of %s", branch, job.path)
+	m.printf("%s\n", dest)
+	return nil
+}
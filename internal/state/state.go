@@ -0,0 +1,206 @@
+// Package state keeps what tugboat learned about each repo in earlier runs,
+// such as when it was last fetched and synced, and a journal of those runs,
+// in a JSON file under the user's state directory.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Repo is the remembered state of one local repo.
+type Repo struct {
+	LastFetch   time.Time `json:"last_fetch,omitempty"`
+	RemoteRef   string    `json:"remote_ref,omitempty"` // e.g. origin/main, the upstream of the checked-out branch
+	RemoteSHA   string    `json:"remote_sha,omitempty"` // RemoteRef's commit at LastFetch
+	LastSync    time.Time `json:"last_sync,omitempty"`  // last pull, push or sync of the repo
+	SyncCommand string    `json:"sync_command,omitempty"`
+	SyncResult  string    `json:"sync_result,omitempty"` // the RepoResult result, e.g. pulled or failed
+}
+
+// MaxRuns is how many runs the journal keeps; older ones are dropped.
+const MaxRuns = 200
+
+// Run is one clone, pull, push or sync recorded in the journal.
+type Run struct {
+	ID         int       `json:"id"`
+	Command    string    `json:"command"`
+	Targets    []string  `json:"targets,omitempty"` // as given; empty for all targets
+	Started    time.Time `json:"started"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"` // why the command as a whole failed
+	Repos      []RunRepo `json:"repos"`
+}
+
+// RunRepo is the outcome of a run for one repo.
+type RunRepo struct {
+	Path    string `json:"path"`
+	Target  string `json:"target"`
+	Result  string `json:"result"`
+	Message string `json:"message,omitempty"`
+}
+
+// Duration returns how long the run took.
+func (r Run) Duration() time.Duration {
+	return time.Duration(r.DurationMS) * time.Millisecond
+}
+
+// Counts returns how many repos of the run ended with each result.
+func (r Run) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, repo := range r.Repos {
+		counts[repo.Result]++
+	}
+	return counts
+}
+
+// file is the on-disk form of a Store.
+type file struct {
+	Repos map[string]Repo `json:"repos"` // by repo path
+	Runs  []Run           `json:"runs,omitempty"`
+}
+
+// Store is the state file at Path. Changes are kept in memory until Save,
+// which merges them into the file as it is then, so concurrent runs only
+// lose each other's updates to the same repo.
+type Store struct {
+	Path string
+
+	mu      sync.Mutex
+	repos   map[string]Repo
+	changed map[string]bool
+	runs    []Run // added since the last Save
+}
+
+// DefaultPath returns the per-user state file:
+// $XDG_STATE_HOME/tugboat/state.json, else ~/.local/state/tugboat/state.json.
+func DefaultPath() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "tugboat", "state.json"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "tugboat", "state.json"), nil
+}
+
+// Open reads the state file at path; a missing file is an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{Path: path, changed: make(map[string]bool)}
+	f, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	s.repos = f.Repos
+	return s, nil
+}
+
+func read(path string) (file, error) {
+	f := file{Repos: make(map[string]Repo)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	} else if err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if f.Repos == nil {
+		f.Repos = make(map[string]Repo)
+	}
+	return f, nil
+}
+
+// Repo returns the remembered state of the repo at path.
+func (s *Store) Repo(path string) (Repo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.repos[path]
+	return r, ok
+}
+
+// Update changes the state of the repo at path with fn. It is safe for
+// concurrent use.
+func (s *Store) Update(path string, fn func(*Repo)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[path]
+	fn(&r)
+	s.repos[path] = r
+	s.changed[path] = true
+}
+
+// AddRun adds run to the journal. Save numbers it after the runs already
+// in the file.
+func (s *Store) AddRun(run Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs = append(s.runs, run)
+}
+
+// Runs returns the journal as the state file holds it, oldest first.
+func (s *Store) Runs() ([]Run, error) {
+	f, err := read(s.Path)
+	return f.Runs, err
+}
+
+// Save writes the updated repos and added runs into the state file, keeping
+// the entries other runs wrote since Open.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.changed) == 0 && len(s.runs) == 0 {
+		return nil
+	}
+	f, err := read(s.Path)
+	if err != nil {
+		return err
+	}
+	for path := range s.changed {
+		f.Repos[path] = s.repos[path]
+	}
+	for _, run := range s.runs {
+		run.ID = 1
+		if n := len(f.Runs); n > 0 {
+			run.ID = f.Runs[n-1].ID + 1
+		}
+		f.Runs = append(f.Runs, run)
+	}
+	if len(f.Runs) > MaxRuns {
+		f.Runs = f.Runs[len(f.Runs)-MaxRuns:]
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), ".state-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.Path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	s.repos = f.Repos
+	s.changed = make(map[string]bool)
+	s.runs = nil
+	return nil
+}
@@ -0,0 +1,66 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveMergesConcurrentRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tugboat", "state.json")
+	first, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	second, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	synced := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	first.Update("/src/a", func(r *Repo) { r.LastSync, r.SyncResult = synced, "pulled" })
+	second.Update("/src/b", func(r *Repo) { r.RemoteSHA = "abc123" })
+	if err := first.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := second.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	a, ok := reopened.Repo("/src/a")
+	if !ok || !a.LastSync.Equal(synced) || a.SyncResult != "pulled" {
+		t.Fatalf("repo a = %+v, %v; want the first run's sync", a, ok)
+	}
+	if b, _ := reopened.Repo("/src/b"); b.RemoteSHA != "abc123" {
+		t.Fatalf("repo b = %+v, want the second run's remote sha", b)
+	}
+}
+
+func TestSaveNumbersAndTrimsRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	for i := 0; i < MaxRuns+2; i++ {
+		s, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		s.AddRun(Run{Command: "pull", Repos: []RunRepo{{Path: "/src/a", Result: "pulled"}}})
+		if err := s.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	s, _ := Open(path)
+	runs, err := s.Runs()
+	if err != nil {
+		t.Fatalf("Runs() error = %v", err)
+	}
+	if len(runs) != MaxRuns || runs[0].ID != 3 || runs[len(runs)-1].ID != MaxRuns+2 {
+		t.Fatalf("got %d runs, IDs %d..%d; want the last %d numbered on", len(runs), runs[0].ID, runs[len(runs)-1].ID, MaxRuns)
+	}
+	if counts := runs[0].Counts(); counts["pulled"] != 1 {
+		t.Fatalf("Counts() = %v", counts)
+	}
+}
@@ -0,0 +1,460 @@
+// Package tui implements the interactive `tugboat ui` dashboard.
+//
+// The dashboard talks to the terminal with plain ANSI escape sequences and
+// switches the tty into non-canonical mode with stty, so it needs no
+// dependencies beyond the standard library.
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/pool"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+const (
+	escAltScreenOn  = "\x1b[?1049h"
+	escAltScreenOff = "\x1b[?1049l"
+	escHideCursor   = "\x1b[?25l"
+	escShowCursor   = "\x1b[?25h"
+	escHome         = "\x1b[H"
+	escClearLine    = "\x1b[K"
+	escClearBelow   = "\x1b[J"
+	escReverse      = "\x1b[7m"
+	escReset        = "\x1b[0m"
+)
+
+// key is a decoded keypress.
+type key string
+
+const (
+	keyUp       key = "up"
+	keyDown     key = "down"
+	keyPageUp   key = "pgup"
+	keyPageDown key = "pgdn"
+)
+
+// Dashboard is a full-screen table of repo statuses with keybindings to pull,
+// push and sync the selected repos.
+type Dashboard struct {
+	manager *repo.Manager
+	targets []string
+	workers int
+	refresh time.Duration
+
+	statuses []repo.RepoStatus
+	selected map[string]bool // keyed by repo path
+	cursor   int
+	offset   int
+	message  string
+	out      io.Writer
+}
+
+// New creates a dashboard for the named targets (all targets when empty).
+// refresh is the interval between automatic status reloads; 0 disables them.
+func New(manager *repo.Manager, targetNames []string, workers int, refresh time.Duration) *Dashboard {
+	// Command output would corrupt the screen; results are summarized in the
+	// dashboard's message line instead.
+	manager.Out = io.Discard
+	manager.JSON = false
+	return &Dashboard{
+		manager:  manager,
+		targets:  targetNames,
+		workers:  workers,
+		refresh:  refresh,
+		selected: make(map[string]bool),
+		out:      os.Stdout,
+	}
+}
+
+// Run takes over the terminal until the user quits.
+func (d *Dashboard) Run() error {
+	saved, err := stty("-g")
+	if err != nil {
+		return fmt.Errorf("ui requires an interactive terminal: %w", err)
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return fmt.Errorf("configuring terminal: %w", err)
+	}
+	fmt.Fprint(d.out, escAltScreenOn+escHideCursor)
+	defer func() {
+		fmt.Fprint(d.out, escShowCursor+escAltScreenOff)
+		stty(strings.TrimSpace(saved))
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	keys := make(chan key)
+	go readKeys(os.Stdin, keys)
+
+	d.message = "Loading..."
+	d.render()
+	d.reload()
+	d.render()
+
+	var tick <-chan time.Time
+	if d.refresh > 0 {
+		ticker := time.NewTicker(d.refresh)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-signals:
+			return nil
+		case <-tick:
+			d.reload()
+		case k, ok := <-keys:
+			if !ok || k == "q" {
+				return nil
+			}
+			d.handleKey(k)
+		}
+		d.render()
+	}
+}
+
+func (d *Dashboard) handleKey(k key) {
+	switch k {
+	case keyUp, "k":
+		d.move(-1)
+	case keyDown, "j":
+		d.move(1)
+	case keyPageUp:
+		d.move(-d.pageSize())
+	case keyPageDown:
+		d.move(d.pageSize())
+	case "g":
+		d.move(-len(d.statuses))
+	case "G":
+		d.move(len(d.statuses))
+	case " ":
+		if s, ok := d.current(); ok {
+			d.selected[s.Path] = !d.selected[s.Path]
+			if !d.selected[s.Path] {
+				delete(d.selected, s.Path)
+			}
+			d.move(1)
+		}
+	case "a":
+		if len(d.selected) == len(d.statuses) {
+			d.selected = make(map[string]bool)
+		} else {
+			for _, s := range d.statuses {
+				d.selected[s.Path] = true
+			}
+		}
+	case "r":
+		d.message = "Refreshing..."
+		d.render()
+		d.reload()
+	case "p":
+		d.run("pull", d.manager.PullRepo)
+	case "P":
+		d.run("push", d.manager.PushRepo)
+	case "s":
+		d.run("sync", d.manager.SyncRepo)
+	}
+}
+
+func (d *Dashboard) move(delta int) {
+	d.cursor += delta
+	if d.cursor >= len(d.statuses) {
+		d.cursor = len(d.statuses) - 1
+	}
+	if d.cursor < 0 {
+		d.cursor = 0
+	}
+}
+
+func (d *Dashboard) current() (repo.RepoStatus, bool) {
+	if d.cursor < 0 || d.cursor >= len(d.statuses) {
+		return repo.RepoStatus{}, false
+	}
+	return d.statuses[d.cursor], true
+}
+
+// reload fetches fresh statuses for all repos.
+func (d *Dashboard) reload() {
+	statuses, err := d.manager.Statuses(d.targets, d.workers)
+	if err != nil {
+		d.message = "Error: " + err.Error()
+		return
+	}
+	d.statuses = statuses
+	d.move(0)
+	d.message = fmt.Sprintf("Loaded %d repos at %s", len(statuses), time.Now().Format("15:04:05"))
+}
+
+// run applies an operation to the selected repos (or the repo under the
+// cursor when nothing is selected) and refreshes their rows.
+func (d *Dashboard) run(name string, op func(repo.RepoStatus) repo.RepoResult) {
+	var targets []repo.RepoStatus
+	for _, s := range d.statuses {
+		if d.selected[s.Path] {
+			targets = append(targets, s)
+		}
+	}
+	if len(targets) == 0 {
+		s, ok := d.current()
+		if !ok {
+			return
+		}
+		targets = []repo.RepoStatus{s}
+	}
+
+	d.message = fmt.Sprintf("Running %s on %d repos...", name, len(targets))
+	d.render()
+
+	results := pool.Run(targets, d.workers, op)
+	refreshed := pool.Run(targets, d.workers, d.manager.RefreshStatus)
+	byPath := make(map[string]repo.RepoStatus, len(refreshed))
+	for _, s := range refreshed {
+		byPath[s.Path] = s
+	}
+	for i, s := range d.statuses {
+		if r, ok := byPath[s.Path]; ok {
+			d.statuses[i] = r
+		}
+	}
+
+	var changed, skipped, failed int
+	var firstProblem string
+	for _, r := range results {
+		switch r.Result {
+		case "unchanged":
+		case "skipped":
+			skipped++
+		case "failed":
+			failed++
+		default:
+			changed++
+		}
+		if (r.Result == "failed" || r.Result == "skipped") && firstProblem == "" {
+			firstProblem = fmt.Sprintf(" (%s: %s)", r.Name, r.Message)
+		}
+	}
+	d.message = fmt.Sprintf("%s: %d done, %d skipped, %d failed%s", name, changed, skipped, failed, firstProblem)
+	d.selected = make(map[string]bool)
+}
+
+// pageSize is the number of table rows that fit on screen below the title and
+// header and above the message and help lines.
+func (d *Dashboard) pageSize() int {
+	rows, _ := terminalSize()
+	return pageSizeFor(rows)
+}
+
+func pageSizeFor(rows int) int {
+	if n := rows - 4; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// render redraws the whole screen.
+func (d *Dashboard) render() {
+	rows, cols := terminalSize()
+	page := pageSizeFor(rows)
+	if d.cursor < d.offset {
+		d.offset = d.cursor
+	}
+	if d.cursor >= d.offset+page {
+		d.offset = d.cursor - page + 1
+	}
+
+	widths := []int{1, 6, 4, 6, 5, 5, 6}
+	headers := []string{" ", "TARGET", "REPO", "BRANCH", "AHEAD", "BEHIND", "STATE"}
+	table := make([][]string, len(d.statuses))
+	for i, s := range d.statuses {
+		mark := " "
+		if d.selected[s.Path] {
+			mark = "*"
+		}
+		table[i] = []string{mark, s.Target, s.Name, s.Branch, strconv.Itoa(s.Ahead), strconv.Itoa(s.Behind), stateOf(s)}
+		for c, v := range table[i] {
+			if len(v) > widths[c] {
+				widths[c] = len(v)
+			}
+		}
+	}
+	for c := 1; c <= 3; c++ {
+		if widths[c] > 32 {
+			widths[c] = 32
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(escHome)
+	line := func(text string, highlight bool) {
+		text = truncate(text, cols)
+		if highlight {
+			b.WriteString(escReverse + text + escClearLine + escReset + "\r\n")
+		} else {
+			b.WriteString(text + escClearLine + "\r\n")
+		}
+	}
+
+	line(fmt.Sprintf("tugboat ui - %d repos, %d selected", len(d.statuses), len(d.selected)), false)
+	line(formatRow(headers, widths), false)
+	for i := d.offset; i < len(table) && i < d.offset+page; i++ {
+		line(formatRow(table[i], widths), i == d.cursor)
+	}
+	for i := len(table) - d.offset; i < page; i++ {
+		line("", false)
+	}
+	line(d.message, false)
+	b.WriteString(truncate("j/k move  space select  a all  p pull  P push  s sync  r refresh  q quit", cols))
+	b.WriteString(escClearLine + escClearBelow)
+	fmt.Fprint(d.out, b.String())
+}
+
+// stateOf summarizes a status the same way `tugboat status` flags it.
+func stateOf(s repo.RepoStatus) string {
+	if s.Error != "" {
+		return "error: " + s.Error
+	}
+	var flags []string
+	if s.Dirty {
+		flags = append(flags, "dirty")
+	}
+	if s.Behind > 0 && !s.CanFastForward {
+		flags = append(flags, "diverged")
+	}
+	if s.RemoteError != "" {
+		flags = append(flags, "remote error")
+	}
+	if len(s.SubmoduleDrift) > 0 {
+		flags = append(flags, "submodules")
+	}
+	if s.Stashes > 0 {
+		flags = append(flags, "stashed")
+	}
+	if s.DefaultDrift != "" {
+		flags = append(flags, "default moved")
+	}
+	if s.Archived {
+		flags = append(flags, "archived")
+	}
+	if s.Orphan {
+		flags = append(flags, "orphan")
+	}
+	if s.Mirror {
+		flags = append(flags, "mirror")
+	}
+	if len(flags) == 0 {
+		return "clean"
+	}
+	return strings.Join(flags, ", ")
+}
+
+func formatRow(cells []string, widths []int) string {
+	parts := make([]string, len(cells))
+	for i, c := range cells {
+		if i == len(cells)-1 {
+			parts[i] = c
+			continue
+		}
+		parts[i] = fmt.Sprintf("%-*s", widths[i], truncate(c, widths[i]))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 || len(r) <= width {
+		return s
+	}
+	if width == 1 {
+		return string(r[:1])
+	}
+	return string(r[:width-1]) + "~"
+}
+
+// readKeys decodes keypresses from r until it fails, then closes keys.
+func readKeys(r io.Reader, keys chan<- key) {
+	defer close(keys)
+	buf := make([]byte, 16)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// decodeKeys splits raw terminal input into keys, recognizing the arrow and
+// page escape sequences.
+func decodeKeys(input []byte) []key {
+	sequences := map[string]key{
+		"\x1b[A":  keyUp,
+		"\x1b[B":  keyDown,
+		"\x1bOA":  keyUp,
+		"\x1bOB":  keyDown,
+		"\x1b[5~": keyPageUp,
+		"\x1b[6~": keyPageDown,
+	}
+	var keys []key
+	s := string(input)
+	for len(s) > 0 {
+		matched := false
+		for seq, k := range sequences {
+			if strings.HasPrefix(s, seq) {
+				keys = append(keys, k)
+				s = s[len(seq):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if s[0] == 0x1b {
+			// Unknown escape sequence: drop the rest of this read.
+			break
+		}
+		keys = append(keys, key(s[:1]))
+		s = s[1:]
+	}
+	return keys
+}
+
+// terminalSize returns the tty size, falling back to 24x80.
+func terminalSize() (rows, cols int) {
+	out, err := stty("size")
+	if err == nil {
+		if fields := strings.Fields(out); len(fields) == 2 {
+			rows, _ = strconv.Atoi(fields[0])
+			cols, _ = strconv.Atoi(fields[1])
+		}
+	}
+	if rows <= 0 {
+		rows = 24
+	}
+	if cols <= 0 {
+		cols = 80
+	}
+	return rows, cols
+}
+
+// stty runs stty against the controlling terminal on stdin.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,50 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	got := decodeKeys([]byte("j\x1b[Ak \x1b[6~q"))
+	want := []key{"j", keyUp, "k", " ", keyPageDown, "q"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestDecodeKeysDropsUnknownEscape(t *testing.T) {
+	got := decodeKeys([]byte("p\x1b[1;5Cq"))
+	want := []key{"p"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestStateOf(t *testing.T) {
+	tests := []struct {
+		status repo.RepoStatus
+		want   string
+	}{
+		{repo.RepoStatus{CanFastForward: true}, "clean"},
+		{repo.RepoStatus{Dirty: true, Behind: 2}, "dirty, diverged"},
+		{repo.RepoStatus{Archived: true, Orphan: true}, "archived, orphan"},
+		{repo.RepoStatus{Error: "not a git repo", Dirty: true}, "error: not a git repo"},
+	}
+	for _, tt := range tests {
+		if got := stateOf(tt.status); got != tt.want {
+			t.Errorf("stateOf(%+v) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("feature/long-branch", 8); got != "feature~" {
+		t.Errorf("truncate() = %q, want %q", got, "feature~")
+	}
+	if got := truncate("main", 8); got != "main" {
+		t.Errorf("truncate() = %q, want %q", got, "main")
+	}
+}
@@ -0,0 +1,138 @@
+// Package webhook receives push webhooks from Gitea and GitHub.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxBody bounds the payload size; push payloads with many commits are
+// rarely above a few hundred kilobytes.
+const maxBody = 5 << 20
+
+// Push describes a verified push event.
+type Push struct {
+	Provider      string // config provider name, from the URL path
+	FullName      string // owner/repo
+	Ref           string // e.g. refs/heads/main
+	DefaultBranch string
+}
+
+// Source is a provider that may deliver webhooks.
+type Source struct {
+	Type   string // gitea | github
+	Secret string
+}
+
+// Handler serves POST /hooks/{provider}. Requests must be signed with the
+// provider's secret; verified pushes are passed to OnPush, which runs after
+// the response has been written so slow pulls do not time out the sender.
+type Handler struct {
+	Sources map[string]Source
+	OnPush  func(Push)
+}
+
+type payload struct {
+	Ref        string `json:"ref"`
+	Repository struct {
+		FullName      string `json:"full_name"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/hooks/")
+	src, ok := h.Sources[name]
+	if !ok || name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody))
+	if err != nil {
+		http.Error(w, "reading body", http.StatusBadRequest)
+		return
+	}
+	if err := verify(src, r.Header, body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch event := eventType(src, r.Header); event {
+	case "push":
+	case "ping":
+		fmt.Fprintln(w, "pong")
+		return
+	default:
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "ignored %q event\n", event)
+		return
+	}
+
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil || p.Repository.FullName == "" {
+		http.Error(w, "invalid push payload", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "queued %s\n", p.Repository.FullName)
+	if h.OnPush != nil {
+		go h.OnPush(Push{
+			Provider:      name,
+			FullName:      p.Repository.FullName,
+			Ref:           p.Ref,
+			DefaultBranch: p.Repository.DefaultBranch,
+		})
+	}
+}
+
+// verify checks the HMAC-SHA256 signature of body. GitHub sends it as
+// X-Hub-Signature-256 ("sha256=<hex>"), Gitea as X-Gitea-Signature ("<hex>").
+func verify(src Source, header http.Header, body []byte) error {
+	if src.Secret == "" {
+		return fmt.Errorf("no webhook secret configured")
+	}
+	var got string
+	switch src.Type {
+	case "github":
+		got = strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+	case "gitea":
+		got = header.Get("X-Gitea-Signature")
+	default:
+		return fmt.Errorf("webhooks are not supported for %s providers", src.Type)
+	}
+	if got == "" {
+		return fmt.Errorf("missing signature")
+	}
+	mac := hmac.New(sha256.New, []byte(src.Secret))
+	mac.Write(body)
+	want := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(got), []byte(want)) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func eventType(src Source, header http.Header) string {
+	if src.Type == "gitea" {
+		return header.Get("X-Gitea-Event")
+	}
+	return header.Get("X-GitHub-Event")
+}
+
+// Branch returns the branch name of a push ref, or "" for tags and other refs.
+func (p Push) Branch() string {
+	if !strings.HasPrefix(p.Ref, "refs/heads/") {
+		return ""
+	}
+	return strings.TrimPrefix(p.Ref, "refs/heads/")
+}
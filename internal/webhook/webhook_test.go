@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHandlerVerifiesSignatureAndPassesPush(t *testing.T) {
+	pushes := make(chan Push, 1)
+	h := &Handler{
+		Sources: map[string]Source{
+			"github": {Type: "github", Secret: "s3cret"},
+			"gitea":  {Type: "gitea", Secret: "other"},
+		},
+		OnPush: func(p Push) { pushes <- p },
+	}
+	body := `{"ref": "refs/heads/main", "repository": {"full_name": "acme/api", "default_branch": "main"}}`
+
+	req := httptest.NewRequest(http.MethodPost, "/hooks/github", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+sign("wrong", body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad signature: status = %d, want 401", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/hooks/github", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+sign("s3cret", body))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+	p := <-pushes
+	if p.Provider != "github" || p.FullName != "acme/api" || p.Branch() != "main" || p.DefaultBranch != "main" {
+		t.Errorf("push = %+v", p)
+	}
+
+	// Gitea signs without a prefix in its own header.
+	req = httptest.NewRequest(http.MethodPost, "/hooks/gitea", strings.NewReader(body))
+	req.Header.Set("X-Gitea-Event", "push")
+	req.Header.Set("X-Gitea-Signature", sign("other", body))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("gitea status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+	<-pushes
+
+	req = httptest.NewRequest(http.MethodPost, "/hooks/gitlab", strings.NewReader(body))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown provider: status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,176 @@
+// Package tugboat is the Go API for embedding tugboat in other programs:
+// load a config, then clone, check and update its targets. It is the
+// supported surface; the packages under internal/ may change at any time.
+//
+//	cfg, err := tugboat.LoadConfigFile("/etc/portal/tugboat.json")
+//	if err != nil {
+//		return err
+//	}
+//	r, err := tugboat.New(cfg, tugboat.Options{Out: logWriter})
+//	if err != nil {
+//		return err
+//	}
+//	results, err := r.Sync(nil)
+package tugboat
+
+import (
+	"io"
+	"log/slog"
+
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/config"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/remote"
+	"gitea.swiftstrike.ai/swiftstrike/tugboat/internal/repo"
+)
+
+type (
+	// Config is a loaded tugboat configuration.
+	Config = config.Config
+	// Target is one configured org, user or repo target.
+	Target = config.Target
+	// Provider is one configured hosting service.
+	Provider = config.Provider
+	// RepoStatus is the state of one local repo, as `tugboat status --json`
+	// reports it.
+	RepoStatus = repo.RepoStatus
+	// RepoResult is the outcome of a clone, pull, push or sync for one repo.
+	RepoResult = repo.RepoResult
+	// RepoFailures is the error of a command that failed for some repos
+	// while the others went through; use errors.As to tell it apart.
+	RepoFailures = repo.RepoFailures
+)
+
+// LoadConfig reads the config from where the tugboat command finds it:
+// $TUGBOAT_CONFIG, the XDG config directory, or ~/.tugboat.json.
+func LoadConfig() (*Config, error) {
+	result, err := config.LoadWithMetadata()
+	if err != nil {
+		return nil, err
+	}
+	return result.Config, nil
+}
+
+// LoadConfigFile reads the config from the file at path.
+func LoadConfigFile(path string) (*Config, error) {
+	return config.LoadFile(path)
+}
+
+// ParseConfig parses config data. Relative includes resolve against the
+// working directory.
+func ParseConfig(data []byte) (*Config, error) {
+	return config.LoadFromBytes(data)
+}
+
+// Options tune a Runner.
+type Options struct {
+	// Workers is how many repos are handled at once; 0 uses the config's
+	// workers, else GOMAXPROCS.
+	Workers int
+	// DryRun reports what clone, pull, push and sync would do instead of
+	// doing it; the results then read would-clone, would-pull and so on.
+	DryRun bool
+	// Offline neither fetches nor asks the provider APIs; statuses are read
+	// against the remote refs of the last fetch.
+	Offline bool
+	// Out receives the human-readable progress tugboat prints, and Err its
+	// warnings; nil discards them.
+	Out io.Writer
+	Err io.Writer
+	// Logger, when set, receives progress as structured records instead of
+	// Out.
+	Logger *slog.Logger
+}
+
+// CloneOptions are the choices of Runner.Clone.
+type CloneOptions struct {
+	ExcludeEmpty    bool // skip repos without commits
+	IncludeArchived bool // clone archived repos too
+	Mirror          bool // bare --mirror clones
+	// IncludeTemplates clones template repos of org and user targets too,
+	// which are left out by default.
+	IncludeTemplates bool
+}
+
+// Runner runs tugboat commands against one config. Commands may run
+// concurrently; each works on its own state.
+type Runner struct {
+	cfg     *Config
+	opts    Options
+	clients map[string]remote.Client
+}
+
+// New builds the provider clients of cfg, running token commands, and
+// returns a Runner for it.
+func New(cfg *Config, opts Options) (*Runner, error) {
+	clients, err := cfg.BuildRemoteClients()
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{cfg: cfg, opts: opts, clients: clients}, nil
+}
+
+// manager returns a manager for one command, collecting the repo results
+// it emits into results when that is not nil.
+func (r *Runner) manager(results *[]RepoResult) *repo.Manager {
+	m := repo.NewManager(r.clients, r.cfg)
+	m.Out, m.Err = r.opts.Out, r.opts.Err
+	if m.Out == nil {
+		m.Out = io.Discard
+	}
+	if m.Err == nil {
+		m.Err = io.Discard
+	}
+	m.Logger = r.opts.Logger
+	m.DryRun = r.opts.DryRun
+	m.Offline = r.opts.Offline
+	if results != nil {
+		m.OnResult = func(res RepoResult) { *results = append(*results, res) }
+	}
+	return m
+}
+
+func (r *Runner) workers() int {
+	if r.opts.Workers > 0 {
+		return r.opts.Workers
+	}
+	return r.cfg.Workers
+}
+
+// Status returns the status of the local repos of the named targets, or of
+// all targets when targets is empty. Target names may be groups and
+// patterns, as on the command line.
+func (r *Runner) Status(targets []string) ([]RepoStatus, error) {
+	return r.manager(nil).Statuses(targets, r.workers())
+}
+
+// Clone clones the missing repos of the named targets, or of all targets.
+// Repos that are already checked out are left alone and have no result.
+func (r *Runner) Clone(targets []string, opts CloneOptions) ([]RepoResult, error) {
+	var results []RepoResult
+	m := r.manager(&results)
+	m.Mirror = opts.Mirror
+	m.ExcludeTemplates = !opts.IncludeTemplates
+	err := m.Clone(targets, opts.ExcludeEmpty, opts.IncludeArchived, r.workers())
+	return results, err
+}
+
+// Pull updates the default branches of the named targets' repos.
+func (r *Runner) Pull(targets []string) ([]RepoResult, error) {
+	var results []RepoResult
+	err := r.manager(&results).Pull(targets, r.workers())
+	return results, err
+}
+
+// Push pushes the named targets' repos that are ahead of origin.
+func (r *Runner) Push(targets []string) ([]RepoResult, error) {
+	var results []RepoResult
+	err := r.manager(&results).Push(targets, r.workers())
+	return results, err
+}
+
+// Sync syncs the default branches of the named targets' repos, like
+// `tugboat sync`.
+func (r *Runner) Sync(targets []string) ([]RepoResult, error) {
+	var results []RepoResult
+	err := r.manager(&results).Sync(targets, r.workers())
+	return results, err
+}
@@ -0,0 +1,56 @@
+package tugboat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunnerStatusOffline(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "app")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main", dir},
+		{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	cfg, err := ParseConfig([]byte(fmt.Sprintf(`{
+		"providers": {"gh": {"type": "github", "token": "t"}},
+		"targets": [{"provider": "gh", "org": "acme", "repo": "app", "path": %q}],
+		"http_cache": false
+	}`, dir)))
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	r, err := New(cfg, Options{Offline: true, Out: &out})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	statuses, err := r.Status(nil)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Path != dir || statuses[0].Branch != "main" || !statuses[0].Offline {
+		t.Fatalf("Status() = %+v, want the app repo on main, offline", statuses)
+	}
+
+	// The repo has no origin, so the pull fails for it.
+	results, err := r.Pull(nil)
+	var failures *RepoFailures
+	if !errors.As(err, &failures) || failures.Failed != 1 {
+		t.Fatalf("Pull() error = %v, want one repo failure", err)
+	}
+	if len(results) != 1 || results[0].Path != dir || results[0].Result != "failed" {
+		t.Fatalf("Pull() results = %+v, want the app repo failed", results)
+	}
+	if out.Len() == 0 {
+		t.Error("Pull() wrote no progress to Out")
+	}
+}